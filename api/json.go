@@ -0,0 +1,172 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"tuidoo/services"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+	maxBodySize     = 1 << 20
+)
+
+// optional tells a field that was left out of a request body apart from one
+// that was explicitly set to null
+type optional[T any] struct {
+	Set   bool
+	Value *T
+}
+
+func (o *optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	o.Value = &v
+	return nil
+}
+
+// page is the envelope of every list response
+type page[T any] struct {
+	Items  []T   `json:"items"`
+	Total  int64 `json:"total"`
+	Limit  int   `json:"limit"`
+	Offset int   `json:"offset"`
+}
+
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// errInvalid marks request errors that map to 400 Bad Request
+type errInvalid struct{ msg string }
+
+func (e errInvalid) Error() string { return e.msg }
+
+func invalid(format string, args ...any) error {
+	return errInvalid{msg: fmt.Sprintf(format, args...)}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorBody{Error: err.Error()})
+}
+
+// writeServiceError maps service errors onto HTTP status codes
+func writeServiceError(w http.ResponseWriter, err error) {
+	var bad errInvalid
+	switch {
+	case errors.As(err, &bad):
+		writeError(w, http.StatusBadRequest, err)
+	case errors.Is(err, services.ErrNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	case errors.Is(err, services.ErrConflict):
+		writeError(w, http.StatusPreconditionFailed, errors.New("the record was modified since it was read; fetch it again and retry"))
+	case errors.Is(err, services.ErrHookRejected):
+		writeError(w, http.StatusUnprocessableEntity, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func readJSON(r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return invalid("invalid request body: %v", err)
+	}
+	return nil
+}
+
+func pathID(r *http.Request) (uint, error) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil || id == 0 {
+		return 0, invalid("invalid id %q", r.PathValue("id"))
+	}
+	return uint(id), nil
+}
+
+// pageParams reads ?limit= and ?offset=
+func pageParams(r *http.Request) (int, int, error) {
+	limit, offset := defaultPageSize, 0
+
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxPageSize {
+			return 0, 0, invalid("limit must be between 1 and %d", maxPageSize)
+		}
+		limit = n
+	}
+	if s := r.URL.Query().Get("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return 0, 0, invalid("offset must be zero or more")
+		}
+		offset = n
+	}
+
+	return limit, offset, nil
+}
+
+// paginate slices an in-memory result the same way the todo query pages
+func paginate[T any](items []T, limit, offset int) page[T] {
+	total := len(items)
+	if offset > total {
+		offset = total
+	}
+	end := min(offset+limit, total)
+
+	return page[T]{Items: items[offset:end], Total: int64(total), Limit: limit, Offset: offset}
+}
+
+func etag(version string) string {
+	return `"` + version + `"`
+}
+
+// notModified answers 304 when If-None-Match already names version, so the
+// client can keep its copy, and reports whether it did
+func notModified(w http.ResponseWriter, r *http.Request, version string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.Trim(strings.TrimPrefix(tag, "W/"), `"`) == version {
+			w.Header().Set("ETag", etag(version))
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatch returns the version a client expects from the If-Match header, or
+// "" when the header is absent or "*"
+func ifMatch(r *http.Request) string {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "*" {
+		return ""
+	}
+	return strings.Trim(strings.TrimPrefix(v, "W/"), `"`)
+}
@@ -0,0 +1,300 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+	"tuidoo/entities"
+	"tuidoo/services"
+)
+
+// GroupResource is how projects and lists are represented; both only carry
+// a name and a color
+type GroupResource struct {
+	ID        uint      `json:"id" yaml:"id"`
+	UID       string    `json:"uid" yaml:"uid"`
+	Name      string    `json:"name" yaml:"name"`
+	Color     string    `json:"color" yaml:"color"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" yaml:"updated_at"`
+	Version   string    `json:"version" yaml:"version"`
+}
+
+type groupInput struct {
+	Name  optional[string] `json:"name"`
+	Color optional[string] `json:"color"`
+}
+
+// apply copies the present fields onto name and color
+func (in groupInput) apply(name, color *string, required bool) error {
+	if required && in.Name.Value == nil {
+		return invalid("name is required")
+	}
+	if in.Name.Set {
+		if in.Name.Value == nil || *in.Name.Value == "" {
+			return invalid("name cannot be empty")
+		}
+		*name = *in.Name.Value
+	}
+	if in.Color.Set {
+		*color = valueOr(in.Color.Value, "")
+	}
+	return nil
+}
+
+// NewProjectResource represents a project
+func NewProjectResource(p *entities.Project) GroupResource {
+	return GroupResource{
+		ID:        p.ID,
+		UID:       p.UID,
+		Name:      p.Name,
+		Color:     p.Color,
+		CreatedAt: p.CreatedAt,
+		UpdatedAt: p.UpdatedAt,
+		Version:   services.Version(p.UpdatedAt),
+	}
+}
+
+// NewListResource represents a list
+func NewListResource(l *entities.ToDoList) GroupResource {
+	return GroupResource{
+		ID:        l.ID,
+		UID:       l.UID,
+		Name:      l.Name,
+		Color:     l.Color,
+		CreatedAt: l.CreatedAt,
+		UpdatedAt: l.UpdatedAt,
+		Version:   services.Version(l.UpdatedAt),
+	}
+}
+
+func writeGroup(w http.ResponseWriter, status int, res GroupResource) {
+	w.Header().Set("ETag", etag(res.Version))
+	writeJSON(w, status, res)
+}
+
+func (s *Server) listProjects(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := pageParams(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	projects, err := s.services.ProjectService.GetAll(false)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	items := make([]GroupResource, 0, len(projects))
+	for i := range projects {
+		items = append(items, NewProjectResource(&projects[i]))
+	}
+
+	writeJSON(w, http.StatusOK, paginate(items, limit, offset))
+}
+
+func (s *Server) getProject(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	project, err := s.services.ProjectService.GetByID(id, false)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	res := NewProjectResource(project)
+	if notModified(w, r, res.Version) {
+		return
+	}
+	writeGroup(w, http.StatusOK, res)
+}
+
+func (s *Server) createProject(w http.ResponseWriter, r *http.Request) {
+	var input groupInput
+	if err := readJSON(r, &input); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	project := &entities.Project{}
+	if err := input.apply(&project.Name, &project.Color, true); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if err := s.services.ProjectService.Create(project); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/projects/%d", project.ID))
+	writeGroup(w, http.StatusCreated, NewProjectResource(project))
+}
+
+func (s *Server) updateProject(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	var input groupInput
+	if err := readJSON(r, &input); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	project, err := s.services.ProjectService.GetByID(id, false)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		project.Color = ""
+	}
+	if err := input.apply(&project.Name, &project.Color, r.Method == http.MethodPut); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if err := s.services.ProjectService.UpdateChecked(project, ifMatch(r)); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeGroup(w, http.StatusOK, NewProjectResource(project))
+}
+
+func (s *Server) deleteProject(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if err := s.services.ProjectService.DeleteChecked(id, ifMatch(r)); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listLists(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := pageParams(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	lists, err := s.services.ToDoListService.GetAll(false)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	items := make([]GroupResource, 0, len(lists))
+	for i := range lists {
+		items = append(items, NewListResource(&lists[i]))
+	}
+
+	writeJSON(w, http.StatusOK, paginate(items, limit, offset))
+}
+
+func (s *Server) getList(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	list, err := s.services.ToDoListService.GetByID(id, false)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	res := NewListResource(list)
+	if notModified(w, r, res.Version) {
+		return
+	}
+	writeGroup(w, http.StatusOK, res)
+}
+
+func (s *Server) createList(w http.ResponseWriter, r *http.Request) {
+	var input groupInput
+	if err := readJSON(r, &input); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	list := &entities.ToDoList{}
+	if err := input.apply(&list.Name, &list.Color, true); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if err := s.services.ToDoListService.Create(list); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/lists/%d", list.ID))
+	writeGroup(w, http.StatusCreated, NewListResource(list))
+}
+
+func (s *Server) updateList(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	var input groupInput
+	if err := readJSON(r, &input); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	list, err := s.services.ToDoListService.GetByID(id, false)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		list.Color = ""
+	}
+	if err := input.apply(&list.Name, &list.Color, r.Method == http.MethodPut); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if err := s.services.ToDoListService.UpdateChecked(list, ifMatch(r)); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeGroup(w, http.StatusOK, NewListResource(list))
+}
+
+func (s *Server) deleteList(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if err := s.services.ToDoListService.DeleteChecked(id, ifMatch(r)); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
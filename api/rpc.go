@@ -0,0 +1,412 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"tuidoo/services"
+
+	"gorm.io/gorm"
+)
+
+// JSON-RPC 2.0 error codes; -32001 and below are tuidoo's own
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+	rpcNotFound       = -32001
+	rpcConflict       = -32002
+	rpcRejected       = -32003
+)
+
+// rpcQueueSize is how many messages may wait for a slow client before it is
+// disconnected
+const rpcQueueSize = 256
+
+// RPCServer serves JSON-RPC 2.0 on a Unix socket for editor integrations.
+// Messages are newline-delimited JSON, one request, response or notification
+// per line. See docs/rpc.md for the methods.
+type RPCServer struct {
+	api      *Server
+	socket   string
+	methods  map[string]rpcMethod
+	listener net.Listener
+	// stopEvents ends the todos.changed broadcasts
+	stopEvents func()
+
+	mu     sync.Mutex
+	conns  map[*rpcConn]bool
+	closed bool
+}
+
+type rpcMethod func(c *rpcConn, params json.RawMessage) (any, error)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type rpcNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// todoChanged is the payload of the todos.changed notification
+type todoChanged struct {
+	Action string        `json:"action"`
+	ID     uint          `json:"id,omitempty"`
+	ToDo   *ToDoResource `json:"todo,omitempty"`
+}
+
+type rpcConn struct {
+	conn       net.Conn
+	out        chan []byte
+	subscribed atomic.Bool
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func NewRPCServer(sc *services.ServiceCollection, socket string) *RPCServer {
+	rs := &RPCServer{
+		api:    NewServer(sc, Options{}),
+		socket: socket,
+		conns:  map[*rpcConn]bool{},
+	}
+	rs.methods = rs.routes()
+	return rs
+}
+
+// Socket returns the path the server listens on
+func (rs *RPCServer) Socket() string {
+	return rs.socket
+}
+
+// Start listens on the socket and serves clients in the background until
+// Close is called
+func (rs *RPCServer) Start() error {
+	listener, err := listenUnix(rs.socket)
+	if err != nil {
+		return err
+	}
+	rs.listener = listener
+
+	rs.stopEvents = rs.api.services.Events.Subscribe(rs.broadcast)
+
+	go rs.accept()
+
+	log.Printf("🔌 JSON-RPC listening on %s", rs.socket)
+	return nil
+}
+
+// Close stops accepting clients, disconnects the connected ones and removes
+// the socket
+func (rs *RPCServer) Close() error {
+	rs.mu.Lock()
+	if rs.closed {
+		rs.mu.Unlock()
+		return nil
+	}
+	rs.closed = true
+	if rs.stopEvents != nil {
+		rs.stopEvents()
+	}
+	conns := rs.conns
+	rs.conns = map[*rpcConn]bool{}
+	rs.mu.Unlock()
+
+	for c := range conns {
+		c.close()
+	}
+
+	if rs.listener != nil {
+		return rs.listener.Close()
+	}
+	return nil
+}
+
+func (rs *RPCServer) accept() {
+	for {
+		conn, err := rs.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		c := &rpcConn{conn: conn, out: make(chan []byte, rpcQueueSize)}
+
+		rs.mu.Lock()
+		if rs.closed {
+			rs.mu.Unlock()
+			conn.Close()
+			return
+		}
+		rs.conns[c] = true
+		rs.mu.Unlock()
+
+		go c.write()
+		go rs.read(c)
+	}
+}
+
+// read handles one client's requests in order until it disconnects
+func (rs *RPCServer) read(c *rpcConn) {
+	defer func() {
+		rs.mu.Lock()
+		delete(rs.conns, c)
+		rs.mu.Unlock()
+		c.close()
+	}()
+
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 64*1024), maxBodySize)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		if reply := rs.handle(c, line); reply != nil {
+			if !c.send(reply) {
+				return
+			}
+		}
+	}
+}
+
+// handle answers a single request or a batch. Notifications get no reply,
+// so the result is nil when there is nothing to send.
+func (rs *RPCServer) handle(c *rpcConn, message []byte) []byte {
+	if message[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(message, &batch); err != nil {
+			return marshalResponse(errorResponse(nil, rpcParseError, "parse error: "+err.Error()))
+		}
+		if len(batch) == 0 {
+			return marshalResponse(errorResponse(nil, rpcInvalidRequest, "empty batch"))
+		}
+
+		var responses []rpcResponse
+		for _, raw := range batch {
+			if res := rs.call(c, raw); res != nil {
+				responses = append(responses, *res)
+			}
+		}
+		if len(responses) == 0 {
+			return nil
+		}
+
+		data, _ := json.Marshal(responses)
+		return data
+	}
+
+	if res := rs.call(c, message); res != nil {
+		return marshalResponse(*res)
+	}
+	return nil
+}
+
+func (rs *RPCServer) call(c *rpcConn, raw json.RawMessage) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			res := errorResponse(nil, rpcParseError, "parse error: "+err.Error())
+			return &res
+		}
+		res := errorResponse(nil, rpcInvalidRequest, "invalid request: "+err.Error())
+		return &res
+	}
+
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		res := errorResponse(req.ID, rpcInvalidRequest, `invalid request: "jsonrpc" must be "2.0" and "method" is required`)
+		return &res
+	}
+
+	method, ok := rs.methods[req.Method]
+	if !ok {
+		if req.ID == nil {
+			return nil
+		}
+		res := errorResponse(req.ID, rpcMethodNotFound, fmt.Sprintf("method %q not found", req.Method))
+		return &res
+	}
+
+	result, err := method(c, req.Params)
+	if req.ID == nil {
+		return nil
+	}
+	if err != nil {
+		res := rpcErrorResponse(req.ID, err)
+		return &res
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		res := errorResponse(req.ID, rpcInternalError, err.Error())
+		return &res
+	}
+
+	return &rpcResponse{JSONRPC: "2.0", Result: data, ID: req.ID}
+}
+
+// broadcast pushes a todos.changed notification to subscribed clients
+func (rs *RPCServer) broadcast(e services.Event) {
+	params, ok := todoChangedParams(e)
+	if !ok {
+		return
+	}
+
+	rs.mu.Lock()
+	var subscribers []*rpcConn
+	for c := range rs.conns {
+		if c.subscribed.Load() {
+			subscribers = append(subscribers, c)
+		}
+	}
+	rs.mu.Unlock()
+
+	if len(subscribers) == 0 {
+		return
+	}
+
+	if params.Action == "create" || params.Action == "update" {
+		if todo, err := rs.api.services.ToDoService.GetByID(params.ID, true); err == nil {
+			res := NewToDoResource(todo)
+			params.ToDo = &res
+		}
+	}
+
+	data, err := json.Marshal(rpcNotification{JSONRPC: "2.0", Method: "todos.changed", Params: params})
+	if err != nil {
+		return
+	}
+
+	for _, c := range subscribers {
+		c.send(data)
+	}
+}
+
+// todoChangedParams maps an event onto a todos.changed notification;
+// events that do not change todos are not sent
+func todoChangedParams(e services.Event) (todoChanged, bool) {
+	if external, ok := e.(services.ExternalChange); ok {
+		e = external.Event
+	}
+
+	switch e.(type) {
+	case services.TodoCreated:
+		id, _ := services.TodoID(e)
+		return todoChanged{Action: "create", ID: id}, true
+	case services.TodoUpdated, services.TodoCompleted, services.TodoReopened:
+		id, _ := services.TodoID(e)
+		return todoChanged{Action: "update", ID: id}, true
+	case services.TodoDeleted:
+		id, _ := services.TodoID(e)
+		return todoChanged{Action: "delete", ID: id}, true
+	case services.DataImported, services.DataReset:
+		return todoChanged{Action: "import"}, true
+	default:
+		return todoChanged{}, false
+	}
+}
+
+// send queues a message without blocking; a client that falls too far
+// behind is disconnected
+func (c *rpcConn) send(message []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return false
+	}
+
+	select {
+	case c.out <- message:
+		return true
+	default:
+		c.closed = true
+		close(c.out)
+		return false
+	}
+}
+
+func (c *rpcConn) write() {
+	w := bufio.NewWriter(c.conn)
+	for message := range c.out {
+		w.Write(message)
+		w.WriteByte('\n')
+		if len(c.out) == 0 {
+			if err := w.Flush(); err != nil {
+				c.conn.Close()
+				return
+			}
+		}
+	}
+	w.Flush()
+	c.conn.Close()
+}
+
+// close lets the writer flush what is queued and hang up
+func (c *rpcConn) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.out)
+	}
+}
+
+func marshalResponse(res rpcResponse) []byte {
+	data, _ := json.Marshal(res)
+	return data
+}
+
+func errorResponse(id json.RawMessage, code int, message string) rpcResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return rpcResponse{JSONRPC: "2.0", Error: &rpcError{Code: code, Message: message}, ID: id}
+}
+
+// rpcErrorResponse maps service errors onto JSON-RPC error codes the same
+// way writeServiceError maps them onto HTTP status codes
+func rpcErrorResponse(id json.RawMessage, err error) rpcResponse {
+	var bad errInvalid
+	switch {
+	case errors.As(err, &bad):
+		return errorResponse(id, rpcInvalidParams, err.Error())
+	case errors.Is(err, services.ErrNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		return errorResponse(id, rpcNotFound, "not found")
+	case errors.Is(err, services.ErrConflict):
+		return errorResponse(id, rpcConflict, "the record was modified since it was read; fetch it again and retry")
+	case errors.Is(err, services.ErrHookRejected):
+		return errorResponse(id, rpcRejected, err.Error())
+	default:
+		return errorResponse(id, rpcInternalError, err.Error())
+	}
+}
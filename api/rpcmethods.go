@@ -0,0 +1,479 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+	"tuidoo/entities"
+	"tuidoo/enums"
+	"tuidoo/formats"
+	"tuidoo/services"
+)
+
+type idParams struct {
+	ID      uint   `json:"id"`
+	Version string `json:"version"`
+}
+
+type pageRequest struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+type todoListParams struct {
+	Project   string          `json:"project"`
+	List      string          `json:"list"`
+	Status    *enums.Status   `json:"status"`
+	Priority  *enums.Priority `json:"priority"`
+	Done      *bool           `json:"done"`
+	Search    string          `json:"q"`
+	DueBefore string          `json:"due_before"`
+	DueAfter  string          `json:"due_after"`
+	pageRequest
+}
+
+type todoUpdateParams struct {
+	idParams
+	todoInput
+}
+
+type groupUpdateParams struct {
+	idParams
+	groupInput
+}
+
+func (rs *RPCServer) routes() map[string]rpcMethod {
+	return map[string]rpcMethod{
+		"ping": rs.ping,
+
+		"todos.list":        rs.listToDos,
+		"todos.get":         rs.getToDo,
+		"todos.create":      rs.createToDo,
+		"todos.update":      rs.updateToDo,
+		"todos.complete":    rs.completeToDo,
+		"todos.reopen":      rs.reopenToDo,
+		"todos.delete":      rs.deleteToDo,
+		"todos.subscribe":   rs.subscribe,
+		"todos.unsubscribe": rs.unsubscribe,
+
+		"projects.list":   rs.listProjects,
+		"projects.get":    rs.getProject,
+		"projects.create": rs.createProject,
+		"projects.update": rs.updateProject,
+		"projects.delete": rs.deleteProject,
+
+		"lists.list":   rs.listLists,
+		"lists.get":    rs.getList,
+		"lists.create": rs.createList,
+		"lists.update": rs.updateList,
+		"lists.delete": rs.deleteList,
+	}
+}
+
+// decodeParams reads by-name params; missing params decode as an empty object
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(params))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return invalid("invalid params: %v", err)
+	}
+	return nil
+}
+
+func requireID(p idParams) error {
+	if p.ID == 0 {
+		return invalid("id is required")
+	}
+	return nil
+}
+
+func (p pageRequest) validate() (int, int, error) {
+	limit := p.Limit
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+	if limit < 1 || limit > maxPageSize {
+		return 0, 0, invalid("limit must be between 1 and %d", maxPageSize)
+	}
+	if p.Offset < 0 {
+		return 0, 0, invalid("offset must be zero or more")
+	}
+	return limit, p.Offset, nil
+}
+
+func (rs *RPCServer) ping(c *rpcConn, params json.RawMessage) (any, error) {
+	return map[string]string{"profile": rs.api.services.Selection.Profile}, nil
+}
+
+func (rs *RPCServer) listToDos(c *rpcConn, params json.RawMessage) (any, error) {
+	var p todoListParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	limit, offset, err := p.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	filter := services.ToDoFilter{
+		Project:  p.Project,
+		List:     p.List,
+		Status:   p.Status,
+		Priority: p.Priority,
+		Done:     p.Done,
+		Search:   p.Search,
+	}
+	if filter.DueBefore, err = parseOptionalDate("due_before", p.DueBefore); err != nil {
+		return nil, err
+	}
+	if filter.DueAfter, err = parseOptionalDate("due_after", p.DueAfter); err != nil {
+		return nil, err
+	}
+
+	todoService := rs.api.services.ToDoService
+	total, err := todoService.CountMatching(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.Limit, filter.Offset = limit, offset
+	todos, err := todoService.Find(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]ToDoResource, 0, len(todos))
+	for i := range todos {
+		items = append(items, NewToDoResource(&todos[i]))
+	}
+
+	return page[ToDoResource]{Items: items, Total: total, Limit: limit, Offset: offset}, nil
+}
+
+func (rs *RPCServer) getToDo(c *rpcConn, params json.RawMessage) (any, error) {
+	var p idParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if err := requireID(p); err != nil {
+		return nil, err
+	}
+	return rs.loadToDo(p.ID)
+}
+
+func (rs *RPCServer) createToDo(c *rpcConn, params json.RawMessage) (any, error) {
+	var input todoInput
+	if err := decodeParams(params, &input); err != nil {
+		return nil, err
+	}
+
+	todo := &entities.ToDo{}
+	if err := rs.api.applyToDoInput(todo, input, true); err != nil {
+		return nil, err
+	}
+
+	if err := rs.api.services.ToDoService.Create(todo); err != nil {
+		return nil, err
+	}
+
+	return rs.loadToDo(todo.ID)
+}
+
+// updateToDo changes the fields present in params, like PATCH /todos/{id}
+func (rs *RPCServer) updateToDo(c *rpcConn, params json.RawMessage) (any, error) {
+	var p todoUpdateParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if err := requireID(p.idParams); err != nil {
+		return nil, err
+	}
+
+	todo, err := rs.api.services.ToDoService.GetByID(p.ID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rs.api.applyToDoInput(todo, p.todoInput, false); err != nil {
+		return nil, err
+	}
+
+	if err := rs.api.services.ToDoService.UpdateChecked(todo, p.Version); err != nil {
+		return nil, err
+	}
+
+	return rs.loadToDo(todo.ID)
+}
+
+func (rs *RPCServer) completeToDo(c *rpcConn, params json.RawMessage) (any, error) {
+	var p idParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if err := requireID(p); err != nil {
+		return nil, err
+	}
+
+	if err := rs.api.services.ToDoService.MarkAsCompleteChecked(p.ID, p.Version); err != nil {
+		return nil, err
+	}
+	return rs.loadToDo(p.ID)
+}
+
+func (rs *RPCServer) reopenToDo(c *rpcConn, params json.RawMessage) (any, error) {
+	var p idParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if err := requireID(p); err != nil {
+		return nil, err
+	}
+
+	if err := rs.api.services.ToDoService.MarkAsIncompleteChecked(p.ID, p.Version); err != nil {
+		return nil, err
+	}
+	return rs.loadToDo(p.ID)
+}
+
+func (rs *RPCServer) deleteToDo(c *rpcConn, params json.RawMessage) (any, error) {
+	var p idParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if err := requireID(p); err != nil {
+		return nil, err
+	}
+
+	if err := rs.api.services.ToDoService.DeleteChecked(p.ID, p.Version); err != nil {
+		return nil, err
+	}
+	return true, nil
+}
+
+// subscribe starts todos.changed notifications on this connection
+func (rs *RPCServer) subscribe(c *rpcConn, params json.RawMessage) (any, error) {
+	c.subscribed.Store(true)
+	return true, nil
+}
+
+func (rs *RPCServer) unsubscribe(c *rpcConn, params json.RawMessage) (any, error) {
+	c.subscribed.Store(false)
+	return true, nil
+}
+
+func (rs *RPCServer) listProjects(c *rpcConn, params json.RawMessage) (any, error) {
+	var p pageRequest
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	limit, offset, err := p.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	projects, err := rs.api.services.ProjectService.GetAll(false)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]GroupResource, 0, len(projects))
+	for i := range projects {
+		items = append(items, NewProjectResource(&projects[i]))
+	}
+
+	return paginate(items, limit, offset), nil
+}
+
+func (rs *RPCServer) getProject(c *rpcConn, params json.RawMessage) (any, error) {
+	var p idParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if err := requireID(p); err != nil {
+		return nil, err
+	}
+
+	project, err := rs.api.services.ProjectService.GetByID(p.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	return NewProjectResource(project), nil
+}
+
+func (rs *RPCServer) createProject(c *rpcConn, params json.RawMessage) (any, error) {
+	var input groupInput
+	if err := decodeParams(params, &input); err != nil {
+		return nil, err
+	}
+
+	project := &entities.Project{}
+	if err := input.apply(&project.Name, &project.Color, true); err != nil {
+		return nil, err
+	}
+
+	if err := rs.api.services.ProjectService.Create(project); err != nil {
+		return nil, err
+	}
+	return NewProjectResource(project), nil
+}
+
+func (rs *RPCServer) updateProject(c *rpcConn, params json.RawMessage) (any, error) {
+	var p groupUpdateParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if err := requireID(p.idParams); err != nil {
+		return nil, err
+	}
+
+	project, err := rs.api.services.ProjectService.GetByID(p.ID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.apply(&project.Name, &project.Color, false); err != nil {
+		return nil, err
+	}
+
+	if err := rs.api.services.ProjectService.UpdateChecked(project, p.Version); err != nil {
+		return nil, err
+	}
+	return NewProjectResource(project), nil
+}
+
+func (rs *RPCServer) deleteProject(c *rpcConn, params json.RawMessage) (any, error) {
+	var p idParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if err := requireID(p); err != nil {
+		return nil, err
+	}
+
+	if err := rs.api.services.ProjectService.DeleteChecked(p.ID, p.Version); err != nil {
+		return nil, err
+	}
+	return true, nil
+}
+
+func (rs *RPCServer) listLists(c *rpcConn, params json.RawMessage) (any, error) {
+	var p pageRequest
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	limit, offset, err := p.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	lists, err := rs.api.services.ToDoListService.GetAll(false)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]GroupResource, 0, len(lists))
+	for i := range lists {
+		items = append(items, NewListResource(&lists[i]))
+	}
+
+	return paginate(items, limit, offset), nil
+}
+
+func (rs *RPCServer) getList(c *rpcConn, params json.RawMessage) (any, error) {
+	var p idParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if err := requireID(p); err != nil {
+		return nil, err
+	}
+
+	list, err := rs.api.services.ToDoListService.GetByID(p.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	return NewListResource(list), nil
+}
+
+func (rs *RPCServer) createList(c *rpcConn, params json.RawMessage) (any, error) {
+	var input groupInput
+	if err := decodeParams(params, &input); err != nil {
+		return nil, err
+	}
+
+	list := &entities.ToDoList{}
+	if err := input.apply(&list.Name, &list.Color, true); err != nil {
+		return nil, err
+	}
+
+	if err := rs.api.services.ToDoListService.Create(list); err != nil {
+		return nil, err
+	}
+	return NewListResource(list), nil
+}
+
+func (rs *RPCServer) updateList(c *rpcConn, params json.RawMessage) (any, error) {
+	var p groupUpdateParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if err := requireID(p.idParams); err != nil {
+		return nil, err
+	}
+
+	list, err := rs.api.services.ToDoListService.GetByID(p.ID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.apply(&list.Name, &list.Color, false); err != nil {
+		return nil, err
+	}
+
+	if err := rs.api.services.ToDoListService.UpdateChecked(list, p.Version); err != nil {
+		return nil, err
+	}
+	return NewListResource(list), nil
+}
+
+func (rs *RPCServer) deleteList(c *rpcConn, params json.RawMessage) (any, error) {
+	var p idParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if err := requireID(p); err != nil {
+		return nil, err
+	}
+
+	if err := rs.api.services.ToDoListService.DeleteChecked(p.ID, p.Version); err != nil {
+		return nil, err
+	}
+	return true, nil
+}
+
+func (rs *RPCServer) loadToDo(id uint) (ToDoResource, error) {
+	todo, err := rs.api.services.ToDoService.GetByID(id, true)
+	if err != nil {
+		return ToDoResource{}, err
+	}
+	return NewToDoResource(todo), nil
+}
+
+func parseOptionalDate(name, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := formats.ParseDate(value)
+	if err != nil {
+		return nil, invalid("%s: %v", name, err)
+	}
+	return &t, nil
+}
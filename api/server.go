@@ -0,0 +1,258 @@
+// Package api serves the tuidoo REST API
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"tuidoo/services"
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// DefaultAddr is where the API listens when neither an address nor a socket
+// is given
+const DefaultAddr = "127.0.0.1:7878"
+
+// Options configures the API server
+type Options struct {
+	// Addr is a loopback host:port to listen on
+	Addr string
+	// Socket is a Unix socket path; it takes precedence over Addr
+	Socket string
+	// Token, when set, must be sent as "Authorization: Bearer <token>"
+	Token string
+	// CORSOrigin, when set, is allowed to call the API from a browser
+	CORSOrigin string
+}
+
+type Server struct {
+	services *services.ServiceCollection
+	options  Options
+}
+
+func NewServer(sc *services.ServiceCollection, options Options) *Server {
+	if options.Addr == "" {
+		options.Addr = DefaultAddr
+	}
+	return &Server{services: sc, options: options}
+}
+
+// Handler returns the API routes wrapped in logging, CORS and auth
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/v1/health", s.health)
+	mux.HandleFunc("GET /api/v1/openapi.yaml", s.openAPI)
+
+	mux.HandleFunc("GET /api/v1/todos", s.listToDos)
+	mux.HandleFunc("POST /api/v1/todos", s.createToDo)
+	mux.HandleFunc("GET /api/v1/todos/{id}", s.getToDo)
+	mux.HandleFunc("PUT /api/v1/todos/{id}", s.replaceToDo)
+	mux.HandleFunc("PATCH /api/v1/todos/{id}", s.patchToDo)
+	mux.HandleFunc("DELETE /api/v1/todos/{id}", s.deleteToDo)
+	mux.HandleFunc("POST /api/v1/todos/{id}/complete", s.completeToDo)
+	mux.HandleFunc("POST /api/v1/todos/{id}/reopen", s.reopenToDo)
+
+	mux.HandleFunc("GET /api/v1/projects", s.listProjects)
+	mux.HandleFunc("POST /api/v1/projects", s.createProject)
+	mux.HandleFunc("GET /api/v1/projects/{id}", s.getProject)
+	mux.HandleFunc("PUT /api/v1/projects/{id}", s.updateProject)
+	mux.HandleFunc("PATCH /api/v1/projects/{id}", s.updateProject)
+	mux.HandleFunc("DELETE /api/v1/projects/{id}", s.deleteProject)
+
+	mux.HandleFunc("GET /api/v1/lists", s.listLists)
+	mux.HandleFunc("POST /api/v1/lists", s.createList)
+	mux.HandleFunc("GET /api/v1/lists/{id}", s.getList)
+	mux.HandleFunc("PUT /api/v1/lists/{id}", s.updateList)
+	mux.HandleFunc("PATCH /api/v1/lists/{id}", s.updateList)
+	mux.HandleFunc("DELETE /api/v1/lists/{id}", s.deleteList)
+
+	return s.logRequests(s.cors(s.authenticate(mux)))
+}
+
+// ListenAndServe serves until ctx is cancelled, then shuts down gracefully
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	listener, err := s.listen()
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() { errs <- server.Serve(listener) }()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("🛑 Shutting down API server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if s.options.Socket != "" {
+		os.Remove(s.options.Socket)
+	}
+	return nil
+}
+
+func (s *Server) listen() (net.Listener, error) {
+	if s.options.Socket != "" {
+		listener, err := listenUnix(s.options.Socket)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Printf("🌐 API listening on unix:%s", s.options.Socket)
+		return listener, nil
+	}
+
+	if err := requireLoopback(s.options.Addr); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", s.options.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", s.options.Addr, err)
+	}
+
+	log.Printf("🌐 API listening on http://%s/api/v1", listener.Addr())
+	return listener, nil
+}
+
+// listenUnix listens on a socket only the current user can connect to
+func listenUnix(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	// A socket file left behind by a crashed server would block the bind
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s is in use by another server", path)
+	}
+	os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	return listener, nil
+}
+
+// requireLoopback refuses addresses reachable from other machines
+func requireLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %w", addr, err)
+	}
+
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+
+	return fmt.Errorf("refusing to listen on %s: only loopback addresses are allowed", addr)
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.options.Token == "" || r.Method == http.MethodOptions || isPublic(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.options.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="tuidoo"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isPublic(path string) bool {
+	return path == "/api/v1/health" || path == "/api/v1/openapi.yaml"
+}
+
+func (s *Server) cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.options.CORSOrigin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("Access-Control-Allow-Origin", s.options.CORSOrigin)
+		h.Set("Access-Control-Allow-Headers", "Authorization, Content-Type, If-Match, If-None-Match")
+		h.Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		h.Set("Access-Control-Expose-Headers", "ETag")
+		h.Add("Vary", "Origin")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (s *Server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		log.Printf("%s %s %d %s", r.Method, r.URL.RequestURI(), rec.status, time.Since(start).Round(time.Millisecond))
+	})
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status":  "ok",
+		"profile": s.services.Selection.Profile,
+	})
+}
+
+func (s *Server) openAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(openAPISpec)
+}
@@ -0,0 +1,381 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+	"tuidoo/entities"
+	"tuidoo/enums"
+	"tuidoo/formats"
+	"tuidoo/services"
+)
+
+// ToDoResource is how a todo is represented by the API and by the CLI's
+// json, ndjson and yaml output
+type ToDoResource struct {
+	ID          uint           `json:"id" yaml:"id"`
+	UID         string         `json:"uid" yaml:"uid"`
+	Name        string         `json:"name" yaml:"name"`
+	Description *string        `json:"description" yaml:"description"`
+	Details     *string        `json:"details" yaml:"details"`
+	ProjectID   uint           `json:"project_id" yaml:"project_id"`
+	Project     string         `json:"project" yaml:"project"`
+	ListID      *uint          `json:"list_id" yaml:"list_id"`
+	List        *string        `json:"list" yaml:"list"`
+	Priority    enums.Priority `json:"priority" yaml:"priority"`
+	Status      enums.Status   `json:"status" yaml:"status"`
+	Color       string         `json:"color" yaml:"color"`
+	Done        bool           `json:"done" yaml:"done"`
+	DueDate     *time.Time     `json:"due_date" yaml:"due_date"`
+	CreatedAt   time.Time      `json:"created_at" yaml:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at" yaml:"updated_at"`
+	Version     string         `json:"version" yaml:"version"`
+}
+
+// todoInput is the body of POST, PUT and PATCH. PATCH only changes the
+// fields that are present; PUT resets missing ones to their defaults.
+type todoInput struct {
+	Name        optional[string]         `json:"name"`
+	Description optional[string]         `json:"description"`
+	Details     optional[string]         `json:"details"`
+	ProjectID   optional[uint]           `json:"project_id"`
+	ListID      optional[uint]           `json:"list_id"`
+	Priority    optional[enums.Priority] `json:"priority"`
+	Status      optional[enums.Status]   `json:"status"`
+	Color       optional[string]         `json:"color"`
+	Done        optional[bool]           `json:"done"`
+	DueDate     optional[time.Time]      `json:"due_date"`
+}
+
+// NewToDoResource represents a todo loaded with its project and list
+func NewToDoResource(todo *entities.ToDo) ToDoResource {
+	res := ToDoResource{
+		ID:          todo.ID,
+		UID:         todo.UID,
+		Name:        todo.Name,
+		Description: todo.Description,
+		Details:     todo.Details,
+		ProjectID:   todo.ProjectID,
+		Project:     todo.Project.Name,
+		Priority:    todo.Priority,
+		Status:      todo.Status,
+		Color:       todo.Color,
+		Done:        todo.Done,
+		DueDate:     todo.DueDate,
+		CreatedAt:   todo.CreatedAt,
+		UpdatedAt:   todo.UpdatedAt,
+		Version:     services.Version(todo.UpdatedAt),
+	}
+
+	if todo.ToDoListID != 0 {
+		listID, list := todo.ToDoListID, todo.ToDoList.Name
+		res.ListID, res.List = &listID, &list
+	}
+
+	return res
+}
+
+func (s *Server) listToDos(w http.ResponseWriter, r *http.Request) {
+	filter, err := todoFilter(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	limit, offset, err := pageParams(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	total, err := s.services.ToDoService.CountMatching(filter)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	filter.Limit, filter.Offset = limit, offset
+	todos, err := s.services.ToDoService.Find(filter)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	items := make([]ToDoResource, 0, len(todos))
+	for i := range todos {
+		items = append(items, NewToDoResource(&todos[i]))
+	}
+
+	writeJSON(w, http.StatusOK, page[ToDoResource]{Items: items, Total: total, Limit: limit, Offset: offset})
+}
+
+func (s *Server) getToDo(w http.ResponseWriter, r *http.Request) {
+	todo, ok := s.loadToDo(w, r)
+	if !ok {
+		return
+	}
+	if notModified(w, r, services.Version(todo.UpdatedAt)) {
+		return
+	}
+	s.writeToDo(w, http.StatusOK, todo)
+}
+
+func (s *Server) createToDo(w http.ResponseWriter, r *http.Request) {
+	var input todoInput
+	if err := readJSON(r, &input); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	todo := &entities.ToDo{}
+	if err := s.applyToDoInput(todo, input, true); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if err := s.services.ToDoService.Create(todo); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	created, err := s.services.ToDoService.GetByID(todo.ID, true)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/todos/%d", created.ID))
+	s.writeToDo(w, http.StatusCreated, created)
+}
+
+func (s *Server) replaceToDo(w http.ResponseWriter, r *http.Request) {
+	s.updateToDo(w, r, true)
+}
+
+func (s *Server) patchToDo(w http.ResponseWriter, r *http.Request) {
+	s.updateToDo(w, r, false)
+}
+
+func (s *Server) updateToDo(w http.ResponseWriter, r *http.Request, replace bool) {
+	todo, ok := s.loadToDo(w, r)
+	if !ok {
+		return
+	}
+
+	var input todoInput
+	if err := readJSON(r, &input); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if replace {
+		*todo = entities.ToDo{Model: todo.Model, UID: todo.UID}
+	}
+	if err := s.applyToDoInput(todo, input, replace); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if err := s.services.ToDoService.UpdateChecked(todo, ifMatch(r)); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	s.respondWithToDo(w, todo.ID)
+}
+
+func (s *Server) deleteToDo(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if err := s.services.ToDoService.DeleteChecked(id, ifMatch(r)); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) completeToDo(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if err := s.services.ToDoService.MarkAsCompleteChecked(id, ifMatch(r)); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	s.respondWithToDo(w, id)
+}
+
+func (s *Server) reopenToDo(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if err := s.services.ToDoService.MarkAsIncompleteChecked(id, ifMatch(r)); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	s.respondWithToDo(w, id)
+}
+
+func (s *Server) loadToDo(w http.ResponseWriter, r *http.Request) (*entities.ToDo, bool) {
+	id, err := pathID(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return nil, false
+	}
+
+	todo, err := s.services.ToDoService.GetByID(id, true)
+	if err != nil {
+		writeServiceError(w, err)
+		return nil, false
+	}
+
+	return todo, true
+}
+
+func (s *Server) respondWithToDo(w http.ResponseWriter, id uint) {
+	todo, err := s.services.ToDoService.GetByID(id, true)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	s.writeToDo(w, http.StatusOK, todo)
+}
+
+func (s *Server) writeToDo(w http.ResponseWriter, status int, todo *entities.ToDo) {
+	res := NewToDoResource(todo)
+	w.Header().Set("ETag", etag(res.Version))
+	writeJSON(w, status, res)
+}
+
+// applyToDoInput copies the fields present in input onto todo. With
+// required set, name and project_id must be present.
+func (s *Server) applyToDoInput(todo *entities.ToDo, input todoInput, required bool) error {
+	if required && (input.Name.Value == nil || input.ProjectID.Value == nil) {
+		return invalid("name and project_id are required")
+	}
+
+	if input.Name.Set {
+		if input.Name.Value == nil || *input.Name.Value == "" {
+			return invalid("name cannot be empty")
+		}
+		todo.Name = *input.Name.Value
+	}
+	if input.ProjectID.Set {
+		if input.ProjectID.Value == nil {
+			return invalid("project_id cannot be null")
+		}
+		if _, err := s.services.ProjectService.GetByID(*input.ProjectID.Value, false); err != nil {
+			return invalid("unknown project_id %d", *input.ProjectID.Value)
+		}
+		todo.ProjectID = *input.ProjectID.Value
+		todo.Project = entities.Project{}
+	}
+	if input.ListID.Set {
+		todo.ToDoListID = 0
+		if input.ListID.Value != nil && *input.ListID.Value != 0 {
+			if _, err := s.services.ToDoListService.GetByID(*input.ListID.Value, false); err != nil {
+				return invalid("unknown list_id %d", *input.ListID.Value)
+			}
+			todo.ToDoListID = *input.ListID.Value
+		}
+		todo.ToDoList = entities.ToDoList{}
+	}
+	if input.Description.Set {
+		todo.Description = emptyToNil(input.Description.Value)
+	}
+	if input.Details.Set {
+		todo.Details = emptyToNil(input.Details.Value)
+	}
+	if input.Priority.Set {
+		todo.Priority = valueOr(input.Priority.Value, enums.Low)
+	}
+	if input.Status.Set {
+		todo.Status = valueOr(input.Status.Value, enums.New)
+	}
+	if input.Color.Set {
+		todo.Color = valueOr(input.Color.Value, "")
+	}
+	if input.Done.Set {
+		todo.Done = valueOr(input.Done.Value, false)
+	}
+	if input.DueDate.Set {
+		todo.DueDate = input.DueDate.Value
+	}
+
+	return nil
+}
+
+// todoFilter reads the filter query parameters of GET /todos
+func todoFilter(r *http.Request) (services.ToDoFilter, error) {
+	q := r.URL.Query()
+	filter := services.ToDoFilter{
+		Project: q.Get("project"),
+		List:    q.Get("list"),
+		Search:  q.Get("q"),
+	}
+
+	if s := q.Get("status"); s != "" {
+		status, err := enums.ParseStatus(s)
+		if err != nil {
+			return filter, invalid("%v", err)
+		}
+		filter.Status = &status
+	}
+	if s := q.Get("priority"); s != "" {
+		priority, err := enums.ParsePriority(s)
+		if err != nil {
+			return filter, invalid("%v", err)
+		}
+		filter.Priority = &priority
+	}
+	if s := q.Get("done"); s != "" {
+		done, err := formats.ParseBool(s)
+		if err != nil {
+			return filter, invalid("done: %v", err)
+		}
+		filter.Done = &done
+	}
+	if s := q.Get("due_before"); s != "" {
+		t, err := formats.ParseDate(s)
+		if err != nil {
+			return filter, invalid("due_before: %v", err)
+		}
+		filter.DueBefore = &t
+	}
+	if s := q.Get("due_after"); s != "" {
+		t, err := formats.ParseDate(s)
+		if err != nil {
+			return filter, invalid("due_after: %v", err)
+		}
+		filter.DueAfter = &t
+	}
+
+	return filter, nil
+}
+
+func emptyToNil(s *string) *string {
+	if s == nil || *s == "" {
+		return nil
+	}
+	return s
+}
+
+func valueOr[T any](v *T, fallback T) T {
+	if v == nil {
+		return fallback
+	}
+	return *v
+}
@@ -1,27 +1,142 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
 
+	"tuidoo/config"
 	"tuidoo/internal/app"
+	tuiapp "tuidoo/tui/app"
 )
 
 func main() {
+	flags := flag.NewFlagSet("tuidoo", flag.ExitOnError)
+	dbPath := flags.String("db", "", "path to the database file (overrides TUIDOO_DB and the profile)")
+	profile := flags.String("profile", "", "profile to use (overrides TUIDOO_PROFILE)")
+	flags.Usage = printHelp
+	flags.Parse(os.Args[1:])
+
+	// config works on the file itself, so it must not need a valid one
+	if flags.Arg(0) == "config" {
+		if err := app.Config(flags.Args()[1:]); err != nil {
+			fail("Config", err)
+		}
+		return
+	}
+
+	cfg, sel, err := config.Resolve(*dbPath, *profile)
+	if err != nil {
+		log.Fatalf("Configuration error: %v", err)
+	}
+
 	// Check for subcommands
-	if len(os.Args) > 1 {
-		command := os.Args[1]
+	if flags.NArg() > 0 {
+		command := flags.Arg(0)
 
 		switch command {
 		case "seed":
-			app.SeedDatabase()
+			app.SeedDatabase(sel)
 			return
 		case "reset":
-			app.ResetDatabase()
+			app.ResetDatabase(sel)
 			return
 		case "clean":
-			app.CleanDatabase()
+			app.CleanDatabase(sel)
+			return
+		case "migrate":
+			if err := app.Migrate(sel, flags.Args()[1:]); err != nil {
+				log.Fatalf("❌ Migrate failed: %v", err)
+			}
+			return
+		case "backup":
+			if err := app.Backup(sel, flags.Args()[1:]); err != nil {
+				log.Fatalf("❌ Backup failed: %v", err)
+			}
+			return
+		case "restore":
+			if err := app.Restore(sel, flags.Args()[1:]); err != nil {
+				log.Fatalf("❌ Restore failed: %v", err)
+			}
+			return
+		case "export":
+			if err := app.Export(sel, flags.Args()[1:]); err != nil {
+				log.Fatalf("❌ Export failed: %v", err)
+			}
+			return
+		case "import":
+			if err := app.Import(sel, flags.Args()[1:]); err != nil {
+				log.Fatalf("❌ Import failed: %v", err)
+			}
+			return
+		case "sync":
+			if err := app.Sync(sel); err != nil {
+				log.Fatalf("❌ Sync failed: %v", err)
+			}
+			return
+		case "serve":
+			if err := app.Serve(sel, flags.Args()[1:]); err != nil {
+				log.Fatalf("❌ Serve failed: %v", err)
+			}
+			return
+		case "rpc":
+			if err := app.RPC(sel, flags.Args()[1:]); err != nil {
+				log.Fatalf("❌ RPC failed: %v", err)
+			}
+			return
+		case "webhooks":
+			if err := app.Webhooks(sel, flags.Args()[1:]); err != nil {
+				log.Fatalf("❌ Webhooks failed: %v", err)
+			}
+			return
+		case "add":
+			if err := app.Add(sel, flags.Args()[1:]); err != nil {
+				fail("Add", err)
+			}
+			return
+		case "list":
+			if err := app.List(sel, flags.Args()[1:]); err != nil {
+				fail("List", err)
+			}
+			return
+		case "show":
+			if err := app.Show(sel, flags.Args()[1:]); err != nil {
+				fail("Show", err)
+			}
+			return
+		case "edit":
+			if err := app.Edit(sel, flags.Args()[1:]); err != nil {
+				fail("Edit", err)
+			}
+			return
+		case "done":
+			if err := app.Done(sel, flags.Args()[1:]); err != nil {
+				fail("Done", err)
+			}
+			return
+		case "undone":
+			if err := app.Undone(sel, flags.Args()[1:]); err != nil {
+				fail("Undone", err)
+			}
+			return
+		case "rm":
+			if err := app.Remove(sel, flags.Args()[1:]); err != nil {
+				fail("Remove", err)
+			}
+			return
+		case "project":
+			if err := app.Projects(sel, flags.Args()[1:]); err != nil {
+				fail("Project", err)
+			}
+			return
+		case "completion":
+			if err := app.Completion(flags.Args()[1:]); err != nil {
+				fail("Completion", err)
+			}
+			return
+		case "__complete":
+			app.Complete(flags.Args()[1:])
 			return
 		case "help", "-h", "--help":
 			printHelp()
@@ -37,30 +152,114 @@ func main() {
 	}
 
 	// No command provided - run TUI app
-	if err := app.RunTUI(); err != nil {
+	if err := tuiapp.RunTUI(cfg, sel); err != nil {
 		log.Fatalf("Application error: %v", err)
 	}
 }
 
+// fail reports a failed task command and exits with the code that tells
+// scripts what went wrong
+func fail(command string, err error) {
+	log.Printf("❌ %s failed: %v", command, err)
+	os.Exit(app.ExitCode(err))
+}
+
 func printHelp() {
 	fmt.Println(`TUIDOO - Terminal UI Todo Application
 
 Usage:
-  tuidoo                Run the TUI application (default)
-  tuidoo seed           Seed the database with sample data
-  tuidoo reset          Reset and reseed the database
-  tuidoo clean          Clean all data from the database
-  tuidoo help           Show this help message
-  tuidoo version        Show version information
+  tuidoo [options] [command]
+
+Commands:
+  (none)                Run the TUI application (default)
+  add NAME...           Add a task (--project, or defaults.project in the config;
+                        --list, --priority, --status, --due, --description;
+                        --quiet prints the ID)
+  list                  List tasks (--project, --list, --status, --priority,
+                        --done, --search, --due-before, --due-after, --week,
+                        --limit)
+  show ID               Show a task
+                        list, show and ls take --output table|json|ndjson|yaml|
+                        tsv or --format '{{.ID}} {{.Name}}' (a Go template)
+  edit ID               Change a task (--name, --project, --priority, ...)
+  done ID...            Mark tasks done
+  undone ID...          Reopen tasks
+  rm ID...              Delete tasks
+  project [ls]          List projects with their task counts
+  project add|edit|rm   Add, rename or recolor, or delete a project by ID or name
+  list ls|add|edit|rm   The same for lists
+  seed                  Seed the database with sample data
+  reset                 Reset and reseed the database
+  clean                 Clean all data from the database
+  migrate status        Show applied and pending schema migrations
+  migrate up [--to N]   Apply pending migrations (up to version N)
+  migrate down [--steps N] [--force]
+                        Revert the last N migrations (default 1); the
+                        initial schema, which holds all data, needs --force
+  backup [create]       Back up the database and apply retention
+  backup list           List backups, newest first
+  backup prune          Delete backups outside the retention policy
+  restore FILE|latest   Verify a backup and restore it (--yes skips the prompt)
+  export                Export data (--format json|csv|markdown|todotxt|ical|
+                        taskwarrior, --out FILE, filters; see export -h)
+  import FILE           Import a tuidoo json, csv, markdown, todo.txt, .ics or
+                        Taskwarrior file (--dry-run; see import -h)
+  sync                  Sync the configured todo.txt file both ways
+  serve                 Serve the REST API on 127.0.0.1:7878 (--addr, --socket,
+                        --token; see serve -h)
+  rpc                   Serve the JSON-RPC socket without the TUI (--path
+                        prints the socket path)
+  webhooks [list]       Show the webhook delivery log (--state, --limit)
+  webhooks show ID      Show a delivery with its payload and last error
+  webhooks replay ID... Send deliveries again (--failed replays all failed)
+  config get [KEY]      Print a setting such as ui.theme, or all of them
+  config set KEY VALUE  Change a setting in the config file ("" removes it)
+  config path           Print where the config file is
+  config validate       Check the config file and list every problem
+  completion SHELL      Print the bash, zsh or fish completion script
+  help                  Show this help message
+  version               Show version information
 
 Options:
+  --db PATH             Use the database at PATH
+  --profile NAME        Use the named profile (e.g. work, personal)
   -h, --help            Show help
   -v, --version         Show version
 
+Exit codes (task, project and list commands):
+  0 success, 1 failure, 2 bad arguments, 3 not found, 4 rejected by a hook
+
+Database location (first match wins):
+  --db, $TUIDOO_DB, the profile's "db" in the config file,
+  then $XDG_DATA_HOME/tuidoo (profiles live in profiles/<name>.db)
+
+Backups:
+  Written to $XDG_DATA_HOME/tuidoo/backups/<profile> (config: backup.dir),
+  keeping 7 daily and 4 weekly (backup.keep_daily, backup.keep_weekly).
+  clean, reset, restore and migrations back up automatically first.
+
+todo.txt sync:
+  Set todotxt.file and todotxt.sync: true in the config file (or per profile)
+  to rewrite the file on every change and pick up edits made by other tools.
+
+Config file:
+  $XDG_CONFIG_HOME/tuidoo/config.yaml (override with $TUIDOO_CONFIG)
+  Sections: db, profile, profiles, defaults (project, list), ui (date_format,
+  week_start, theme, layout.menu_width, layout.header), features (seed, rpc),
+  keys (TUI keybindings by action, e.g. toggle_done: [space, x]; sequences
+  such as "g g" work too), backup, todotxt, webhooks, hooks. The TUI reloads
+  it when it changes.
+
 Examples:
-  tuidoo                # Start the TUI
-  tuidoo seed           # Add sample data
-  tuidoo reset          # Fresh start with sample data`)
+  tuidoo                        # Start the TUI
+  tuidoo --profile work         # Start the TUI on the work profile
+  tuidoo --db ./scratch.db seed # Add sample data to a scratch database
+  tuidoo reset                  # Fresh start with sample data
+  tuidoo add Call Bob --project work --due tomorrow
+  tuidoo list --project work --done false
+  tuidoo list --output json | jq '.[].name'
+  tuidoo edit 12 --priority high
+  tuidoo config set ui.date_format eu`)
 }
 
 func printVersion() {
@@ -2,10 +2,17 @@ package main
 
 import (
 	"log"
+	"tuidoo/config"
 	"tuidoo/internal/app"
 )
 
 func main() {
 	log.Println("🧹 Cleaning database...")
-	app.CleanDatabase()
+
+	_, sel, err := config.Resolve("", "")
+	if err != nil {
+		log.Fatalf("Configuration error: %v", err)
+	}
+
+	app.CleanDatabase(sel)
 }
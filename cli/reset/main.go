@@ -2,10 +2,17 @@ package main
 
 import (
 	"log"
+	"tuidoo/config"
 	"tuidoo/internal/app"
 )
 
 func main() {
 	log.Println("🔄 Resetting database...")
-	app.ResetDatabase()
+
+	_, sel, err := config.Resolve("", "")
+	if err != nil {
+		log.Fatalf("Configuration error: %v", err)
+	}
+
+	app.ResetDatabase(sel)
 }
@@ -0,0 +1,299 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultProfile is used when no profile is selected
+const DefaultProfile = "default"
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Config is the user configuration stored in ConfigFile()
+type Config struct {
+	// DbPath is the database of the default profile
+	DbPath string `yaml:"db,omitempty"`
+
+	// Profile is the profile used when none is given at launch
+	Profile string `yaml:"profile,omitempty"`
+
+	// Profiles maps profile names to their settings
+	Profiles map[string]Profile `yaml:"profiles,omitempty"`
+
+	// Backup controls where backups go and how many are kept
+	Backup BackupConfig `yaml:"backup,omitempty"`
+
+	// TodoTxt is the todo.txt file of the default profile
+	TodoTxt TodoTxtConfig `yaml:"todotxt,omitempty"`
+
+	// Webhooks apply to every profile
+	Webhooks []WebhookConfig `yaml:"webhooks,omitempty"`
+
+	// Hooks runs scripts when tasks change
+	Hooks HooksConfig `yaml:"hooks,omitempty"`
+
+	// Defaults picks the project and list of new tasks
+	Defaults DefaultsConfig `yaml:"defaults,omitempty"`
+
+	// UI controls date display and the TUI layout
+	UI UIConfig `yaml:"ui,omitempty"`
+
+	// Features turns optional behavior on and off
+	Features FeaturesConfig `yaml:"features,omitempty"`
+
+	// Keys rebinds TUI actions, by the action names of tui/keys
+	Keys map[string]KeyList `yaml:"keys,omitempty"`
+}
+
+// Profile holds the settings of a single named profile
+type Profile struct {
+	DbPath   string          `yaml:"db,omitempty"`
+	TodoTxt  TodoTxtConfig   `yaml:"todotxt,omitempty"`
+	Webhooks []WebhookConfig `yaml:"webhooks,omitempty"`
+	Hooks    HooksConfig     `yaml:"hooks,omitempty"`
+}
+
+// TodoTxtConfig points a profile at a todo.txt file
+type TodoTxtConfig struct {
+	File string `yaml:"file,omitempty"`
+	// Sync keeps the file and the database in step on every change
+	Sync bool `yaml:"sync,omitempty"`
+	// Project is used for lines without a +project
+	Project string `yaml:"project,omitempty"`
+	// State remembers what was last written, so lines deleted from the file
+	// can be told apart from todos added to the database. It is resolved per
+	// profile and not configurable.
+	State string `yaml:"-"`
+}
+
+// DefaultTodoTxtProject receives todo.txt lines without a +project
+const DefaultTodoTxtProject = "Inbox"
+
+// BackupConfig holds backup location and retention. Zero values fall back to
+// the defaults below.
+type BackupConfig struct {
+	Dir        string `yaml:"dir,omitempty"`
+	KeepDaily  int    `yaml:"keep_daily,omitempty"`
+	KeepWeekly int    `yaml:"keep_weekly,omitempty"`
+}
+
+const (
+	DefaultKeepDaily  = 7
+	DefaultKeepWeekly = 4
+)
+
+// Selection is the profile and database resolved for a run
+type Selection struct {
+	Profile string
+	DbPath  string
+
+	// Backup is resolved for the profile: Dir is absolute and per profile
+	Backup BackupConfig
+
+	// TodoTxt is resolved for the profile: File is absolute
+	TodoTxt TodoTxtConfig
+
+	// RPCSocket is the profile's JSON-RPC socket in RuntimeDir
+	RPCSocket string
+
+	// Webhooks are the profile's webhooks with secrets expanded and
+	// defaults applied
+	Webhooks []WebhookConfig
+
+	// Hooks is resolved for the profile: Dir is absolute
+	Hooks HooksConfig
+
+	Defaults DefaultsConfig
+	UI       UIConfig
+	Features FeaturesConfig
+}
+
+// Load reads the config file, returning an empty config if it does not exist
+func Load() (*Config, error) {
+	data, err := os.ReadFile(ConfigFile())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &Config{}, nil
+		}
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	return parse(ConfigFile(), data)
+}
+
+// validate checks the settings that would otherwise only fail later,
+// reporting every problem rather than the first
+func (c *Config) validate(report func(key, format string, args ...any)) {
+	if err := validateWebhooks(c.Webhooks, "top-level"); err != nil {
+		report("webhooks", "%v", err)
+	}
+
+	for name, profile := range c.Profiles {
+		if !profileNamePattern.MatchString(name) {
+			report("profiles."+name, "invalid profile name (use letters, digits, - and _)")
+		}
+		if err := validateWebhooks(profile.Webhooks, "profile "+name); err != nil {
+			report("profiles."+name+".webhooks", "%v", err)
+		}
+	}
+
+	if c.Profile != "" && !profileNamePattern.MatchString(c.Profile) {
+		report("profile", "invalid profile name %q (use letters, digits, - and _)", c.Profile)
+	}
+
+	validateUI(c.UI, report)
+}
+
+// Select resolves the profile and database for this run.
+//
+// The profile comes from the --profile flag, TUIDOO_PROFILE, the config file,
+// then DefaultProfile. The database comes from the --db flag, TUIDOO_DB, the
+// profile's configured path, then the XDG data directory.
+func (c *Config) Select(dbFlag, profileFlag string) (Selection, error) {
+	profile := firstNonEmpty(profileFlag, os.Getenv("TUIDOO_PROFILE"), c.Profile, DefaultProfile)
+	if !profileNamePattern.MatchString(profile) {
+		return Selection{}, fmt.Errorf("invalid profile name %q", profile)
+	}
+
+	sel := c.ProfileSelection(profile)
+	if path := firstNonEmpty(dbFlag, os.Getenv("TUIDOO_DB")); path != "" {
+		sel.DbPath = absPath(ExpandPath(path))
+	}
+
+	return sel, nil
+}
+
+// ProfileSelection resolves a profile's database and backup settings,
+// ignoring flag and environment overrides
+func (c *Config) ProfileSelection(profile string) Selection {
+	if profile == "" {
+		profile = DefaultProfile
+	}
+
+	backup := c.Backup
+	dir := filepath.Join(DataDir(), "backups")
+	if backup.Dir != "" {
+		dir = absPath(ExpandPath(backup.Dir))
+	}
+	backup.Dir = filepath.Join(dir, profile)
+
+	if backup.KeepDaily <= 0 {
+		backup.KeepDaily = DefaultKeepDaily
+	}
+	if backup.KeepWeekly <= 0 {
+		backup.KeepWeekly = DefaultKeepWeekly
+	}
+
+	return Selection{
+		Profile: profile,
+		DbPath:  c.ProfileDbPath(profile),
+		Backup:  backup,
+		TodoTxt: c.profileTodoTxt(profile),
+
+		RPCSocket: filepath.Join(RuntimeDir(), profile+".sock"),
+		Webhooks:  c.profileWebhooks(profile),
+		Hooks:     c.profileHooks(profile),
+
+		Defaults: c.Defaults,
+		UI:       c.UI,
+		Features: c.Features,
+	}
+}
+
+// profileTodoTxt resolves a profile's todo.txt settings. The top-level
+// todotxt section only applies to the default profile so two profiles never
+// share a file by accident.
+func (c *Config) profileTodoTxt(profile string) TodoTxtConfig {
+	todoTxt := c.Profiles[profile].TodoTxt
+	if todoTxt.File == "" && profile == DefaultProfile {
+		todoTxt = c.TodoTxt
+	}
+
+	if todoTxt.File != "" {
+		todoTxt.File = absPath(ExpandPath(todoTxt.File))
+	}
+	if todoTxt.Project == "" {
+		todoTxt.Project = DefaultTodoTxtProject
+	}
+	todoTxt.State = filepath.Join(DataDir(), "sync", profile+"-todotxt.json")
+
+	return todoTxt
+}
+
+// ProfileDbPath returns the database path of a profile, ignoring flag and
+// environment overrides
+func (c *Config) ProfileDbPath(profile string) string {
+	if p, ok := c.Profiles[profile]; ok && p.DbPath != "" {
+		return absPath(ExpandPath(p.DbPath))
+	}
+
+	if profile == DefaultProfile || profile == "" {
+		if c.DbPath != "" {
+			return absPath(ExpandPath(c.DbPath))
+		}
+		return filepath.Join(DataDir(), "tuidoo.db")
+	}
+
+	return filepath.Join(DataDir(), "profiles", profile+".db")
+}
+
+// ProfileNames lists the default profile, every configured profile and every
+// profile database found in the data directory
+func (c *Config) ProfileNames() []string {
+	seen := map[string]bool{DefaultProfile: true}
+
+	for name := range c.Profiles {
+		seen[name] = true
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(DataDir(), "profiles", "*.db"))
+	for _, match := range matches {
+		seen[strings.TrimSuffix(filepath.Base(match), ".db")] = true
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
+
+// Resolve loads the config file and selects the profile and database for a run
+func Resolve(dbFlag, profileFlag string) (*Config, Selection, error) {
+	cfg, err := Load()
+	if err != nil {
+		return nil, Selection{}, err
+	}
+
+	sel, err := cfg.Select(dbFlag, profileFlag)
+	if err != nil {
+		return nil, Selection{}, err
+	}
+
+	return cfg, sel, nil
+}
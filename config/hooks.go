@@ -0,0 +1,40 @@
+package config
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// DefaultHookTimeout is how long a hook script may run before it is killed
+const DefaultHookTimeout = 5 * time.Second
+
+// HooksConfig points at the directory of hook scripts
+type HooksConfig struct {
+	// Dir defaults to hooks/ in the config directory
+	Dir     string        `yaml:"dir,omitempty"`
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// profileHooks resolves a profile's hook settings: the profile's own
+// override the top-level ones field by field
+func (c *Config) profileHooks(profile string) HooksConfig {
+	hooks := c.Hooks
+	own := c.Profiles[profile].Hooks
+	if own.Dir != "" {
+		hooks.Dir = own.Dir
+	}
+	if own.Timeout > 0 {
+		hooks.Timeout = own.Timeout
+	}
+
+	if hooks.Dir == "" {
+		hooks.Dir = filepath.Join(ConfigDir(), "hooks")
+	} else {
+		hooks.Dir = absPath(ExpandPath(hooks.Dir))
+	}
+	if hooks.Timeout <= 0 {
+		hooks.Timeout = DefaultHookTimeout
+	}
+
+	return hooks
+}
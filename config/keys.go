@@ -0,0 +1,29 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KeyList is the keys bound to one action. It is written as a single key,
+// a list of keys, or an empty list to unbind the action. A key may be a
+// sequence typed one after the other, such as "g g".
+type KeyList []string
+
+func (k *KeyList) UnmarshalYAML(n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		*k = KeyList{n.Value}
+		return nil
+	case yaml.SequenceNode:
+		var keys []string
+		if err := n.Decode(&keys); err != nil {
+			return fmt.Errorf("expected a list of keys")
+		}
+		*k = keys
+		return nil
+	default:
+		return fmt.Errorf("expected a key or a list of keys")
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const appName = "tuidoo"
+
+// ConfigDir returns $XDG_CONFIG_HOME/tuidoo, falling back to ~/.config/tuidoo
+func ConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, appName)
+	}
+	return filepath.Join(homeDir(), ".config", appName)
+}
+
+// DataDir returns $XDG_DATA_HOME/tuidoo, falling back to ~/.local/share/tuidoo
+func DataDir() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, appName)
+	}
+	return filepath.Join(homeDir(), ".local", "share", appName)
+}
+
+// RuntimeDir returns $XDG_RUNTIME_DIR/tuidoo for sockets, falling back to
+// the run directory inside DataDir
+func RuntimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, appName)
+	}
+	return filepath.Join(DataDir(), "run")
+}
+
+// HistoryFile returns where the TUI keeps a history, such as the recent
+// commands of the palette
+func HistoryFile(name string) string {
+	return filepath.Join(DataDir(), "history", name)
+}
+
+// ConfigFile returns the path of the config file, honouring TUIDOO_CONFIG
+func ConfigFile() string {
+	if path := os.Getenv("TUIDOO_CONFIG"); path != "" {
+		return ExpandPath(path)
+	}
+	return filepath.Join(ConfigDir(), "config.yaml")
+}
+
+// ExpandPath expands a leading ~ and environment variables in a path
+func ExpandPath(path string) string {
+	path = os.ExpandEnv(path)
+	if path == "~" {
+		return homeDir()
+	}
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(homeDir(), path[2:])
+	}
+	return path
+}
+
+func homeDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "."
+	}
+	return home
+}
@@ -0,0 +1,586 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is one thing wrong with the config file
+type Problem struct {
+	// Line is 0 when the key is not in the file
+	Line int
+	Key  string
+	Msg  string
+}
+
+func (p Problem) String() string {
+	var b strings.Builder
+	if p.Line > 0 {
+		fmt.Fprintf(&b, "line %d: ", p.Line)
+	}
+	if p.Key != "" {
+		b.WriteString(p.Key + ": ")
+	}
+	b.WriteString(p.Msg)
+	return b.String()
+}
+
+// ValidationError lists every problem found in a config file
+type ValidationError struct {
+	File     string
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return fmt.Sprintf("invalid %s: %s", e.File, e.Problems[0])
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid %s (%d problems):", e.File, len(e.Problems))
+	for _, p := range e.Problems {
+		b.WriteString("\n  " + p.String())
+	}
+	return b.String()
+}
+
+// parse decodes a config file, checking every key against the Config
+// schema before the values are validated
+func parse(file string, data []byte) (*Config, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+
+	cfg := &Config{}
+	root := documentRoot(&doc)
+	if root == nil {
+		return cfg, nil
+	}
+
+	v := &validation{root: root}
+	v.checkNode(root, reflect.TypeOf(Config{}), "")
+
+	// Values of the wrong type were reported above and are left zero, so
+	// the rest can still be validated
+	var typeErr *yaml.TypeError
+	if err := root.Decode(cfg); err != nil && (len(v.problems) == 0 || !errors.As(err, &typeErr)) {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	cfg.validate(v.report)
+
+	if len(v.problems) > 0 {
+		slices.SortStableFunc(v.problems, func(a, b Problem) int { return a.Line - b.Line })
+		return nil, &ValidationError{File: file, Problems: v.problems}
+	}
+	return cfg, nil
+}
+
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		return doc.Content[0]
+	}
+	return nil
+}
+
+// validation collects the problems of one config file
+type validation struct {
+	root     *yaml.Node
+	problems []Problem
+}
+
+// report records a problem with a key, finding its line in the file
+func (v *validation) report(key, format string, args ...any) {
+	line := 0
+	if n := lookup(v.root, key); n != nil {
+		line = n.Line
+	}
+	v.problems = append(v.problems, Problem{Line: line, Key: key, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (v *validation) problem(n *yaml.Node, key, format string, args ...any) {
+	v.problems = append(v.problems, Problem{Line: n.Line, Key: key, Msg: fmt.Sprintf(format, args...)})
+}
+
+var (
+	durationType    = reflect.TypeOf(time.Duration(0))
+	unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+)
+
+// checkNode compares a node with the type it decodes into: sections must be
+// mappings of known keys, lists sequences and values of the right kind
+func (v *validation) checkNode(n *yaml.Node, t reflect.Type, key string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+		return
+	}
+
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		if err := n.Decode(reflect.New(t).Interface()); err != nil {
+			v.problem(n, key, "%v", err)
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			v.problem(n, key, "expected a section of keys, not %s", describeNode(n))
+			return
+		}
+		v.checkDuplicates(n, key)
+		fields := schemaFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			name, value := n.Content[i].Value, n.Content[i+1]
+			field, ok := fields[name]
+			if !ok {
+				v.problem(n.Content[i], key, "unknown key %q%s", name, suggest(name, slices.Sorted(maps.Keys(fields)), ""))
+				continue
+			}
+			v.checkNode(value, field, joinKey(key, name))
+		}
+
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			v.problem(n, key, "expected a section of keys, not %s", describeNode(n))
+			return
+		}
+		v.checkDuplicates(n, key)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			v.checkNode(n.Content[i+1], t.Elem(), joinKey(key, n.Content[i].Value))
+		}
+
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			v.problem(n, key, "expected a list, not %s", describeNode(n))
+			return
+		}
+		for i, item := range n.Content {
+			v.checkNode(item, t.Elem(), joinKey(key, strconv.Itoa(i+1)))
+		}
+
+	default:
+		if n.Kind != yaml.ScalarNode {
+			v.problem(n, key, "expected %s, not %s", describeType(t), describeNode(n))
+			return
+		}
+		if err := n.Decode(reflect.New(t).Interface()); err != nil {
+			v.problem(n, key, "expected %s, not %q", describeType(t), n.Value)
+		}
+	}
+}
+
+// checkDuplicates reports keys a mapping defines more than once
+func (v *validation) checkDuplicates(n *yaml.Node, key string) {
+	lines := map[string]int{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		name := n.Content[i]
+		if first, ok := lines[name.Value]; ok {
+			v.problem(name, key, "%q is defined twice (first on line %d)", name.Value, first)
+			continue
+		}
+		lines[name.Value] = name.Line
+	}
+}
+
+// schemaFields maps the yaml keys of a struct to their types
+func schemaFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func describeType(t reflect.Type) string {
+	switch {
+	case t == durationType:
+		return "a duration such as 5s or 1m30s"
+	case t.Kind() == reflect.Bool:
+		return "true or false"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Float64:
+		return "a number"
+	default:
+		return "a value"
+	}
+}
+
+func describeNode(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "a section"
+	case yaml.SequenceNode:
+		return "a list"
+	default:
+		return strconv.Quote(n.Value)
+	}
+}
+
+// suggest proposes the known key closest to a misspelt one, as a key in
+// parent
+func suggest(name string, known []string, parent string) string {
+	best, bestDistance := "", 3
+	for _, k := range known {
+		if d := editDistance(name, k); d < bestDistance {
+			best, bestDistance = k, d
+		}
+	}
+	if best != "" {
+		return fmt.Sprintf(" (did you mean %q?)", joinKey(parent, best))
+	}
+	return fmt.Sprintf(" (expected one of %s)", strings.Join(known, ", "))
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func joinKey(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// lookup finds the node of a dotted key; list items are numbered from 1
+func lookup(n *yaml.Node, key string) *yaml.Node {
+	if key == "" {
+		return n
+	}
+	for _, name := range strings.Split(key, ".") {
+		if n == nil {
+			return nil
+		}
+		switch n.Kind {
+		case yaml.MappingNode:
+			var next *yaml.Node
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == name {
+					next = n.Content[i+1]
+				}
+			}
+			n = next
+		case yaml.SequenceNode:
+			i, err := strconv.Atoi(name)
+			if err != nil || i < 1 || i > len(n.Content) {
+				return nil
+			}
+			n = n.Content[i-1]
+		default:
+			return nil
+		}
+	}
+	return n
+}
+
+// ErrUnknownKey is returned for keys that are not in the Config schema
+var ErrUnknownKey = errors.New("unknown key")
+
+// keyType resolves the type a dotted key decodes into
+func keyType(key string) (reflect.Type, error) {
+	t := reflect.TypeOf(Config{})
+	path := ""
+	for _, name := range strings.Split(key, ".") {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			fields := schemaFields(t)
+			field, ok := fields[name]
+			if !ok {
+				return nil, fmt.Errorf("%w %q%s", ErrUnknownKey, joinKey(path, name), suggest(name, slices.Sorted(maps.Keys(fields)), path))
+			}
+			t = field
+		case reflect.Map:
+			t = t.Elem()
+		case reflect.Slice:
+			if i, err := strconv.Atoi(name); err != nil || i < 1 {
+				return nil, fmt.Errorf("%s is a list; address its items as %s.1, %s.2, …", path, path, path)
+			}
+			t = t.Elem()
+		default:
+			return nil, fmt.Errorf("%s is a value, not a section", path)
+		}
+		path = joinKey(path, name)
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t, nil
+}
+
+// Get returns the effective value of a dotted key such as ui.theme: the
+// config file's value, or the default. A section is returned as YAML.
+func (c *Config) Get(key string) (string, error) {
+	if key != "" {
+		if _, err := keyType(key); err != nil {
+			return "", err
+		}
+	}
+
+	var doc yaml.Node
+	if err := doc.Encode(c.effective()); err != nil {
+		return "", err
+	}
+	n := lookup(&doc, key)
+	if n == nil {
+		return "", nil
+	}
+	if n.Kind == yaml.ScalarNode {
+		return n.Value, nil
+	}
+
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(n); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(b.String(), "\n"), enc.Close()
+}
+
+// effective fills in the defaults of the top-level settings
+func (c *Config) effective() *Config {
+	e := *c
+	on := true
+
+	e.DbPath = c.ProfileDbPath(DefaultProfile)
+	e.Profile = firstNonEmpty(c.Profile, DefaultProfile)
+
+	e.UI.DateFormat = firstNonEmpty(c.UI.DateFormat, DefaultDateFormat)
+	e.UI.WeekStart = firstNonEmpty(c.UI.WeekStart, DefaultWeekStart)
+	e.UI.Theme = c.UI.ThemeID()
+	e.UI.Layout.MenuWidth = c.UI.Layout.MenuColumns()
+	if e.UI.Layout.Header == nil {
+		e.UI.Layout.Header = &on
+	}
+	if e.Features.Seed == nil {
+		e.Features.Seed = &on
+	}
+	if e.Features.RPC == nil {
+		e.Features.RPC = &on
+	}
+
+	sel := c.ProfileSelection(DefaultProfile)
+	e.Backup.Dir = filepath.Dir(sel.Backup.Dir)
+	e.Backup.KeepDaily, e.Backup.KeepWeekly = sel.Backup.KeepDaily, sel.Backup.KeepWeekly
+	e.Hooks = sel.Hooks
+
+	return &e
+}
+
+// Set changes one value in the config file, keeping its comments and
+// layout. An empty value removes the key. Nothing is written unless the
+// result is valid.
+func Set(key, value string) error {
+	t, err := keyType(key)
+	if err != nil {
+		return err
+	}
+	switch {
+	case reflect.PointerTo(t).Implements(unmarshalerType):
+	case t.Kind() == reflect.Struct, t.Kind() == reflect.Map:
+		return fmt.Errorf("%s is a section; set its keys one by one", key)
+	case t.Kind() == reflect.Slice:
+		return fmt.Errorf("%s is a list; edit it in %s", key, ConfigFile())
+	}
+
+	file := ConfigFile()
+	data, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	if documentRoot(&doc) == nil {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	names := strings.Split(key, ".")
+	if value == "" {
+		unsetKey(documentRoot(&doc), names)
+	} else {
+		scalar := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+		if t.Kind() == reflect.String {
+			scalar.Tag = "!!str"
+		}
+		if err := setKey(documentRoot(&doc), names, scalar); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	if _, err := parse(file, b.Bytes()); err != nil {
+		var invalid *ValidationError
+		if errors.As(err, &invalid) {
+			for _, p := range invalid.Problems {
+				if p.Key == key {
+					return fmt.Errorf("%s: %s", key, p.Msg)
+				}
+			}
+			// Report the other problems with the lines of the file as it is
+			if _, current := parse(file, data); current != nil {
+				return current
+			}
+		}
+		return err
+	}
+	return writeFile(file, b.Bytes())
+}
+
+// setKey puts a value under a dotted key, creating the sections on the way
+func setKey(n *yaml.Node, names []string, value *yaml.Node) error {
+	if n.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s is not a section", describeNode(n))
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value != names[0] {
+			continue
+		}
+		if len(names) == 1 {
+			value.HeadComment, value.LineComment = n.Content[i+1].HeadComment, n.Content[i+1].LineComment
+			n.Content[i+1] = value
+			return nil
+		}
+		return setKey(n.Content[i+1], names[1:], value)
+	}
+
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Value: names[0]}
+	if len(names) == 1 {
+		n.Content = append(n.Content, keyNode, value)
+		return nil
+	}
+	section := &yaml.Node{Kind: yaml.MappingNode}
+	n.Content = append(n.Content, keyNode, section)
+	return setKey(section, names[1:], value)
+}
+
+// unsetKey removes a dotted key and the sections it leaves empty
+func unsetKey(n *yaml.Node, names []string) {
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value != names[0] {
+			continue
+		}
+		if len(names) > 1 {
+			unsetKey(n.Content[i+1], names[1:])
+			if len(n.Content[i+1].Content) > 0 {
+				return
+			}
+		}
+		n.Content = slices.Delete(n.Content, i, i+2)
+		return
+	}
+}
+
+// writeFile replaces a file in one step, keeping its permissions
+func writeFile(path string, data []byte) error {
+	mode := os.FileMode(0o600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// Keys lists the dotted keys `config set` accepts outside profiles and
+// lists, for completion
+func Keys() []string {
+	var keys []string
+	var walk func(t reflect.Type, prefix string)
+	walk = func(t reflect.Type, prefix string) {
+		for name, field := range schemaFields(t) {
+			for field.Kind() == reflect.Pointer {
+				field = field.Elem()
+			}
+			switch field.Kind() {
+			case reflect.Struct:
+				walk(field, joinKey(prefix, name))
+			case reflect.Map, reflect.Slice:
+			default:
+				keys = append(keys, joinKey(prefix, name))
+			}
+		}
+	}
+	walk(reflect.TypeOf(Config{}), "")
+	slices.Sort(keys)
+	return keys
+}
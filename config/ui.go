@@ -0,0 +1,177 @@
+package config
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"time"
+	"tuidoo/dictionaries"
+)
+
+// DefaultsConfig picks where tasks go when no project or list is given
+type DefaultsConfig struct {
+	Project string `yaml:"project,omitempty"`
+	List    string `yaml:"list,omitempty"`
+}
+
+// UIConfig controls how dates are shown and how the TUI is laid out
+type UIConfig struct {
+	// DateFormat is a preset from DateFormats or a Go time layout
+	DateFormat string `yaml:"date_format,omitempty"`
+	// WeekStart is the weekday weeks begin on
+	WeekStart string `yaml:"week_start,omitempty"`
+	// Theme pins the theme at startup; empty keeps the one last picked in
+	// the TUI
+	Theme  string       `yaml:"theme,omitempty"`
+	Layout LayoutConfig `yaml:"layout,omitempty"`
+}
+
+// LayoutConfig sizes the parts of the TUI
+type LayoutConfig struct {
+	MenuWidth int `yaml:"menu_width,omitempty"`
+	// Header shows the logo above the menu and content
+	Header *bool `yaml:"header,omitempty"`
+}
+
+// FeaturesConfig turns optional behavior on and off. Unset toggles are on.
+type FeaturesConfig struct {
+	// Seed fills a new database with sample projects and tasks
+	Seed *bool `yaml:"seed,omitempty"`
+	// RPC serves the JSON-RPC socket while the TUI runs
+	RPC *bool `yaml:"rpc,omitempty"`
+}
+
+const (
+	DefaultTheme      = "dark"
+	DefaultDateFormat = "iso"
+	DefaultWeekStart  = "monday"
+	DefaultMenuWidth  = 28
+
+	minMenuWidth = 16
+	maxMenuWidth = 60
+)
+
+// DateFormats are the named date layouts ui.date_format accepts
+var DateFormats = map[string]string{
+	"iso":  "2006-01-02",
+	"us":   "01/02/2006",
+	"eu":   "02.01.2006",
+	"uk":   "02/01/2006",
+	"long": "Jan 2, 2006",
+}
+
+// weekdays are the values ui.week_start accepts
+var weekdays = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+// DateLayout is the Go time layout dates are shown with
+func (u UIConfig) DateLayout() string {
+	format := firstNonEmpty(u.DateFormat, DefaultDateFormat)
+	if layout, ok := DateFormats[strings.ToLower(format)]; ok {
+		return layout
+	}
+	return format
+}
+
+// FormatDate shows t in the configured date layout, with the time of day
+// unless it is midnight
+func (u UIConfig) FormatDate(t time.Time) string {
+	local := t.Local()
+	if local.Hour() == 0 && local.Minute() == 0 {
+		return local.Format(u.DateLayout())
+	}
+	return local.Format(u.DateLayout() + " 15:04")
+}
+
+// FirstWeekday is the weekday weeks begin on
+func (u UIConfig) FirstWeekday() time.Weekday {
+	index := slices.Index(weekdays, strings.ToLower(firstNonEmpty(u.WeekStart, DefaultWeekStart)))
+	if index < 0 {
+		return time.Monday
+	}
+	return time.Weekday(index)
+}
+
+// WeekOf returns the local midnight the week containing t begins at
+func (u UIConfig) WeekOf(t time.Time) time.Time {
+	local := t.Local()
+	days := (int(local.Weekday()) - int(u.FirstWeekday()) + 7) % 7
+	return time.Date(local.Year(), local.Month(), local.Day()-days, 0, 0, 0, 0, time.Local)
+}
+
+// ThemeID is the theme new databases start with
+func (u UIConfig) ThemeID() string {
+	return firstNonEmpty(u.Theme, DefaultTheme)
+}
+
+// MenuColumns is the width of the menu column
+func (l LayoutConfig) MenuColumns() int {
+	if l.MenuWidth <= 0 {
+		return DefaultMenuWidth
+	}
+	return l.MenuWidth
+}
+
+// ShowHeader reports whether the logo is drawn
+func (l LayoutConfig) ShowHeader() bool {
+	return enabled(l.Header)
+}
+
+// SeedEnabled reports whether new databases get sample data
+func (f FeaturesConfig) SeedEnabled() bool {
+	return enabled(f.Seed)
+}
+
+// RPCEnabled reports whether the TUI serves the JSON-RPC socket
+func (f FeaturesConfig) RPCEnabled() bool {
+	return enabled(f.RPC)
+}
+
+func enabled(toggle *bool) bool {
+	return toggle == nil || *toggle
+}
+
+// validateUI checks the ui section, reporting each problem under its key
+func validateUI(u UIConfig, report func(key, format string, args ...any)) {
+	if u.DateFormat != "" {
+		if _, ok := DateFormats[strings.ToLower(u.DateFormat)]; !ok && !isDateLayout(u.DateFormat) {
+			report("ui.date_format", "%q is neither a preset (%s) nor a Go time layout such as \"02 Jan 2006\"",
+				u.DateFormat, strings.Join(slices.Sorted(maps.Keys(DateFormats)), ", "))
+		}
+	}
+
+	if u.WeekStart != "" && !slices.Contains(weekdays, strings.ToLower(u.WeekStart)) {
+		report("ui.week_start", "unknown weekday %q (expected one of %s)", u.WeekStart, strings.Join(weekdays, ", "))
+	}
+
+	if u.Theme != "" {
+		if _, ok := dictionaries.Themes[u.Theme]; !ok {
+			report("ui.theme", "unknown theme %q (expected one of %s)", u.Theme, strings.Join(ThemeNames(), ", "))
+		}
+	}
+
+	if w := u.Layout.MenuWidth; w != 0 && (w < minMenuWidth || w > maxMenuWidth) {
+		report("ui.layout.menu_width", "%d is out of range (expected %d to %d)", w, minMenuWidth, maxMenuWidth)
+	}
+}
+
+// isDateLayout reports whether a layout shows a year, a month and a day.
+// Formatting a date that differs in each of them tells the elements apart
+// from literal text, which would print the same for every date.
+func isDateLayout(layout string) bool {
+	a := time.Date(2001, 2, 3, 0, 0, 0, 0, time.UTC).Format(layout)
+	for _, other := range []time.Time{
+		time.Date(2002, 2, 3, 0, 0, 0, 0, time.UTC),
+		time.Date(2001, 3, 3, 0, 0, 0, 0, time.UTC),
+		time.Date(2001, 2, 4, 0, 0, 0, 0, time.UTC),
+	} {
+		if other.Format(layout) == a {
+			return false
+		}
+	}
+	return true
+}
+
+// ThemeNames lists the themes ui.theme accepts
+func ThemeNames() []string {
+	return slices.Sorted(maps.Keys(dictionaries.Themes))
+}
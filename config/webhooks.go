@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"tuidoo/enums"
+)
+
+// WebhookEvents are the events a webhook can subscribe to
+var WebhookEvents = []string{"created", "updated", "completed", "deleted"}
+
+// DefaultWebhookMaxAttempts is how often a delivery is tried before it is
+// marked failed
+const DefaultWebhookMaxAttempts = 6
+
+// WebhookConfig is an outgoing webhook. Empty filters match every task.
+type WebhookConfig struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// Secret signs the payload with HMAC-SHA256; $VARS are expanded so it
+	// can be kept out of the file
+	Secret string `yaml:"secret,omitempty"`
+	// Events defaults to all WebhookEvents
+	Events      []string `yaml:"events,omitempty"`
+	Projects    []string `yaml:"projects,omitempty"`
+	Lists       []string `yaml:"lists,omitempty"`
+	Priorities  []string `yaml:"priorities,omitempty"`
+	Statuses    []string `yaml:"statuses,omitempty"`
+	MaxAttempts int      `yaml:"max_attempts,omitempty"`
+}
+
+// validateWebhooks checks one set of webhooks; names must be unique within it
+func validateWebhooks(hooks []WebhookConfig, where string) error {
+	seen := map[string]bool{}
+
+	for i, hook := range hooks {
+		if hook.Name == "" {
+			return fmt.Errorf("%s webhook %d: name is required", where, i+1)
+		}
+		if seen[hook.Name] {
+			return fmt.Errorf("%s webhook %q is defined twice", where, hook.Name)
+		}
+		seen[hook.Name] = true
+
+		u, err := url.Parse(hook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook %q: url must be an http or https URL", hook.Name)
+		}
+		for _, event := range hook.Events {
+			if !slices.Contains(WebhookEvents, event) {
+				return fmt.Errorf("webhook %q: unknown event %q (expected one of %v)", hook.Name, event, WebhookEvents)
+			}
+		}
+		for _, p := range hook.Priorities {
+			if _, err := enums.ParsePriority(p); err != nil {
+				return fmt.Errorf("webhook %q: %w", hook.Name, err)
+			}
+		}
+		for _, s := range hook.Statuses {
+			if _, err := enums.ParseStatus(s); err != nil {
+				return fmt.Errorf("webhook %q: %w", hook.Name, err)
+			}
+		}
+		if hook.MaxAttempts < 0 {
+			return fmt.Errorf("webhook %q: max_attempts cannot be negative", hook.Name)
+		}
+	}
+
+	return nil
+}
+
+// profileWebhooks resolves a profile's webhooks: the top-level ones apply to
+// every profile and the profile's own are added. A profile webhook replaces
+// a top-level one of the same name.
+func (c *Config) profileWebhooks(profile string) []WebhookConfig {
+	own := c.Profiles[profile].Webhooks
+
+	var hooks []WebhookConfig
+	for _, hook := range c.Webhooks {
+		if !slices.ContainsFunc(own, func(h WebhookConfig) bool { return h.Name == hook.Name }) {
+			hooks = append(hooks, hook)
+		}
+	}
+	hooks = append(hooks, own...)
+
+	for i := range hooks {
+		hooks[i].Secret = os.ExpandEnv(hooks[i].Secret)
+		if len(hooks[i].Events) == 0 {
+			hooks[i].Events = WebhookEvents
+		}
+		if hooks[i].MaxAttempts == 0 {
+			hooks[i].MaxAttempts = DefaultWebhookMaxAttempts
+		}
+	}
+
+	return hooks
+}
@@ -4,7 +4,16 @@ import "gorm.io/gorm"
 
 type Project struct {
 	gorm.Model
+	UID   string `gorm:"uniqueIndex"`
 	Name  string
 	Color string
 	ToDos []ToDo
 }
+
+// BeforeCreate assigns a stable UID to new records
+func (p *Project) BeforeCreate(tx *gorm.DB) error {
+	if p.UID == "" {
+		p.UID = NewUID()
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package entities
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Rule is a user-defined automation: when its trigger fires for a task
+// that meets every condition, its actions run. Conditions and actions are
+// kept in the rule language (see services.ParseConditions and
+// services.ParseActions).
+type Rule struct {
+	gorm.Model
+	UID     string `gorm:"uniqueIndex"`
+	Name    string
+	Enabled bool
+	// Trigger is created, status_changed, completed or due_approaching
+	Trigger string
+	// DueWithin is how close the due date must be for due_approaching rules
+	DueWithin  time.Duration
+	Conditions string
+	Actions    string
+	// LastFiredAt is when the rule last changed or created a task
+	LastFiredAt *time.Time
+}
+
+// BeforeCreate assigns a stable UID to new records
+func (r *Rule) BeforeCreate(tx *gorm.DB) error {
+	if r.UID == "" {
+		r.UID = NewUID()
+	}
+	return nil
+}
+
+// RuleFiring records that a due_approaching rule ran for a task's due date,
+// so it runs once per due date even with several tuidoo processes open
+type RuleFiring struct {
+	ID        uint      `gorm:"primarykey"`
+	RuleID    uint      `gorm:"uniqueIndex:idx_rule_firings_once"`
+	ToDoID    uint      `gorm:"uniqueIndex:idx_rule_firings_once"`
+	DueDate   time.Time `gorm:"uniqueIndex:idx_rule_firings_once"`
+	CreatedAt time.Time
+}
@@ -0,0 +1,10 @@
+package entities
+
+import "time"
+
+// SchemaMigration records a migration that has been applied to the database
+type SchemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
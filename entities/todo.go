@@ -9,6 +9,7 @@ import (
 
 type ToDo struct {
 	gorm.Model
+	UID         string `gorm:"uniqueIndex"`
 	ProjectID   uint
 	Project     Project
 	ToDoListID  uint
@@ -22,3 +23,11 @@ type ToDo struct {
 	Done        bool
 	DueDate     *time.Time
 }
+
+// BeforeCreate assigns a stable UID to new records
+func (t *ToDo) BeforeCreate(tx *gorm.DB) error {
+	if t.UID == "" {
+		t.UID = NewUID()
+	}
+	return nil
+}
@@ -4,7 +4,16 @@ import "gorm.io/gorm"
 
 type ToDoList struct {
 	gorm.Model
+	UID   string `gorm:"uniqueIndex"`
 	Name  string
 	Color string
 	ToDos []ToDo
 }
+
+// BeforeCreate assigns a stable UID to new records
+func (l *ToDoList) BeforeCreate(tx *gorm.DB) error {
+	if l.UID == "" {
+		l.UID = NewUID()
+	}
+	return nil
+}
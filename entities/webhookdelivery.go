@@ -0,0 +1,36 @@
+package entities
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookDelivery is the delivery log entry of one event sent to one
+// webhook, kept so failed deliveries can be inspected and replayed
+type WebhookDelivery struct {
+	gorm.Model
+	UID     string `gorm:"uniqueIndex"`
+	Webhook string `gorm:"index"`
+	URL     string
+	Event   string
+	ToDoID  uint
+	// Payload is the exact JSON body that is signed and sent
+	Payload string
+	// State is pending, delivered or failed
+	State         string `gorm:"index"`
+	Attempts      int
+	MaxAttempts   int
+	StatusCode    int
+	LastError     string
+	NextAttemptAt *time.Time `gorm:"index"`
+	DeliveredAt   *time.Time
+}
+
+// BeforeCreate assigns a stable UID to new records
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.UID == "" {
+		d.UID = NewUID()
+	}
+	return nil
+}
@@ -1,5 +1,10 @@
 package enums
 
+import (
+	"fmt"
+	"strings"
+)
+
 var PriorityOptions = []string{"Low", "Medium", "High", "Urgent"}
 
 type Priority int
@@ -18,3 +23,28 @@ func (p Priority) String() string {
 	}
 	return PriorityOptions[p]
 }
+
+// ParsePriority parses a priority name case-insensitively
+func ParsePriority(s string) (Priority, error) {
+	for i, option := range PriorityOptions {
+		if strings.EqualFold(strings.TrimSpace(s), option) {
+			return Priority(i), nil
+		}
+	}
+	return Low, fmt.Errorf("unknown priority %q (expected one of %s)", s, strings.Join(PriorityOptions, ", "))
+}
+
+// MarshalText encodes the priority by name
+func (p Priority) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText decodes a priority name
+func (p *Priority) UnmarshalText(text []byte) error {
+	parsed, err := ParsePriority(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
@@ -1,5 +1,10 @@
 package enums
 
+import (
+	"fmt"
+	"strings"
+)
+
 var StatusOptions = []string{"New", "In Progress", "On Hold", "Pending", "Closed", "Done"}
 
 type Status int
@@ -20,3 +25,35 @@ func (s Status) String() string {
 	}
 	return StatusOptions[s]
 }
+
+// ParseStatus parses a status name case-insensitively. Spaces, dashes and
+// underscores are interchangeable, so "in-progress" matches "In Progress".
+func ParseStatus(s string) (Status, error) {
+	normalized := normalizeStatus(s)
+	for i, option := range StatusOptions {
+		if normalizeStatus(option) == normalized {
+			return Status(i), nil
+		}
+	}
+	return New, fmt.Errorf("unknown status %q (expected one of %s)", s, strings.Join(StatusOptions, ", "))
+}
+
+// MarshalText encodes the status by name
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a status name
+func (s *Status) UnmarshalText(text []byte) error {
+	parsed, err := ParseStatus(string(text))
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+func normalizeStatus(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return strings.NewReplacer("-", " ", "_", " ").Replace(s)
+}
@@ -0,0 +1,309 @@
+package formats
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"tuidoo/entities"
+	"tuidoo/enums"
+)
+
+// CSVColumns are the columns exported by default, in order. The uid lets a
+// re-import update the todos it came from instead of duplicating them.
+var CSVColumns = []string{
+	FieldName, FieldProject, FieldList, FieldPriority,
+	FieldStatus, FieldDue, FieldDone, FieldDescription, FieldUID,
+}
+
+// csvExtraColumns can be selected but are not exported by default
+var csvExtraColumns = []string{FieldDetails}
+
+// csvAliases are header names recognised without an explicit mapping
+var csvAliases = map[string][]string{
+	FieldName:    {"title", "task", "summary"},
+	FieldList:    {"context"},
+	FieldDue:     {"due date", "due_date", "deadline"},
+	FieldDetails: {"notes"},
+}
+
+// ParseColumns parses a comma-separated column list such as
+// "name,project,due"
+func ParseColumns(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return CSVColumns, nil
+	}
+
+	var columns []string
+	for _, column := range strings.Split(s, ",") {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if !isCSVColumn(column) {
+			return nil, fmt.Errorf("unknown column %q (expected %s)", column, strings.Join(allCSVColumns(), ", "))
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// ParseMapping parses field=Header pairs such as "name=Task,due=Deadline"
+func ParseMapping(s string) (map[string]string, error) {
+	mapping := map[string]string{}
+	if strings.TrimSpace(s) == "" {
+		return mapping, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		field, header, ok := strings.Cut(pair, "=")
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !ok || strings.TrimSpace(header) == "" {
+			return nil, fmt.Errorf("invalid mapping %q (expected field=Header)", pair)
+		}
+		if !isCSVColumn(field) {
+			return nil, fmt.Errorf("unknown field %q in mapping (expected %s)", field, strings.Join(allCSVColumns(), ", "))
+		}
+		mapping[field] = strings.TrimSpace(header)
+	}
+	return mapping, nil
+}
+
+// WriteCSV writes todos with a header row. Project and list must be
+// preloaded.
+func WriteCSV(w io.Writer, todos []entities.ToDo, columns []string) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+
+	row := make([]string, len(columns))
+	for _, todo := range todos {
+		for i, column := range columns {
+			row[i] = csvValue(todo, column)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadCSV reads todos from CSV with a header row. mapping maps fields to
+// header names; unmapped fields use a header with the field's name. Rows
+// that cannot be parsed are returned with Err set so the caller can report
+// them and carry on.
+func ReadCSV(r io.Reader, mapping map[string]string) ([]Record, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("CSV file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	index, err := csvColumnIndex(header, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	for field := range index {
+		fields = append(fields, field)
+	}
+
+	var records []Record
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				records = append(records, Record{Line: parseErr.StartLine, Fields: fields, Err: parseErr.Err})
+				continue
+			}
+			return records, fmt.Errorf("failed to read CSV: %w", err)
+		}
+
+		// FieldPos only knows rows that were read without an error
+		line, _ := cr.FieldPos(0)
+		record := Record{Line: line, Fields: fields}
+
+		if isBlankRow(row) {
+			continue
+		}
+
+		get := func(field string) string {
+			i, ok := index[field]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+
+		record.Err = parseCSVRow(&record, get)
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func parseCSVRow(record *Record, get func(string) string) error {
+	record.UID = get(FieldUID)
+	record.Name = get(FieldName)
+	record.Project = get(FieldProject)
+	record.List = get(FieldList)
+	record.Description = optionalString(get(FieldDescription))
+	record.Details = optionalString(get(FieldDetails))
+
+	if record.Name == "" {
+		return errors.New("name is empty")
+	}
+
+	if s := get(FieldPriority); s != "" {
+		priority, err := enums.ParsePriority(s)
+		if err != nil {
+			return err
+		}
+		record.Priority = priority
+	}
+
+	if s := get(FieldStatus); s != "" {
+		status, err := enums.ParseStatus(s)
+		if err != nil {
+			return err
+		}
+		record.Status = status
+	}
+
+	if s := get(FieldDue); s != "" {
+		due, err := ParseDate(s)
+		if err != nil {
+			return err
+		}
+		record.DueDate = &due
+	}
+
+	// An empty done cell leaves done to follow the status
+	if s := get(FieldDone); s != "" {
+		done, err := ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("done: %w", err)
+		}
+		record.Done = done
+	} else {
+		record.Fields = without(record.Fields, FieldDone)
+	}
+
+	return nil
+}
+
+func csvColumnIndex(header []string, mapping map[string]string) (map[string]int, error) {
+	find := func(name string) (int, bool) {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), name) {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+
+	index := map[string]int{}
+	for _, field := range allCSVColumns() {
+		if name, ok := mapping[field]; ok {
+			i, found := find(name)
+			if !found {
+				return nil, fmt.Errorf("column %q mapped to %s not found in header", name, field)
+			}
+			index[field] = i
+			continue
+		}
+
+		for _, name := range append([]string{field}, csvAliases[field]...) {
+			if i, found := find(name); found {
+				index[field] = i
+				break
+			}
+		}
+	}
+
+	if _, ok := index[FieldName]; !ok {
+		return nil, errors.New("no name column found (map one with --map name=Header)")
+	}
+
+	return index, nil
+}
+
+func csvValue(todo entities.ToDo, column string) string {
+	switch column {
+	case FieldUID:
+		return todo.UID
+	case FieldName:
+		return todo.Name
+	case FieldProject:
+		return todo.Project.Name
+	case FieldList:
+		return todo.ToDoList.Name
+	case FieldPriority:
+		return todo.Priority.String()
+	case FieldStatus:
+		return todo.Status.String()
+	case FieldDue:
+		if todo.DueDate == nil {
+			return ""
+		}
+		return FormatDate(*todo.DueDate)
+	case FieldDone:
+		return strconv.FormatBool(todo.Done)
+	case FieldDescription:
+		return stringValue(todo.Description)
+	case FieldDetails:
+		return stringValue(todo.Details)
+	default:
+		return ""
+	}
+}
+
+func isCSVColumn(column string) bool {
+	for _, c := range allCSVColumns() {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+func allCSVColumns() []string {
+	return append(append([]string{}, CSVColumns...), csvExtraColumns...)
+}
+
+func isBlankRow(row []string) bool {
+	for _, v := range row {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func without(fields []string, field string) []string {
+	out := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if f != field {
+			out = append(out, f)
+		}
+	}
+	return out
+}
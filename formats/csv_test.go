@@ -0,0 +1,214 @@
+package formats
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"tuidoo/entities"
+	"tuidoo/enums"
+)
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		mapping string
+		want    []string
+	}{
+		{
+			name: "quoted commas and doubled quotes",
+			csv:  "name,project\n\"Say \"\"hi\"\", then leave\",\"R&D, labs\"\n",
+			want: []string{`name="Say \"hi\", then leave" project="R&D, labs"`},
+		},
+		{
+			name: "newlines inside a quoted cell",
+			csv:  "name,description\n\"Plan\",\"first line\nsecond line\"\nNext,\n",
+			want: []string{`name="Plan" description="first line\nsecond line"`, `name="Next"`},
+		},
+		{
+			name: "crlf line ends",
+			csv:  "name,due\r\nShip it,2026-05-04\r\n",
+			want: []string{`name="Ship it" due=2026-05-04`},
+		},
+		{
+			name: "bom, aliases and spaces after commas",
+			csv:  "\ufeffTitle, Context, Due Date, Notes\nShip it, Office, 2026-05-04, soon\n",
+			want: []string{`name="Ship it" list="Office" due=2026-05-04 details="soon"`},
+		},
+		{
+			name:    "mapped headers",
+			csv:     "Task,Deadline,Urgency\nShip it,2026-05-04T09:30:00Z,high\n",
+			mapping: "name=Task,due=Deadline,priority=Urgency",
+			want:    []string{`name="Ship it" priority=High due=2026-05-04T09:30:00Z`},
+		},
+		{
+			name: "blank rows are skipped, short rows padded",
+			csv:  "name,project,list\n,,\nShip it\n",
+			want: []string{`name="Ship it"`},
+		},
+		{
+			name: "spreadsheet spellings of done",
+			csv:  "name,done\nA,x\nB,✓\nC,no\n",
+			want: []string{`name="A" done`, `name="B" done`, `name="C"`},
+		},
+		{
+			name: "bad rows are reported and the rest read",
+			csv:  "name,priority,due\n,low,\nShip it,whenever,\nLater,,soonish\nFine,urgent,\n",
+			want: []string{
+				"error: name is empty",
+				`error: unknown priority "whenever" (expected one of Low, Medium, High, Urgent)`,
+				`error: invalid date "soonish" (expected YYYY-MM-DD)`,
+				`name="Fine" priority=Urgent`,
+			},
+		},
+		{
+			name: "a bare quote spoils only its row",
+			csv:  "name,project\nSay \"hi\",Work\nShip it,Work\n",
+			want: []string{`error: bare " in non-quoted-field`, `name="Ship it" project="Work"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping, err := ParseMapping(tt.mapping)
+			if err != nil {
+				t.Fatalf("ParseMapping: %v", err)
+			}
+			records, err := ReadCSV(strings.NewReader(tt.csv), mapping)
+			if err != nil {
+				t.Fatalf("ReadCSV: %v", err)
+			}
+
+			var got []string
+			for _, r := range records {
+				got = append(got, describe(r))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("read\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestReadCSVRejectsHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		mapping string
+		wantErr string
+	}{
+		{"empty file", "", "", "CSV file is empty"},
+		{"no name column", "project,due\nWork,2026-05-04\n", "", "no name column"},
+		{"mapped header missing", "Task\nShip it\n", "name=Task,due=Deadline", `column "Deadline" mapped to due not found`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping, err := ParseMapping(tt.mapping)
+			if err != nil {
+				t.Fatalf("ParseMapping: %v", err)
+			}
+			_, err = ReadCSV(strings.NewReader(tt.csv), mapping)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadCSVLines(t *testing.T) {
+	csv := "name,description\nFirst,\"spans\ntwo lines\"\nSecond,\n\nThird,\n"
+	records, err := ReadCSV(strings.NewReader(csv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []int
+	for _, r := range records {
+		lines = append(lines, r.Line)
+	}
+	if want := []int{2, 4, 6}; !equalInts(lines, want) {
+		t.Errorf("lines = %v, want %v", lines, want)
+	}
+}
+
+func TestReadCSVFieldsOnlyFromHeader(t *testing.T) {
+	records, err := ReadCSV(strings.NewReader("name,status,done\nShip it,done,\n"), nil)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("ReadCSV = %v, %v", records, err)
+	}
+
+	// An update must leave columns the file does not have alone, and an
+	// empty done cell lets done follow the status
+	r := records[0]
+	for field, want := range map[string]bool{FieldName: true, FieldStatus: true, FieldDone: false, FieldProject: false, FieldDue: false} {
+		if r.Has(field) != want {
+			t.Errorf("Has(%s) = %v, want %v", field, r.Has(field), want)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	tricky := newTodo("uid-1", "R&D, \"labs\"", "", `Say "hi"`)
+	tricky.Description = ptr("first line\nsecond")
+	tricky.DueDate = ptr(time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local))
+
+	timed := newTodo("uid-2", "Work", "Calls", "Call Anna")
+	timed.Priority = enums.High
+	timed.Done = true
+	timed.DueDate = ptr(time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC))
+
+	tests := []struct {
+		name    string
+		todos   []entities.ToDo
+		columns []string
+		want    string
+	}{
+		{
+			name:    "quotes what needs it",
+			todos:   []entities.ToDo{tricky},
+			columns: []string{FieldName, FieldProject, FieldDescription, FieldDue},
+			want:    "name,project,description,due\n\"Say \"\"hi\"\"\",\"R&D, \"\"labs\"\"\",\"first line\nsecond\",2026-03-01\n",
+		},
+		{
+			name:    "times of day and done",
+			todos:   []entities.ToDo{timed},
+			columns: []string{FieldName, FieldList, FieldPriority, FieldDue, FieldDone, FieldUID},
+			want:    "name,list,priority,due,done,uid\nCall Anna,Calls,High," + FormatDate(*timed.DueDate) + ",true,uid-2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteCSV(&buf, tt.todos, tt.columns); err != nil {
+				t.Fatalf("WriteCSV: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("wrote\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+
+			// What was written reads back the same
+			records, err := ReadCSV(&buf, nil)
+			if err != nil || len(records) != len(tt.todos) {
+				t.Fatalf("ReadCSV = %v, %v", records, err)
+			}
+			if records[0].Name != tt.todos[0].Name || stringValue(records[0].Description) != stringValue(tt.todos[0].Description) {
+				t.Errorf("read back %s", describe(records[0]))
+			}
+		})
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package formats
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Names are the export formats, as Detect returns them
+var Names = []string{"json", "csv", "markdown", "todotxt", "ical", "taskwarrior"}
+
+// Detect uses the explicit format, which may be an alias such as md, or
+// falls back to the file extension of path. Without either, such as for
+// stdin and stdout, it is json.
+func Detect(format, path string) (string, error) {
+	if format != "" {
+		format = strings.ToLower(format)
+		switch format {
+		case "md":
+			return "markdown", nil
+		case "todo.txt", "txt":
+			return "todotxt", nil
+		case "ics", "icalendar":
+			return "ical", nil
+		case "task", "tw":
+			return "taskwarrior", nil
+		}
+		for _, name := range Names {
+			if format == name {
+				return format, nil
+			}
+		}
+		return "", fmt.Errorf("unknown format %q (expected one of %s)", format, strings.Join(Names, ", "))
+	}
+
+	if path == "" || path == "-" {
+		return "json", nil
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		return "json", nil
+	case ".csv":
+		return "csv", nil
+	case ".md", ".markdown":
+		return "markdown", nil
+	case ".txt", ".todotxt":
+		return "todotxt", nil
+	case ".ics", ".ical":
+		return "ical", nil
+	default:
+		return "", fmt.Errorf("cannot tell the format of %s from its extension (expected .json, .csv, .md, .txt, .todotxt or .ics)", path)
+	}
+}
+
+// Extension is the usual file extension of a format
+func Extension(format string) string {
+	switch format {
+	case "markdown":
+		return ".md"
+	case "todotxt":
+		return ".txt"
+	case "ical":
+		return ".ics"
+	case "csv":
+		return ".csv"
+	default:
+		return ".json"
+	}
+}
@@ -0,0 +1,34 @@
+package formats
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		format, path string
+		want         string
+	}{
+		{"", "-", "json"},
+		{"", "", "json"},
+		{"", "backup.JSON", "json"},
+		{"", "tasks.csv", "csv"},
+		{"", "notes.markdown", "markdown"},
+		{"", "todo.txt", "todotxt"},
+		{"", "tasks.todotxt", "todotxt"},
+		{"", "calendar.ics", "ical"},
+		{"md", "tasks.csv", "markdown"},
+		{"TW", "-", "taskwarrior"},
+		{"csv", "tasks.todotxt", "csv"},
+	}
+	for _, tt := range tests {
+		got, err := Detect(tt.format, tt.path)
+		if err != nil || got != tt.want {
+			t.Errorf("Detect(%q, %q) = %q, %v; want %q", tt.format, tt.path, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range [][2]string{{"", "tasks.xlsx"}, {"", "tasks"}, {"jsn", "-"}} {
+		if got, err := Detect(bad[0], bad[1]); err == nil {
+			t.Errorf("Detect(%q, %q) = %q, want an error", bad[0], bad[1], got)
+		}
+	}
+}
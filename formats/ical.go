@@ -0,0 +1,380 @@
+package formats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"tuidoo/entities"
+	"tuidoo/enums"
+)
+
+const (
+	icalDateLayout     = "20060102"
+	icalDateTimeLayout = "20060102T150405Z"
+	icalLocalLayout    = "20060102T150405"
+	icalLineLimit      = 75
+)
+
+// icalPriorities maps priorities to RFC 5545 PRIORITY values, where 1 is
+// the highest and 9 the lowest
+var icalPriorities = map[enums.Priority]int{
+	enums.Urgent: 1,
+	enums.High:   3,
+	enums.Medium: 5,
+	enums.Low:    9,
+}
+
+// WriteICal writes todos as an RFC 5545 calendar of VTODO components. The
+// todo's UID becomes the component UID, project and list become CATEGORIES,
+// and the exact tuidoo status is kept in X-TUIDOO-STATUS because STATUS has
+// fewer values. Project and list must be preloaded.
+func WriteICal(w io.Writer, todos []entities.ToDo) error {
+	bw := bufio.NewWriter(w)
+	now := time.Now().UTC().Format(icalDateTimeLayout)
+
+	writeICalLine(bw, "BEGIN:VCALENDAR")
+	writeICalLine(bw, "VERSION:2.0")
+	writeICalLine(bw, "PRODID:-//tuidoo//tuidoo//EN")
+
+	for _, todo := range todos {
+		writeICalLine(bw, "BEGIN:VTODO")
+		writeICalLine(bw, "UID:"+icalEscape(todo.UID))
+		writeICalLine(bw, "DTSTAMP:"+now)
+		if !todo.CreatedAt.IsZero() {
+			writeICalLine(bw, "CREATED:"+todo.CreatedAt.UTC().Format(icalDateTimeLayout))
+		}
+		if !todo.UpdatedAt.IsZero() {
+			writeICalLine(bw, "LAST-MODIFIED:"+todo.UpdatedAt.UTC().Format(icalDateTimeLayout))
+		}
+		writeICalLine(bw, "SUMMARY:"+icalEscape(todo.Name))
+		if desc := stringValue(todo.Description); desc != "" {
+			writeICalLine(bw, "DESCRIPTION:"+icalEscape(desc))
+		}
+		if todo.DueDate != nil {
+			writeICalLine(bw, "DUE"+icalDate(*todo.DueDate))
+		}
+		writeICalLine(bw, "PRIORITY:"+strconv.Itoa(icalPriorities[todo.Priority]))
+		writeICalLine(bw, "STATUS:"+icalStatus(todo))
+		writeICalLine(bw, "X-TUIDOO-STATUS:"+icalEscape(todo.Status.String()))
+		if todo.Done {
+			writeICalLine(bw, "COMPLETED:"+todo.UpdatedAt.UTC().Format(icalDateTimeLayout))
+			writeICalLine(bw, "PERCENT-COMPLETE:100")
+		}
+
+		categories := []string{icalEscape(todo.Project.Name)}
+		if todo.ToDoList.Name != "" {
+			categories = append(categories, icalEscape(todo.ToDoList.Name))
+		}
+		writeICalLine(bw, "CATEGORIES:"+strings.Join(categories, ","))
+		writeICalLine(bw, "X-TUIDOO-PROJECT:"+icalEscape(todo.Project.Name))
+		writeICalLine(bw, "X-TUIDOO-LIST:"+icalEscape(todo.ToDoList.Name))
+
+		writeICalLine(bw, "END:VTODO")
+	}
+
+	writeICalLine(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+// ReadICal reads the VTODO components of an .ics file. Other components
+// such as events are skipped. Without tuidoo's own X- properties the first
+// category names the project and the second the list.
+func ReadICal(r io.Reader) ([]Record, error) {
+	lines, err := unfoldICal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	var todo map[string]icalProperty
+	var categories []string
+	start, nested := 0, 0
+
+	for _, line := range lines {
+		prop := parseICalLine(line.text)
+
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VTODO"):
+			todo = map[string]icalProperty{}
+			categories, nested = nil, 0
+			start = line.number
+			continue
+		case prop.name == "END" && strings.EqualFold(prop.value, "VTODO") && todo != nil && nested == 0:
+			record := Record{Line: start}
+			record.Err = parseVTodo(&record, todo, categories)
+			records = append(records, record)
+			todo = nil
+			continue
+		}
+
+		if todo == nil {
+			continue
+		}
+
+		// Components inside a task, such as alarms, have a DESCRIPTION and
+		// SUMMARY of their own
+		switch {
+		case prop.name == "BEGIN":
+			nested++
+			continue
+		case prop.name == "END" && nested > 0:
+			nested--
+			continue
+		case nested > 0:
+			continue
+		}
+
+		if prop.name == "CATEGORIES" {
+			categories = append(categories, splitICalList(prop.value)...)
+			continue
+		}
+		todo[prop.name] = prop
+	}
+
+	return records, nil
+}
+
+func parseVTodo(record *Record, props map[string]icalProperty, categories []string) error {
+	record.Fields = []string{FieldName, FieldDescription, FieldPriority, FieldStatus, FieldDone, FieldDue}
+
+	record.UID = icalUnescape(props["UID"].value)
+	record.Name = icalUnescape(props["SUMMARY"].value)
+	record.Description = optionalString(icalUnescape(props["DESCRIPTION"].value))
+
+	if p, ok := props["X-TUIDOO-PROJECT"]; ok {
+		record.Project = icalUnescape(p.value)
+		record.List = icalUnescape(props["X-TUIDOO-LIST"].value)
+		record.Fields = append(record.Fields, FieldProject, FieldList)
+	} else if len(categories) > 0 {
+		record.Project = icalUnescape(categories[0])
+		record.Fields = append(record.Fields, FieldProject)
+		if len(categories) > 1 {
+			record.List = icalUnescape(categories[1])
+			record.Fields = append(record.Fields, FieldList)
+		}
+	}
+
+	if p, ok := props["PRIORITY"]; ok && p.value != "" {
+		n, err := strconv.Atoi(strings.TrimSpace(p.value))
+		if err != nil || n < 0 || n > 9 {
+			return fmt.Errorf("invalid PRIORITY %q", p.value)
+		}
+		record.Priority = icalPriority(n)
+	}
+
+	switch strings.ToUpper(props["STATUS"].value) {
+	case "COMPLETED":
+		record.Status, record.Done = enums.Done, true
+	case "CANCELLED":
+		record.Status = enums.Closed
+	case "IN-PROCESS":
+		record.Status = enums.InProgress
+	default:
+		record.Status = enums.New
+	}
+	if _, ok := props["COMPLETED"]; ok {
+		record.Done = true
+		if record.Status == enums.New {
+			record.Status = enums.Done
+		}
+	}
+	if p, ok := props["X-TUIDOO-STATUS"]; ok {
+		status, err := enums.ParseStatus(icalUnescape(p.value))
+		if err != nil {
+			return err
+		}
+		record.Status = status
+	}
+
+	if p, ok := props["DUE"]; ok {
+		due, err := parseICalTime(p)
+		if err != nil {
+			return fmt.Errorf("invalid DUE: %w", err)
+		}
+		record.DueDate = &due
+	}
+
+	if record.Name == "" {
+		return fmt.Errorf("VTODO %s has no SUMMARY", record.UID)
+	}
+	return nil
+}
+
+type icalProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+type icalLine struct {
+	number int
+	text   string
+}
+
+// unfoldICal joins folded content lines, remembering where each started
+func unfoldICal(r io.Reader) ([]icalLine, error) {
+	var lines []icalLine
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1].text += text[1:]
+			continue
+		}
+		if text != "" {
+			lines = append(lines, icalLine{number: n, text: text})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+	return lines, nil
+}
+
+func parseICalLine(line string) icalProperty {
+	// The value starts at the first colon outside a quoted parameter
+	inQuotes := false
+	split := -1
+	for i, c := range line {
+		if c == '"' {
+			inQuotes = !inQuotes
+		}
+		if c == ':' && !inQuotes {
+			split = i
+			break
+		}
+	}
+	if split < 0 {
+		return icalProperty{name: strings.ToUpper(line)}
+	}
+
+	head, value := line[:split], line[split+1:]
+	parts := strings.Split(head, ";")
+
+	prop := icalProperty{name: strings.ToUpper(parts[0]), params: map[string]string{}, value: value}
+	for _, param := range parts[1:] {
+		key, val, _ := strings.Cut(param, "=")
+		prop.params[strings.ToUpper(key)] = strings.Trim(val, `"`)
+	}
+	return prop
+}
+
+func parseICalTime(prop icalProperty) (time.Time, error) {
+	value := strings.TrimSpace(prop.value)
+
+	if strings.EqualFold(prop.params["VALUE"], "DATE") || len(value) == len(icalDateLayout) {
+		return time.ParseInLocation(icalDateLayout, value, time.Local)
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse(icalDateTimeLayout, value)
+	}
+
+	loc := time.Local
+	if tzid := prop.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	return time.ParseInLocation(icalLocalLayout, value, loc)
+}
+
+// icalDate renders a due date as a DATE when it falls on local midnight and
+// as a UTC DATE-TIME otherwise, mirroring FormatDate
+func icalDate(t time.Time) string {
+	local := t.Local()
+	if local.Hour() == 0 && local.Minute() == 0 && local.Second() == 0 && local.Nanosecond() == 0 {
+		return ";VALUE=DATE:" + local.Format(icalDateLayout)
+	}
+	return ":" + t.UTC().Format(icalDateTimeLayout)
+}
+
+func icalStatus(todo entities.ToDo) string {
+	switch {
+	case todo.Done || todo.Status == enums.Done:
+		return "COMPLETED"
+	case todo.Status == enums.Closed:
+		return "CANCELLED"
+	case todo.Status == enums.InProgress:
+		return "IN-PROCESS"
+	default:
+		return "NEEDS-ACTION"
+	}
+}
+
+func icalPriority(n int) enums.Priority {
+	switch {
+	case n == 0:
+		return enums.Low
+	case n <= 2:
+		return enums.Urgent
+	case n <= 4:
+		return enums.High
+	case n == 5:
+		return enums.Medium
+	default:
+		return enums.Low
+	}
+}
+
+var (
+	icalEscaper   = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	icalUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+)
+
+func icalEscape(s string) string {
+	return icalEscaper.Replace(s)
+}
+
+func icalUnescape(s string) string {
+	return icalUnescaper.Replace(s)
+}
+
+// splitICalList splits a comma-separated value, honouring escaped commas
+func splitICalList(value string) []string {
+	var items []string
+	var current strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && i+1 < len(value):
+			current.WriteByte(value[i])
+			current.WriteByte(value[i+1])
+			i++
+		case value[i] == ',':
+			items = append(items, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(value[i])
+		}
+	}
+	items = append(items, current.String())
+
+	return items
+}
+
+// writeICalLine writes a content line, folding it at 75 octets without
+// splitting a UTF-8 sequence
+func writeICalLine(w *bufio.Writer, line string) {
+	limit := icalLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// Continuation lines lose one octet to the leading space
+		limit = icalLineLimit - 1
+	}
+	w.WriteString(line + "\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
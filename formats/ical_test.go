@@ -0,0 +1,331 @@
+package formats
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"tuidoo/entities"
+	"tuidoo/enums"
+	"unicode/utf8"
+)
+
+// vtodo wraps properties in a calendar with a single VTODO
+func vtodo(props ...string) string {
+	lines := append([]string{"BEGIN:VCALENDAR", "VERSION:2.0", "BEGIN:VTODO"}, props...)
+	return strings.Join(append(lines, "END:VTODO", "END:VCALENDAR"), "\r\n") + "\r\n"
+}
+
+func TestReadICal(t *testing.T) {
+	floating := FormatDate(time.Date(2026, 3, 1, 9, 30, 0, 0, time.Local))
+
+	tests := []struct {
+		name string
+		ics  string
+		want []string
+	}{
+		// Folding and escaping
+		{
+			name: "folded lines are joined",
+			ics:  vtodo("SUMMARY:Renew th", " e pass", "DESCRIPTION:Gr\xc3", "\t\xb6\xc3\x9fe"),
+			want: []string{`name="Renew the pass" description="Größe"`},
+		},
+		{
+			name: "bare newlines",
+			ics:  strings.ReplaceAll(vtodo("SUMMARY:Renew", " pass"), "\r\n", "\n"),
+			want: []string{`name="Renewpass"`},
+		},
+		{
+			name: "escaped text",
+			ics:  vtodo(`SUMMARY:Back\\slash\; semi\, comma`, `DESCRIPTION:one\ntwo\Nthree \\n`),
+			want: []string{`name="Back\\slash; semi, comma" description="one\ntwo\nthree \\n"`},
+		},
+		{
+			name: "lowercase names and colons in the value",
+			ics:  vtodo("summary:Meet at 10:30", "uid:abc"),
+			want: []string{`name="Meet at 10:30" uid=abc`},
+		},
+
+		// Due dates
+		{
+			name: "due as a date",
+			ics:  vtodo("SUMMARY:A", "DUE;VALUE=DATE:20260301"),
+			want: []string{`name="A" due=2026-03-01`},
+		},
+		{
+			name: "due as a date without VALUE",
+			ics:  vtodo("SUMMARY:A", "DUE:20260301"),
+			want: []string{`name="A" due=2026-03-01`},
+		},
+		{
+			name: "due in UTC",
+			ics:  vtodo("SUMMARY:A", "DUE:20260301T143000Z"),
+			want: []string{`name="A" due=2026-03-01T14:30:00Z`},
+		},
+		{
+			name: "due in a named timezone",
+			ics:  vtodo("SUMMARY:A", "DUE;TZID=Europe/Berlin:20260301T093000"),
+			want: []string{`name="A" due=2026-03-01T09:30:00+01:00`},
+		},
+		{
+			name: "quoted TZID",
+			ics:  vtodo("SUMMARY:A", `DUE;TZID="America/New_York":20260301T093000`),
+			want: []string{`name="A" due=2026-03-01T09:30:00-05:00`},
+		},
+		{
+			name: "floating and unknown timezones are local",
+			ics:  vtodo("SUMMARY:A", "DUE:20260301T093000") + vtodo("SUMMARY:B", "DUE;TZID=Mars/Olympus:20260301T093000"),
+			want: []string{`name="A" due=` + floating, `name="B" due=` + floating},
+		},
+
+		// Status and priority
+		{
+			name: "STATUS",
+			ics: vtodo("SUMMARY:A", "STATUS:NEEDS-ACTION") + vtodo("SUMMARY:B", "STATUS:IN-PROCESS") +
+				vtodo("SUMMARY:C", "STATUS:completed") + vtodo("SUMMARY:D", "STATUS:CANCELLED") +
+				vtodo("SUMMARY:E", "COMPLETED:20260301T090000Z") + vtodo("SUMMARY:F"),
+			want: []string{
+				`name="A"`,
+				`name="B" status=In Progress`,
+				`name="C" status=Done done`,
+				`name="D" status=Closed`,
+				`name="E" status=Done done`,
+				`name="F"`,
+			},
+		},
+		{
+			name: "tuidoo's own status wins",
+			ics:  vtodo("SUMMARY:A", "STATUS:IN-PROCESS", "X-TUIDOO-STATUS:On Hold"),
+			want: []string{`name="A" status=On Hold`},
+		},
+		{
+			name: "PRIORITY",
+			ics: vtodo("SUMMARY:0", "PRIORITY:0") + vtodo("SUMMARY:1", "PRIORITY:1") + vtodo("SUMMARY:2", "PRIORITY:2") +
+				vtodo("SUMMARY:3", "PRIORITY:3") + vtodo("SUMMARY:4", "PRIORITY:4") + vtodo("SUMMARY:5", "PRIORITY:5") +
+				vtodo("SUMMARY:6", "PRIORITY:6") + vtodo("SUMMARY:9", "PRIORITY:9"),
+			want: []string{
+				`name="0"`,
+				`name="1" priority=Urgent`,
+				`name="2" priority=Urgent`,
+				`name="3" priority=High`,
+				`name="4" priority=High`,
+				`name="5" priority=Medium`,
+				`name="6"`,
+				`name="9"`,
+			},
+		},
+
+		// Project and list
+		{
+			name: "categories name project and list",
+			ics:  vtodo("SUMMARY:A", `CATEGORIES:R&D\, labs,Papers,Extra`) + vtodo("SUMMARY:B", "CATEGORIES:Home", "CATEGORIES:Errands"),
+			want: []string{`name="A" project="R&D, labs" list="Papers"`, `name="B" project="Home" list="Errands"`},
+		},
+		{
+			name: "tuidoo's own project and list win",
+			ics:  vtodo("SUMMARY:A", "CATEGORIES:Home,Papers", "X-TUIDOO-PROJECT:Work", "X-TUIDOO-LIST:"),
+			want: []string{`name="A" project="Work"`},
+		},
+
+		// Other components
+		{
+			name: "events and alarms are skipped",
+			ics: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:Not a task\r\nEND:VEVENT\r\n" +
+				"BEGIN:VTODO\r\nSUMMARY:Renew pass\r\nBEGIN:VALARM\r\nACTION:DISPLAY\r\nDESCRIPTION:Reminder\r\n" +
+				"END:VALARM\r\nUID:abc\r\nEND:VTODO\r\nEND:VCALENDAR\r\n",
+			want: []string{`name="Renew pass" uid=abc`},
+		},
+
+		// Bad components
+		{
+			name: "bad components are reported and the rest read",
+			ics: vtodo("UID:no-summary") + vtodo("SUMMARY:A", "PRIORITY:10") + vtodo("SUMMARY:B", "PRIORITY:high") +
+				vtodo("SUMMARY:C", "DUE:soon") + vtodo("SUMMARY:D", "X-TUIDOO-STATUS:whenever") + vtodo("SUMMARY:Fine"),
+			want: []string{
+				"error: VTODO no-summary has no SUMMARY",
+				`error: invalid PRIORITY "10"`,
+				`error: invalid PRIORITY "high"`,
+				`error: invalid DUE: parsing time "soon" as "20060102T150405": cannot parse "soon" as "2006"`,
+				`error: unknown status "whenever" (expected one of New, In Progress, On Hold, Pending, Closed, Done)`,
+				`name="Fine"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := ReadICal(strings.NewReader(tt.ics))
+			if err != nil {
+				t.Fatalf("ReadICal: %v", err)
+			}
+
+			var got []string
+			for _, r := range records {
+				got = append(got, describe(r))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("read\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestReadICalLines(t *testing.T) {
+	records, err := ReadICal(strings.NewReader(vtodo("SUMMARY:A", " folded") + vtodo("SUMMARY:B")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []int
+	for _, r := range records {
+		lines = append(lines, r.Line)
+	}
+	if want := []int{3, 10}; !equalInts(lines, want) {
+		t.Errorf("lines = %v, want %v", lines, want)
+	}
+}
+
+func TestWriteICal(t *testing.T) {
+	updated := time.Date(2026, 1, 3, 18, 0, 0, 0, time.UTC)
+
+	escaped := newTodo("uid-1", "R&D; labs", "A, B", `Back\slash; semi, comma`)
+	escaped.Description = ptr("line one\nline two")
+	escaped.DueDate = ptr(time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local))
+	escaped.Priority = enums.High
+	escaped.Status = enums.OnHold
+
+	paid := newTodo("uid-2", "Home", "", "Pay rent")
+	paid.UpdatedAt = updated
+	paid.DueDate = ptr(time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC))
+	paid.Done = true
+	paid.Status = enums.Done
+
+	tests := []struct {
+		name string
+		todo entities.ToDo
+		want []string
+	}{
+		{
+			name: "escaped text and a due date",
+			todo: escaped,
+			want: []string{
+				"UID:uid-1",
+				`SUMMARY:Back\\slash\; semi\, comma`,
+				`DESCRIPTION:line one\nline two`,
+				"DUE;VALUE=DATE:20260301",
+				"PRIORITY:3",
+				"STATUS:NEEDS-ACTION",
+				"X-TUIDOO-STATUS:On Hold",
+				`CATEGORIES:R&D\; labs,A\, B`,
+				`X-TUIDOO-PROJECT:R&D\; labs`,
+				`X-TUIDOO-LIST:A\, B`,
+			},
+		},
+		{
+			name: "done with a time of day",
+			todo: paid,
+			want: []string{
+				"UID:uid-2",
+				"LAST-MODIFIED:20260103T180000Z",
+				"SUMMARY:Pay rent",
+				"DUE:20260302T143000Z",
+				"PRIORITY:9",
+				"STATUS:COMPLETED",
+				"X-TUIDOO-STATUS:Done",
+				"COMPLETED:20260103T180000Z",
+				"PERCENT-COMPLETE:100",
+				"CATEGORIES:Home",
+				"X-TUIDOO-PROJECT:Home",
+				"X-TUIDOO-LIST:",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteICal(&buf, []entities.ToDo{tt.todo}); err != nil {
+				t.Fatalf("WriteICal: %v", err)
+			}
+
+			// DTSTAMP is the time of writing
+			var got []string
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+			for _, line := range lines[4 : len(lines)-2] {
+				if !strings.HasPrefix(line, "DTSTAMP:") {
+					got = append(got, line)
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("wrote\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+
+			// What was written reads back the same
+			records, err := ReadICal(&buf)
+			if err != nil || len(records) != 1 {
+				t.Fatalf("ReadICal = %v, %v", records, err)
+			}
+			r := records[0]
+			if r.Err != nil || r.Name != tt.todo.Name || r.Project != tt.todo.Project.Name || r.List != tt.todo.ToDoList.Name ||
+				r.Status != tt.todo.Status || stringValue(r.Description) != stringValue(tt.todo.Description) {
+				t.Errorf("read back %s", describe(r))
+			}
+		})
+	}
+}
+
+func TestWriteICalFolding(t *testing.T) {
+	// SUMMARY: takes 8 of the first line's 75 octets
+	tests := []struct {
+		name  string
+		text  string
+		lines int
+	}{
+		{"exactly one line", strings.Repeat("x", 67), 1},
+		{"one octet over", strings.Repeat("x", 68), 2},
+		{"continuations hold 74 octets", strings.Repeat("x", 67+74), 2},
+		{"and one more", strings.Repeat("x", 67+74+1), 3},
+		{"multi-byte characters stay whole", strings.Repeat("x", 66) + "📦📦", 2},
+		{"long multi-byte text", strings.Repeat("Größe 📦 ", 20), 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			writeICalLine(w, "SUMMARY:"+tt.text)
+			w.Flush()
+
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+			var unfolded string
+			for i, line := range lines {
+				if len(line) > icalLineLimit {
+					t.Errorf("line %d is %d octets", i, len(line))
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("line %d splits a character: %q", i, line)
+				}
+				if i > 0 {
+					if !strings.HasPrefix(line, " ") {
+						t.Fatalf("line %d does not continue with a space: %q", i, line)
+					}
+					line = line[1:]
+				}
+				unfolded += line
+			}
+			if unfolded != "SUMMARY:"+tt.text {
+				t.Errorf("unfolded to %q", unfolded)
+			}
+
+			if len(lines) != tt.lines {
+				t.Errorf("folded into %d lines, want %d", len(lines), tt.lines)
+			}
+
+			records, err := ReadICal(strings.NewReader(vtodo(lines...)))
+			if err != nil || len(records) != 1 || records[0].Name != tt.text {
+				t.Errorf("read back %v, %v", records, err)
+			}
+		})
+	}
+}
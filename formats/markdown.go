@@ -0,0 +1,321 @@
+package formats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"tuidoo/entities"
+	"tuidoo/enums"
+)
+
+var (
+	// A closing run of #s only counts after whitespace, so "C#" keeps its #
+	markdownHeading = regexp.MustCompile(`^(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+	markdownClosing = regexp.MustCompile(`\s#+$`)
+	markdownTask    = regexp.MustCompile(`^(\s*)(?:[-*+]|\d+[.)])\s+\[([ xX])\]\s+(.*)$`)
+	markdownUID     = regexp.MustCompile(`\s*<!--\s*uid:(\S+)\s*-->\s*$`)
+
+	// Words of a name that read like annotations have their colon escaped
+	markdownAnnotationLike = regexp.MustCompile(`(^|\s)(due|priority|status):`)
+	markdownEscapedColon   = regexp.MustCompile(`(^|\s)(due|priority|status)\\:`)
+)
+
+// markdownFields are the fields a markdown checklist carries
+var markdownFields = []string{
+	FieldName, FieldProject, FieldList, FieldDone, FieldStatus,
+	FieldPriority, FieldDue, FieldDescription,
+}
+
+// WriteMarkdown renders todos as GitHub task lists: a heading per project,
+// a sub-heading per list, and one checklist item per todo. Due date,
+// priority and status are inline annotations (priority and status only
+// when they differ from the default), the description is indented below
+// the item and the UID is kept in an HTML comment for re-import. Project
+// and list must be preloaded.
+func WriteMarkdown(w io.Writer, todos []entities.ToDo) error {
+	bw := bufio.NewWriter(w)
+
+	type group struct {
+		project entities.Project
+		lists   map[uint][]entities.ToDo
+		listIDs []uint
+		names   map[uint]string
+	}
+
+	var groups []*group
+	byProject := map[uint]*group{}
+	for _, todo := range todos {
+		g, ok := byProject[todo.ProjectID]
+		if !ok {
+			g = &group{project: todo.Project, lists: map[uint][]entities.ToDo{}, names: map[uint]string{}}
+			byProject[todo.ProjectID] = g
+			groups = append(groups, g)
+		}
+		if _, ok := g.lists[todo.ToDoListID]; !ok {
+			g.listIDs = append(g.listIDs, todo.ToDoListID)
+			g.names[todo.ToDoListID] = todo.ToDoList.Name
+		}
+		g.lists[todo.ToDoListID] = append(g.lists[todo.ToDoListID], todo)
+	}
+
+	for i, g := range groups {
+		if i > 0 {
+			fmt.Fprintln(bw)
+		}
+		fmt.Fprintf(bw, "# %s\n", markdownHeadingText(g.project.Name))
+
+		// Todos without a list come first, directly under the project
+		sort.SliceStable(g.listIDs, func(a, b int) bool { return g.listIDs[a] == 0 && g.listIDs[b] != 0 })
+
+		for _, listID := range g.listIDs {
+			fmt.Fprintln(bw)
+			if listID != 0 {
+				fmt.Fprintf(bw, "## %s\n\n", markdownHeadingText(g.names[listID]))
+			}
+			for _, todo := range g.lists[listID] {
+				writeMarkdownTask(bw, todo)
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+func writeMarkdownTask(w io.Writer, todo entities.ToDo) {
+	check := " "
+	if todo.Done {
+		check = "x"
+	}
+
+	line := fmt.Sprintf("- [%s] %s", check, markdownAnnotationLike.ReplaceAllString(todo.Name, `$1$2\:`))
+	for _, annotation := range markdownAnnotations(todo) {
+		line += " " + annotation
+	}
+	if todo.UID != "" {
+		line += fmt.Sprintf(" <!-- uid:%s -->", todo.UID)
+	}
+	fmt.Fprintln(w, line)
+
+	if desc := stringValue(todo.Description); desc != "" {
+		for _, l := range strings.Split(desc, "\n") {
+			fmt.Fprintf(w, "  %s\n", l)
+		}
+	}
+}
+
+func markdownAnnotations(todo entities.ToDo) []string {
+	var annotations []string
+
+	if todo.DueDate != nil {
+		annotations = append(annotations, "due:"+FormatDate(*todo.DueDate))
+	}
+	if todo.Priority != enums.Low {
+		annotations = append(annotations, "priority:"+annotationValue(todo.Priority.String()))
+	}
+	if todo.Status != defaultStatus(todo.Done) {
+		annotations = append(annotations, "status:"+annotationValue(todo.Status.String()))
+	}
+
+	return annotations
+}
+
+// ReadMarkdown parses GitHub task lists. The shallowest heading level in
+// the document names projects and the next level names lists; checked
+// items are done. Other markdown is ignored.
+func ReadMarkdown(r io.Reader) ([]Record, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read markdown: %w", err)
+	}
+
+	projectLevel, listLevel := markdownHeadingLevels(lines)
+
+	var records []Record
+	var project, list string
+	inFence := false
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		if m := markdownHeading.FindStringSubmatch(line); m != nil {
+			switch len(m[1]) {
+			case projectLevel:
+				project, list = m[2], ""
+			case listLevel:
+				list = m[2]
+			}
+			continue
+		}
+
+		m := markdownTask.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		record := Record{
+			Line:    i + 1,
+			Project: project,
+			List:    list,
+			Done:    m[2] != " ",
+			Fields:  markdownFields,
+		}
+		record.Err = parseMarkdownTask(&record, m[3])
+
+		// Description lines are indented further than the item marker
+		indent := len(m[1]) + 2
+		var desc []string
+		for i+1 < len(lines) && isMarkdownContinuation(lines[i+1:], indent) {
+			i++
+			desc = append(desc, strings.TrimPrefix(lines[i], strings.Repeat(" ", indent)))
+		}
+		for len(desc) > 0 && strings.TrimSpace(desc[len(desc)-1]) == "" {
+			desc = desc[:len(desc)-1]
+		}
+		record.Description = optionalString(strings.Join(desc, "\n"))
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func parseMarkdownTask(record *Record, text string) error {
+	if m := markdownUID.FindStringSubmatch(text); m != nil {
+		record.UID = m[1]
+		text = text[:len(text)-len(m[0])]
+	}
+
+	record.Status = defaultStatus(record.Done)
+
+	// Annotations are trailing key:value tokens
+	words := strings.Fields(text)
+	for len(words) > 0 {
+		key, value, ok := strings.Cut(words[len(words)-1], ":")
+		if !ok {
+			break
+		}
+
+		switch key {
+		case "due":
+			due, err := ParseDate(value)
+			if err != nil {
+				return err
+			}
+			record.DueDate = &due
+		case "priority":
+			priority, err := enums.ParsePriority(value)
+			if err != nil {
+				return err
+			}
+			record.Priority = priority
+		case "status":
+			status, err := enums.ParseStatus(value)
+			if err != nil {
+				return err
+			}
+			record.Status = status
+		default:
+			key = ""
+		}
+		if key == "" {
+			break
+		}
+		words = words[:len(words)-1]
+	}
+
+	record.Name = markdownEscapedColon.ReplaceAllString(strings.Join(words, " "), "$1$2:")
+	if record.Name == "" {
+		return fmt.Errorf("task has no name")
+	}
+	return nil
+}
+
+// markdownHeadingText is a heading for name. A name ending in a run of #s
+// after a space gets a closing sequence of its own, which is stripped on
+// reading instead of the name's.
+func markdownHeadingText(name string) string {
+	if markdownClosing.MatchString(name) {
+		return name + " #"
+	}
+	return name
+}
+
+// markdownHeadingLevels returns the heading levels used for projects and
+// lists: the shallowest level found and the next one below it
+func markdownHeadingLevels(lines []string) (int, int) {
+	levels := map[int]bool{}
+	inFence := false
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if m := markdownHeading.FindStringSubmatch(line); m != nil && !inFence {
+			levels[len(m[1])] = true
+		}
+	}
+
+	var found []int
+	for level := 1; level <= 6; level++ {
+		if levels[level] {
+			found = append(found, level)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return 0, 0
+	case 1:
+		return found[0], 0
+	default:
+		return found[0], found[1]
+	}
+}
+
+// isMarkdownContinuation reports whether the first of lines belongs to the
+// description of an item indented by indent. Blank lines do when indented
+// text follows them.
+func isMarkdownContinuation(lines []string, indent int) bool {
+	line := lines[0]
+	if strings.TrimSpace(line) == "" {
+		for _, next := range lines[1:] {
+			if strings.TrimSpace(next) != "" {
+				return isMarkdownContinuation([]string{next}, indent)
+			}
+		}
+		return false
+	}
+	if !strings.HasPrefix(line, strings.Repeat(" ", indent)) {
+		return false
+	}
+	return !markdownTask.MatchString(line)
+}
+
+// defaultStatus is the status implied by a done flag alone
+func defaultStatus(done bool) enums.Status {
+	if done {
+		return enums.Done
+	}
+	return enums.New
+}
+
+// annotationValue writes an enum name as a single token, "In Progress"
+// becoming "in-progress"
+func annotationValue(s string) string {
+	return strings.ReplaceAll(strings.ToLower(s), " ", "-")
+}
@@ -0,0 +1,203 @@
+package formats
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"tuidoo/entities"
+	"tuidoo/enums"
+)
+
+func TestReadMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		md   string
+		want []string
+	}{
+		{
+			name: "headings name projects and lists",
+			md:   "# Work\n- [ ] Plan\n## Reports\n- [ ] Write\n# Home\n- [ ] Sweep\n",
+			want: []string{
+				`name="Plan" project="Work"`,
+				`name="Write" project="Work" list="Reports"`,
+				`name="Sweep" project="Home"`,
+			},
+		},
+		{
+			name: "the shallowest heading level names projects",
+			md:   "## Work\n### Reports\n#### Notes\n- [ ] Write\n",
+			want: []string{`name="Write" project="Work" list="Reports"`},
+		},
+		{
+			name: "closing #s are stripped but a name's own # is kept",
+			md:   "# C# ##\n## F# #\n- [ ] Learn C#\n",
+			want: []string{`name="Learn C#" project="C#" list="F#"`},
+		},
+		{
+			name: "every list marker and checked spelling",
+			md:   "- [ ] Dash\n* [x] Star\n+ [X] Plus\n1. [ ] Dot\n2) [ ] Paren\n- [] Not a task\n- Plain item\n",
+			want: []string{
+				`name="Dash"`,
+				`name="Star" status=Done done`,
+				`name="Plus" status=Done done`,
+				`name="Dot"`,
+				`name="Paren"`,
+			},
+		},
+		{
+			name: "trailing annotations",
+			md:   "- [ ] Ship due:2026-10-20 priority:high status:in-progress\n- [x] Filed status:closed\n",
+			want: []string{
+				`name="Ship" priority=High status=In Progress due=2026-10-20`,
+				`name="Filed" status=Closed done`,
+			},
+		},
+		{
+			name: "annotations only count at the end",
+			md:   "- [ ] Discuss priority:urgent with Anna\n- [ ] Note: 3:2 ratio due:2026-10-20\n",
+			want: []string{
+				`name="Discuss priority:urgent with Anna"`,
+				`name="Note: 3:2 ratio" due=2026-10-20`,
+			},
+		},
+		{
+			name: "escaped colons stay in the name",
+			md:   "- [ ] Fix status\\:page due\\:soon\n- [ ] priority\\:high\n",
+			want: []string{`name="Fix status:page due:soon"`, `name="priority:high"`},
+		},
+		{
+			name: "uid comment",
+			md:   "- [ ] Ship priority:medium <!-- uid:0b6e4c56 -->\n",
+			want: []string{`name="Ship" priority=Medium uid=0b6e4c56`},
+		},
+		{
+			name: "indented lines are the description",
+			md:   "- [ ] Ship\n  first line\n\n    indented line\n\n- [ ] Next\nNot indented\n",
+			want: []string{`name="Ship" description="first line\n\n  indented line"`, `name="Next"`},
+		},
+		{
+			name: "nested items are tasks of their own",
+			md:   "- [ ] Parent\n  - [ ] Child\n",
+			want: []string{`name="Parent"`, `name="Child"`},
+		},
+		{
+			name: "fenced code is ignored",
+			md:   "# Work\n```\n# Not a project\n- [ ] Not a task\n```\n- [ ] Real\n",
+			want: []string{`name="Real" project="Work"`},
+		},
+		{
+			name: "bad annotations are reported and the rest read",
+			md: "- [ ] A status:whenever\n- [ ] B priority:asap\n- [ ] C due:soonish\n" +
+				"- [ ] due:2026-10-20\n- [ ] Fine\n",
+			want: []string{
+				`error: unknown status "whenever" (expected one of New, In Progress, On Hold, Pending, Closed, Done)`,
+				`error: unknown priority "asap" (expected one of Low, Medium, High, Urgent)`,
+				`error: invalid date "soonish" (expected YYYY-MM-DD)`,
+				"error: task has no name",
+				`name="Fine"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := ReadMarkdown(strings.NewReader(tt.md))
+			if err != nil {
+				t.Fatalf("ReadMarkdown: %v", err)
+			}
+
+			var got []string
+			for _, r := range records {
+				got = append(got, describe(r))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("read\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestReadMarkdownLines(t *testing.T) {
+	records, err := ReadMarkdown(strings.NewReader("# Work\n\n- [ ] First\n  notes\n- [ ] Second\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []int
+	for _, r := range records {
+		lines = append(lines, r.Line)
+	}
+	if want := []int{3, 5}; !equalInts(lines, want) {
+		t.Errorf("lines = %v, want %v", lines, want)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	loose := newTodo("uid-1", "Work", "", "Call Anna")
+	loose.DueDate = ptr(time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local))
+
+	report := newTodo("uid-2", "Work", "Reports", "Fix status:page")
+	report.Priority = enums.High
+	report.Status = enums.InProgress
+	report.Description = ptr("first line\n  indented")
+
+	filed := newTodo("", "Work", "Reports", "Filed")
+	filed.Done = true
+	filed.Status = enums.Closed
+
+	milk := newTodo("", "C#", "F# ##", "Buy milk")
+	milk.Done = true
+	milk.Status = enums.Done
+
+	tests := []struct {
+		name  string
+		todos []entities.ToDo
+		want  string
+	}{
+		{
+			name:  "lists under their project, loose todos first",
+			todos: []entities.ToDo{report, loose, filed},
+			want: "# Work\n\n" +
+				"- [ ] Call Anna due:2026-03-01 <!-- uid:uid-1 -->\n\n" +
+				"## Reports\n\n" +
+				"- [ ] Fix status\\:page priority:high status:in-progress <!-- uid:uid-2 -->\n" +
+				"  first line\n" +
+				"    indented\n" +
+				"- [x] Filed status:closed\n",
+		},
+		{
+			name:  "headings ending in # get a closing sequence",
+			todos: []entities.ToDo{milk},
+			want:  "# C#\n\n## F# ## #\n\n- [x] Buy milk\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteMarkdown(&buf, tt.todos); err != nil {
+				t.Fatalf("WriteMarkdown: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("wrote\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+
+			// What was written reads back the same
+			records, err := ReadMarkdown(&buf)
+			if err != nil || len(records) != len(tt.todos) {
+				t.Fatalf("ReadMarkdown = %v, %v", records, err)
+			}
+			names := map[string]entities.ToDo{}
+			for _, todo := range tt.todos {
+				names[todo.Name] = todo
+			}
+			for _, r := range records {
+				todo, ok := names[r.Name]
+				if r.Err != nil || !ok || r.List != todo.ToDoList.Name || stringValue(r.Description) != stringValue(todo.Description) {
+					t.Errorf("read back %s", describe(r))
+				}
+			}
+		})
+	}
+}
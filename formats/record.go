@@ -0,0 +1,117 @@
+// Package formats converts todos to and from external file formats
+package formats
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"tuidoo/enums"
+)
+
+// Record fields, shared by every format that reads or writes them
+const (
+	FieldUID         = "uid"
+	FieldName        = "name"
+	FieldProject     = "project"
+	FieldList        = "list"
+	FieldPriority    = "priority"
+	FieldStatus      = "status"
+	FieldDue         = "due"
+	FieldDone        = "done"
+	FieldDescription = "description"
+	FieldDetails     = "details"
+)
+
+// DateLayout is used for due dates without a time of day
+const DateLayout = "2006-01-02"
+
+// Record is a todo read from an external format. Projects and lists are
+// referenced by name and resolved when the record is imported.
+type Record struct {
+	// Line is the position of the record in the source, for error reporting
+	Line        int
+	UID         string
+	Project     string
+	List        string
+	Name        string
+	Description *string
+	Details     *string
+	Priority    enums.Priority
+	Status      enums.Status
+	Done        bool
+	DueDate     *time.Time
+	// Deleted asks for the matching todo to be deleted. Records without a
+	// match are skipped.
+	Deleted bool
+	// Fields lists the fields the source provided. Fields that are not
+	// listed are left alone when an existing todo is updated. Nil means all.
+	Fields []string
+	// Err is set when the record could not be parsed
+	Err error
+}
+
+// Has reports whether the source provided a field
+func (r *Record) Has(field string) bool {
+	if r.Fields == nil {
+		return true
+	}
+	for _, f := range r.Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatDate renders a due date as a plain date when it falls on local
+// midnight and as RFC 3339 otherwise, so it parses back to the same instant
+func FormatDate(t time.Time) string {
+	local := t.Local()
+	if local.Hour() == 0 && local.Minute() == 0 && local.Second() == 0 && local.Nanosecond() == 0 {
+		return local.Format(DateLayout)
+	}
+	return t.Format(time.RFC3339)
+}
+
+// ParseDate accepts a plain date (local midnight), a date with a time of
+// day, or RFC 3339
+func ParseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{DateLayout, "2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date %q (expected YYYY-MM-DD)", s)
+}
+
+// ParseBool accepts the usual spreadsheet spellings of true and false
+func ParseBool(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "true", "yes", "y", "1", "x", "done", "✓":
+		return true, nil
+	case "false", "no", "n", "0", "", "open":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid boolean %q", s)
+	}
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,79 @@
+package formats
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"tuidoo/entities"
+	"tuidoo/enums"
+)
+
+// newTodo is a todo with its project and list preloaded, the way the
+// writers expect them
+func newTodo(uid, project, list, name string) entities.ToDo {
+	todo := entities.ToDo{UID: uid, Name: name}
+	todo.ProjectID = nameID(project)
+	todo.Project.ID = todo.ProjectID
+	todo.Project.Name = project
+	if list != "" {
+		todo.ToDoListID = nameID(list)
+		todo.ToDoList.ID = todo.ToDoListID
+		todo.ToDoList.Name = list
+	}
+	return todo
+}
+
+// nameID is a stable ID for a project or list name
+func nameID(name string) uint {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return uint(h.Sum32())
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// describe renders the fields a record was read with, in a fixed order and
+// leaving out empty ones, so tables can spell out what they expect
+func describe(r Record) string {
+	if r.Err != nil {
+		return "error: " + r.Err.Error()
+	}
+
+	parts := []string{fmt.Sprintf("name=%q", r.Name)}
+	add := func(field string, value any) {
+		parts = append(parts, fmt.Sprintf("%s=%v", field, value))
+	}
+	if r.Project != "" {
+		add("project", fmt.Sprintf("%q", r.Project))
+	}
+	if r.List != "" {
+		add("list", fmt.Sprintf("%q", r.List))
+	}
+	if r.Priority != enums.Low {
+		add("priority", r.Priority)
+	}
+	if r.Status != enums.New {
+		add("status", r.Status)
+	}
+	if r.Done {
+		parts = append(parts, "done")
+	}
+	if r.DueDate != nil {
+		add("due", FormatDate(*r.DueDate))
+	}
+	if r.Description != nil {
+		add("description", fmt.Sprintf("%q", *r.Description))
+	}
+	if r.Details != nil {
+		add("details", fmt.Sprintf("%q", *r.Details))
+	}
+	if r.UID != "" {
+		add("uid", r.UID)
+	}
+	if r.Deleted {
+		parts = append(parts, "deleted")
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,254 @@
+package formats
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+	"tuidoo/entities"
+	"tuidoo/enums"
+)
+
+const taskwarriorTimeLayout = "20060102T150405Z"
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// TaskwarriorTask is a task as written by `task export`. TuidooStatus and
+// TuidooPriority are user-defined attributes that keep what Taskwarrior's
+// own status and priority cannot express.
+type TaskwarriorTask struct {
+	UUID           string                  `json:"uuid"`
+	Description    string                  `json:"description"`
+	Status         string                  `json:"status"`
+	Project        string                  `json:"project,omitempty"`
+	Tags           []string                `json:"tags,omitempty"`
+	Priority       string                  `json:"priority,omitempty"`
+	Due            string                  `json:"due,omitempty"`
+	Wait           string                  `json:"wait,omitempty"`
+	Entry          string                  `json:"entry,omitempty"`
+	Modified       string                  `json:"modified,omitempty"`
+	End            string                  `json:"end,omitempty"`
+	Annotations    []TaskwarriorAnnotation `json:"annotations,omitempty"`
+	TuidooStatus   string                  `json:"tuidoostatus,omitempty"`
+	TuidooPriority string                  `json:"tuidoopriority,omitempty"`
+}
+
+type TaskwarriorAnnotation struct {
+	Entry       string `json:"entry"`
+	Description string `json:"description"`
+}
+
+// WriteTaskwarrior writes todos as a `task export` style JSON array that
+// `task import` accepts. The list becomes the first tag and each line of
+// the description an annotation. Project and list must be preloaded.
+func WriteTaskwarrior(w io.Writer, todos []entities.ToDo) error {
+	tasks := make([]TaskwarriorTask, 0, len(todos))
+	for _, todo := range todos {
+		tasks = append(tasks, taskwarriorTask(todo))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(tasks)
+}
+
+func taskwarriorTask(todo entities.ToDo) TaskwarriorTask {
+	task := TaskwarriorTask{
+		UUID:        TaskwarriorUUID(todo.UID),
+		Description: todo.Name,
+		Status:      "pending",
+		Project:     todo.Project.Name,
+		Entry:       todo.CreatedAt.UTC().Format(taskwarriorTimeLayout),
+		Modified:    todo.UpdatedAt.UTC().Format(taskwarriorTimeLayout),
+	}
+
+	switch {
+	case todo.Done:
+		task.Status = "completed"
+		task.End = todo.UpdatedAt.UTC().Format(taskwarriorTimeLayout)
+	case todo.Status == enums.OnHold:
+		task.Status = "waiting"
+	}
+	if todo.Status != taskwarriorDefaultStatus(task.Status) {
+		task.TuidooStatus = todo.Status.String()
+	}
+
+	switch todo.Priority {
+	case enums.Urgent:
+		task.Priority = "H"
+		task.TuidooPriority = todo.Priority.String()
+	case enums.High:
+		task.Priority = "H"
+	case enums.Medium:
+		task.Priority = "M"
+	}
+
+	if todo.ToDoList.Name != "" {
+		task.Tags = []string{todoTxtTag(todo.ToDoList.Name)}
+	}
+	if todo.DueDate != nil {
+		task.Due = todo.DueDate.UTC().Format(taskwarriorTimeLayout)
+	}
+
+	if desc := stringValue(todo.Description); desc != "" {
+		for _, line := range strings.Split(desc, "\n") {
+			task.Annotations = append(task.Annotations, TaskwarriorAnnotation{
+				Entry:       task.Modified,
+				Description: line,
+			})
+		}
+	}
+
+	return task
+}
+
+// ReadTaskwarrior reads `task export` output: a JSON array, or one object
+// per line as older versions wrote it. Deleted tasks delete the matching
+// todo; recurring templates are skipped.
+func ReadTaskwarrior(r io.Reader) ([]Record, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Taskwarrior export: %w", err)
+	}
+
+	var tasks []TaskwarriorTask
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		if err := json.Unmarshal(data, &tasks); err != nil {
+			return nil, fmt.Errorf("invalid Taskwarrior export: %w", err)
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		for dec.More() {
+			var task TaskwarriorTask
+			if err := dec.Decode(&task); err != nil {
+				return nil, fmt.Errorf("invalid Taskwarrior export: %w", err)
+			}
+			tasks = append(tasks, task)
+		}
+	}
+
+	var records []Record
+	for i, task := range tasks {
+		if task.Status == "recurring" {
+			continue
+		}
+
+		record := Record{Line: i + 1}
+		record.Err = parseTaskwarriorTask(&record, task)
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func parseTaskwarriorTask(record *Record, task TaskwarriorTask) error {
+	record.Fields = []string{FieldName, FieldProject, FieldList, FieldPriority, FieldStatus, FieldDone, FieldDue, FieldDescription}
+
+	record.UID = task.UUID
+	record.Name = task.Description
+	record.Project = task.Project
+	record.Deleted = task.Status == "deleted"
+
+	if record.UID == "" {
+		return errors.New("task has no uuid")
+	}
+	if record.Name == "" {
+		return errors.New("task has no description")
+	}
+
+	if len(task.Tags) > 0 {
+		record.List = todoTxtName(task.Tags[0])
+	}
+
+	switch strings.ToUpper(task.Priority) {
+	case "H":
+		record.Priority = enums.High
+	case "M":
+		record.Priority = enums.Medium
+	case "L", "":
+		record.Priority = enums.Low
+	default:
+		return fmt.Errorf("invalid priority %q", task.Priority)
+	}
+	if task.TuidooPriority != "" {
+		priority, err := enums.ParsePriority(task.TuidooPriority)
+		if err != nil {
+			return err
+		}
+		record.Priority = priority
+	}
+
+	record.Done = task.Status == "completed"
+	record.Status = taskwarriorDefaultStatus(task.Status)
+	// Taskwarrior 2.6 and later keep waiting tasks pending until their wait date
+	if task.Status == "pending" && task.Wait != "" {
+		wait, err := parseTaskwarriorTime(task.Wait)
+		if err != nil {
+			return fmt.Errorf("invalid wait: %w", err)
+		}
+		if wait.After(time.Now()) {
+			record.Status = enums.OnHold
+		}
+	}
+	if task.TuidooStatus != "" {
+		status, err := enums.ParseStatus(task.TuidooStatus)
+		if err != nil {
+			return err
+		}
+		record.Status = status
+	}
+
+	if task.Due != "" {
+		due, err := parseTaskwarriorTime(task.Due)
+		if err != nil {
+			return fmt.Errorf("invalid due: %w", err)
+		}
+		record.DueDate = &due
+	}
+
+	var notes []string
+	for _, annotation := range task.Annotations {
+		notes = append(notes, annotation.Description)
+	}
+	record.Description = optionalString(strings.Join(notes, "\n"))
+
+	return nil
+}
+
+func taskwarriorDefaultStatus(status string) enums.Status {
+	switch status {
+	case "completed":
+		return enums.Done
+	case "waiting":
+		return enums.OnHold
+	default:
+		return enums.New
+	}
+}
+
+func parseTaskwarriorTime(s string) (time.Time, error) {
+	if t, err := time.Parse(taskwarriorTimeLayout, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// TaskwarriorUUID returns uid when it already is a UUID, as it is for every
+// todo created by tuidoo, and otherwise derives a stable name-based UUID so
+// tasks imported from elsewhere export the same way every time
+func TaskwarriorUUID(uid string) string {
+	if uuidPattern.MatchString(uid) {
+		return strings.ToLower(uid)
+	}
+
+	sum := sha1.Sum([]byte("tuidoo:" + uid))
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
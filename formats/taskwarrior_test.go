@@ -0,0 +1,240 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+	"tuidoo/entities"
+	"tuidoo/enums"
+)
+
+const twUUID = "a1b2c3d4-0000-4000-8000-000000000001"
+
+func TestReadTaskwarrior(t *testing.T) {
+	tests := []struct {
+		name string
+		task string
+		want string
+	}{
+		// Status
+		{"pending", `"status":"pending"`, `name="Ship"`},
+		{"completed", `"status":"completed","end":"20260301T090000Z"`, `name="Ship" status=Done done`},
+		{"deleted", `"status":"deleted"`, `name="Ship" deleted`},
+		{"waiting", `"status":"waiting","wait":"20260301T090000Z"`, `name="Ship" status=On Hold`},
+		{"pending until a later wait date", `"status":"pending","wait":"29991231T000000Z"`, `name="Ship" status=On Hold`},
+		{"pending after the wait date passed", `"status":"pending","wait":"20200101T000000Z"`, `name="Ship"`},
+		{"unknown status", `"status":"someday"`, `name="Ship"`},
+		{"tuidoo's own status wins", `"status":"completed","tuidoostatus":"Closed"`, `name="Ship" status=Closed done`},
+		{"tuidoo status while pending", `"status":"pending","tuidoostatus":"In Progress"`, `name="Ship" status=In Progress`},
+
+		// Priority
+		{"priority H", `"priority":"H"`, `name="Ship" priority=High`},
+		{"priority M", `"priority":"M"`, `name="Ship" priority=Medium`},
+		{"priority L", `"priority":"L"`, `name="Ship"`},
+		{"lowercase priority", `"priority":"h"`, `name="Ship" priority=High`},
+		{"tuidoo's own priority wins", `"priority":"H","tuidoopriority":"Urgent"`, `name="Ship" priority=Urgent`},
+
+		// Dates
+		{"due in Taskwarrior's layout", `"due":"20260302T143000Z"`, `name="Ship" due=2026-03-02T14:30:00Z`},
+		{"due in RFC 3339", `"due":"2026-03-02T14:30:00+01:00"`, `name="Ship" due=2026-03-02T14:30:00+01:00`},
+
+		// Project, tags and annotations
+		{"project", `"project":"Side Project"`, `name="Ship" project="Side Project"`},
+		{"first tag is the list", `"tags":["next_week","home"]`, `name="Ship" list="next week"`},
+		{
+			"annotations are the description",
+			`"annotations":[{"entry":"20260301T090000Z","description":"first"},{"entry":"20260302T090000Z","description":"second"}]`,
+			`name="Ship" description="first\nsecond"`,
+		},
+
+		// Bad tasks
+		{"no uuid", `"uuid":""`, "error: task has no uuid"},
+		{"no description", `"description":""`, "error: task has no description"},
+		{"bad priority", `"priority":"X"`, `error: invalid priority "X"`},
+		{"bad tuidoo priority", `"tuidoopriority":"asap"`, `error: unknown priority "asap" (expected one of Low, Medium, High, Urgent)`},
+		{"bad due", `"due":"soon"`, `error: invalid due: parsing time "soon" as "2006-01-02T15:04:05Z07:00": cannot parse "soon" as "2006"`},
+		{"bad wait", `"wait":"soon"`, `error: invalid wait: parsing time "soon" as "2006-01-02T15:04:05Z07:00": cannot parse "soon" as "2006"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Later keys override the defaults
+			task := `{"uuid":"` + twUUID + `","description":"Ship","status":"pending",` + tt.task + "}"
+			records, err := ReadTaskwarrior(strings.NewReader(task))
+			if err != nil {
+				t.Fatalf("ReadTaskwarrior: %v", err)
+			}
+			if len(records) != 1 {
+				t.Fatalf("read %d records, want 1", len(records))
+			}
+
+			got := strings.Replace(describe(records[0]), " uid="+twUUID, "", 1)
+			if got != tt.want {
+				t.Errorf("read  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadTaskwarriorLayouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		export  string
+		want    []int
+		wantErr string
+	}{
+		{"json array", `[{"uuid":"a","description":"A","status":"pending"},{"uuid":"b","description":"B","status":"pending"}]`, []int{1, 2}, ""},
+		{"one object per line", "{\"uuid\":\"a\",\"description\":\"A\",\"status\":\"pending\"}\n{\"uuid\":\"b\",\"description\":\"B\",\"status\":\"pending\"}\n", []int{1, 2}, ""},
+		{"recurring templates are skipped", `[{"uuid":"a","description":"A","status":"recurring"},{"uuid":"b","description":"B","status":"pending"}]`, []int{2}, ""},
+		{"empty", "  \n", nil, ""},
+		{"broken array", `[{"uuid":"a"`, nil, "invalid Taskwarrior export"},
+		{"broken line", "{\"uuid\":\"a\"}\n{\"uuid\":\n", nil, "invalid Taskwarrior export"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := ReadTaskwarrior(strings.NewReader(tt.export))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadTaskwarrior: %v", err)
+			}
+
+			var lines []int
+			for _, r := range records {
+				lines = append(lines, r.Line)
+			}
+			if !equalInts(lines, tt.want) {
+				t.Errorf("lines = %v, want %v", lines, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskwarriorTaskStatus(t *testing.T) {
+	tests := []struct {
+		status       enums.Status
+		done         bool
+		want         string
+		tuidooStatus string
+	}{
+		{enums.New, false, "pending", ""},
+		{enums.InProgress, false, "pending", "In Progress"},
+		{enums.Pending, false, "pending", "Pending"},
+		{enums.OnHold, false, "waiting", ""},
+		{enums.Done, true, "completed", ""},
+		{enums.Closed, true, "completed", "Closed"},
+		{enums.OnHold, true, "completed", "On Hold"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status.String(), func(t *testing.T) {
+			todo := newTodo(twUUID, "Work", "", "Ship")
+			todo.Status, todo.Done = tt.status, tt.done
+
+			task := taskwarriorTask(todo)
+			if task.Status != tt.want || task.TuidooStatus != tt.tuidooStatus {
+				t.Errorf("status = %q, tuidoostatus = %q, want %q, %q", task.Status, task.TuidooStatus, tt.want, tt.tuidooStatus)
+			}
+			if (task.End != "") != tt.done {
+				t.Errorf("end = %q", task.End)
+			}
+		})
+	}
+}
+
+func TestTaskwarriorTaskPriority(t *testing.T) {
+	tests := []struct {
+		priority       enums.Priority
+		want           string
+		tuidooPriority string
+	}{
+		{enums.Low, "", ""},
+		{enums.Medium, "M", ""},
+		{enums.High, "H", ""},
+		{enums.Urgent, "H", "Urgent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.priority.String(), func(t *testing.T) {
+			todo := newTodo(twUUID, "Work", "", "Ship")
+			todo.Priority = tt.priority
+
+			task := taskwarriorTask(todo)
+			if task.Priority != tt.want || task.TuidooPriority != tt.tuidooPriority {
+				t.Errorf("priority = %q, tuidoopriority = %q, want %q, %q", task.Priority, task.TuidooPriority, tt.want, tt.tuidooPriority)
+			}
+		})
+	}
+}
+
+func TestWriteTaskwarrior(t *testing.T) {
+	todo := newTodo("0B6E4C56-1C1E-4D0F-9A44-6F1B2D7D1A51", "Side Project", "Next Week", "Write report")
+	todo.CreatedAt = time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	todo.UpdatedAt = time.Date(2026, 1, 3, 18, 0, 0, 0, time.UTC)
+	todo.DueDate = ptr(time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC))
+	todo.Description = ptr("first\nsecond")
+	todo.Done = true
+	todo.Status = enums.Done
+	todo.Priority = enums.Medium
+
+	var buf bytes.Buffer
+	if err := WriteTaskwarrior(&buf, []entities.ToDo{todo}); err != nil {
+		t.Fatalf("WriteTaskwarrior: %v", err)
+	}
+
+	want := `[{"uuid":"0b6e4c56-1c1e-4d0f-9a44-6f1b2d7d1a51","description":"Write report","status":"completed",` +
+		`"project":"Side Project","tags":["Next_Week"],"priority":"M","due":"20260302T143000Z",` +
+		`"entry":"20260102T100000Z","modified":"20260103T180000Z","end":"20260103T180000Z",` +
+		`"annotations":[{"entry":"20260103T180000Z","description":"first"},{"entry":"20260103T180000Z","description":"second"}]}]`
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, buf.Bytes()); err != nil {
+		t.Fatalf("WriteTaskwarrior wrote invalid JSON: %v", err)
+	}
+	if compact.String() != want {
+		t.Errorf("wrote\n%s\nwant\n%s", compact.String(), want)
+	}
+
+	// What was written reads back the same
+	records, err := ReadTaskwarrior(&buf)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("ReadTaskwarrior = %v, %v", records, err)
+	}
+	wantRecord := `name="Write report" project="Side Project" list="Next Week" priority=Medium status=Done done ` +
+		`due=2026-03-02T14:30:00Z description="first\nsecond" uid=0b6e4c56-1c1e-4d0f-9a44-6f1b2d7d1a51`
+	if got := describe(records[0]); got != wantRecord {
+		t.Errorf("read back %s\nwant      %s", got, wantRecord)
+	}
+}
+
+func TestTaskwarriorUUID(t *testing.T) {
+	tests := []struct {
+		uid  string
+		want string
+	}{
+		{"0b6e4c56-1c1e-4d0f-9a44-6f1b2d7d1a01", "0b6e4c56-1c1e-4d0f-9a44-6f1b2d7d1a01"},
+		{"0B6E4C56-1C1E-4D0F-9A44-6F1B2D7D1A01", "0b6e4c56-1c1e-4d0f-9a44-6f1b2d7d1a01"},
+		{"todotxt-42", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		got := TaskwarriorUUID(tt.uid)
+		if !uuidPattern.MatchString(got) {
+			t.Errorf("TaskwarriorUUID(%q) = %q, not a UUID", tt.uid, got)
+		}
+		if tt.want != "" && got != tt.want {
+			t.Errorf("TaskwarriorUUID(%q) = %q, want %q", tt.uid, got, tt.want)
+		}
+		// Derived UUIDs are version 5 and the same every time
+		if tt.want == "" && (got[14] != '5' || TaskwarriorUUID(tt.uid) != got) {
+			t.Errorf("TaskwarriorUUID(%q) = %q, want a stable version 5 UUID", tt.uid, got)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package formats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"tuidoo/entities"
+	"tuidoo/enums"
+)
+
+var (
+	todoTxtPriority = regexp.MustCompile(`^\(([A-Z])\)$`)
+	todoTxtDate     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+// todoTxtPriorities maps todo.txt priority letters to priorities. Letters
+// after C, and no letter at all, are Low.
+var todoTxtPriorities = map[string]enums.Priority{
+	"A": enums.Urgent,
+	"B": enums.High,
+	"C": enums.Medium,
+}
+
+// WriteTodoTxt writes one todo.txt line per todo. Projects become +project,
+// lists become @context, and due date, status, UID and the priority of
+// completed tasks are key:value tags. Spaces in project and list names are
+// written as underscores. Project and list must be preloaded.
+func WriteTodoTxt(w io.Writer, todos []entities.ToDo) error {
+	bw := bufio.NewWriter(w)
+	for _, todo := range todos {
+		fmt.Fprintln(bw, TodoTxtLine(todo))
+	}
+	return bw.Flush()
+}
+
+// TodoTxtLine renders a single todo in todo.txt format
+func TodoTxtLine(todo entities.ToDo) string {
+	var parts []string
+
+	letter := todoTxtLetter(todo.Priority)
+	if todo.Done {
+		parts = append(parts, "x", todo.UpdatedAt.Local().Format(DateLayout))
+	} else if letter != "" {
+		parts = append(parts, "("+letter+")")
+	}
+	if !todo.CreatedAt.IsZero() {
+		parts = append(parts, todo.CreatedAt.Local().Format(DateLayout))
+	}
+
+	parts = append(parts, todo.Name)
+
+	if todo.Project.Name != "" {
+		parts = append(parts, "+"+todoTxtTag(todo.Project.Name))
+	}
+	if todo.ToDoList.Name != "" {
+		parts = append(parts, "@"+todoTxtTag(todo.ToDoList.Name))
+	}
+	if todo.DueDate != nil {
+		parts = append(parts, "due:"+FormatDate(*todo.DueDate))
+	}
+	if todo.Status != defaultStatus(todo.Done) {
+		parts = append(parts, "status:"+annotationValue(todo.Status.String()))
+	}
+	// Completed tasks lose their (A) prefix, so the priority moves to a tag
+	if todo.Done && letter != "" {
+		parts = append(parts, "pri:"+letter)
+	}
+	if todo.UID != "" {
+		parts = append(parts, "uid:"+todo.UID)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// ReadTodoTxt parses a todo.txt file. The last +project and @context of a
+// line name its project and list; earlier ones stay part of the name.
+func ReadTodoTxt(r io.Reader) ([]Record, error) {
+	var records []Record
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		record := Record{Line: line}
+		record.Err = parseTodoTxtLine(&record, text)
+		records = append(records, record)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return records, fmt.Errorf("failed to read todo.txt: %w", err)
+	}
+
+	return records, nil
+}
+
+func parseTodoTxtLine(record *Record, text string) error {
+	words := strings.Fields(text)
+	record.Fields = []string{FieldName, FieldList, FieldPriority, FieldStatus, FieldDone, FieldDue}
+
+	if len(words) > 0 && words[0] == "x" {
+		record.Done = true
+		words = words[1:]
+		// Completion date, then creation date
+		if len(words) > 0 && todoTxtDate.MatchString(words[0]) {
+			words = words[1:]
+		}
+	} else if len(words) > 0 {
+		if m := todoTxtPriority.FindStringSubmatch(words[0]); m != nil {
+			record.Priority = todoTxtPriorities[m[1]]
+			words = words[1:]
+		}
+	}
+	if len(words) > 0 && todoTxtDate.MatchString(words[0]) {
+		words = words[1:]
+	}
+
+	record.Status = defaultStatus(record.Done)
+
+	// The last +project and @context are the ones WriteTodoTxt appends
+	projectAt, listAt := -1, -1
+	for i, word := range words {
+		switch {
+		case len(word) > 1 && word[0] == '+':
+			projectAt = i
+		case len(word) > 1 && word[0] == '@':
+			listAt = i
+		}
+	}
+	if projectAt >= 0 {
+		record.Project = todoTxtName(words[projectAt][1:])
+		record.Fields = append(record.Fields, FieldProject)
+	}
+	if listAt >= 0 {
+		record.List = todoTxtName(words[listAt][1:])
+	}
+
+	var name []string
+	for i, word := range words {
+		if i == projectAt || i == listAt {
+			continue
+		}
+
+		key, value, ok := strings.Cut(word, ":")
+		if !ok || value == "" {
+			name = append(name, word)
+			continue
+		}
+
+		switch key {
+		case "due":
+			due, err := ParseDate(value)
+			if err != nil {
+				return err
+			}
+			record.DueDate = &due
+		case "status":
+			status, err := enums.ParseStatus(value)
+			if err != nil {
+				return err
+			}
+			record.Status = status
+		case "pri":
+			priority, ok := todoTxtPriorities[strings.ToUpper(value)]
+			if !ok && !todoTxtPriority.MatchString("("+strings.ToUpper(value)+")") {
+				return fmt.Errorf("invalid priority %q", value)
+			}
+			record.Priority = priority
+		case "uid":
+			record.UID = value
+		default:
+			name = append(name, word)
+		}
+	}
+
+	// Ticking off a line in another tool leaves a stale status tag behind
+	if record.Done && record.Status != enums.Closed {
+		record.Status = enums.Done
+	}
+
+	record.Name = strings.Join(name, " ")
+	if record.Name == "" {
+		return fmt.Errorf("task has no text")
+	}
+	return nil
+}
+
+func todoTxtLetter(p enums.Priority) string {
+	for letter, priority := range todoTxtPriorities {
+		if priority == p {
+			return letter
+		}
+	}
+	return ""
+}
+
+// todoTxtTag turns a name into a single +project or @context token
+func todoTxtTag(name string) string {
+	return strings.Join(strings.Fields(name), "_")
+}
+
+func todoTxtName(tag string) string {
+	return strings.ReplaceAll(tag, "_", " ")
+}
@@ -0,0 +1,150 @@
+package formats
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"tuidoo/entities"
+	"tuidoo/enums"
+)
+
+func TestReadTodoTxt(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		// Priorities
+		{"(A) Call Mom", `name="Call Mom" priority=Urgent`},
+		{"(B) Call Mom", `name="Call Mom" priority=High`},
+		{"(C) Call Mom", `name="Call Mom" priority=Medium`},
+		{"(D) Call Mom", `name="Call Mom"`},
+		{"(a) Call Mom", `name="(a) Call Mom"`},
+		{"Call Mom (A)", `name="Call Mom (A)"`},
+
+		// Completion and creation dates
+		{"(A) 2026-01-02 Call Mom", `name="Call Mom" priority=Urgent`},
+		{"2026-01-02 Call Mom", `name="Call Mom"`},
+		{"x 2026-01-03 2026-01-02 Pay rent", `name="Pay rent" status=Done done`},
+		{"x 2026-01-03 Pay rent pri:B", `name="Pay rent" priority=High status=Done done`},
+		{"x Pay rent", `name="Pay rent" status=Done done`},
+		{"xylophone lesson", `name="xylophone lesson"`},
+		{"X Pay rent", `name="X Pay rent"`},
+
+		// Projects and contexts
+		{"Call Mom +Family @phone", `name="Call Mom" project="Family" list="phone"`},
+		{"Plan +Side_Project @Next_Week", `name="Plan" project="Side Project" list="Next Week"`},
+		{"Tag +bug and @home inline +Work @office", `name="Tag +bug and @home inline" project="Work" list="office"`},
+		{"Mail me@example.com a+b", `name="Mail me@example.com a+b"`},
+
+		// Tags
+		{"Ship due:2026-10-20", `name="Ship" due=2026-10-20`},
+		{"Ship due:2026-10-20T09:30:00Z", `name="Ship" due=2026-10-20T09:30:00Z`},
+		{"Ship status:on-hold uid:abc-123", `name="Ship" status=On Hold uid=abc-123`},
+		{"x Ticked elsewhere status:in-progress", `name="Ticked elsewhere" status=Done done`},
+		{"x Dropped status:closed pri:A", `name="Dropped" priority=Urgent status=Closed done`},
+		{"Read url:https://example.com due: later", `name="Read url:https://example.com due: later"`},
+
+		// Bad lines
+		{"Ship due:someday", `error: invalid date "someday" (expected YYYY-MM-DD)`},
+		{"Ship status:whenever", `error: unknown status "whenever" (expected one of New, In Progress, On Hold, Pending, Closed, Done)`},
+		{"x Ship pri:1", `error: invalid priority "1"`},
+		{"(A) 2026-01-02 +OnlyAProject @home", "error: task has no text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			records, err := ReadTodoTxt(strings.NewReader(tt.line + "\n"))
+			if err != nil {
+				t.Fatalf("ReadTodoTxt: %v", err)
+			}
+			if len(records) != 1 {
+				t.Fatalf("read %d records, want 1", len(records))
+			}
+			if got := describe(records[0]); got != tt.want {
+				t.Errorf("read  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadTodoTxtLines(t *testing.T) {
+	records, err := ReadTodoTxt(strings.NewReader("First\n\n   \nSecond\r\nThird"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []int
+	for _, r := range records {
+		lines = append(lines, r.Line)
+	}
+	if want := []int{1, 4, 5}; !equalInts(lines, want) {
+		t.Errorf("lines = %v, want %v", lines, want)
+	}
+	if records[1].Name != "Second" {
+		t.Errorf("name = %q, want the CR trimmed", records[1].Name)
+	}
+}
+
+func TestTodoTxtLine(t *testing.T) {
+	created := time.Date(2026, 1, 2, 10, 0, 0, 0, time.Local)
+	updated := time.Date(2026, 1, 3, 18, 0, 0, 0, time.Local)
+
+	todo := func(project, list, name string) entities.ToDo {
+		todo := newTodo("", project, list, name)
+		todo.CreatedAt, todo.UpdatedAt = created, updated
+		return todo
+	}
+
+	plain := todo("Work", "", "Call Anna")
+	plain.CreatedAt = time.Time{}
+
+	spaced := todo("Side  Project", "Next Week", "Plan")
+	spaced.Priority = enums.Urgent
+	spaced.DueDate = ptr(time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local))
+	spaced.UID = "uid-1"
+
+	held := todo("Work", "", "Wait")
+	held.Priority = enums.Low
+	held.Status = enums.OnHold
+
+	paid := todo("Home", "", "Pay rent")
+	paid.Done = true
+	paid.Status = enums.Done
+	paid.Priority = enums.High
+
+	dropped := todo("Work", "", "Dropped")
+	dropped.Done = true
+	dropped.Status = enums.Closed
+
+	tests := []struct {
+		name string
+		todo entities.ToDo
+		want string
+	}{
+		{"no dates", plain, "Call Anna +Work"},
+		{"priority, tags and underscores", spaced, "(A) 2026-01-02 Plan +Side_Project @Next_Week due:2026-03-01 uid:uid-1"},
+		{"low has no letter", held, "2026-01-02 Wait +Work status:on-hold"},
+		{"done moves the priority to a tag", paid, "x 2026-01-03 2026-01-02 Pay rent +Home pri:B"},
+		{"done keeps a closed status", dropped, "x 2026-01-03 2026-01-02 Dropped +Work status:closed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := TodoTxtLine(tt.todo)
+			if line != tt.want {
+				t.Errorf("wrote %s\nwant  %s", line, tt.want)
+			}
+
+			// What was written reads back the same
+			records, err := ReadTodoTxt(strings.NewReader(line))
+			if err != nil || len(records) != 1 {
+				t.Fatalf("ReadTodoTxt = %v, %v", records, err)
+			}
+			r := records[0]
+			if r.Err != nil || r.Name != tt.todo.Name || r.Priority != tt.todo.Priority ||
+				r.Status != tt.todo.Status || r.Done != tt.todo.Done || r.UID != tt.todo.UID {
+				t.Errorf("read back %s", describe(r))
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"tuidoo/config"
+	"tuidoo/services"
+)
+
+// Backup runs `tuidoo backup [create|list|prune]`
+func Backup(sel config.Selection, args []string) error {
+	command := "create"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	db := services.NewDbService(sel.DbPath)
+	if err := db.Open(); err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	backups := services.NewBackupService(db, sel.Backup)
+
+	switch command {
+	case "create":
+		backup, err := backups.Create("manual")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ Backup written to %s (%s)\n", backup.Path, formatSize(backup.Size))
+
+		removed, err := backups.Prune()
+		for _, b := range removed {
+			fmt.Printf("🗑️  Pruned %s\n", b.Path)
+		}
+		return err
+
+	case "list":
+		list, err := backups.List()
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			fmt.Printf("No backups in %s\n", backups.Dir())
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CREATED\tREASON\tSIZE\tPATH")
+		for _, b := range list {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				b.CreatedAt.Format("2006-01-02 15:04:05"), b.Reason, formatSize(b.Size), b.Path)
+		}
+		return w.Flush()
+
+	case "prune":
+		removed, err := backups.Prune()
+		for _, b := range removed {
+			fmt.Printf("🗑️  Pruned %s\n", b.Path)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ Kept the last %d daily and %d weekly backups\n", sel.Backup.KeepDaily, sel.Backup.KeepWeekly)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown backup command %q (expected create, list or prune)", command)
+	}
+}
+
+// Restore runs `tuidoo restore [--yes] <file|latest>`
+func Restore(sel config.Selection, args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	yes := flags.Bool("yes", false, "do not ask for confirmation")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: tuidoo restore [--yes] <file|latest>")
+	}
+
+	db := services.NewDbService(sel.DbPath)
+	if err := db.Open(); err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	backups := services.NewBackupService(db, sel.Backup)
+
+	path := flags.Arg(0)
+	if path == "latest" {
+		latest, err := backups.Latest()
+		if err != nil {
+			return err
+		}
+		path = latest.Path
+	}
+
+	if err := services.VerifyBackup(path); err != nil {
+		return fmt.Errorf("refusing to restore: %w", err)
+	}
+
+	if !*yes {
+		fmt.Printf("⚠️  This will replace %s with %s. Continue? (y/N)\n", sel.DbPath, path)
+		var response string
+		fmt.Scanln(&response)
+
+		if response != "y" && response != "Y" {
+			fmt.Println("Restore cancelled")
+			return nil
+		}
+	}
+
+	safety, err := backups.Restore(path)
+	if safety != nil {
+		fmt.Printf("💾 Previous data saved to %s\n", safety.Path)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("✅ Database restored successfully")
+	return nil
+}
+
+func formatSize(bytes int64) string {
+	switch {
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(bytes)/(1<<20))
+	case bytes >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(bytes)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", bytes)
+	}
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"tuidoo/entities"
+	"tuidoo/services"
+
+	"gorm.io/gorm"
+)
+
+// Exit codes of the scriptable commands
+const (
+	ExitFailure  = 1
+	ExitUsage    = 2
+	ExitNotFound = 3
+	ExitRejected = 4
+)
+
+// usageError marks bad arguments, which exit with ExitUsage like flag errors
+type usageError struct{ msg string }
+
+func (e usageError) Error() string { return e.msg }
+
+func usage(format string, args ...any) error {
+	return usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// ExitCode maps a command error onto the exit code scripts can check
+func ExitCode(err error) int {
+	var bad usageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &bad):
+		return ExitUsage
+	case errors.Is(err, services.ErrNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		return ExitNotFound
+	case errors.Is(err, services.ErrHookRejected):
+		return ExitRejected
+	default:
+		return ExitFailure
+	}
+}
+
+// parseInterspersed parses flags before, between and after the positional
+// arguments, so `edit 5 --priority high` works like `edit --priority high 5`.
+// Everything after "--" is positional.
+func parseInterspersed(flags *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		flags.Parse(args)
+		rest := flags.Args()
+		if len(rest) == 0 {
+			return positional
+		}
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...)
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// flagsSet returns the names of the flags given on the command line
+func flagsSet(flags *flag.FlagSet) map[string]bool {
+	set := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	return set
+}
+
+func parseTaskID(s string) (uint, error) {
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || id == 0 {
+		return 0, usage("invalid task id %q", s)
+	}
+	return uint(id), nil
+}
+
+// findProject resolves a project by ID or by name
+func findProject(sc *services.ServiceCollection, ref string) (*entities.Project, error) {
+	if id, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		project, err := sc.ProjectService.GetByID(uint(id), false)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("project %d %w", id, services.ErrNotFound)
+		}
+		return project, err
+	}
+	return sc.ProjectService.GetByName(ref)
+}
+
+// findList resolves a list by ID or by name
+func findList(sc *services.ServiceCollection, ref string) (*entities.ToDoList, error) {
+	if id, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		list, err := sc.ToDoListService.GetByID(uint(id), false)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("list %d %w", id, services.ErrNotFound)
+		}
+		return list, err
+	}
+	return sc.ToDoListService.GetByName(ref)
+}
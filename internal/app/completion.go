@@ -0,0 +1,506 @@
+package app
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"tuidoo/config"
+	"tuidoo/enums"
+	"tuidoo/services"
+)
+
+// completion is one shell completion candidate
+type completion struct {
+	value       string
+	description string
+}
+
+// completeFiles is printed instead of candidates to have the shell complete
+// file names
+const completeFiles = ":files"
+
+// completionCommand describes a command for shell completion
+type completionCommand struct {
+	name        string
+	description string
+	// flags registers the command's flags, with the same helpers the command
+	// uses where it has them
+	flags func(*flag.FlagSet)
+	// values completes flag values the shared flagValues does not know, or
+	// knows differently for this command
+	values map[string]func(c *completer, prefix string) []completion
+	// args completes the n-th positional argument (from 0)
+	args        func(c *completer, n int, prefix string) []completion
+	subcommands []completionCommand
+}
+
+var exportFormats = []string{"json", "csv", "markdown", "todotxt", "ical", "taskwarrior"}
+
+func groupSubcommands(kind string, names func(c *completer, prefix string) []completion) []completionCommand {
+	byName := func(c *completer, n int, prefix string) []completion {
+		if n > 0 {
+			return nil
+		}
+		return names(c, prefix)
+	}
+	return []completionCommand{
+		{name: "ls", description: "List " + kind + "s", flags: func(fs *flag.FlagSet) { addOutputFlags(fs) }},
+		{name: "add", description: "Add a " + kind, flags: func(fs *flag.FlagSet) { fs.String("color", "", "color") }},
+		{name: "edit", description: "Rename or recolor a " + kind, args: byName, flags: func(fs *flag.FlagSet) {
+			fs.String("name", "", "new name")
+			fs.String("color", "", "color, empty for none")
+		}},
+		{name: "rm", description: "Delete a " + kind, args: byName, flags: func(fs *flag.FlagSet) {
+			fs.Bool("force", false, "delete even if tasks still belong to it")
+		}},
+	}
+}
+
+func openTaskArgs(c *completer, n int, prefix string) []completion {
+	return c.tasks(prefix, false)
+}
+
+// completionCommands mirrors the commands of cli/app/main.go
+var completionCommands = []completionCommand{
+	{name: "add", description: "Add a task", flags: func(fs *flag.FlagSet) {
+		addTaskFlags(fs, false)
+		fs.Bool("quiet", false, "print only the new task's ID")
+	}},
+	{name: "list", description: "List tasks, or manage lists", flags: func(fs *flag.FlagSet) {
+		addFilterFlags(fs)
+		fs.Int("limit", 0, "show at most this many tasks")
+		fs.Bool("week", false, "only tasks due this week")
+		addOutputFlags(fs)
+	}, subcommands: groupSubcommands("list", (*completer).listNames)},
+	{name: "show", description: "Show a task", args: openTaskArgs, flags: func(fs *flag.FlagSet) { addOutputFlags(fs) }},
+	{name: "edit", description: "Change a task", args: openTaskArgs, flags: func(fs *flag.FlagSet) { addTaskFlags(fs, true) }},
+	{name: "done", description: "Mark tasks done", args: openTaskArgs},
+	{name: "undone", description: "Reopen tasks", args: func(c *completer, n int, prefix string) []completion {
+		return c.tasks(prefix, true)
+	}},
+	{name: "rm", description: "Delete tasks", args: openTaskArgs},
+	{name: "project", description: "Manage projects", flags: func(fs *flag.FlagSet) { addOutputFlags(fs) },
+		subcommands: groupSubcommands("project", (*completer).projectNames)},
+	{name: "seed", description: "Seed the database with sample data"},
+	{name: "reset", description: "Reset and reseed the database"},
+	{name: "clean", description: "Clean all data from the database"},
+	{name: "migrate", description: "Show, apply or revert schema migrations", subcommands: []completionCommand{
+		{name: "status", description: "Show applied and pending migrations"},
+		{name: "up", description: "Apply pending migrations", flags: func(fs *flag.FlagSet) {
+			fs.Int("to", 0, "migrate up to this version (default: latest)")
+		}},
+		{name: "down", description: "Revert migrations", flags: func(fs *flag.FlagSet) {
+			fs.Int("steps", 1, "number of migrations to revert")
+		}},
+	}},
+	{name: "backup", description: "Back up the database", subcommands: []completionCommand{
+		{name: "create", description: "Back up the database and apply retention"},
+		{name: "list", description: "List backups, newest first"},
+		{name: "prune", description: "Delete backups outside the retention policy"},
+	}},
+	{name: "restore", description: "Restore a backup", flags: func(fs *flag.FlagSet) {
+		fs.Bool("yes", false, "do not ask for confirmation")
+	}, args: func(c *completer, n int, prefix string) []completion {
+		if n > 0 {
+			return nil
+		}
+		return []completion{{value: completeFiles}, {value: "latest", description: "the newest backup"}}
+	}},
+	{name: "export", description: "Export data", flags: func(fs *flag.FlagSet) {
+		fs.String("format", "", "export format")
+		fs.String("out", "-", "file to write, - for stdout")
+		fs.String("columns", "", "csv columns, comma separated")
+		addFilterFlags(fs)
+	}, values: map[string]func(*completer, string) []completion{"format": formatValues}},
+	{name: "import", description: "Import a file", flags: func(fs *flag.FlagSet) {
+		fs.String("format", "", "import format")
+		fs.String("mode", "", "json: merge or replace")
+		fs.Bool("dry-run", false, "report what would change without writing")
+		fs.String("map", "", "csv: map fields to headers")
+		fs.String("project", "", "project for records that do not name one")
+		fs.String("list", "", "list for records that do not name one")
+	}, values: map[string]func(*completer, string) []completion{"format": formatValues},
+		args: func(c *completer, n int, prefix string) []completion { return []completion{{value: completeFiles}} }},
+	{name: "sync", description: "Sync the configured todo.txt file"},
+	{name: "serve", description: "Serve the REST API", flags: func(fs *flag.FlagSet) {
+		fs.String("addr", "", "loopback address to listen on")
+		fs.String("socket", "", "listen on this Unix socket instead of --addr")
+		fs.String("token", "", "require this bearer token")
+		fs.String("cors-origin", "", "allow browser requests from this origin")
+	}},
+	{name: "rpc", description: "Serve the JSON-RPC socket", flags: func(fs *flag.FlagSet) {
+		fs.Bool("path", false, "print the socket path and exit")
+	}},
+	{name: "webhooks", description: "Show and replay webhook deliveries", subcommands: []completionCommand{
+		{name: "list", description: "Show the delivery log", flags: func(fs *flag.FlagSet) {
+			fs.String("state", "", "only deliveries in this state")
+			fs.Int("limit", 50, "show at most this many deliveries")
+		}},
+		{name: "show", description: "Show a delivery"},
+		{name: "replay", description: "Send deliveries again", flags: func(fs *flag.FlagSet) {
+			fs.Bool("failed", false, "replay every failed delivery")
+		}},
+	}},
+	{name: "config", description: "Show, change and check the config file", subcommands: []completionCommand{
+		{name: "get", description: "Print a setting, or all of them", args: configKeyArgs},
+		{name: "set", description: "Change a setting", args: configKeyArgs},
+		{name: "path", description: "Print where the config file is"},
+		{name: "validate", description: "Check the config file"},
+	}},
+	{name: "completion", description: "Print a shell completion script", args: func(c *completer, n int, prefix string) []completion {
+		if n > 0 {
+			return nil
+		}
+		return []completion{{value: "bash"}, {value: "zsh"}, {value: "fish"}}
+	}},
+	{name: "help", description: "Show help"},
+	{name: "version", description: "Show version information"},
+}
+
+func configKeyArgs(c *completer, n int, prefix string) []completion {
+	if n > 0 {
+		return nil
+	}
+	return plain(config.Keys())
+}
+
+func globalFlags(fs *flag.FlagSet) {
+	fs.String("db", "", "path to the database file")
+	fs.String("profile", "", "profile to use")
+}
+
+func formatValues(c *completer, prefix string) []completion {
+	return plain(exportFormats)
+}
+
+// flagValues completes the values of flags shared between commands
+func flagValues(c *completer, name, prefix string) []completion {
+	switch name {
+	case "project":
+		return c.projectNames(prefix)
+	case "list":
+		return c.listNames(prefix)
+	case "priority":
+		return lowercase(enums.PriorityOptions)
+	case "status":
+		return lowercase(enums.StatusOptions)
+	case "done":
+		return plain([]string{"true", "false"})
+	case "output":
+		return plain(OutputModes)
+	case "state":
+		return plain([]string{services.WebhookPending, services.WebhookDelivered, services.WebhookFailed})
+	case "mode":
+		return plain([]string{string(services.ImportMerge), string(services.ImportReplace)})
+	case "profile":
+		if cfg, err := config.Load(); err == nil {
+			return plain(cfg.ProfileNames())
+		}
+	case "db", "out", "socket":
+		return []completion{{value: completeFiles}}
+	}
+	return nil
+}
+
+func plain(values []string) []completion {
+	completions := make([]completion, len(values))
+	for i, v := range values {
+		completions[i] = completion{value: v}
+	}
+	return completions
+}
+
+// lowercase offers enum names the way they are typed: "in-progress" for
+// "In Progress", so no quoting is needed
+func lowercase(options []string) []completion {
+	completions := make([]completion, len(options))
+	for i, o := range options {
+		completions[i] = completion{value: strings.ToLower(strings.ReplaceAll(o, " ", "-")), description: o}
+	}
+	return completions
+}
+
+// completer looks values up in the database the command line selects. The
+// database is opened read-only on first use.
+type completer struct {
+	dbFlag      string
+	profileFlag string
+	service     *services.CompletionService
+	opened      bool
+}
+
+func (c *completer) completions() *services.CompletionService {
+	if c.opened {
+		return c.service
+	}
+	c.opened = true
+
+	_, sel, err := config.Resolve(c.dbFlag, c.profileFlag)
+	if err != nil {
+		return nil
+	}
+	db := services.NewDbService(sel.DbPath)
+	if db.OpenReadOnly() != nil {
+		return nil
+	}
+	c.service = services.NewCompletionService(db)
+	return c.service
+}
+
+func (c *completer) projectNames(prefix string) []completion {
+	if cs := c.completions(); cs != nil {
+		names, _ := cs.ProjectNames(prefix)
+		return plain(names)
+	}
+	return nil
+}
+
+func (c *completer) listNames(prefix string) []completion {
+	if cs := c.completions(); cs != nil {
+		names, _ := cs.ListNames(prefix)
+		return plain(names)
+	}
+	return nil
+}
+
+func (c *completer) tasks(prefix string, done bool) []completion {
+	cs := c.completions()
+	if cs == nil {
+		return nil
+	}
+	tasks, _ := cs.Tasks(prefix, done)
+	completions := make([]completion, len(tasks))
+	for i, t := range tasks {
+		completions[i] = completion{value: fmt.Sprint(t.ID), description: t.Name}
+	}
+	return completions
+}
+
+// Complete runs the hidden `tuidoo __complete WORD...` that the completion
+// scripts call. The words are the command line after "tuidoo", the last
+// one being completed (empty for a new word). Candidates are printed one
+// per line as "value<TAB>description", or :files for file names.
+func Complete(args []string) error {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	words, current := args[:len(args)-1], args[len(args)-1]
+
+	c := &completer{}
+	var cmd *completionCommand
+	subcommands := completionCommands
+	flags := newCompletionFlags(nil)
+	pending := "" // flag whose value comes next
+	positional := 0
+
+	for _, word := range words {
+		switch {
+		case word == "=" && pending != "":
+			// bash splits --flag=value into three words
+		case pending != "":
+			c.setGlobal(cmd, pending, word)
+			pending = ""
+		case strings.HasPrefix(word, "-") && len(word) > 1:
+			name, value, hasValue := strings.Cut(strings.TrimLeft(word, "-"), "=")
+			if hasValue {
+				c.setGlobal(cmd, name, value)
+			} else if f := flags.Lookup(name); f != nil && !isBoolFlag(f) {
+				pending = name
+			}
+		default:
+			if sub := findCommand(subcommands, word); sub != nil && positional == 0 {
+				cmd, subcommands = sub, sub.subcommands
+				flags = newCompletionFlags(cmd)
+				continue
+			}
+			positional++
+		}
+	}
+
+	var candidates []completion
+	switch {
+	case pending != "":
+		if current == "=" {
+			current = ""
+		}
+		candidates = c.values(cmd, pending, current)
+	case strings.HasPrefix(current, "-") && strings.Contains(current, "="):
+		name, value, _ := strings.Cut(current, "=")
+		for _, v := range c.values(cmd, strings.TrimLeft(name, "-"), value) {
+			if v.value != completeFiles {
+				v.value = name + "=" + v.value
+			}
+			candidates = append(candidates, v)
+		}
+		current = name + "=" + value
+	case strings.HasPrefix(current, "-"):
+		flags.VisitAll(func(f *flag.Flag) {
+			candidates = append(candidates, completion{value: "--" + f.Name, description: f.Usage})
+		})
+	default:
+		if positional == 0 {
+			for _, sub := range subcommands {
+				candidates = append(candidates, completion{value: sub.name, description: sub.description})
+			}
+		}
+		if cmd != nil && cmd.args != nil {
+			candidates = append(candidates, cmd.args(c, positional, current)...)
+		}
+	}
+
+	writeCompletions(os.Stdout, candidates, current)
+	return nil
+}
+
+// setGlobal remembers --db and --profile given before the command, so
+// values come from the database the command will use
+func (c *completer) setGlobal(cmd *completionCommand, name, value string) {
+	if cmd != nil {
+		return
+	}
+	switch name {
+	case "db":
+		c.dbFlag = value
+	case "profile":
+		c.profileFlag = value
+	}
+}
+
+func (c *completer) values(cmd *completionCommand, name, prefix string) []completion {
+	if cmd != nil {
+		if values, ok := cmd.values[name]; ok {
+			return values(c, prefix)
+		}
+	}
+	return flagValues(c, name, prefix)
+}
+
+// newCompletionFlags returns the flags of a command, or the global flags
+// before any command
+func newCompletionFlags(cmd *completionCommand) *flag.FlagSet {
+	fs := flag.NewFlagSet("complete", flag.ContinueOnError)
+	switch {
+	case cmd == nil:
+		globalFlags(fs)
+	case cmd.flags != nil:
+		cmd.flags(fs)
+	}
+	return fs
+}
+
+func findCommand(commands []completionCommand, name string) *completionCommand {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// writeCompletions prints the candidates that start with prefix, ignoring
+// case. Task IDs and names from the database are already filtered.
+func writeCompletions(w io.Writer, candidates []completion, prefix string) {
+	for _, c := range candidates {
+		if c.value == completeFiles {
+			fmt.Fprintln(w, completeFiles)
+			continue
+		}
+		if !strings.HasPrefix(strings.ToLower(c.value), strings.ToLower(prefix)) {
+			continue
+		}
+		if c.description != "" {
+			fmt.Fprintf(w, "%s\t%s\n", c.value, strings.ReplaceAll(c.description, "\n", " "))
+		} else {
+			fmt.Fprintln(w, c.value)
+		}
+	}
+}
+
+// Completion runs `tuidoo completion bash|zsh|fish`
+func Completion(args []string) error {
+	if len(args) != 1 {
+		return usage("usage: tuidoo completion bash|zsh|fish")
+	}
+
+	scripts := map[string]string{"bash": bashCompletion, "zsh": zshCompletion, "fish": fishCompletion}
+	script, ok := scripts[args[0]]
+	if !ok {
+		return usage("unknown shell %q (expected bash, zsh or fish)", args[0])
+	}
+	_, err := io.WriteString(os.Stdout, script)
+	return err
+}
+
+const bashCompletion = `# bash completion for tuidoo
+# eval "$(tuidoo completion bash)" in ~/.bashrc
+_tuidoo() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" IFS=$'\n' line files=
+    local lines=($(tuidoo __complete "${COMP_WORDS[@]:1:COMP_CWORD-1}" "$cur" 2>/dev/null))
+    [[ "$cur" == "=" ]] && cur=
+    COMPREPLY=()
+    for line in "${lines[@]}"; do
+        if [[ "$line" == ":files" ]]; then
+            files=1
+        else
+            COMPREPLY+=("$(printf '%q' "${line%%$'\t'*}")")
+        fi
+    done
+    if [[ -n "$files" ]]; then
+        compopt -o filenames 2>/dev/null
+        COMPREPLY+=($(compgen -f -- "$cur"))
+    fi
+}
+complete -F _tuidoo tuidoo
+`
+
+const zshCompletion = `#compdef tuidoo
+# zsh completion for tuidoo
+# eval "$(tuidoo completion zsh)" in ~/.zshrc, or save as _tuidoo in $fpath
+_tuidoo() {
+    local -a lines candidates
+    local line files
+    lines=("${(@f)$(tuidoo __complete "${(@)words[2,CURRENT-1]}" "${words[CURRENT]}" 2>/dev/null)}")
+    for line in "${lines[@]}"; do
+        [[ -z "$line" ]] && continue
+        if [[ "$line" == ":files" ]]; then
+            files=1
+        elif [[ "$line" == *$'\t'* ]]; then
+            candidates+=("${${line%%$'\t'*}//:/\\:}:${line#*$'\t'}")
+        else
+            candidates+=("${line//:/\\:}")
+        fi
+    done
+    (( ${#candidates} )) && _describe -t values tuidoo candidates
+    [[ -n "$files" ]] && _files
+    return 0
+}
+if [[ "${funcstack[1]}" == "_tuidoo" ]]; then
+    _tuidoo "$@"
+else
+    compdef _tuidoo tuidoo
+fi
+`
+
+const fishCompletion = `# fish completion for tuidoo
+# tuidoo completion fish > ~/.config/fish/completions/tuidoo.fish
+function __tuidoo_complete
+    set -l tokens (commandline -opc)
+    set -e tokens[1]
+    set -l current (commandline -ct)
+    for line in (tuidoo __complete $tokens "$current" 2>/dev/null)
+        if test "$line" = ":files"
+            __fish_complete_path "$current"
+        else
+            echo $line
+        end
+    end
+end
+complete -c tuidoo -f -a '(__tuidoo_complete)'
+`
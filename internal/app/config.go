@@ -0,0 +1,83 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"tuidoo/config"
+	"tuidoo/tui/keys"
+)
+
+// Config runs `tuidoo config get [KEY]|set KEY VALUE|path|validate`. It
+// works on the config file alone, so it also runs when the file is invalid.
+func Config(args []string) error {
+	if len(args) == 0 {
+		return usage("usage: tuidoo config get [KEY]|set KEY VALUE|path|validate")
+	}
+
+	switch command, args := args[0], args[1:]; command {
+	case "path":
+		if len(args) > 0 {
+			return usage("usage: tuidoo config path")
+		}
+		fmt.Println(config.ConfigFile())
+		return nil
+
+	case "validate":
+		if len(args) > 0 {
+			return usage("usage: tuidoo config validate")
+		}
+		if _, err := os.Stat(config.ConfigFile()); errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("No config file at %s; using the defaults\n", config.ConfigFile())
+			return nil
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		if _, err := keys.Load(cfg.Keys); err != nil {
+			return fmt.Errorf("invalid %s: %w", config.ConfigFile(), err)
+		}
+		fmt.Printf("✅ %s is valid\n", config.ConfigFile())
+		return nil
+
+	case "get":
+		if len(args) > 1 {
+			return usage("usage: tuidoo config get [KEY]")
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		key := ""
+		if len(args) == 1 {
+			key = args[0]
+		}
+		value, err := cfg.Get(key)
+		if err != nil {
+			return usage("%v", err)
+		}
+		fmt.Println(value)
+		return nil
+
+	case "set":
+		if len(args) != 2 {
+			return usage("usage: tuidoo config set KEY VALUE (an empty VALUE removes the key)")
+		}
+		if err := config.Set(args[0], args[1]); err != nil {
+			if errors.Is(err, config.ErrUnknownKey) {
+				return usage("%v", err)
+			}
+			return err
+		}
+		if args[1] == "" {
+			fmt.Printf("✅ Removed %s from %s\n", args[0], config.ConfigFile())
+		} else {
+			fmt.Printf("✅ Set %s to %s in %s\n", args[0], args[1], config.ConfigFile())
+		}
+		return nil
+
+	default:
+		return usage("unknown config command %q (expected get, set, path or validate)", command)
+	}
+}
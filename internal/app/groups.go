@@ -0,0 +1,266 @@
+package app
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"tuidoo/api"
+	"tuidoo/config"
+	"tuidoo/entities"
+	"tuidoo/services"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// group is a project or a list as the group commands show it
+type group struct {
+	ID    uint
+	Name  string
+	Color string
+	Tasks int
+	// entity is the *entities.Project or *entities.ToDoList for templates
+	entity any
+	// resource is its REST API representation for json, ndjson and yaml
+	resource any
+}
+
+func newProjectGroup(p *entities.Project) group {
+	return group{ID: p.ID, Name: p.Name, Color: p.Color, Tasks: len(p.ToDos), entity: p, resource: api.NewProjectResource(p)}
+}
+
+func newListGroup(l *entities.ToDoList) group {
+	return group{ID: l.ID, Name: l.Name, Color: l.Color, Tasks: len(l.ToDos), entity: l, resource: api.NewListResource(l)}
+}
+
+// groupListing prints projects and lists; the machine-readable records are
+// the REST API's Project and List schemas
+var groupListing = listing[group]{
+	columns: []column[group]{
+		{name: "ID", value: func(g *group) string { return strconv.FormatUint(uint64(g.ID), 10) }},
+		{name: "NAME", value: func(g *group) string { return g.Name }, flex: true},
+		{name: "COLOR", value: func(g *group) string { return g.Color }, color: func(g *group, theme *entities.Theme) tcell.Color {
+			if g.Color == "" {
+				return theme.Colors.TextSecondary
+			}
+			return tcell.GetColor(g.Color)
+		}},
+		{name: "TASKS", value: func(g *group) string { return strconv.Itoa(g.Tasks) }},
+	},
+	resource: func(g *group) any { return g.resource },
+	entity:   func(g *group) any { return g.entity },
+}
+
+// groupCommands adapts ProjectService and ToDoListService to the shared
+// `project` and `list` subcommands
+type groupCommands struct {
+	kind   string
+	all    func() ([]group, error)
+	find   func(ref string) (*group, error)
+	create func(g *group) error
+	update func(g *group) error
+	remove func(id uint) error
+}
+
+func isGroupCommand(command string) bool {
+	switch command {
+	case "ls", "add", "edit", "rm":
+		return true
+	}
+	return false
+}
+
+// Projects runs `tuidoo project [ls|add NAME|edit ID|NAME|rm ID|NAME]`
+func Projects(sel config.Selection, args []string) error {
+	sc, err := services.NewCommandServiceCollection(sel)
+	if err != nil {
+		return fmt.Errorf("failed to initialize services: %w", err)
+	}
+	defer sc.Close()
+
+	projects := sc.ProjectService
+	return runGroup(groupCommands{
+		kind: "project",
+		all: func() ([]group, error) {
+			all, err := projects.GetAll(true)
+			var groups []group
+			for i := range all {
+				groups = append(groups, newProjectGroup(&all[i]))
+			}
+			return groups, err
+		},
+		find: func(ref string) (*group, error) {
+			p, err := findProject(sc, ref)
+			if err != nil {
+				return nil, err
+			}
+			if p, err = projects.GetByID(p.ID, true); err != nil {
+				return nil, err
+			}
+			g := newProjectGroup(p)
+			return &g, nil
+		},
+		create: func(g *group) error {
+			p := &entities.Project{Name: g.Name, Color: g.Color}
+			err := projects.Create(p)
+			g.ID = p.ID
+			return err
+		},
+		update: func(g *group) error {
+			p, err := projects.GetByID(g.ID, false)
+			if err != nil {
+				return err
+			}
+			p.Name, p.Color = g.Name, g.Color
+			return projects.UpdateChecked(p, services.Version(p.UpdatedAt))
+		},
+		remove: projects.Delete,
+	}, activeTheme(sc), args)
+}
+
+// Lists runs `tuidoo list ls|add NAME|edit ID|NAME|rm ID|NAME`
+func Lists(sel config.Selection, args []string) error {
+	sc, err := services.NewCommandServiceCollection(sel)
+	if err != nil {
+		return fmt.Errorf("failed to initialize services: %w", err)
+	}
+	defer sc.Close()
+
+	lists := sc.ToDoListService
+	return runGroup(groupCommands{
+		kind: "list",
+		all: func() ([]group, error) {
+			all, err := lists.GetAll(true)
+			var groups []group
+			for i := range all {
+				groups = append(groups, newListGroup(&all[i]))
+			}
+			return groups, err
+		},
+		find: func(ref string) (*group, error) {
+			l, err := findList(sc, ref)
+			if err != nil {
+				return nil, err
+			}
+			if l, err = lists.GetByID(l.ID, true); err != nil {
+				return nil, err
+			}
+			g := newListGroup(l)
+			return &g, nil
+		},
+		create: func(g *group) error {
+			l := &entities.ToDoList{Name: g.Name, Color: g.Color}
+			err := lists.Create(l)
+			g.ID = l.ID
+			return err
+		},
+		update: func(g *group) error {
+			l, err := lists.GetByID(g.ID, false)
+			if err != nil {
+				return err
+			}
+			l.Name, l.Color = g.Name, g.Color
+			return lists.UpdateChecked(l, services.Version(l.UpdatedAt))
+		},
+		remove: lists.Delete,
+	}, activeTheme(sc), args)
+}
+
+func runGroup(gc groupCommands, theme entities.Theme, args []string) error {
+	command := "ls"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "ls":
+		flags := flag.NewFlagSet(gc.kind+" ls", flag.ExitOnError)
+		output := addOutputFlags(flags)
+		if rest := parseInterspersed(flags, args); len(rest) > 0 {
+			return usage("usage: tuidoo %s ls [--output MODE|--format TEMPLATE]", gc.kind)
+		}
+		if err := output.check(); err != nil {
+			return err
+		}
+
+		groups, err := gc.all()
+		if err != nil {
+			return err
+		}
+		if len(groups) == 0 && output.human() {
+			fmt.Printf("No %ss\n", gc.kind)
+			return nil
+		}
+
+		return render(os.Stdout, output, groupListing, theme, groups, false)
+
+	case "add":
+		flags := flag.NewFlagSet(gc.kind+" add", flag.ExitOnError)
+		color := flags.String("color", "", "color")
+		rest := parseInterspersed(flags, args)
+		if len(rest) != 1 {
+			return usage("usage: tuidoo %s add NAME [--color C]", gc.kind)
+		}
+
+		g := &group{Name: rest[0], Color: *color}
+		if err := gc.create(g); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Added %s %d: %s\n", gc.kind, g.ID, g.Name)
+		return nil
+
+	case "edit":
+		flags := flag.NewFlagSet(gc.kind+" edit", flag.ExitOnError)
+		name := flags.String("name", "", "new name")
+		color := flags.String("color", "", "color, empty for none")
+		rest := parseInterspersed(flags, args)
+		if len(rest) != 1 {
+			return usage("usage: tuidoo %s edit ID|NAME [--name N] [--color C]", gc.kind)
+		}
+		set := flagsSet(flags)
+		if len(set) == 0 {
+			return usage("nothing to change (see tuidoo %s edit -h)", gc.kind)
+		}
+
+		g, err := gc.find(rest[0])
+		if err != nil {
+			return err
+		}
+		if set["name"] {
+			g.Name = *name
+		}
+		if set["color"] {
+			g.Color = *color
+		}
+		if err := gc.update(g); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Updated %s %d: %s\n", gc.kind, g.ID, g.Name)
+		return nil
+
+	case "rm":
+		flags := flag.NewFlagSet(gc.kind+" rm", flag.ExitOnError)
+		force := flags.Bool("force", false, "delete even if tasks still belong to it")
+		rest := parseInterspersed(flags, args)
+		if len(rest) != 1 {
+			return usage("usage: tuidoo %s rm ID|NAME [--force]", gc.kind)
+		}
+
+		g, err := gc.find(rest[0])
+		if err != nil {
+			return err
+		}
+		if g.Tasks > 0 && !*force {
+			return fmt.Errorf("%s %q still has %d tasks (use --force to delete it anyway)", gc.kind, g.Name, g.Tasks)
+		}
+		if err := gc.remove(g.ID); err != nil {
+			return err
+		}
+		fmt.Printf("🗑️  Removed %s %d: %s\n", gc.kind, g.ID, g.Name)
+		return nil
+
+	default:
+		return usage("unknown %s command %q (expected ls, add, edit or rm)", gc.kind, command)
+	}
+}
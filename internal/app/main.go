@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"tuidoo/config"
 	"tuidoo/entities"
 	"tuidoo/enums"
 	"tuidoo/managers"
@@ -110,8 +111,8 @@ type menuItem struct {
 // Initialization
 // ============================================================================
 
-func RunTUI() error {
-	sc, err := services.NewServiceCollection()
+func RunTUI(sel config.Selection) error {
+	sc, err := services.NewServiceCollection(sel)
 	if err != nil {
 		return fmt.Errorf("failed to initialize services: %w", err)
 	}
@@ -760,8 +761,8 @@ func applyUserTheme(sc *services.ServiceCollection, tm *managers.ThemeManager) e
 }
 
 // SeedDatabase seeds the database with sample data
-func SeedDatabase() {
-	sc, err := services.NewServiceCollection()
+func SeedDatabase(sel config.Selection) {
+	sc, err := services.NewServiceCollection(sel)
 	if err != nil {
 		log.Fatalf("Failed to initialize services: %v", err)
 	}
@@ -776,8 +777,8 @@ func SeedDatabase() {
 }
 
 // ResetDatabase cleans and reseeds the database
-func ResetDatabase() {
-	sc, err := services.NewServiceCollection()
+func ResetDatabase(sel config.Selection) {
+	sc, err := services.NewServiceCollection(sel)
 	if err != nil {
 		log.Fatalf("Failed to initialize services: %v", err)
 	}
@@ -792,6 +793,10 @@ func ResetDatabase() {
 		os.Exit(0)
 	}
 
+	if _, err := sc.BackupService.Create("pre-reset"); err != nil {
+		log.Fatalf("❌ Backup before reset failed: %v", err)
+	}
+
 	if err := services.ResetAndSeed(sc.DbService); err != nil {
 		log.Fatalf("❌ Reset failed: %v", err)
 	}
@@ -801,8 +806,8 @@ func ResetDatabase() {
 }
 
 // CleanDatabase removes all data from the database
-func CleanDatabase() {
-	sc, err := services.NewServiceCollection()
+func CleanDatabase(sel config.Selection) {
+	sc, err := services.NewServiceCollection(sel)
 	if err != nil {
 		log.Fatalf("Failed to initialize services: %v", err)
 	}
@@ -817,6 +822,10 @@ func CleanDatabase() {
 		os.Exit(0)
 	}
 
+	if _, err := sc.BackupService.Create("pre-clean"); err != nil {
+		log.Fatalf("❌ Backup before clean failed: %v", err)
+	}
+
 	if err := services.CleanDatabase(sc.DbService); err != nil {
 		log.Fatalf("❌ Clean failed: %v", err)
 	}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"tuidoo/config"
+	"tuidoo/services"
+)
+
+// Migrate runs `tuidoo migrate status|up|down`
+func Migrate(sel config.Selection, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: tuidoo migrate status|up|down")
+	}
+
+	// Open without migrating so status and down work on any schema version
+	db := services.NewDbService(sel.DbPath)
+	if err := db.Open(); err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	migrator := services.NewMigrationService(db)
+	backups := services.NewBackupService(db, sel.Backup)
+
+	// Changing the schema always starts from a fresh backup
+	backupFirst := func() error {
+		if !db.GetDB().Migrator().HasTable("to_dos") {
+			return nil
+		}
+		backup, err := backups.Create("pre-migrate")
+		if err != nil {
+			return fmt.Errorf("pre-migration backup failed: %w", err)
+		}
+		fmt.Printf("💾 Backup written to %s\n", backup.Path)
+		return nil
+	}
+
+	switch args[0] {
+	case "status":
+		return printMigrationStatus(migrator, sel)
+
+	case "up":
+		flags := flag.NewFlagSet("migrate up", flag.ExitOnError)
+		target := flags.Int("to", 0, "migrate up to this version (default: latest)")
+		flags.Parse(args[1:])
+
+		pending, err := migrator.Pending()
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			if err := backupFirst(); err != nil {
+				return err
+			}
+		}
+
+		applied, err := migrator.Up(*target)
+		for _, m := range applied {
+			fmt.Printf("✅ Applied %d: %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date")
+		}
+		return nil
+
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ExitOnError)
+		steps := flags.Int("steps", 1, "number of migrations to revert")
+		force := flags.Bool("force", false, "also revert the initial schema, deleting all data")
+		flags.Parse(args[1:])
+
+		// Refuse before the backup, so a refused down leaves nothing behind
+		todo, err := migrator.ToRevert(*steps, *force)
+		if errors.Is(err, services.ErrBaseline) {
+			return fmt.Errorf("%w; pass --force to drop every table anyway", err)
+		}
+		if err != nil {
+			return err
+		}
+		if len(todo) == 0 {
+			fmt.Println("Nothing to revert")
+			return nil
+		}
+
+		if err := backupFirst(); err != nil {
+			return err
+		}
+
+		reverted, err := migrator.Down(*steps, *force)
+		for _, m := range reverted {
+			fmt.Printf("✅ Reverted %d: %s\n", m.Version, m.Name)
+		}
+		if errors.Is(err, services.ErrBaseline) {
+			return fmt.Errorf("%w; pass --force to drop every table anyway", err)
+		}
+		return err
+
+	default:
+		return fmt.Errorf("unknown migrate command %q (expected status, up or down)", args[0])
+	}
+}
+
+func printMigrationStatus(migrator *services.MigrationService, sel config.Selection) error {
+	statuses, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+
+	current, err := migrator.CurrentVersion()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Database: %s (profile %s)\n", sel.DbPath, sel.Profile)
+	fmt.Printf("Schema version: %d (binary supports %d)\n\n", current, services.LatestSchemaVersion())
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, s := range statuses {
+		state := "pending"
+		appliedAt := "-"
+		if s.Applied {
+			state = "applied"
+			appliedAt = s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		if s.Unknown {
+			state = "unknown (newer binary)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,310 @@
+package app
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+	"tuidoo/entities"
+	"tuidoo/formats"
+	"tuidoo/managers"
+	"tuidoo/services"
+	"tuidoo/tui/context"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+)
+
+// Output modes of the read commands
+const (
+	OutputTable  = "table"
+	OutputJSON   = "json"
+	OutputNDJSON = "ndjson"
+	OutputYAML   = "yaml"
+	OutputTSV    = "tsv"
+)
+
+// OutputModes lists the values --output accepts
+var OutputModes = []string{OutputTable, OutputJSON, OutputNDJSON, OutputYAML, OutputTSV}
+
+// minFlexWidth is how narrow a flexible table column may get before the
+// table overflows the terminal instead
+const minFlexWidth = 8
+
+// outputFlags are --output and --format of a read command
+type outputFlags struct {
+	mode     *string
+	format   *string
+	template *template.Template
+}
+
+func addOutputFlags(flags *flag.FlagSet) *outputFlags {
+	return &outputFlags{
+		mode:   flags.String("output", OutputTable, "output: "+strings.Join(OutputModes, ", ")),
+		format: flags.String("format", "", "Go template executed for each record, e.g. '{{.ID}} {{.Name}}'"),
+	}
+}
+
+// check validates the flags and compiles the template
+func (o *outputFlags) check() error {
+	if !slices.Contains(OutputModes, *o.mode) {
+		return usage("unknown output %q (expected one of %s)", *o.mode, strings.Join(OutputModes, ", "))
+	}
+	if *o.format == "" {
+		return nil
+	}
+	if *o.mode != OutputTable {
+		return usage("--format cannot be combined with --output %s", *o.mode)
+	}
+
+	tmpl, err := template.New("format").Funcs(templateFuncs).Parse(*o.format)
+	if err != nil {
+		return usage("--format: %v", err)
+	}
+	o.template = tmpl
+	return nil
+}
+
+// human reports whether the records should be printed for people
+func (o *outputFlags) human() bool {
+	return *o.mode == OutputTable && o.template == nil
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"date": func(t any) string {
+		switch t := t.(type) {
+		case time.Time:
+			return formats.FormatDate(t)
+		case *time.Time:
+			if t != nil {
+				return formats.FormatDate(*t)
+			}
+		}
+		return ""
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// listing describes how one kind of record is printed
+type listing[E any] struct {
+	columns []column[E]
+	// muted rows are drawn in the disabled text color
+	muted func(*E) bool
+	// resource is what json, ndjson and yaml encode
+	resource func(*E) any
+	// entity is what --format templates see
+	entity func(*E) any
+}
+
+type column[E any] struct {
+	name  string
+	value func(*E) string
+	// short is what the table shows when value is too exact for people
+	short func(*E) string
+	// flex columns are truncated to fit the terminal
+	flex bool
+	// color picks a theme color for a cell; nil keeps the text color
+	color func(*E, *entities.Theme) tcell.Color
+}
+
+// render prints records in the chosen output mode. single prints the one
+// record as an object rather than a one-element array.
+func render[E any](w io.Writer, o *outputFlags, l listing[E], theme entities.Theme, items []E, single bool) error {
+	if o.template != nil {
+		for i := range items {
+			var b strings.Builder
+			if err := o.template.Execute(&b, l.entity(&items[i])); err != nil {
+				return fmt.Errorf("--format: %w", err)
+			}
+			line := b.String()
+			if !strings.HasSuffix(line, "\n") {
+				line += "\n"
+			}
+			if _, err := io.WriteString(w, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	resources := make([]any, 0, len(items))
+	for i := range items {
+		resources = append(resources, l.resource(&items[i]))
+	}
+	var doc any = resources
+	if single && len(resources) == 1 {
+		doc = resources[0]
+	}
+
+	switch *o.mode {
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+
+	case OutputNDJSON:
+		enc := json.NewEncoder(w)
+		for _, r := range resources {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case OutputYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		return enc.Close()
+
+	case OutputTSV:
+		header := make([]string, len(l.columns))
+		for i, c := range l.columns {
+			header[i] = strings.ToLower(c.name)
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(header, "\t")); err != nil {
+			return err
+		}
+		for i := range items {
+			row := make([]string, len(l.columns))
+			for j, c := range l.columns {
+				row[j] = tsvEscaper.Replace(c.value(&items[i]))
+			}
+			if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return writeTable(w, l, theme, items)
+	}
+}
+
+// tsvEscaper keeps every record on one line with one cell per column
+var tsvEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+
+// writeTable prints an aligned table colored with the theme. On a terminal
+// the flex columns are truncated so rows fit its width.
+func writeTable[E any](w io.Writer, l listing[E], theme entities.Theme, items []E) error {
+	cells := make([][]string, len(items))
+	widths := make([]int, len(l.columns))
+	for i, c := range l.columns {
+		widths[i] = runewidth.StringWidth(c.name)
+	}
+	for r := range items {
+		cells[r] = make([]string, len(l.columns))
+		for i, c := range l.columns {
+			value := c.value
+			if c.short != nil {
+				value = c.short
+			}
+			cells[r][i] = strings.Join(strings.Fields(value(&items[r])), " ")
+			widths[i] = max(widths[i], runewidth.StringWidth(cells[r][i]))
+		}
+	}
+	fitWidths(l.columns, widths, terminalWidth())
+
+	header := lipgloss.NewStyle().Bold(true).Foreground(context.TcellToLipgloss(theme.Colors.Primary))
+	muted := lipgloss.NewStyle().Foreground(context.TcellToLipgloss(theme.Colors.TextDisabled))
+
+	var b strings.Builder
+	for i, c := range l.columns {
+		writeCell(&b, header, c.name, widths[i], i == len(l.columns)-1)
+	}
+	b.WriteString("\n")
+
+	for r := range items {
+		isMuted := l.muted != nil && l.muted(&items[r])
+		for i, c := range l.columns {
+			style := lipgloss.NewStyle()
+			switch {
+			case isMuted:
+				style = muted
+			case c.color != nil:
+				style = style.Foreground(context.TcellToLipgloss(c.color(&items[r], &theme)))
+			}
+			writeCell(&b, style, cells[r][i], widths[i], i == len(l.columns)-1)
+		}
+		b.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeCell pads a cell to its column; the last column is not padded
+func writeCell(b *strings.Builder, style lipgloss.Style, text string, width int, last bool) {
+	text = runewidth.Truncate(text, width, "…")
+	if !last {
+		text = runewidth.FillRight(text, width)
+	}
+	b.WriteString(style.Render(text))
+	if !last {
+		b.WriteString("  ")
+	}
+}
+
+// fitWidths shrinks the flex columns, widest first, until the row fits in
+// total columns or they are all at minFlexWidth
+func fitWidths[E any](columns []column[E], widths []int, total int) {
+	if total <= 0 {
+		return
+	}
+
+	used := 2 * (len(widths) - 1)
+	for _, w := range widths {
+		used += w
+	}
+	for used > total {
+		widest := -1
+		for i, c := range columns {
+			if c.flex && widths[i] > minFlexWidth && (widest < 0 || widths[i] > widths[widest]) {
+				widest = i
+			}
+		}
+		if widest < 0 {
+			return
+		}
+		widths[widest]--
+		used--
+	}
+}
+
+// terminalWidth is the width of the terminal stdout is on, or 0 when it is
+// redirected and tables should not be truncated
+func terminalWidth() int {
+	fd := int(os.Stdout.Fd())
+	if !term.IsTerminal(fd) {
+		return 0
+	}
+	width, _, err := term.GetSize(fd)
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+// activeTheme is the theme the TUI is using, for coloring tables
+func activeTheme(sc *services.ServiceCollection) entities.Theme {
+	themes := managers.NewThemeManager()
+	if id, err := sc.SettingsService.GetActiveTheme(); err == nil && id != "" {
+		themes.SetTheme(id)
+	}
+	return themes.GetCurrentTheme()
+}
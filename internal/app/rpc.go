@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"tuidoo/api"
+	"tuidoo/config"
+	"tuidoo/services"
+)
+
+// RPC runs `tuidoo rpc [--path]`, serving the profile's JSON-RPC socket
+// without the TUI
+func RPC(sel config.Selection, args []string) error {
+	flags := flag.NewFlagSet("rpc", flag.ExitOnError)
+	printPath := flags.Bool("path", false, "print the socket path and exit")
+	flags.Parse(args)
+
+	if *printPath {
+		fmt.Println(sel.RPCSocket)
+		return nil
+	}
+
+	sc, err := services.NewServiceCollection(sel)
+	if err != nil {
+		return fmt.Errorf("failed to initialize services: %w", err)
+	}
+	defer sc.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := api.NewRPCServer(sc, sel.RPCSocket)
+	if err := server.Start(); err != nil {
+		return err
+	}
+	defer server.Close()
+
+	// Tell editors about changes made by the CLI and other TUIs too
+	if err := sc.WatchService.Start(); err != nil {
+		log.Printf("⚠️  Not watching for external changes: %v", err)
+	}
+
+	// Long-running processes run the due_approaching rules
+	sc.RuleService.Start(sc.ToDoService)
+
+	<-ctx.Done()
+	return nil
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"tuidoo/api"
+	"tuidoo/config"
+	"tuidoo/services"
+)
+
+// Serve runs `tuidoo serve [--addr HOST:PORT | --socket PATH] [--token TOKEN]`
+func Serve(sel config.Selection, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", api.DefaultAddr, "loopback address to listen on")
+	socket := flags.String("socket", "", "listen on this Unix socket instead of --addr")
+	token := flags.String("token", os.Getenv("TUIDOO_API_TOKEN"), "require this bearer token (default: $TUIDOO_API_TOKEN)")
+	corsOrigin := flags.String("cors-origin", "", "allow browser requests from this origin")
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		return fmt.Errorf("usage: tuidoo serve [--addr HOST:PORT | --socket PATH] [--token TOKEN]")
+	}
+
+	sc, err := services.NewServiceCollection(sel)
+	if err != nil {
+		return fmt.Errorf("failed to initialize services: %w", err)
+	}
+	defer sc.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Long-running processes run the due_approaching rules
+	sc.RuleService.Start(sc.ToDoService)
+
+	server := api.NewServer(sc, api.Options{
+		Addr:       *addr,
+		Socket:     config.ExpandPath(*socket),
+		Token:      *token,
+		CORSOrigin: *corsOrigin,
+	})
+	return server.ListenAndServe(ctx)
+}
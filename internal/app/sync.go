@@ -0,0 +1,23 @@
+package app
+
+import (
+	"fmt"
+	"tuidoo/config"
+	"tuidoo/services"
+)
+
+// Sync runs `tuidoo sync`, syncing the profile's todo.txt file both ways
+func Sync(sel config.Selection) error {
+	sc, err := services.NewCommandServiceCollection(sel)
+	if err != nil {
+		return fmt.Errorf("failed to initialize services: %w", err)
+	}
+	defer sc.Close()
+
+	if err := sc.TodoTxtService.Sync(); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ %s is in sync\n", sc.TodoTxtService.File())
+	return nil
+}
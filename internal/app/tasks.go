@@ -0,0 +1,437 @@
+package app
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"tuidoo/api"
+	"tuidoo/config"
+	"tuidoo/entities"
+	"tuidoo/enums"
+	"tuidoo/formats"
+	"tuidoo/services"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// taskListing prints tasks with due dates in the table shown in
+// ui.date_format. The json, ndjson and yaml records are the REST API's Todo
+// schema (see api/openapi.yaml).
+func taskListing(ui config.UIConfig) listing[entities.ToDo] {
+	return listing[entities.ToDo]{
+		columns: []column[entities.ToDo]{
+			{name: "ID", value: func(t *entities.ToDo) string { return strconv.FormatUint(uint64(t.ID), 10) }},
+			{name: "DONE", value: func(t *entities.ToDo) string { return doneMark(t.Done) }},
+			{name: "PRIORITY", value: func(t *entities.ToDo) string { return t.Priority.String() }, color: priorityColor},
+			{name: "STATUS", value: func(t *entities.ToDo) string { return t.Status.String() }, color: statusColor},
+			{name: "DUE", value: func(t *entities.ToDo) string { return dueDate(t.DueDate) }, short: func(t *entities.ToDo) string { return localDue(t.DueDate, ui) }},
+			{name: "PROJECT", value: func(t *entities.ToDo) string { return t.Project.Name }, flex: true},
+			{name: "LIST", value: func(t *entities.ToDo) string { return t.ToDoList.Name }, flex: true},
+			{name: "NAME", value: func(t *entities.ToDo) string { return t.Name }, flex: true},
+		},
+		muted:    func(t *entities.ToDo) bool { return t.Done },
+		resource: func(t *entities.ToDo) any { return api.NewToDoResource(t) },
+		entity:   func(t *entities.ToDo) any { return t },
+	}
+}
+
+func priorityColor(t *entities.ToDo, theme *entities.Theme) tcell.Color {
+	switch t.Priority {
+	case enums.Urgent, enums.High:
+		return theme.Colors.Error
+	case enums.Medium:
+		return theme.Colors.Warning
+	default:
+		return theme.Colors.Info
+	}
+}
+
+func statusColor(t *entities.ToDo, theme *entities.Theme) tcell.Color {
+	return theme.StatusColor(strings.ToLower(strings.ReplaceAll(t.Status.String(), " ", "-")))
+}
+
+// taskFlags are the task fields add and edit accept
+type taskFlags struct {
+	name        *string
+	project     *string
+	list        *string
+	priority    *string
+	status      *string
+	due         *string
+	description *string
+	details     *string
+	color       *string
+}
+
+func addTaskFlags(flags *flag.FlagSet, withName bool) *taskFlags {
+	f := &taskFlags{
+		project:     flags.String("project", "", "project name or ID"),
+		list:        flags.String("list", "", "list name or ID, empty for none"),
+		priority:    flags.String("priority", "", "priority: "+strings.Join(enums.PriorityOptions, ", ")),
+		status:      flags.String("status", "", "status: "+strings.Join(enums.StatusOptions, ", ")),
+		due:         flags.String("due", "", "due date: YYYY-MM-DD, ui.date_format, RFC 3339, today, tomorrow or an offset such as +3d; empty for none"),
+		description: flags.String("description", "", "description"),
+		details:     flags.String("details", "", "details"),
+		color:       flags.String("color", "", "color"),
+	}
+	if withName {
+		f.name = flags.String("name", "", "new name")
+	}
+	return f
+}
+
+// apply copies the flags given on the command line onto todo
+func (f *taskFlags) apply(sc *services.ServiceCollection, todo *entities.ToDo, set map[string]bool) error {
+	if set["name"] {
+		if *f.name == "" {
+			return usage("--name cannot be empty")
+		}
+		todo.Name = *f.name
+	}
+	if set["project"] {
+		project, err := findProject(sc, *f.project)
+		if err != nil {
+			return err
+		}
+		todo.ProjectID, todo.Project = project.ID, entities.Project{}
+	}
+	if set["list"] {
+		todo.ToDoListID, todo.ToDoList = 0, entities.ToDoList{}
+		if *f.list != "" {
+			list, err := findList(sc, *f.list)
+			if err != nil {
+				return err
+			}
+			todo.ToDoListID = list.ID
+		}
+	}
+	if set["priority"] {
+		p, err := enums.ParsePriority(*f.priority)
+		if err != nil {
+			return usage("%v", err)
+		}
+		todo.Priority = p
+	}
+	if set["status"] {
+		s, err := enums.ParseStatus(*f.status)
+		if err != nil {
+			return usage("%v", err)
+		}
+		todo.Status = s
+		todo.Done = s == enums.Done
+	}
+	if set["due"] {
+		todo.DueDate = nil
+		if *f.due != "" {
+			t, err := services.ParseDueInput(*f.due, sc.Selection.UI, time.Now())
+			if err != nil {
+				return usage("--due: %v", err)
+			}
+			todo.DueDate = &t
+		}
+	}
+	if set["description"] {
+		todo.Description = optional(*f.description)
+	}
+	if set["details"] {
+		todo.Details = optional(*f.details)
+	}
+	if set["color"] {
+		todo.Color = *f.color
+	}
+	return nil
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// Add runs `tuidoo add NAME... --project P [fields]`. The project and list
+// fall back to the defaults section of the config file.
+func Add(sel config.Selection, args []string) error {
+	flags := flag.NewFlagSet("add", flag.ExitOnError)
+	fields := addTaskFlags(flags, false)
+	quiet := flags.Bool("quiet", false, "print only the new task's ID")
+	name := strings.Join(parseInterspersed(flags, args), " ")
+
+	set := flagsSet(flags)
+	if name == "" {
+		return usage("usage: tuidoo add NAME... --project P")
+	}
+	if !set["project"] && sel.Defaults.Project != "" {
+		*fields.project, set["project"] = sel.Defaults.Project, true
+	}
+	if !set["project"] {
+		return usage("--project is required (or set defaults.project in %s)", config.ConfigFile())
+	}
+	if !set["list"] && sel.Defaults.List != "" {
+		*fields.list, set["list"] = sel.Defaults.List, true
+	}
+
+	sc, err := services.NewCommandServiceCollection(sel)
+	if err != nil {
+		return fmt.Errorf("failed to initialize services: %w", err)
+	}
+	defer sc.Close()
+
+	todo := &entities.ToDo{Name: name}
+	if err := fields.apply(sc, todo, set); err != nil {
+		return err
+	}
+	if err := sc.ToDoService.Create(todo); err != nil {
+		return err
+	}
+
+	if *quiet {
+		fmt.Println(todo.ID)
+	} else {
+		fmt.Printf("✅ Added task %d: %s\n", todo.ID, todo.Name)
+	}
+	return nil
+}
+
+// List runs `tuidoo list [filters]`, or the list group for
+// `tuidoo list ls|add|edit|rm`
+func List(sel config.Selection, args []string) error {
+	if len(args) > 0 && isGroupCommand(args[0]) {
+		return Lists(sel, args)
+	}
+
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	filter := addFilterFlags(flags)
+	limit := flags.Int("limit", 0, "show at most this many tasks")
+	week := flags.Bool("week", false, "only tasks due this week (see ui.week_start)")
+	output := addOutputFlags(flags)
+	if rest := parseInterspersed(flags, args); len(rest) > 0 {
+		return usage("unexpected argument %q (lists are managed with list ls|add|edit|rm)", rest[0])
+	}
+	if err := output.check(); err != nil {
+		return err
+	}
+
+	todoFilter, _, err := filter()
+	if err != nil {
+		return usage("%v", err)
+	}
+	todoFilter.Limit = *limit
+	if *week {
+		if todoFilter.DueBefore != nil || todoFilter.DueAfter != nil {
+			return usage("--week cannot be combined with --due-before or --due-after")
+		}
+		start := sel.UI.WeekOf(time.Now())
+		end := start.AddDate(0, 0, 7)
+		todoFilter.DueAfter, todoFilter.DueBefore = &start, &end
+	}
+
+	sc, err := services.NewCommandServiceCollection(sel)
+	if err != nil {
+		return fmt.Errorf("failed to initialize services: %w", err)
+	}
+	defer sc.Close()
+
+	todos, err := sc.ToDoService.Find(todoFilter)
+	if err != nil {
+		return err
+	}
+	if len(todos) == 0 && output.human() {
+		fmt.Println("No tasks")
+		return nil
+	}
+
+	return render(os.Stdout, output, taskListing(sel.UI), activeTheme(sc), todos, false)
+}
+
+// Show runs `tuidoo show ID [--output MODE|--format TEMPLATE]`
+func Show(sel config.Selection, args []string) error {
+	flags := flag.NewFlagSet("show", flag.ExitOnError)
+	output := addOutputFlags(flags)
+	rest := parseInterspersed(flags, args)
+
+	if len(rest) != 1 {
+		return usage("usage: tuidoo show ID")
+	}
+	id, err := parseTaskID(rest[0])
+	if err != nil {
+		return err
+	}
+	if err := output.check(); err != nil {
+		return err
+	}
+
+	sc, err := services.NewCommandServiceCollection(sel)
+	if err != nil {
+		return fmt.Errorf("failed to initialize services: %w", err)
+	}
+	defer sc.Close()
+
+	todo, err := sc.ToDoService.GetByID(id, true)
+	if err != nil {
+		return err
+	}
+	if !output.human() {
+		return render(os.Stdout, output, taskListing(sel.UI), activeTheme(sc), []entities.ToDo{*todo}, true)
+	}
+	printTask(todo, sel.UI)
+	return nil
+}
+
+// Edit runs `tuidoo edit ID [fields]`
+func Edit(sel config.Selection, args []string) error {
+	flags := flag.NewFlagSet("edit", flag.ExitOnError)
+	fields := addTaskFlags(flags, true)
+	rest := parseInterspersed(flags, args)
+
+	if len(rest) != 1 {
+		return usage("usage: tuidoo edit ID [--name N] [--project P] [--priority P] ...")
+	}
+	id, err := parseTaskID(rest[0])
+	if err != nil {
+		return err
+	}
+	set := flagsSet(flags)
+	if len(set) == 0 {
+		return usage("nothing to change (see tuidoo edit -h)")
+	}
+
+	sc, err := services.NewCommandServiceCollection(sel)
+	if err != nil {
+		return fmt.Errorf("failed to initialize services: %w", err)
+	}
+	defer sc.Close()
+
+	todo, err := sc.ToDoService.GetByID(id, false)
+	if err != nil {
+		return err
+	}
+	if err := fields.apply(sc, todo, set); err != nil {
+		return err
+	}
+	if err := sc.ToDoService.UpdateChecked(todo, services.Version(todo.UpdatedAt)); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Updated task %d: %s\n", todo.ID, todo.Name)
+	return nil
+}
+
+// Done runs `tuidoo done ID...`
+func Done(sel config.Selection, args []string) error {
+	return eachTask(sel, "done", args, "✅ Completed", func(sc *services.ServiceCollection, id uint) error {
+		return sc.ToDoService.MarkAsComplete(id)
+	})
+}
+
+// Undone runs `tuidoo undone ID...`
+func Undone(sel config.Selection, args []string) error {
+	return eachTask(sel, "undone", args, "↩️  Reopened", func(sc *services.ServiceCollection, id uint) error {
+		return sc.ToDoService.MarkAsIncomplete(id)
+	})
+}
+
+// Remove runs `tuidoo rm ID...`
+func Remove(sel config.Selection, args []string) error {
+	return eachTask(sel, "rm", args, "🗑️  Removed", func(sc *services.ServiceCollection, id uint) error {
+		return sc.ToDoService.Delete(id)
+	})
+}
+
+// eachTask runs change for every task ID in args and reports each one. It
+// carries on past failures and returns the first, so the exit code reflects
+// what went wrong.
+func eachTask(sel config.Selection, command string, args []string, verb string, change func(*services.ServiceCollection, uint) error) error {
+	if len(args) == 0 {
+		return usage("usage: tuidoo %s ID...", command)
+	}
+	var ids []uint
+	for _, arg := range args {
+		id, err := parseTaskID(arg)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	sc, err := services.NewCommandServiceCollection(sel)
+	if err != nil {
+		return fmt.Errorf("failed to initialize services: %w", err)
+	}
+	defer sc.Close()
+
+	var first error
+	failures := 0
+	for _, id := range ids {
+		todo, err := sc.ToDoService.GetByID(id, false)
+		if err == nil {
+			err = change(sc, id)
+		}
+		if err != nil {
+			failures++
+			if first == nil {
+				first = err
+			}
+			fmt.Fprintf(os.Stderr, "❌ %d: %v\n", id, err)
+			continue
+		}
+		fmt.Printf("%s task %d: %s\n", verb, id, todo.Name)
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d of %d tasks failed: %w", failures, len(ids), first)
+	}
+	return nil
+}
+
+func printTask(t *entities.ToDo, ui config.UIConfig) {
+	fmt.Printf("Task:        %d (%s)\n", t.ID, t.UID)
+	fmt.Printf("Name:        %s\n", t.Name)
+	fmt.Printf("Project:     %s\n", t.Project.Name)
+	if t.ToDoListID != 0 {
+		fmt.Printf("List:        %s\n", t.ToDoList.Name)
+	}
+	fmt.Printf("Priority:    %s\n", t.Priority)
+	fmt.Printf("Status:      %s\n", t.Status)
+	fmt.Printf("Done:        %t\n", t.Done)
+	if t.DueDate != nil {
+		fmt.Printf("Due:         %s\n", localDue(t.DueDate, ui))
+	}
+	if t.Color != "" {
+		fmt.Printf("Color:       %s\n", t.Color)
+	}
+	fmt.Printf("Created:     %s\n", t.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("Updated:     %s\n", t.UpdatedAt.Local().Format("2006-01-02 15:04:05"))
+	if t.Description != nil {
+		fmt.Printf("\n%s\n", *t.Description)
+	}
+	if t.Details != nil {
+		fmt.Printf("\n%s\n", *t.Details)
+	}
+}
+
+func doneMark(done bool) string {
+	if done {
+		return "x"
+	}
+	return ""
+}
+
+// localDue shows a due date in local time and ui.date_format, leaving off
+// midnight
+func localDue(t *time.Time, ui config.UIConfig) string {
+	if t == nil {
+		return ""
+	}
+	return ui.FormatDate(*t)
+}
+
+func dueDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return formats.FormatDate(*t)
+}
@@ -0,0 +1,378 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"tuidoo/config"
+	"tuidoo/enums"
+	"tuidoo/formats"
+	"tuidoo/services"
+)
+
+// Export runs `tuidoo export [--format json|csv|markdown|todotxt|ical|taskwarrior] [--out FILE] [filters]`
+func Export(sel config.Selection, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", "", "export format: json, csv, markdown, todotxt, ical or taskwarrior (default: from --out, else json)")
+	out := flags.String("out", "-", "file to write, - for stdout")
+	columns := flags.String("columns", "", "csv columns, comma separated (default: "+strings.Join(formats.CSVColumns, ",")+")")
+	filter := addFilterFlags(flags)
+	flags.Parse(args)
+
+	todoFilter, filtered, err := filter()
+	if err != nil {
+		return err
+	}
+
+	// Checked before --out is opened, which would truncate the file
+	exportFormat, err := formats.Detect(*format, *out)
+	if err != nil {
+		return usage("%v", err)
+	}
+
+	sc, err := services.NewCommandServiceCollection(sel)
+	if err != nil {
+		return fmt.Errorf("failed to initialize services: %w", err)
+	}
+	defer sc.Close()
+
+	w, closeOut, err := openOutput(*out)
+	if err != nil {
+		return err
+	}
+	defer closeOut()
+
+	switch exportFormat {
+	case "json":
+		if filtered {
+			return fmt.Errorf("the json export is a full snapshot and cannot be filtered")
+		}
+
+		snapshot, err := sc.TransferService.Export()
+		if err != nil {
+			return err
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(snapshot)
+
+	case "csv":
+		cols, err := formats.ParseColumns(*columns)
+		if err != nil {
+			return err
+		}
+
+		todos, err := sc.ToDoService.Find(todoFilter)
+		if err != nil {
+			return err
+		}
+
+		return formats.WriteCSV(w, todos, cols)
+
+	case "markdown":
+		todos, err := sc.ToDoService.Find(todoFilter)
+		if err != nil {
+			return err
+		}
+
+		return formats.WriteMarkdown(w, todos)
+
+	case "todotxt":
+		todos, err := sc.ToDoService.Find(todoFilter)
+		if err != nil {
+			return err
+		}
+
+		return formats.WriteTodoTxt(w, todos)
+
+	case "ical":
+		todos, err := sc.ToDoService.Find(todoFilter)
+		if err != nil {
+			return err
+		}
+
+		return formats.WriteICal(w, todos)
+
+	case "taskwarrior":
+		todos, err := sc.ToDoService.Find(todoFilter)
+		if err != nil {
+			return err
+		}
+
+		return formats.WriteTaskwarrior(w, todos)
+
+	default:
+		return fmt.Errorf("unknown export format %q", exportFormat)
+	}
+}
+
+// Import runs `tuidoo import [--format json|csv|markdown|todotxt|ical|taskwarrior] [options] FILE`
+func Import(sel config.Selection, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "", "import format: json, csv, markdown, todotxt, ical or taskwarrior (default: from the file extension; json arrays are read as taskwarrior)")
+	mode := flags.String("mode", string(services.ImportMerge), "json: merge or replace")
+	dryRun := flags.Bool("dry-run", false, "report what would change without writing")
+	mapping := flags.String("map", "", "csv: map fields to headers, e.g. name=Task,due=Deadline")
+	project := flags.String("project", "", "project for records that do not name one")
+	list := flags.String("list", "", "list for records that do not name one")
+	rest := parseInterspersed(flags, args)
+
+	if len(rest) != 1 {
+		return usage("usage: tuidoo import [--format json|csv|markdown|todotxt|ical|taskwarrior] [--dry-run] FILE")
+	}
+
+	importFormat, err := formats.Detect(*format, rest[0])
+	if err != nil {
+		return usage("%v", err)
+	}
+
+	in, closeIn, err := openInput(rest[0])
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+
+	// `task export` writes a JSON array, tuidoo exports are an object
+	r := bufio.NewReader(in)
+	if importFormat == "json" && *format == "" && startsWithArray(r) {
+		importFormat = "taskwarrior"
+	}
+
+	// Parse before touching the database so a bad file changes nothing
+	var snapshot services.Snapshot
+	var records []formats.Record
+
+	switch importFormat {
+	case "json":
+		if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+			return fmt.Errorf("invalid export file: %w", err)
+		}
+
+	case "csv":
+		columnMapping, err := formats.ParseMapping(*mapping)
+		if err != nil {
+			return err
+		}
+		if records, err = formats.ReadCSV(r, columnMapping); err != nil {
+			return err
+		}
+
+	case "markdown":
+		if records, err = formats.ReadMarkdown(r); err != nil {
+			return err
+		}
+
+	case "todotxt":
+		if records, err = formats.ReadTodoTxt(r); err != nil {
+			return err
+		}
+
+	case "ical":
+		if records, err = formats.ReadICal(r); err != nil {
+			return err
+		}
+
+	case "taskwarrior":
+		if records, err = formats.ReadTaskwarrior(r); err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("unknown import format %q", importFormat)
+	}
+
+	sc, err := services.NewCommandServiceCollection(sel)
+	if err != nil {
+		return fmt.Errorf("failed to initialize services: %w", err)
+	}
+	defer sc.Close()
+
+	if !*dryRun {
+		if _, err := sc.BackupService.Create("pre-import"); err != nil {
+			return fmt.Errorf("backup before import failed: %w", err)
+		}
+	}
+
+	var report *services.ImportReport
+	if importFormat == "json" {
+		report, err = sc.TransferService.Import(&snapshot, services.ImportMode(*mode), *dryRun)
+	} else {
+		report, err = sc.TransferService.ImportRecords(records, services.RecordImportOptions{
+			DryRun:         *dryRun,
+			DefaultProject: *project,
+			DefaultList:    *list,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	printImportReport(report)
+	return nil
+}
+
+// addFilterFlags registers the todo filter flags shared by commands that
+// work on a set of todos. The returned function builds the filter after
+// parsing and reports whether any filter was given.
+func addFilterFlags(flags *flag.FlagSet) func() (services.ToDoFilter, bool, error) {
+	project := flags.String("project", "", "only todos in this project")
+	list := flags.String("list", "", "only todos in this list")
+	status := flags.String("status", "", "only todos with this status")
+	priority := flags.String("priority", "", "only todos with this priority")
+	done := flags.String("done", "", "only done (true) or open (false) todos")
+	search := flags.String("search", "", "only todos whose name contains this text")
+	dueBefore := flags.String("due-before", "", "only todos due before this date")
+	dueAfter := flags.String("due-after", "", "only todos due on or after this date")
+
+	return func() (services.ToDoFilter, bool, error) {
+		filter := services.ToDoFilter{Project: *project, List: *list, Search: *search}
+
+		if *status != "" {
+			s, err := enums.ParseStatus(*status)
+			if err != nil {
+				return filter, false, err
+			}
+			filter.Status = &s
+		}
+		if *priority != "" {
+			p, err := enums.ParsePriority(*priority)
+			if err != nil {
+				return filter, false, err
+			}
+			filter.Priority = &p
+		}
+		if *done != "" {
+			d, err := formats.ParseBool(*done)
+			if err != nil {
+				return filter, false, fmt.Errorf("--done: %w", err)
+			}
+			filter.Done = &d
+		}
+		if *dueBefore != "" {
+			t, err := services.ParseDue(*dueBefore, time.Now())
+			if err != nil {
+				return filter, false, fmt.Errorf("--due-before: %w", err)
+			}
+			filter.DueBefore = &t
+		}
+		if *dueAfter != "" {
+			t, err := services.ParseDue(*dueAfter, time.Now())
+			if err != nil {
+				return filter, false, fmt.Errorf("--due-after: %w", err)
+			}
+			filter.DueAfter = &t
+		}
+
+		return filter, filterSet(filter), nil
+	}
+}
+
+func filterSet(f services.ToDoFilter) bool {
+	return f.Project != "" || f.List != "" || f.Search != "" || f.Status != nil ||
+		f.Priority != nil || f.Done != nil || f.DueBefore != nil || f.DueAfter != nil
+}
+
+// startsWithArray peeks at the first non-space byte of r
+func startsWithArray(r *bufio.Reader) bool {
+	for i := 1; ; i++ {
+		b, err := r.Peek(i)
+		if err != nil {
+			return false
+		}
+		switch b[i-1] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '[':
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+func printImportReport(report *services.ImportReport) {
+	if report.DryRun {
+		if report.Mode != "" {
+			fmt.Printf("Dry run (%s) — nothing was written\n", report.Mode)
+		} else {
+			fmt.Println("Dry run — nothing was written")
+		}
+		for _, c := range report.Changes {
+			line := fmt.Sprintf("  %-6s %-8s %s", c.Action, c.Kind, c.Name)
+			if len(c.Fields) > 0 {
+				line += " (" + strings.Join(c.Fields, ", ") + ")"
+			}
+			fmt.Println(line)
+		}
+	}
+
+	for _, e := range report.Errors {
+		if e.Name != "" {
+			fmt.Printf("⚠️  line %d (%s): %s\n", e.Line, e.Name, e.Error)
+		} else {
+			fmt.Printf("⚠️  line %d: %s\n", e.Line, e.Error)
+		}
+	}
+
+	summary := importSummary(report)
+	if len(report.Errors) > 0 {
+		fmt.Printf("⚠️  %s, %d skipped\n", summary, len(report.Errors))
+		return
+	}
+	fmt.Printf("✅ %s\n", summary)
+}
+
+// importSummary counts each kind of record apart, so the projects and
+// lists created for imported tasks are not counted as tasks
+func importSummary(report *services.ImportReport) string {
+	kinds := []struct{ kind, plural string }{
+		{"todo", "tasks"}, {"project", "projects"}, {"list", "lists"}, {"settings", "settings"},
+	}
+	actions := []struct{ action, done string }{
+		{"create", "created"}, {"update", "updated"}, {"delete", "deleted"},
+	}
+
+	var parts []string
+	for _, k := range kinds {
+		var counts []string
+		for _, a := range actions {
+			if n := report.CountKind(k.kind, a.action); n > 0 {
+				counts = append(counts, fmt.Sprintf("%d %s", n, a.done))
+			}
+		}
+		if len(counts) > 0 {
+			parts = append(parts, k.plural+": "+strings.Join(counts, ", "))
+		}
+	}
+	return strings.Join(append(parts, fmt.Sprintf("%d unchanged", report.Unchanged)), "; ")
+}
+
+func openOutput(path string) (io.Writer, func(), error) {
+	if path == "" || path == "-" {
+		return os.Stdout, func() {}, nil
+	}
+
+	f, err := os.Create(config.ExpandPath(path))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	return f, func() { f.Close() }, nil
+}
+
+func openInput(path string) (io.Reader, func(), error) {
+	if path == "-" {
+		return os.Stdin, func() {}, nil
+	}
+
+	f, err := os.Open(config.ExpandPath(path))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	return f, func() { f.Close() }, nil
+}
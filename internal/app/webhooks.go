@@ -0,0 +1,163 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"tuidoo/config"
+	"tuidoo/entities"
+	"tuidoo/services"
+)
+
+// Webhooks runs `tuidoo webhooks [list|show ID|replay ID...|replay --failed]`
+func Webhooks(sel config.Selection, args []string) error {
+	command := "list"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	sc, err := services.NewCommandServiceCollection(sel)
+	if err != nil {
+		return fmt.Errorf("failed to initialize services: %w", err)
+	}
+	defer sc.Close()
+
+	webhooks := sc.WebhookService
+
+	switch command {
+	case "list":
+		flags := flag.NewFlagSet("webhooks list", flag.ExitOnError)
+		state := flags.String("state", "", "only deliveries in this state: pending, delivered or failed")
+		limit := flags.Int("limit", 50, "show at most this many deliveries")
+		flags.Parse(args)
+
+		deliveries, err := webhooks.Deliveries(*state, *limit)
+		if err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			fmt.Println("No webhook deliveries")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tCREATED\tWEBHOOK\tEVENT\tTODO\tSTATE\tATTEMPTS\tLAST ERROR")
+		for _, d := range deliveries {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%s\t%d/%d\t%s\n",
+				d.ID, d.CreatedAt.Local().Format("2006-01-02 15:04:05"), d.Webhook, d.Event,
+				d.ToDoID, d.State, d.Attempts, d.MaxAttempts, d.LastError)
+		}
+		return w.Flush()
+
+	case "show":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: tuidoo webhooks show ID")
+		}
+		id, err := parseDeliveryID(args[0])
+		if err != nil {
+			return err
+		}
+
+		delivery, err := webhooks.Delivery(id)
+		if err != nil {
+			return err
+		}
+		printDelivery(delivery)
+		return nil
+
+	case "replay":
+		flags := flag.NewFlagSet("webhooks replay", flag.ExitOnError)
+		failed := flags.Bool("failed", false, "replay every failed delivery")
+		flags.Parse(args)
+
+		var ids []uint
+		if *failed {
+			deliveries, err := webhooks.Deliveries(services.WebhookFailed, 0)
+			if err != nil {
+				return err
+			}
+			for _, d := range deliveries {
+				ids = append(ids, d.ID)
+			}
+			if len(ids) == 0 {
+				fmt.Println("No failed deliveries")
+				return nil
+			}
+		} else {
+			if flags.NArg() == 0 {
+				return fmt.Errorf("usage: tuidoo webhooks replay ID... | --failed")
+			}
+			for _, arg := range flags.Args() {
+				id, err := parseDeliveryID(arg)
+				if err != nil {
+					return err
+				}
+				ids = append(ids, id)
+			}
+		}
+
+		failures := 0
+		for _, id := range ids {
+			delivery, err := webhooks.Replay(id)
+			switch {
+			case err != nil:
+				failures++
+				fmt.Printf("❌ %d: %v\n", id, err)
+			case delivery.State == services.WebhookDelivered:
+				fmt.Printf("✅ %d delivered to %s (%d)\n", id, delivery.Webhook, delivery.StatusCode)
+			case delivery.State == services.WebhookFailed:
+				failures++
+				fmt.Printf("❌ %d to %s failed: %s\n", id, delivery.Webhook, delivery.LastError)
+			default:
+				failures++
+				fmt.Printf("⚠️  %d to %s failed, will retry: %s\n", id, delivery.Webhook, delivery.LastError)
+			}
+		}
+		if failures > 0 {
+			return fmt.Errorf("%d of %d deliveries did not go through", failures, len(ids))
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown webhooks command %q (expected list, show or replay)", command)
+	}
+}
+
+func parseDeliveryID(s string) (uint, error) {
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("invalid delivery id %q", s)
+	}
+	return uint(id), nil
+}
+
+func printDelivery(d *entities.WebhookDelivery) {
+	fmt.Printf("Delivery:  %d (%s)\n", d.ID, d.UID)
+	fmt.Printf("Webhook:   %s → %s\n", d.Webhook, d.URL)
+	fmt.Printf("Event:     %s (todo %d)\n", d.Event, d.ToDoID)
+	fmt.Printf("State:     %s after %d of %d attempts\n", d.State, d.Attempts, d.MaxAttempts)
+	fmt.Printf("Created:   %s\n", d.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+	if d.StatusCode != 0 {
+		fmt.Printf("Status:    %d\n", d.StatusCode)
+	}
+	if d.LastError != "" {
+		fmt.Printf("Error:     %s\n", d.LastError)
+	}
+	if d.NextAttemptAt != nil {
+		fmt.Printf("Next try:  %s\n", d.NextAttemptAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	if d.DeliveredAt != nil {
+		fmt.Printf("Delivered: %s\n", d.DeliveredAt.Local().Format("2006-01-02 15:04:05"))
+	}
+
+	var payload bytes.Buffer
+	if json.Indent(&payload, []byte(d.Payload), "", "  ") != nil {
+		payload.Reset()
+		payload.WriteString(d.Payload)
+	}
+	fmt.Printf("\n%s\n", payload.String())
+}
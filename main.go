@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"log"
+	"tuidoo/config"
 	"tuidoo/entities"
 
 	"tuidoo/managers"
@@ -63,8 +64,14 @@ func initializeApp() (*App, error) {
 		log.Printf("Warning: Could not detect system theme: %v", err)
 	}
 
+	// Resolve database location
+	_, sel, err := config.Resolve("", "")
+	if err != nil {
+		return nil, fmt.Errorf("config resolution failed: %w", err)
+	}
+
 	// Initialize services
-	sc, err := services.NewServiceCollection()
+	sc, err := services.NewServiceCollection(sel)
 	if err != nil {
 		return nil, fmt.Errorf("service initialization failed: %w", err)
 	}
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"tuidoo/config"
+	"tuidoo/entities"
+
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const backupTimeLayout = "20060102-150405"
+
+type BackupService struct {
+	db     *DbService
+	config config.BackupConfig
+}
+
+// Backup is a backup file found in the backup directory
+type Backup struct {
+	Path      string
+	Reason    string
+	CreatedAt time.Time
+	Size      int64
+}
+
+func NewBackupService(dbService *DbService, backupConfig config.BackupConfig) *BackupService {
+	return &BackupService{db: dbService, config: backupConfig}
+}
+
+// Dir returns the directory backups are written to
+func (bs *BackupService) Dir() string {
+	return bs.config.Dir
+}
+
+// Create writes a consistent snapshot of the live database using VACUUM INTO,
+// which is safe while other processes are reading and writing
+func (bs *BackupService) Create(reason string) (*Backup, error) {
+	if err := os.MkdirAll(bs.config.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("tuidoo-%s-%s", now.Format(backupTimeLayout), reason)
+	path := filepath.Join(bs.config.Dir, name+".db")
+
+	// Two backups within the same second get a numeric suffix
+	for i := 2; fileExists(path); i++ {
+		path = filepath.Join(bs.config.Dir, fmt.Sprintf("%s-%d.db", name, i))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	quoted := strings.ReplaceAll(path, "'", "''")
+	if err := bs.db.GetDB().WithContext(ctx).Exec("VACUUM INTO '" + quoted + "'").Error; err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("backup was not written: %w", err)
+	}
+
+	bs.db.progress().Printf("💾 Backup written to %s", path)
+	return &Backup{Path: path, Reason: reason, CreatedAt: now, Size: info.Size()}, nil
+}
+
+// List returns all backups, newest first
+func (bs *BackupService) List() ([]Backup, error) {
+	matches, err := filepath.Glob(filepath.Join(bs.config.Dir, "tuidoo-*.db"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	backups := make([]Backup, 0, len(matches))
+	for _, path := range matches {
+		backup, ok := parseBackupName(path)
+		if !ok {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			backup.Size = info.Size()
+		}
+		backups = append(backups, backup)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+
+	return backups, nil
+}
+
+// Latest returns the newest backup
+func (bs *BackupService) Latest() (*Backup, error) {
+	backups, err := bs.List()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(backups) == 0 {
+		return nil, fmt.Errorf("no backups found in %s", bs.config.Dir)
+	}
+
+	return &backups[0], nil
+}
+
+// Prune applies the retention policy: the newest backup of each of the last
+// KeepDaily days and of each of the last KeepWeekly ISO weeks is kept, as is
+// the newest backup overall. Automatic pre-* safety backups younger than
+// KeepDaily days are kept too. Everything else is deleted.
+func (bs *BackupService) Prune() ([]Backup, error) {
+	backups, err := bs.List()
+	if err != nil {
+		return nil, err
+	}
+
+	keep := retainedBackups(backups, bs.config.KeepDaily, bs.config.KeepWeekly)
+
+	var removed []Backup
+	for _, b := range backups {
+		if keep[b.Path] {
+			continue
+		}
+		if err := os.Remove(b.Path); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", b.Path, err)
+		}
+		removed = append(removed, b)
+	}
+
+	return removed, nil
+}
+
+// Restore verifies a backup and copies it over the live database using the
+// SQLite online backup API, so other open connections see a consistent
+// switch. A safety backup of the current data is taken first. Older backups
+// are migrated forward afterwards.
+func (bs *BackupService) Restore(path string) (*Backup, error) {
+	if err := VerifyBackup(path); err != nil {
+		return nil, err
+	}
+
+	safety, err := bs.Create("pre-restore")
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up current database: %w", err)
+	}
+
+	if err := bs.copyInto(path); err != nil {
+		return safety, err
+	}
+
+	if _, err := NewMigrationService(bs.db).Up(0); err != nil {
+		return safety, fmt.Errorf("restored database could not be migrated: %w", err)
+	}
+
+	bs.db.progress().Printf("♻️  Restored %s", path)
+	return safety, nil
+}
+
+func (bs *BackupService) copyInto(path string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	liveDB, err := bs.db.GetDB().DB()
+	if err != nil {
+		return err
+	}
+
+	live, err := liveDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire database connection: %w", err)
+	}
+	defer live.Close()
+
+	srcDB, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer srcDB.Close()
+
+	src, err := srcDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer src.Close()
+
+	return live.Raw(func(liveRaw any) error {
+		return src.Raw(func(srcRaw any) error {
+			dest, ok := liveRaw.(*sqlite3.SQLiteConn)
+			if !ok {
+				return errors.New("live database is not a SQLite connection")
+			}
+			source, ok := srcRaw.(*sqlite3.SQLiteConn)
+			if !ok {
+				return errors.New("backup is not a SQLite connection")
+			}
+
+			backup, err := dest.Backup("main", source, "main")
+			if err != nil {
+				return fmt.Errorf("failed to start restore: %w", err)
+			}
+
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return fmt.Errorf("failed to restore: %w", err)
+			}
+
+			return backup.Finish()
+		})
+	})
+}
+
+// VerifyBackup checks that a file is an intact tuidoo database that this
+// binary can open
+func VerifyBackup(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("backup not found: %w", err)
+	}
+
+	db, err := gorm.Open(sqlite.Open("file:"+path+"?mode=ro"), &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	var results []string
+	if err := db.Raw("PRAGMA integrity_check").Scan(&results).Error; err != nil {
+		return fmt.Errorf("integrity check failed: %w", err)
+	}
+	if len(results) != 1 || results[0] != "ok" {
+		return fmt.Errorf("backup is corrupt: %s", strings.Join(results, "; "))
+	}
+
+	if !db.Migrator().HasTable(&entities.ToDo{}) {
+		return fmt.Errorf("%s is not a tuidoo database", path)
+	}
+
+	if db.Migrator().HasTable(&entities.SchemaMigration{}) {
+		var version int
+		if err := db.Model(&entities.SchemaMigration{}).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&version).Error; err != nil {
+			return fmt.Errorf("failed to read backup schema version: %w", err)
+		}
+		if version > LatestSchemaVersion() {
+			return fmt.Errorf("%w (backup is at version %d)", ErrSchemaTooNew, version)
+		}
+	}
+
+	return nil
+}
+
+func retainedBackups(backups []Backup, keepDaily, keepWeekly int) map[string]bool {
+	keep := map[string]bool{}
+	if len(backups) == 0 {
+		return keep
+	}
+
+	// backups are sorted newest first, so the first hit per bucket is the
+	// newest backup of that day or week
+	keep[backups[0].Path] = true
+
+	days := map[string]bool{}
+	weeks := map[string]bool{}
+	safetyCutoff := time.Now().AddDate(0, 0, -keepDaily)
+
+	for _, b := range backups {
+		local := b.CreatedAt.Local()
+
+		if strings.HasPrefix(b.Reason, "pre-") && local.After(safetyCutoff) {
+			keep[b.Path] = true
+		}
+
+		day := local.Format("2006-01-02")
+		if !days[day] && len(days) < keepDaily {
+			days[day] = true
+			keep[b.Path] = true
+		}
+
+		year, week := local.ISOWeek()
+		weekKey := fmt.Sprintf("%d-%02d", year, week)
+		if !weeks[weekKey] && len(weeks) < keepWeekly {
+			weeks[weekKey] = true
+			keep[b.Path] = true
+		}
+	}
+
+	return keep
+}
+
+func parseBackupName(path string) (Backup, bool) {
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "tuidoo-"), ".db")
+	if len(name) < len(backupTimeLayout) {
+		return Backup{}, false
+	}
+
+	createdAt, err := time.ParseInLocation(backupTimeLayout, name[:len(backupTimeLayout)], time.Local)
+	if err != nil {
+		return Backup{}, false
+	}
+
+	return Backup{
+		Path:      path,
+		Reason:    strings.TrimPrefix(name[len(backupTimeLayout):], "-"),
+		CreatedAt: createdAt,
+	}, true
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+	"tuidoo/entities"
+)
+
+// CompletionLimit caps how many values a completion query returns
+const CompletionLimit = 100
+
+// TaskRef is a task ID with its name, as offered by shell completion
+type TaskRef struct {
+	ID   uint
+	Name string
+}
+
+// CompletionService looks up values for shell completion. It is meant for a
+// database opened with OpenReadOnly, and only runs queries that use an
+// index or read small tables, so completing stays fast on large databases.
+type CompletionService struct {
+	db *DbService
+}
+
+func NewCompletionService(dbService *DbService) *CompletionService {
+	return &CompletionService{db: dbService}
+}
+
+// ProjectNames returns project names starting with prefix, ignoring case
+func (cs *CompletionService) ProjectNames(prefix string) ([]string, error) {
+	return cs.names(&entities.Project{}, prefix)
+}
+
+// ListNames returns list names starting with prefix, ignoring case
+func (cs *CompletionService) ListNames(prefix string) ([]string, error) {
+	return cs.names(&entities.ToDoList{}, prefix)
+}
+
+func (cs *CompletionService) names(model any, prefix string) ([]string, error) {
+	ctx, cancel := cs.db.NewContext()
+	defer cancel()
+
+	var names []string
+	err := cs.db.GetDB().WithContext(ctx).
+		Model(model).
+		Where("name LIKE ? ESCAPE '\\'", likePrefix(prefix)).
+		Order("name").
+		Limit(CompletionLimit).
+		Distinct().
+		Pluck("name", &names).Error
+	return names, err
+}
+
+// Tasks returns tasks whose ID starts with prefix and that are done or open,
+// newest first. The ID prefix is turned into ID ranges (1, 10–19, 100–199,
+// ...) so the lookup walks the primary key instead of scanning every task.
+func (cs *CompletionService) Tasks(prefix string, done bool) ([]TaskRef, error) {
+	ctx, cancel := cs.db.NewContext()
+	defer cancel()
+
+	query := cs.db.GetDB().WithContext(ctx).
+		Model(&entities.ToDo{}).
+		Select("id", "name").
+		Where("done = ?", done)
+
+	if prefix != "" {
+		n, err := strconv.ParseUint(prefix, 10, 32)
+		if err != nil || n == 0 {
+			return nil, nil
+		}
+
+		var ranges []string
+		var args []any
+		for low, span := n, uint64(1); low <= 1<<32; low, span = low*10, span*10 {
+			ranges = append(ranges, "id BETWEEN ? AND ?")
+			args = append(args, low, low+span-1)
+		}
+		query = query.Where("("+strings.Join(ranges, " OR ")+")", args...)
+	}
+
+	var tasks []TaskRef
+	err := query.Order("id DESC").Limit(CompletionLimit).Find(&tasks).Error
+	return tasks, err
+}
+
+// likePrefix escapes LIKE wildcards in a prefix and appends one
+func likePrefix(prefix string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(prefix) + "%"
+}
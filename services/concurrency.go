@@ -0,0 +1,43 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound is returned when a record does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a record changed after the caller read it
+	ErrConflict = errors.New("was modified since it was read")
+)
+
+// Version returns an opaque token that changes whenever a record is saved.
+// Callers pass it back to the *Checked methods to make sure they are not
+// overwriting a change they have not seen.
+func Version(updatedAt time.Time) string {
+	return strconv.FormatInt(updatedAt.UnixNano(), 36)
+}
+
+// checkVersion fails with ErrNotFound or ErrConflict unless the record
+// exists and, when version is set, is still at that version. Run it in the
+// same transaction as the write so the check and the write are atomic.
+func checkVersion(tx *gorm.DB, model any, id uint, version string) error {
+	var current struct{ UpdatedAt time.Time }
+
+	result := tx.Model(model).Select("updated_at").Where("id = ?", id).Limit(1).Scan(&current)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	if version != "" && Version(current.UpdatedAt) != version {
+		return ErrConflict
+	}
+	return nil
+}
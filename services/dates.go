@@ -0,0 +1,63 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"tuidoo/config"
+	"tuidoo/formats"
+)
+
+// ParseDue reads a due value: a date, now, today, tomorrow, or an
+// offset such as +3d, -2h or +1w. Day and week offsets count from the start
+// of today, so they give plain dates.
+func ParseDue(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	// Keywords and offsets match in any case; dates are read as typed, as
+	// RFC 3339 needs its T and Z
+	word := strings.ToLower(s)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch word {
+	case "now":
+		return now, nil
+	case "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	}
+
+	if len(word) >= 3 && (word[0] == '+' || word[0] == '-') {
+		n, err := strconv.Atoi(word[1 : len(word)-1])
+		if err == nil {
+			if word[0] == '-' {
+				n = -n
+			}
+			switch word[len(word)-1] {
+			case 'h':
+				return now.Add(time.Duration(n) * time.Hour), nil
+			case 'd':
+				return today.AddDate(0, 0, n), nil
+			case 'w':
+				return today.AddDate(0, 0, 7*n), nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("invalid offset %q (expected e.g. +3d, -2h or +1w)", s)
+	}
+
+	return formats.ParseDate(s)
+}
+
+// ParseDueInput reads a due date typed by hand, in the CLI or the task
+// form: what ParseDue reads, or a date in ui.date_format
+func ParseDueInput(s string, ui config.UIConfig, now time.Time) (time.Time, error) {
+	t, err := ParseDue(s, now)
+	if err == nil {
+		return t, nil
+	}
+	if local, layoutErr := time.ParseInLocation(ui.DateLayout(), strings.TrimSpace(s), time.Local); layoutErr == nil {
+		return local, nil
+	}
+	return time.Time{}, err
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"tuidoo/config"
+)
+
+func TestParseDueInput(t *testing.T) {
+	now := time.Date(2026, 10, 19, 15, 30, 0, 0, time.Local)
+	eu := config.UIConfig{DateFormat: "eu"}
+
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"today", time.Date(2026, 10, 19, 0, 0, 0, 0, time.Local)},
+		{"Tomorrow", time.Date(2026, 10, 20, 0, 0, 0, 0, time.Local)},
+		{"+3d", time.Date(2026, 10, 22, 0, 0, 0, 0, time.Local)},
+		{"-1w", time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local)},
+		{"+2h", now.Add(2 * time.Hour)},
+		{"2026-11-01", time.Date(2026, 11, 1, 0, 0, 0, 0, time.Local)},
+		{"2026-11-01T09:00:00-04:00", time.Date(2026, 11, 1, 13, 0, 0, 0, time.UTC)},
+		{" 2026-11-01T09:00:00Z ", time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC)},
+		{"01.11.2026", time.Date(2026, 11, 1, 0, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		got, err := ParseDueInput(tt.in, eu, now)
+		if err != nil {
+			t.Errorf("ParseDueInput(%q): %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseDueInput(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"soon", "+3x", "32.13.2026"} {
+		if _, err := ParseDueInput(in, eu, now); err == nil {
+			t.Errorf("ParseDueInput(%q) accepted", in)
+		}
+	}
+}
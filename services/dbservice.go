@@ -2,61 +2,101 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
-	e "tuidoo/entities"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
-var (
+type DbService struct {
+	path    string
 	db      *gorm.DB
 	once    sync.Once
 	initErr error
-)
+	// quiet drops progress messages, see progress
+	quiet bool
+}
 
-type DbService struct {
-	db *gorm.DB
+// quietLogger is the progress logger of a quiet database
+var quietLogger = log.New(io.Discard, "", 0)
+
+// dbLogger is gorm's default logger, except that missing records are not
+// logged: lookups report them as ErrNotFound, and in the TUI the log line
+// would be drawn over the screen. It writes to stderr, keeping stdout for
+// command output.
+var dbLogger = logger.New(log.New(os.Stderr, "\r\n", log.LstdFlags), logger.Config{
+	SlowThreshold:             200 * time.Millisecond,
+	LogLevel:                  logger.Warn,
+	IgnoreRecordNotFoundError: true,
+	Colorful:                  true,
+})
+
+// progress is the logger for what the services go through with the
+// database, such as migrations, seeding, backups and syncs. Warnings and
+// errors go to the standard logger instead, so quiet never hides them.
+func (d *DbService) progress() *log.Logger {
+	if d.quiet {
+		return quietLogger
+	}
+	return log.Default()
+}
+
+// NewDbService creates a database service for the SQLite file at path
+func NewDbService(path string) *DbService {
+	return &DbService{path: path}
 }
 
-func (d *DbService) Connect() error {
-	once.Do(func() {
-		db, initErr = gorm.Open(sqlite.Open("test_tuidoo.db"), &gorm.Config{})
-		if initErr != nil {
-			log.Printf("Failed to connect to database: %v", initErr)
+// Open opens the database connection without touching the schema
+func (d *DbService) Open() error {
+	d.once.Do(func() {
+		if d.initErr = os.MkdirAll(filepath.Dir(d.path), 0o755); d.initErr != nil {
+			d.initErr = fmt.Errorf("failed to create database directory: %w", d.initErr)
 			return
 		}
 
-		initErr = db.AutoMigrate(
-			&e.Settings{},
-			&e.Project{},
-			&e.ToDoList{},
-			&e.ToDo{},
-		)
-		if initErr != nil {
-			log.Printf("Failed to run migrations: %v", initErr)
+		// WAL lets readers carry on while another process writes, and
+		// writers wait for each other instead of failing with "database is
+		// locked". Transactions take the write lock up front so a read
+		// followed by a write cannot deadlock against another writer.
+		dsn := d.path + "?_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate"
+		d.db, d.initErr = gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: dbLogger})
+		if d.initErr != nil {
+			log.Printf("Failed to connect to database: %v", d.initErr)
+		}
+	})
+
+	return d.initErr
+}
+
+// OpenReadOnly opens an existing database for reading only, for quick
+// lookups that must neither create the file nor wait long for a writer
+func (d *DbService) OpenReadOnly() error {
+	d.once.Do(func() {
+		if _, d.initErr = os.Stat(d.path); d.initErr != nil {
 			return
 		}
 
-		log.Println("Database connected and migrated successfully")
+		dsn := "file:" + d.path + "?mode=ro&_busy_timeout=200"
+		d.db, d.initErr = gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
 	})
-	if initErr != nil {
-		return initErr
-	}
 
-	d.db = db
-	return nil
+	return d.initErr
 }
 
 // Close closes the database connection
 func (d *DbService) Close() error {
-	if db == nil {
+	if d.db == nil {
 		return nil
 	}
 
-	sqlDB, err := db.DB()
+	sqlDB, err := d.db.DB()
 	if err != nil {
 		return err
 	}
@@ -69,9 +109,12 @@ func (d *DbService) GetDB() *gorm.DB {
 	return d.db
 }
 
+// Path returns the location of the database file
+func (d *DbService) Path() string {
+	return d.path
+}
+
 // NewContext creates a context with timeout for database operations
 func (d *DbService) NewContext() (context.Context, context.CancelFunc) {
 	return context.WithTimeout(context.Background(), 5*time.Second)
 }
-
-var Db = &DbService{}
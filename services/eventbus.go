@@ -0,0 +1,156 @@
+package services
+
+import "sync"
+
+// Event is published on the EventBus after a change has been committed
+type Event interface {
+	event()
+}
+
+// Todo events carry the ID of the changed todo
+type (
+	TodoCreated struct{ ID uint }
+	TodoUpdated struct{ ID uint }
+	// TodoCompleted is published instead of TodoUpdated when a change marks
+	// an open todo done
+	TodoCompleted struct{ ID uint }
+	// TodoReopened is published instead of TodoUpdated when a change marks a
+	// done todo open again
+	TodoReopened struct{ ID uint }
+	TodoDeleted  struct{ ID uint }
+)
+
+// Project and list events carry the ID of the changed record
+type (
+	ProjectCreated struct{ ID uint }
+	ProjectUpdated struct{ ID uint }
+	ProjectDeleted struct{ ID uint }
+	ListCreated    struct{ ID uint }
+	ListUpdated    struct{ ID uint }
+	ListDeleted    struct{ ID uint }
+)
+
+// ThemeChanged is published when the active theme setting changes
+type ThemeChanged struct{ ThemeID string }
+
+// DataImported is published after an import or sync changed any number of
+// records at once
+type DataImported struct{}
+
+// DataReset is published after the database was cleaned or reseeded
+type DataReset struct{}
+
+// ExternalChange wraps an event for a change another process committed to
+// the database (see WatchService). Only the TUI and RPC clients act on
+// these; webhooks and todo.txt sync are left to the process that made the
+// change.
+type ExternalChange struct{ Event Event }
+
+// HookFailed is published when a hook script failed, timed out or vetoed a
+// change. ID is the todo, or zero for one that was about to be created.
+type HookFailed struct {
+	Hook string
+	ID   uint
+	Err  error
+}
+
+// RuleFired is published when an automation rule changed or created a task.
+// ID is the task the rule fired for; Err is set when an action failed.
+type RuleFired struct {
+	Rule string
+	ID   uint
+	Err  error
+}
+
+func (TodoCreated) event()    {}
+func (TodoUpdated) event()    {}
+func (TodoCompleted) event()  {}
+func (TodoReopened) event()   {}
+func (TodoDeleted) event()    {}
+func (ProjectCreated) event() {}
+func (ProjectUpdated) event() {}
+func (ProjectDeleted) event() {}
+func (ListCreated) event()    {}
+func (ListUpdated) event()    {}
+func (ListDeleted) event()    {}
+func (ThemeChanged) event()   {}
+func (DataImported) event()   {}
+func (DataReset) event()      {}
+func (ExternalChange) event() {}
+func (HookFailed) event()     {}
+func (RuleFired) event()      {}
+
+// TodoID returns the todo a todo event is about
+func TodoID(e Event) (uint, bool) {
+	switch e := e.(type) {
+	case TodoCreated:
+		return e.ID, true
+	case TodoUpdated:
+		return e.ID, true
+	case TodoCompleted:
+		return e.ID, true
+	case TodoReopened:
+		return e.ID, true
+	case TodoDeleted:
+		return e.ID, true
+	default:
+		return 0, false
+	}
+}
+
+// EventBus is an in-process publish/subscribe bus. Handlers run
+// synchronously in the publishing goroutine, in the order they subscribed,
+// so they must be quick and hand slow work to a goroutine of their own.
+type EventBus struct {
+	mu       sync.Mutex
+	next     int
+	handlers []subscription
+}
+
+type subscription struct {
+	id      int
+	handler func(Event)
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe registers a handler for every event and returns a function that
+// removes it again
+func (b *EventBus) Subscribe(handler func(Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.next++
+	id := b.next
+	b.handlers = append(b.handlers, subscription{id: id, handler: handler})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		for i, s := range b.handlers {
+			if s.id == id {
+				b.handlers = append(b.handlers[:i:i], b.handlers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish delivers an event to every subscriber. Handlers may publish
+// events themselves.
+func (b *EventBus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	handlers := b.handlers
+	b.mu.Unlock()
+
+	for _, s := range handlers {
+		s.handler(e)
+	}
+}
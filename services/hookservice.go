@@ -0,0 +1,354 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"tuidoo/config"
+	"tuidoo/entities"
+	"tuidoo/enums"
+)
+
+// Hook names. pre-* hooks run before a change and may veto it; on-* hooks
+// run after it was committed.
+const (
+	HookPreAdd      = "pre-add"
+	HookPreModify   = "pre-modify"
+	HookPreComplete = "pre-complete"
+	HookPreDelete   = "pre-delete"
+	HookOnAdd       = "on-add"
+	HookOnModify    = "on-modify"
+	HookOnComplete  = "on-complete"
+	HookOnDelete    = "on-delete"
+)
+
+// hookQueueSize is how many on-* hook runs may wait before new ones are
+// dropped
+const hookQueueSize = 64
+
+// ErrHookRejected is returned when a pre-* hook vetoed a change
+var ErrHookRejected = errors.New("rejected by hook")
+
+// HookTask is the task JSON hooks read on stdin. pre-add, pre-modify and
+// pre-complete hooks may print it back changed; the project and list are
+// changed through their IDs, the names are informational.
+type HookTask struct {
+	ID          uint           `json:"id,omitempty"`
+	UID         string         `json:"uid,omitempty"`
+	Name        string         `json:"name"`
+	Description *string        `json:"description"`
+	Details     *string        `json:"details"`
+	ProjectID   uint           `json:"project_id"`
+	Project     string         `json:"project,omitempty"`
+	ListID      uint           `json:"list_id,omitempty"`
+	List        string         `json:"list,omitempty"`
+	Priority    enums.Priority `json:"priority"`
+	Status      enums.Status   `json:"status"`
+	Color       string         `json:"color,omitempty"`
+	Done        bool           `json:"done"`
+	DueDate     *time.Time     `json:"due_date"`
+}
+
+// HookService runs executable scripts from the profile's hooks directory
+// when tasks change, git-hooks style. A hook is every executable whose name
+// starts with the hook name (on-add, on-add.py, on-add-timelog), run in
+// name order.
+type HookService struct {
+	db      *DbService
+	events  *EventBus
+	profile string
+	config  config.HooksConfig
+
+	stopEvents func()
+	done       chan struct{}
+
+	mu    sync.Mutex
+	queue chan hookRun
+}
+
+type hookRun struct {
+	hook  string
+	id    uint
+	input []byte
+}
+
+func NewHookService(dbService *DbService, events *EventBus, profile string, hooksConfig config.HooksConfig) *HookService {
+	return &HookService{db: dbService, events: events, profile: profile, config: hooksConfig}
+}
+
+// Dir returns the directory hooks are read from
+func (hs *HookService) Dir() string {
+	return hs.config.Dir
+}
+
+// Start runs on-* hooks in the background, in the order the changes were
+// made, until Close is called
+func (hs *HookService) Start() {
+	queue := make(chan hookRun, hookQueueSize)
+	hs.queue = queue
+	hs.done = make(chan struct{})
+	hs.stopEvents = hs.events.Subscribe(hs.enqueue)
+
+	go hs.run(queue)
+}
+
+// Close waits for queued on-* hooks to finish
+func (hs *HookService) Close() {
+	if hs.done == nil {
+		return
+	}
+
+	hs.stopEvents()
+
+	hs.mu.Lock()
+	close(hs.queue)
+	hs.queue = nil
+	hs.mu.Unlock()
+
+	<-hs.done
+	hs.done = nil
+}
+
+// Before runs the pre-* hooks of a change. A hook that exits non-zero or
+// times out rejects the change with ErrHookRejected. Hooks other than
+// pre-delete may print the task JSON back with changes, which are applied
+// to todo and passed on to the next hook.
+func (hs *HookService) Before(hook string, todo *entities.ToDo) error {
+	if hs == nil {
+		return nil
+	}
+
+	scripts := hs.scripts(hook)
+	if len(scripts) == 0 {
+		return nil
+	}
+
+	task := hs.task(todo)
+	for _, script := range scripts {
+		input, err := json.Marshal(task)
+		if err != nil {
+			return err
+		}
+
+		output, err := hs.exec(script, hook, input)
+		if err != nil {
+			return hs.fail(script, todo.ID, fmt.Errorf("%w: %s: %v", ErrHookRejected, filepath.Base(script), err))
+		}
+
+		output = bytes.TrimSpace(output)
+		if hook == HookPreDelete || len(output) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(output, &task); err != nil {
+			return hs.fail(script, todo.ID, fmt.Errorf("%w: %s printed invalid task JSON: %v", ErrHookRejected, filepath.Base(script), err))
+		}
+	}
+
+	task.applyTo(todo)
+	return nil
+}
+
+// enqueue queues the on-* hooks for a change made by this process. The
+// task is read now so the hooks see it as the change left it.
+func (hs *HookService) enqueue(e Event) {
+	var hook string
+	switch e.(type) {
+	case TodoCreated:
+		hook = HookOnAdd
+	case TodoUpdated, TodoReopened:
+		hook = HookOnModify
+	case TodoCompleted:
+		hook = HookOnComplete
+	case TodoDeleted:
+		hook = HookOnDelete
+	default:
+		return
+	}
+	if len(hs.scripts(hook)) == 0 {
+		return
+	}
+
+	id, _ := TodoID(e)
+	task := HookTask{ID: id}
+	var todos []entities.ToDo
+	hs.db.GetDB().Unscoped().Preload("Project").Preload("ToDoList").Limit(1).Find(&todos, id)
+	if len(todos) == 1 {
+		task = hs.task(&todos[0])
+	}
+
+	input, err := json.Marshal(task)
+	if err != nil {
+		return
+	}
+
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if hs.queue == nil {
+		return
+	}
+	select {
+	case hs.queue <- hookRun{hook: hook, id: id, input: input}:
+	default:
+		log.Printf("⚠️  Hook queue full, skipped %s for todo %d", hook, id)
+	}
+}
+
+func (hs *HookService) run(queue chan hookRun) {
+	defer close(hs.done)
+
+	for job := range queue {
+		for _, script := range hs.scripts(job.hook) {
+			if _, err := hs.exec(script, job.hook, job.input); err != nil {
+				hs.fail(script, job.id, fmt.Errorf("%s: %w", filepath.Base(script), err))
+			}
+		}
+	}
+}
+
+// fail logs and publishes a hook failure and returns err
+func (hs *HookService) fail(script string, id uint, err error) error {
+	log.Printf("⚠️  Hook %v", err)
+	hs.events.Publish(HookFailed{Hook: filepath.Base(script), ID: id, Err: err})
+	return err
+}
+
+// scripts lists the executables for a hook in name order. The directory is
+// read every time so hooks can be added without restarting.
+func (hs *HookService) scripts(hook string) []string {
+	entries, err := os.ReadDir(hs.config.Dir)
+	if err != nil {
+		return nil
+	}
+
+	var scripts []string
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), hook) || entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(hs.config.Dir, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+		scripts = append(scripts, path)
+	}
+
+	slices.Sort(scripts)
+	return scripts
+}
+
+// exec runs one script with the task JSON on stdin and returns what it
+// printed. The error carries the first line of stderr when there is one.
+func (hs *HookService) exec(script, hook string, input []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), hs.config.Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, script)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(),
+		"TUIDOO_HOOK="+hook,
+		"TUIDOO_PROFILE="+hs.profile,
+		"TUIDOO_DB="+hs.db.Path(),
+	)
+	// Do not wait for background processes the script left holding stdout
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("timed out after %s", hs.config.Timeout)
+	}
+	if err != nil {
+		if line := firstLine(stderr.Bytes()); line != "" {
+			return nil, errors.New(line)
+		}
+		return nil, err
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// task builds the hook JSON for a todo, looking up the project and list
+// names when they are not loaded
+func (hs *HookService) task(todo *entities.ToDo) HookTask {
+	task := HookTask{
+		ID:          todo.ID,
+		UID:         todo.UID,
+		Name:        todo.Name,
+		Description: todo.Description,
+		Details:     todo.Details,
+		ProjectID:   todo.ProjectID,
+		Project:     todo.Project.Name,
+		ListID:      todo.ToDoListID,
+		List:        todo.ToDoList.Name,
+		Priority:    todo.Priority,
+		Status:      todo.Status,
+		Color:       todo.Color,
+		Done:        todo.Done,
+		DueDate:     todo.DueDate,
+	}
+
+	if task.Project == "" && task.ProjectID != 0 {
+		task.Project = hs.name(&entities.Project{}, task.ProjectID)
+	}
+	if task.List == "" && task.ListID != 0 {
+		task.List = hs.name(&entities.ToDoList{}, task.ListID)
+	}
+
+	return task
+}
+
+func (hs *HookService) name(model any, id uint) string {
+	var names []string
+	hs.db.GetDB().Model(model).Where("id = ?", id).Limit(1).Pluck("name", &names)
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
+
+// applyTo copies the fields a hook may change onto a todo
+func (t HookTask) applyTo(todo *entities.ToDo) {
+	todo.Name = t.Name
+	todo.Description = t.Description
+	todo.Details = t.Details
+	todo.Priority = t.Priority
+	todo.Status = t.Status
+	todo.Color = t.Color
+	todo.Done = t.Done
+	todo.DueDate = t.DueDate
+
+	if t.ProjectID != todo.ProjectID {
+		todo.ProjectID = t.ProjectID
+		todo.Project = entities.Project{}
+	}
+	if t.ListID != todo.ToDoListID {
+		todo.ToDoListID = t.ListID
+		todo.ToDoList = entities.ToDoList{}
+	}
+}
+
+func firstLine(data []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			return line
+		}
+	}
+	return ""
+}
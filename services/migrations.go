@@ -0,0 +1,194 @@
+package services
+
+import (
+	"fmt"
+	"time"
+	"tuidoo/entities"
+
+	"gorm.io/gorm"
+)
+
+// Migration is a single, ordered schema change. Up and Down run inside a
+// transaction together with the schema_migrations bookkeeping.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// migrations lists every schema change in version order. Append new entries;
+// never edit or reorder ones that have shipped. Migrations use their own
+// snapshot structs so later entity changes do not alter past steps.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial schema",
+		Up: func(tx *gorm.DB) error {
+			// AutoMigrate keeps this step a no-op for databases created
+			// before versioned migrations existed
+			return tx.AutoMigrate(&v1Settings{}, &v1Project{}, &v1ToDoList{}, &v1ToDo{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v1ToDo{}, &v1ToDoList{}, &v1Project{}, &v1Settings{})
+		},
+	},
+	{
+		Version: 2,
+		Name:    "stable uids",
+		Up: func(tx *gorm.DB) error {
+			for _, table := range uidTables {
+				if err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN uid text", table)).Error; err != nil {
+					return err
+				}
+
+				var ids []uint
+				if err := tx.Table(table).Where("uid IS NULL").Pluck("id", &ids).Error; err != nil {
+					return err
+				}
+				for _, id := range ids {
+					if err := tx.Table(table).Where("id = ?", id).Update("uid", entities.NewUID()).Error; err != nil {
+						return err
+					}
+				}
+
+				if err := tx.Exec(fmt.Sprintf("CREATE UNIQUE INDEX idx_%s_uid ON %s(uid)", table, table)).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, table := range uidTables {
+				if err := tx.Exec(fmt.Sprintf("DROP INDEX IF EXISTS idx_%s_uid", table)).Error; err != nil {
+					return err
+				}
+				if err := tx.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN uid", table)).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 3,
+		Name:    "webhook deliveries",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&v3WebhookDelivery{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v3WebhookDelivery{})
+		},
+	},
+	{
+		Version: 4,
+		Name:    "automation rules",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&v4Rule{}, &v4RuleFiring{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v4RuleFiring{}, &v4Rule{})
+		},
+	},
+}
+
+// uidTables are the tables that carry a stable uid column
+var uidTables = []string{"projects", "to_do_lists", "to_dos"}
+
+// LatestSchemaVersion is the newest schema version this binary knows about
+func LatestSchemaVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// ============================================================================
+// Schema snapshots
+// ============================================================================
+
+type v1Settings struct {
+	gorm.Model
+	ActiveThemeID string `gorm:"column:active_theme_id"`
+}
+
+func (v1Settings) TableName() string { return "settings" }
+
+type v1Project struct {
+	gorm.Model
+	Name  string
+	Color string
+	ToDos []v1ToDo `gorm:"foreignKey:ProjectID"`
+}
+
+func (v1Project) TableName() string { return "projects" }
+
+type v1ToDoList struct {
+	gorm.Model
+	Name  string
+	Color string
+	ToDos []v1ToDo `gorm:"foreignKey:ToDoListID"`
+}
+
+func (v1ToDoList) TableName() string { return "to_do_lists" }
+
+type v1ToDo struct {
+	gorm.Model
+	ProjectID   uint
+	Project     v1Project
+	ToDoListID  uint
+	ToDoList    v1ToDoList
+	Name        string
+	Description *string
+	Details     *string
+	Priority    int
+	Status      int
+	Color       string
+	Done        bool
+	DueDate     *time.Time
+}
+
+func (v1ToDo) TableName() string { return "to_dos" }
+
+type v3WebhookDelivery struct {
+	gorm.Model
+	UID           string `gorm:"uniqueIndex"`
+	Webhook       string `gorm:"index"`
+	URL           string
+	Event         string
+	ToDoID        uint
+	Payload       string
+	State         string `gorm:"index"`
+	Attempts      int
+	MaxAttempts   int
+	StatusCode    int
+	LastError     string
+	NextAttemptAt *time.Time `gorm:"index"`
+	DeliveredAt   *time.Time
+}
+
+func (v3WebhookDelivery) TableName() string { return "webhook_deliveries" }
+
+type v4Rule struct {
+	gorm.Model
+	UID         string `gorm:"uniqueIndex"`
+	Name        string
+	Enabled     bool
+	Trigger     string
+	DueWithin   time.Duration
+	Conditions  string
+	Actions     string
+	LastFiredAt *time.Time
+}
+
+func (v4Rule) TableName() string { return "rules" }
+
+type v4RuleFiring struct {
+	ID        uint      `gorm:"primarykey"`
+	RuleID    uint      `gorm:"uniqueIndex:idx_rule_firings_once"`
+	ToDoID    uint      `gorm:"uniqueIndex:idx_rule_firings_once"`
+	DueDate   time.Time `gorm:"uniqueIndex:idx_rule_firings_once"`
+	CreatedAt time.Time
+}
+
+func (v4RuleFiring) TableName() string { return "rule_firings" }
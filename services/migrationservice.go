@@ -0,0 +1,270 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	"tuidoo/entities"
+
+	"gorm.io/gorm"
+)
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer binary
+var ErrSchemaTooNew = errors.New("database schema is newer than this version of tuidoo")
+
+// ErrBaseline is returned when reverting would undo the initial schema,
+// which drops every table and all data with it
+var ErrBaseline = errors.New("reverting the initial schema deletes all data")
+
+type MigrationService struct {
+	db *DbService
+}
+
+// MigrationStatus describes a known or recorded migration
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	// Unknown is set for versions recorded in the database but missing
+	// from this binary
+	Unknown bool
+}
+
+func NewMigrationService(dbService *DbService) *MigrationService {
+	return &MigrationService{db: dbService}
+}
+
+// CurrentVersion returns the highest applied schema version
+func (ms *MigrationService) CurrentVersion() (int, error) {
+	if err := ms.ensureTable(); err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := ms.db.NewContext()
+	defer cancel()
+
+	var version int
+	if err := ms.db.GetDB().WithContext(ctx).
+		Model(&entities.SchemaMigration{}).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&version).Error; err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	return version, nil
+}
+
+// CheckCompatible fails with ErrSchemaTooNew if the database is ahead of
+// this binary
+func (ms *MigrationService) CheckCompatible() error {
+	current, err := ms.CurrentVersion()
+	if err != nil {
+		return err
+	}
+
+	if current > LatestSchemaVersion() {
+		return fmt.Errorf("%w (database is at version %d, binary supports up to %d)",
+			ErrSchemaTooNew, current, LatestSchemaVersion())
+	}
+
+	return nil
+}
+
+// Pending returns the migrations that have not been applied yet
+func (ms *MigrationService) Pending() ([]Migration, error) {
+	applied, err := ms.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+
+	return pending, nil
+}
+
+// Status lists every known migration and any unknown recorded version
+func (ms *MigrationService) Status() ([]MigrationStatus, error) {
+	applied, err := ms.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	known := map[int]bool{}
+
+	for _, m := range migrations {
+		known[m.Version] = true
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if record, ok := applied[m.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	for version, record := range applied {
+		if known[version] {
+			continue
+		}
+		appliedAt := record.AppliedAt
+		statuses = append(statuses, MigrationStatus{
+			Version:   version,
+			Name:      record.Name,
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Unknown:   true,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, nil
+}
+
+// Up applies pending migrations up to and including target. A target of 0
+// applies everything.
+func (ms *MigrationService) Up(target int) ([]Migration, error) {
+	if err := ms.CheckCompatible(); err != nil {
+		return nil, err
+	}
+
+	pending, err := ms.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range pending {
+		if target > 0 && m.Version > target {
+			break
+		}
+
+		if err := ms.apply(m); err != nil {
+			return done, err
+		}
+		done = append(done, m)
+	}
+
+	return done, nil
+}
+
+// Down reverts the given number of most recently applied migrations. The
+// initial schema is only reverted with dropBaseline; otherwise reaching it
+// stops with ErrBaseline before anything is reverted.
+func (ms *MigrationService) Down(steps int, dropBaseline bool) ([]Migration, error) {
+	todo, err := ms.ToRevert(steps, dropBaseline)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range todo {
+		if err := ms.revert(m); err != nil {
+			return done, err
+		}
+		done = append(done, m)
+	}
+
+	return done, nil
+}
+
+// ToRevert lists the migrations Down would revert, newest first, without
+// reverting them. It fails with ErrBaseline where Down would.
+func (ms *MigrationService) ToRevert(steps int, dropBaseline bool) ([]Migration, error) {
+	if err := ms.CheckCompatible(); err != nil {
+		return nil, err
+	}
+
+	applied, err := ms.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var todo []Migration
+	for i := len(migrations) - 1; i >= 0 && len(todo) < steps; i-- {
+		if _, ok := applied[migrations[i].Version]; ok {
+			todo = append(todo, migrations[i])
+		}
+	}
+	if len(todo) > 0 && todo[len(todo)-1].Version == migrations[0].Version && !dropBaseline {
+		return nil, ErrBaseline
+	}
+
+	return todo, nil
+}
+
+func (ms *MigrationService) apply(m Migration) error {
+	ms.db.progress().Printf("⬆️  Applying migration %d: %s", m.Version, m.Name)
+
+	err := ms.db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := m.Up(tx); err != nil {
+			return err
+		}
+
+		return tx.Create(&entities.SchemaMigration{
+			Version:   m.Version,
+			Name:      m.Name,
+			AppliedAt: time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+	}
+
+	return nil
+}
+
+func (ms *MigrationService) revert(m Migration) error {
+	ms.db.progress().Printf("⬇️  Reverting migration %d: %s", m.Version, m.Name)
+
+	err := ms.db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := m.Down(tx); err != nil {
+			return err
+		}
+
+		return tx.Delete(&entities.SchemaMigration{}, m.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("reverting migration %d (%s) failed: %w", m.Version, m.Name, err)
+	}
+
+	return nil
+}
+
+func (ms *MigrationService) applied() (map[int]entities.SchemaMigration, error) {
+	if err := ms.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := ms.db.NewContext()
+	defer cancel()
+
+	var records []entities.SchemaMigration
+	if err := ms.db.GetDB().WithContext(ctx).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	applied := make(map[int]entities.SchemaMigration, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+
+	return applied, nil
+}
+
+func (ms *MigrationService) ensureTable() error {
+	if err := ms.db.GetDB().AutoMigrate(&entities.SchemaMigration{}); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
@@ -3,14 +3,17 @@ package services
 import (
 	"fmt"
 	"tuidoo/entities"
+
+	"gorm.io/gorm"
 )
 
 type ProjectService struct {
-	db *DbService
+	db     *DbService
+	events *EventBus
 }
 
-func NewProjectService(dbService *DbService) *ProjectService {
-	return &ProjectService{db: dbService}
+func NewProjectService(dbService *DbService, events *EventBus) *ProjectService {
+	return &ProjectService{db: dbService, events: events}
 }
 
 func (ps *ProjectService) Create(project *entities.Project) error {
@@ -25,6 +28,7 @@ func (ps *ProjectService) Create(project *entities.Project) error {
 		return fmt.Errorf("failed to create project: %w", err)
 	}
 
+	ps.events.Publish(ProjectCreated{ID: project.ID})
 	return nil
 }
 
@@ -46,6 +50,26 @@ func (ps *ProjectService) GetByID(id uint, includeToDos bool) (*entities.Project
 	return &project, nil
 }
 
+// GetByName retrieves the first project with the given name, ignoring case
+func (ps *ProjectService) GetByName(name string) (*entities.Project, error) {
+	ctx, cancel := ps.db.NewContext()
+	defer cancel()
+
+	var projects []entities.Project
+	if err := ps.db.GetDB().WithContext(ctx).
+		Where("LOWER(name) = LOWER(?)", name).
+		Order("id").
+		Limit(1).
+		Find(&projects).Error; err != nil {
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+	if len(projects) == 0 {
+		return nil, fmt.Errorf("project %q %w", name, ErrNotFound)
+	}
+
+	return &projects[0], nil
+}
+
 func (ps *ProjectService) GetAll(includeToDos bool) ([]entities.Project, error) {
 	ctx, cancel := ps.db.NewContext()
 	defer cancel()
@@ -76,6 +100,51 @@ func (ps *ProjectService) Update(project *entities.Project) error {
 		return fmt.Errorf("failed to update project: %w", err)
 	}
 
+	ps.events.Publish(ProjectUpdated{ID: project.ID})
+	return nil
+}
+
+// UpdateChecked saves a project only if it is still at the given version
+// (see Version). An empty version skips the check.
+func (ps *ProjectService) UpdateChecked(project *entities.Project, version string) error {
+	ctx, cancel := ps.db.NewContext()
+	defer cancel()
+
+	if project.Name == "" {
+		return fmt.Errorf("project name cannot be empty")
+	}
+
+	err := ps.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkVersion(tx, &entities.Project{}, project.ID, version); err != nil {
+			return err
+		}
+		return tx.Omit("ToDos").Save(project).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update project %d: %w", project.ID, err)
+	}
+
+	ps.events.Publish(ProjectUpdated{ID: project.ID})
+	return nil
+}
+
+// DeleteChecked deletes a project only if it is still at the given version.
+// An empty version skips the check.
+func (ps *ProjectService) DeleteChecked(id uint, version string) error {
+	ctx, cancel := ps.db.NewContext()
+	defer cancel()
+
+	err := ps.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkVersion(tx, &entities.Project{}, id, version); err != nil {
+			return err
+		}
+		return tx.Delete(&entities.Project{}, id).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete project %d: %w", id, err)
+	}
+
+	ps.events.Publish(ProjectDeleted{ID: id})
 	return nil
 }
 
@@ -90,9 +159,10 @@ func (ps *ProjectService) Delete(id uint) error {
 	}
 
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("project with ID %d not found", id)
+		return fmt.Errorf("project with ID %d %w", id, ErrNotFound)
 	}
 
+	ps.events.Publish(ProjectDeleted{ID: id})
 	return nil
 }
 
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"tuidoo/entities"
+	"tuidoo/enums"
+	"tuidoo/formats"
+
+	"gorm.io/gorm"
+)
+
+// RecordImportOptions controls how records from external formats are
+// imported
+type RecordImportOptions struct {
+	DryRun bool
+	// DefaultProject and DefaultList are used for records that do not name
+	// one
+	DefaultProject string
+	DefaultList    string
+}
+
+// ImportRecords imports todos read from an external format. Projects and
+// lists are resolved by name (case-insensitively) and created when missing.
+// Records with a UID update the matching todo instead of creating a new one,
+// or delete it when the record is marked deleted. Records without one match
+// a todo with the same project, list and name. A record that fails is
+// reported and skipped; the rest are still imported.
+func (ts *TransferService) ImportRecords(records []formats.Record, opts RecordImportOptions) (*ImportReport, error) {
+	ctx, cancel := ts.db.NewContext()
+	defer cancel()
+
+	report := &ImportReport{DryRun: opts.DryRun, Changes: []ImportChange{}}
+
+	err := ts.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		resolver := newNameResolver(tx, report)
+
+		for _, record := range records {
+			if record.Err != nil {
+				report.fail(record, record.Err)
+				continue
+			}
+
+			// A failed statement leaves the SQLite transaction usable, so a
+			// bad record only skips itself
+			if err := importRecord(tx, resolver, record, opts, report); err != nil {
+				report.fail(record, err)
+			}
+		}
+
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, fmt.Errorf("import failed: %w", err)
+	}
+
+	if !opts.DryRun {
+		ts.events.Publish(DataImported{})
+	}
+
+	return report, nil
+}
+
+func importRecord(tx *gorm.DB, resolver *nameResolver, record formats.Record, opts RecordImportOptions, report *ImportReport) error {
+	if strings.TrimSpace(record.Name) == "" {
+		return errors.New("todo name cannot be empty")
+	}
+
+	normalizeDone(&record)
+
+	projectName := record.Project
+	if projectName == "" {
+		projectName = opts.DefaultProject
+	}
+	listName := record.List
+	if listName == "" {
+		listName = opts.DefaultList
+	}
+
+	var existing entities.ToDo
+	found := false
+	var err error
+	if record.UID != "" {
+		found, err = findByUID(tx.Unscoped(), &existing, record.UID)
+	} else if projectName != "" {
+		found, err = resolver.match(&existing, projectName, listName, record.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if found {
+		resolver.matched[existing.ID] = true
+	}
+
+	if record.Deleted {
+		if !found || existing.DeletedAt.Valid {
+			report.Unchanged++
+			return nil
+		}
+		if err := tx.Delete(&existing).Error; err != nil {
+			return fmt.Errorf("failed to delete todo: %w", err)
+		}
+		report.record("todo", "delete", existing.UID, existing.Name)
+		return nil
+	}
+
+	if !found {
+		if projectName == "" {
+			return errors.New("no project given (use --project to pick one)")
+		}
+
+		projectID, err := resolver.project(projectName)
+		if err != nil {
+			return err
+		}
+		listID, err := resolver.list(listName)
+		if err != nil {
+			return err
+		}
+
+		todo := entities.ToDo{
+			UID:         record.UID,
+			ProjectID:   projectID,
+			ToDoListID:  listID,
+			Name:        record.Name,
+			Description: record.Description,
+			Details:     record.Details,
+			Priority:    record.Priority,
+			Status:      record.Status,
+			Done:        record.Done,
+			DueDate:     record.DueDate,
+		}
+		if err := tx.Omit("Project", "ToDoList").Create(&todo).Error; err != nil {
+			return fmt.Errorf("failed to create todo: %w", err)
+		}
+		resolver.matched[todo.ID] = true
+
+		report.record("todo", "create", todo.UID, todo.Name)
+		return nil
+	}
+
+	changes := map[string]any{}
+
+	if record.Has(formats.FieldProject) && projectName != "" {
+		projectID, err := resolver.project(projectName)
+		if err != nil {
+			return err
+		}
+		if existing.ProjectID != projectID {
+			changes["project_id"] = projectID
+		}
+	}
+	if record.Has(formats.FieldList) {
+		listID, err := resolver.list(listName)
+		if err != nil {
+			return err
+		}
+		if existing.ToDoListID != listID {
+			changes["to_do_list_id"] = listID
+		}
+	}
+	if record.Has(formats.FieldName) && existing.Name != record.Name {
+		changes["name"] = record.Name
+	}
+	if record.Has(formats.FieldDescription) && !sameString(existing.Description, record.Description) {
+		changes["description"] = record.Description
+	}
+	if record.Has(formats.FieldDetails) && !sameString(existing.Details, record.Details) {
+		changes["details"] = record.Details
+	}
+	if record.Has(formats.FieldPriority) && existing.Priority != record.Priority {
+		changes["priority"] = record.Priority
+	}
+	if record.Has(formats.FieldStatus) && existing.Status != record.Status {
+		changes["status"] = record.Status
+	}
+	if record.Has(formats.FieldDone) && existing.Done != record.Done {
+		changes["done"] = record.Done
+	}
+	if record.Has(formats.FieldDue) && !sameTime(existing.DueDate, record.DueDate) {
+		changes["due_date"] = record.DueDate
+	}
+	// The source still has the todo, so a local soft delete is undone
+	if existing.DeletedAt.Valid {
+		changes["deleted_at"] = nil
+	}
+
+	if len(changes) > 0 {
+		changes["updated_at"] = time.Now()
+		if err := tx.Unscoped().Model(&existing).UpdateColumns(changes).Error; err != nil {
+			return fmt.Errorf("failed to update todo: %w", err)
+		}
+		delete(changes, "updated_at")
+	}
+
+	recordChanges(report, "todo", existing.UID, record.Name, changes)
+	return nil
+}
+
+// normalizeDone keeps Done and Status consistent when a source only
+// provides one of them, the same way MarkAsComplete does
+func normalizeDone(record *formats.Record) {
+	hasDone := record.Has(formats.FieldDone)
+	hasStatus := record.Has(formats.FieldStatus)
+
+	switch {
+	case hasDone && !hasStatus && record.Done:
+		record.Status = enums.Done
+		record.Fields = appendField(record.Fields, formats.FieldStatus)
+	case hasStatus && !hasDone && record.Status == enums.Done:
+		record.Done = true
+		record.Fields = appendField(record.Fields, formats.FieldDone)
+	}
+}
+
+func appendField(fields []string, field string) []string {
+	if fields == nil {
+		return nil
+	}
+	return append(append([]string{}, fields...), field)
+}
+
+func (r *ImportReport) fail(record formats.Record, err error) {
+	r.Errors = append(r.Errors, ImportError{Line: record.Line, Name: record.Name, Error: err.Error()})
+}
+
+// nameResolver looks up projects and lists by name, creating missing ones
+type nameResolver struct {
+	tx       *gorm.DB
+	report   *ImportReport
+	projects map[string]uint
+	lists    map[string]uint
+	// matched are the todos this import already created or updated
+	matched map[uint]bool
+}
+
+func newNameResolver(tx *gorm.DB, report *ImportReport) *nameResolver {
+	return &nameResolver{
+		tx:       tx,
+		report:   report,
+		projects: map[string]uint{},
+		lists:    map[string]uint{},
+		matched:  map[uint]bool{},
+	}
+}
+
+// match finds a todo by project, list (case-insensitively) and name, for
+// records without a UID. Todos already matched by this import are skipped,
+// so two same-named records stay two todos.
+func (r *nameResolver) match(todo *entities.ToDo, project, list, name string) (bool, error) {
+	q := r.tx.Model(&entities.ToDo{}).
+		Joins("JOIN projects ON projects.id = to_dos.project_id AND projects.deleted_at IS NULL").
+		Where("LOWER(projects.name) = ? AND to_dos.name = ?", strings.ToLower(strings.TrimSpace(project)), name)
+
+	if key := strings.ToLower(strings.TrimSpace(list)); key == "" {
+		q = q.Where("to_dos.to_do_list_id = 0")
+	} else {
+		q = q.Joins("JOIN to_do_lists ON to_do_lists.id = to_dos.to_do_list_id AND to_do_lists.deleted_at IS NULL").
+			Where("LOWER(to_do_lists.name) = ?", key)
+	}
+
+	if len(r.matched) > 0 {
+		ids := make([]uint, 0, len(r.matched))
+		for id := range r.matched {
+			ids = append(ids, id)
+		}
+		q = q.Where("to_dos.id NOT IN ?", ids)
+	}
+
+	result := q.Order("to_dos.id").Limit(1).Find(todo)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *nameResolver) project(name string) (uint, error) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if id, ok := r.projects[key]; ok {
+		return id, nil
+	}
+
+	var project entities.Project
+	result := r.tx.Where("LOWER(name) = ?", key).Order("id").Limit(1).Find(&project)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		project = entities.Project{Name: strings.TrimSpace(name)}
+		if err := r.tx.Create(&project).Error; err != nil {
+			return 0, fmt.Errorf("failed to create project %s: %w", name, err)
+		}
+		r.report.record("project", "create", project.UID, project.Name)
+	}
+
+	r.projects[key] = project.ID
+	return project.ID, nil
+}
+
+func (r *nameResolver) list(name string) (uint, error) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if key == "" {
+		return 0, nil
+	}
+	if id, ok := r.lists[key]; ok {
+		return id, nil
+	}
+
+	var list entities.ToDoList
+	result := r.tx.Where("LOWER(name) = ?", key).Order("id").Limit(1).Find(&list)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		list = entities.ToDoList{Name: strings.TrimSpace(name)}
+		if err := r.tx.Create(&list).Error; err != nil {
+			return 0, fmt.Errorf("failed to create list %s: %w", name, err)
+		}
+		r.report.record("list", "create", list.UID, list.Name)
+	}
+
+	r.lists[key] = list.ID
+	return list.ID, nil
+}
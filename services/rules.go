@@ -0,0 +1,386 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"tuidoo/enums"
+	"tuidoo/formats"
+)
+
+// Rule triggers
+const (
+	TriggerCreated        = "created"
+	TriggerStatusChanged  = "status_changed"
+	TriggerCompleted      = "completed"
+	TriggerDueApproaching = "due_approaching"
+)
+
+// RuleTriggers lists the triggers a rule can have
+var RuleTriggers = []string{TriggerCreated, TriggerStatusChanged, TriggerCompleted, TriggerDueApproaching}
+
+// Rule actions
+const (
+	ActionSet    = "set"
+	ActionMove   = "move"
+	ActionCreate = "create"
+	ActionNote   = "note"
+)
+
+// Condition compares one task field with a value, as in `priority >= High`
+type Condition struct {
+	Field string
+	Op    string
+	Value string
+	// None is set when Value is the bare word none, which matches an empty
+	// field
+	None bool
+}
+
+// Action is one step of a rule, as in `move list=Urgent`. Text is the name
+// template of create and the text of note.
+type Action struct {
+	Kind string
+	Text string
+	Args []ActionArg
+}
+
+// ActionArg is a field=value argument of an action
+type ActionArg struct {
+	Field string
+	Value string
+}
+
+// conditionOps are the operators each condition field accepts
+var conditionOps = map[string][]string{
+	"name":            {"=", "!=", "~"},
+	"description":     {"=", "!=", "~"},
+	"details":         {"=", "!=", "~"},
+	"color":           {"=", "!=", "~"},
+	"project":         {"=", "!=", "~"},
+	"list":            {"=", "!=", "~"},
+	"priority":        {"=", "!=", "<", "<=", ">", ">="},
+	"status":          {"=", "!=", "<", "<=", ">", ">="},
+	"previous_status": {"=", "!=", "<", "<=", ">", ">="},
+	"done":            {"=", "!="},
+	"due":             {"=", "!=", "<", "<=", ">", ">="},
+}
+
+// ConditionFields lists the fields conditions can test, in display order
+var ConditionFields = []string{"name", "description", "details", "project", "list", "priority", "status", "previous_status", "done", "due", "color"}
+
+// actionFields are the field=value arguments each action accepts
+var actionFields = map[string][]string{
+	ActionSet:    {"name", "description", "details", "priority", "status", "color", "done", "due"},
+	ActionMove:   {"project", "list"},
+	ActionCreate: {"project", "list", "priority", "status", "due", "description"},
+	ActionNote:   nil,
+}
+
+// ActionKinds lists the actions in display order
+var ActionKinds = []string{ActionSet, ActionMove, ActionCreate, ActionNote}
+
+// ParseConditions parses conditions joined by `and`, such as
+// `project = Work and priority >= High`. Values with spaces are quoted. An
+// empty string matches every task.
+func ParseConditions(s string) ([]Condition, error) {
+	tokens, err := lexRule(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var conditions []Condition
+	for len(tokens) > 0 {
+		if len(conditions) > 0 {
+			if !tokens[0].is("and") {
+				return nil, fmt.Errorf("expected \"and\" before %q", tokens[0].text)
+			}
+			tokens = tokens[1:]
+		}
+		if len(tokens) < 3 {
+			return nil, fmt.Errorf("condition %d: expected FIELD OP VALUE", len(conditions)+1)
+		}
+
+		field, op, value := tokens[0], tokens[1], tokens[2]
+		tokens = tokens[3:]
+
+		condition := Condition{
+			Field: strings.ToLower(field.text),
+			Op:    op.text,
+			Value: value.text,
+			None:  value.is("none"),
+		}
+		if err := condition.validate(field, op, value); err != nil {
+			return nil, fmt.Errorf("condition %d: %w", len(conditions)+1, err)
+		}
+		conditions = append(conditions, condition)
+	}
+
+	return conditions, nil
+}
+
+func (c Condition) validate(field, op, value ruleToken) error {
+	ops, ok := conditionOps[c.Field]
+	if field.kind != tokenWord || !ok {
+		return fmt.Errorf("unknown field %q (expected one of %s)", field.text, strings.Join(ConditionFields, ", "))
+	}
+	if op.kind != tokenOp || !slices.Contains(ops, c.Op) {
+		return fmt.Errorf("%s does not support %q (expected one of %s)", c.Field, op.text, strings.Join(ops, " "))
+	}
+	if value.kind == tokenOp || value.kind == tokenSeparator {
+		return fmt.Errorf("expected a value after %s %s", c.Field, c.Op)
+	}
+	if c.None {
+		return nil
+	}
+
+	switch c.Field {
+	case "priority":
+		_, err := enums.ParsePriority(c.Value)
+		return err
+	case "status", "previous_status":
+		_, err := enums.ParseStatus(c.Value)
+		return err
+	case "done":
+		_, err := formats.ParseBool(c.Value)
+		return err
+	case "due":
+		_, err := ParseDue(c.Value, time.Now())
+		return err
+	}
+	return nil
+}
+
+// String renders the condition back in the rule language
+func (c Condition) String() string {
+	value := quoteRuleValue(c.Value)
+	if c.None {
+		value = "none"
+	}
+	return c.Field + " " + c.Op + " " + value
+}
+
+// ParseActions parses actions separated by semicolons, such as
+// `move list=Urgent; create "Follow up: {name}" due=+3d; note "Escalated"`
+func ParseActions(s string) ([]Action, error) {
+	tokens, err := lexRule(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var actions []Action
+	for _, group := range splitTokens(tokens) {
+		if len(group) == 0 {
+			continue
+		}
+
+		action, err := parseAction(group)
+		if err != nil {
+			return nil, fmt.Errorf("action %d: %w", len(actions)+1, err)
+		}
+		actions = append(actions, action)
+	}
+
+	if len(actions) == 0 {
+		return nil, fmt.Errorf("a rule needs at least one action (%s)", strings.Join(ActionKinds, ", "))
+	}
+	return actions, nil
+}
+
+func parseAction(tokens []ruleToken) (Action, error) {
+	kind := strings.ToLower(tokens[0].text)
+	fields, ok := actionFields[kind]
+	if tokens[0].kind != tokenWord || !ok {
+		return Action{}, fmt.Errorf("unknown action %q (expected one of %s)", tokens[0].text, strings.Join(ActionKinds, ", "))
+	}
+
+	action := Action{Kind: kind}
+	tokens = tokens[1:]
+
+	switch kind {
+	case ActionCreate, ActionNote:
+		if len(tokens) == 0 || tokens[0].kind == tokenOp || (len(tokens) > 1 && tokens[1].is("=")) {
+			return Action{}, fmt.Errorf("%s needs a quoted text first", kind)
+		}
+		action.Text = tokens[0].text
+		tokens = tokens[1:]
+	}
+
+	for len(tokens) > 0 {
+		if len(tokens) < 3 || !tokens[1].is("=") || tokens[2].kind == tokenOp {
+			return Action{}, fmt.Errorf("expected FIELD=VALUE at %q", tokens[0].text)
+		}
+
+		arg := ActionArg{Field: strings.ToLower(tokens[0].text), Value: tokens[2].text}
+		tokens = tokens[3:]
+
+		if !slices.Contains(fields, arg.Field) {
+			if len(fields) == 0 {
+				return Action{}, fmt.Errorf("%s takes no fields", kind)
+			}
+			return Action{}, fmt.Errorf("%s cannot set %q (expected one of %s)", kind, arg.Field, strings.Join(fields, ", "))
+		}
+		if err := validateActionArg(arg); err != nil {
+			return Action{}, fmt.Errorf("%s: %w", arg.Field, err)
+		}
+		action.Args = append(action.Args, arg)
+	}
+
+	switch {
+	case kind == ActionSet && len(action.Args) == 0:
+		return Action{}, fmt.Errorf("set needs at least one FIELD=VALUE")
+	case kind == ActionMove && len(action.Args) == 0:
+		return Action{}, fmt.Errorf("move needs project=NAME and/or list=NAME")
+	}
+	return action, nil
+}
+
+func validateActionArg(arg ActionArg) error {
+	if strings.EqualFold(arg.Value, "none") {
+		switch arg.Field {
+		case "list", "description", "details", "due", "color":
+			return nil
+		}
+	}
+
+	switch arg.Field {
+	case "name", "project":
+		if strings.TrimSpace(arg.Value) == "" {
+			return fmt.Errorf("cannot be empty")
+		}
+	case "priority":
+		_, err := enums.ParsePriority(arg.Value)
+		return err
+	case "status":
+		_, err := enums.ParseStatus(arg.Value)
+		return err
+	case "done":
+		_, err := formats.ParseBool(arg.Value)
+		return err
+	case "due":
+		_, err := ParseDue(arg.Value, time.Now())
+		return err
+	}
+	return nil
+}
+
+// String renders the action back in the rule language
+func (a Action) String() string {
+	parts := []string{a.Kind}
+	if a.Text != "" {
+		parts = append(parts, strconv.Quote(a.Text))
+	}
+	for _, arg := range a.Args {
+		parts = append(parts, arg.Field+"="+quoteRuleValue(arg.Value))
+	}
+	return strings.Join(parts, " ")
+}
+
+// quoteRuleValue quotes a value when it would not lex as a single word
+func quoteRuleValue(s string) string {
+	tokens, err := lexRule(s)
+	if err == nil && len(tokens) == 1 && tokens[0].kind == tokenWord && tokens[0].text == s {
+		return s
+	}
+	return strconv.Quote(s)
+}
+
+// ============================================================================
+// Lexer
+// ============================================================================
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenQuoted
+	tokenOp
+	tokenSeparator
+)
+
+type ruleToken struct {
+	kind tokenKind
+	text string
+}
+
+// is reports whether the token is the given keyword or operator
+func (t ruleToken) is(text string) bool {
+	return t.kind != tokenQuoted && strings.EqualFold(t.text, text)
+}
+
+const ruleOpChars = "=!<>~"
+
+// lexRule splits rule text into words, quoted strings, operators and
+// semicolons
+func lexRule(s string) ([]ruleToken, error) {
+	var tokens []ruleToken
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == ';':
+			tokens = append(tokens, ruleToken{kind: tokenSeparator, text: ";"})
+			i++
+
+		case c == '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated quote at %q", s[i:])
+			}
+			text, err := strconv.Unquote(s[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted text %s", s[i:end+1])
+			}
+			tokens = append(tokens, ruleToken{kind: tokenQuoted, text: text})
+			i = end + 1
+
+		case strings.IndexByte(ruleOpChars, c) >= 0:
+			end := i
+			for end < len(s) && strings.IndexByte(ruleOpChars, s[end]) >= 0 {
+				end++
+			}
+			op := s[i:end]
+			switch op {
+			case "=", "!=", "~", "<", "<=", ">", ">=":
+			default:
+				return nil, fmt.Errorf("unknown operator %q", op)
+			}
+			tokens = append(tokens, ruleToken{kind: tokenOp, text: op})
+			i = end
+
+		default:
+			end := i
+			for end < len(s) && !strings.ContainsRune(" \t\r\n;\""+ruleOpChars, rune(s[end])) {
+				end++
+			}
+			tokens = append(tokens, ruleToken{kind: tokenWord, text: s[i:end]})
+			i = end
+		}
+	}
+
+	return tokens, nil
+}
+
+// splitTokens splits tokens at semicolons
+func splitTokens(tokens []ruleToken) [][]ruleToken {
+	var groups [][]ruleToken
+	start := 0
+	for i, token := range tokens {
+		if token.kind == tokenSeparator {
+			groups = append(groups, tokens[start:i])
+			start = i + 1
+		}
+	}
+	return append(groups, tokens[start:])
+}
@@ -0,0 +1,781 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"tuidoo/entities"
+	"tuidoo/enums"
+	"tuidoo/formats"
+
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// DefaultDueWithin is how close the due date of a task must be before a
+	// due_approaching rule runs, unless the rule says otherwise
+	DefaultDueWithin = 24 * time.Hour
+	// maxRuleDepth is how many tasks deep a chain of tasks created by rules
+	// may go before rules stop running for the newest one
+	maxRuleDepth = 3
+	// ruleCheckInterval is how often due dates are checked for
+	// due_approaching rules
+	ruleCheckInterval = time.Minute
+)
+
+// RuleService stores automation rules and runs them for ToDoService. Rules
+// run inside the change that triggered them, so their edits are saved
+// together with it; tasks they create are added right after.
+//
+// Loops are cut in two ways: within one change every rule fires at most
+// once, however often other rules change the task back and forth, and tasks
+// created by rules stop running rules maxRuleDepth levels down.
+type RuleService struct {
+	db     *DbService
+	events *EventBus
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewRuleService(dbService *DbService, events *EventBus) *RuleService {
+	return &RuleService{db: dbService, events: events}
+}
+
+// Create validates and stores a new rule
+func (rs *RuleService) Create(rule *entities.Rule) error {
+	if err := rs.Validate(rule); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	ctx, cancel := rs.db.NewContext()
+	defer cancel()
+
+	if err := rs.db.GetDB().WithContext(ctx).Create(rule).Error; err != nil {
+		return fmt.Errorf("failed to create rule: %w", err)
+	}
+	return nil
+}
+
+// Update validates and saves a rule
+func (rs *RuleService) Update(rule *entities.Rule) error {
+	if err := rs.Validate(rule); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	ctx, cancel := rs.db.NewContext()
+	defer cancel()
+
+	if err := rs.db.GetDB().WithContext(ctx).Save(rule).Error; err != nil {
+		return fmt.Errorf("failed to update rule: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a rule
+func (rs *RuleService) Delete(id uint) error {
+	ctx, cancel := rs.db.NewContext()
+	defer cancel()
+
+	result := rs.db.GetDB().WithContext(ctx).Delete(&entities.Rule{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete rule: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("rule with ID %d %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// GetAll returns every rule in the order they run
+func (rs *RuleService) GetAll() ([]entities.Rule, error) {
+	ctx, cancel := rs.db.NewContext()
+	defer cancel()
+
+	var rules []entities.Rule
+	if err := rs.db.GetDB().WithContext(ctx).Order("id").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to get rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Validate checks a rule's trigger, conditions and actions. A
+// due_approaching rule without DueWithin gets DefaultDueWithin.
+func (rs *RuleService) Validate(rule *entities.Rule) error {
+	if strings.TrimSpace(rule.Name) == "" {
+		return fmt.Errorf("rule name cannot be empty")
+	}
+	if !slices.Contains(RuleTriggers, rule.Trigger) {
+		return fmt.Errorf("unknown trigger %q (expected one of %s)", rule.Trigger, strings.Join(RuleTriggers, ", "))
+	}
+	if rule.Trigger == TriggerDueApproaching && rule.DueWithin <= 0 {
+		rule.DueWithin = DefaultDueWithin
+	}
+	if _, err := ParseConditions(rule.Conditions); err != nil {
+		return fmt.Errorf("conditions: %w", err)
+	}
+	if _, err := ParseActions(rule.Actions); err != nil {
+		return fmt.Errorf("actions: %w", err)
+	}
+	return nil
+}
+
+// Start runs due_approaching rules in the background until Close is
+// called. Starting a running service does nothing.
+func (rs *RuleService) Start(todos *ToDoService) {
+	if rs.stop != nil {
+		return
+	}
+
+	rs.stop = make(chan struct{})
+	rs.done = make(chan struct{})
+	go rs.run(todos)
+}
+
+// Close stops checking due dates
+func (rs *RuleService) Close() {
+	if rs.stop == nil {
+		return
+	}
+
+	close(rs.stop)
+	<-rs.done
+	rs.stop = nil
+}
+
+func (rs *RuleService) run(todos *ToDoService) {
+	defer close(rs.done)
+
+	ticker := time.NewTicker(ruleCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		rs.checkDue(todos)
+
+		select {
+		case <-rs.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkDue runs due_approaching rules for open tasks that are due within
+// the rule's window, once per task and due date. The firing is recorded
+// first, so another process checking at the same time skips it.
+func (rs *RuleService) checkDue(todos *ToDoService) {
+	var rules []entities.Rule
+	err := rs.db.GetDB().
+		Where(map[string]any{"enabled": true, "trigger": TriggerDueApproaching}).
+		Order("id").
+		Find(&rules).Error
+	if err != nil {
+		log.Printf("⚠️  rules: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range rules {
+		rule := &rules[i]
+
+		var due []entities.ToDo
+		err := rs.db.GetDB().
+			Select("id", "due_date").
+			Where("done = ? AND due_date > ? AND due_date <= ?", false, now, now.Add(rule.DueWithin)).
+			Order("id").
+			Find(&due).Error
+		if err != nil {
+			log.Printf("⚠️  rules: %v", err)
+			return
+		}
+
+		for _, todo := range due {
+			firing := entities.RuleFiring{RuleID: rule.ID, ToDoID: todo.ID, DueDate: *todo.DueDate}
+			result := rs.db.GetDB().Clauses(clause.OnConflict{DoNothing: true}).Create(&firing)
+			if result.Error != nil || result.RowsAffected == 0 {
+				continue
+			}
+
+			if err := todos.applyDueRule(rule, todo.ID); err != nil {
+				log.Printf("⚠️  Rule %q on todo %d: %v", rule.Name, todo.ID, err)
+			}
+		}
+	}
+}
+
+// ruleChange describes the change rules are evaluated for
+type ruleChange struct {
+	// created is set for a new task; before is the task as it was otherwise
+	created bool
+	before  *entities.ToDo
+	// due is the due_approaching rule being run by checkDue
+	due *entities.Rule
+	// depth counts the rule-created tasks leading up to this change
+	depth int
+}
+
+// triggers returns the triggers the change fires
+func (c ruleChange) triggers(todo *entities.ToDo) []string {
+	var triggers []string
+	if c.created {
+		triggers = append(triggers, TriggerCreated)
+	}
+	if c.before != nil {
+		if c.before.Status != todo.Status {
+			triggers = append(triggers, TriggerStatusChanged)
+		}
+		if todo.Done && !c.before.Done {
+			triggers = append(triggers, TriggerCompleted)
+		}
+	}
+	return triggers
+}
+
+// ruleOutcome is what the rules did during one change
+type ruleOutcome struct {
+	fired []entities.Rule
+	// changed is set when an action edited the task
+	changed   bool
+	followUps []ruleFollowUp
+	// errs holds the failed actions, by rule ID
+	errs map[uint]error
+}
+
+type ruleFollowUp struct {
+	rule string
+	todo entities.ToDo
+}
+
+// evaluate runs the enabled rules whose trigger the change fires and whose
+// conditions todo meets, applying their edits to todo. Edits may fire more
+// triggers, so rules are run again until no new one fires.
+func (rs *RuleService) evaluate(change ruleChange, todo *entities.ToDo) *ruleOutcome {
+	outcome := &ruleOutcome{errs: map[uint]error{}}
+	if rs == nil {
+		return outcome
+	}
+
+	var rules []entities.Rule
+	if err := rs.db.GetDB().Where("enabled = ?", true).Order("id").Find(&rules).Error; err != nil {
+		log.Printf("⚠️  rules: %v", err)
+		return outcome
+	}
+	if len(rules) == 0 {
+		return outcome
+	}
+	if change.depth >= maxRuleDepth {
+		log.Printf("⚠️  Rules not run for %q: it was created by a chain of %d rules", todo.Name, change.depth)
+		return outcome
+	}
+
+	names := newRuleNames(rs)
+	now := time.Now()
+	fired := map[uint]bool{}
+
+	for {
+		triggers := change.triggers(todo)
+		progress := false
+
+		for i := range rules {
+			rule := &rules[i]
+			if fired[rule.ID] {
+				continue
+			}
+			isDue := change.due != nil && change.due.ID == rule.ID
+			if !isDue && !slices.Contains(triggers, rule.Trigger) {
+				continue
+			}
+
+			conditions, err := ParseConditions(rule.Conditions)
+			if err != nil {
+				continue
+			}
+			if !matchAll(conditions, change.before, todo, names, now) {
+				continue
+			}
+			actions, err := ParseActions(rule.Actions)
+			if err != nil {
+				continue
+			}
+
+			fired[rule.ID] = true
+			progress = true
+			outcome.fired = append(outcome.fired, *rule)
+
+			for _, action := range actions {
+				result, err := applyAction(action, todo, names, now)
+				if err != nil {
+					outcome.errs[rule.ID] = errors.Join(outcome.errs[rule.ID], fmt.Errorf("%s: %w", action.Kind, err))
+					continue
+				}
+				if result.followUp != nil {
+					outcome.followUps = append(outcome.followUps, ruleFollowUp{rule: rule.Name, todo: *result.followUp})
+				}
+				if action.Kind != ActionCreate && len(result.changes) > 0 {
+					outcome.changed = true
+				}
+			}
+		}
+
+		if !progress {
+			return outcome
+		}
+	}
+}
+
+// record notes when the rules of a saved change fired and tells
+// subscribers about them
+func (rs *RuleService) record(outcome *ruleOutcome, todoID uint) {
+	ids := make([]uint, 0, len(outcome.fired))
+	for _, rule := range outcome.fired {
+		ids = append(ids, rule.ID)
+	}
+
+	err := rs.db.GetDB().Model(&entities.Rule{}).
+		Where("id IN ?", ids).
+		UpdateColumn("last_fired_at", time.Now()).Error
+	if err != nil {
+		log.Printf("⚠️  rules: failed to record firing: %v", err)
+	}
+
+	for _, rule := range outcome.fired {
+		err := outcome.errs[rule.ID]
+		if err != nil {
+			log.Printf("⚠️  Rule %q on todo %d: %v", rule.Name, todoID, err)
+		}
+		rs.events.Publish(RuleFired{Rule: rule.Name, ID: todoID, Err: err})
+	}
+}
+
+// RuleReport is what a rule would do to a task, see DryRun
+type RuleReport struct {
+	Task       string
+	Conditions []ConditionResult
+	Matched    bool
+	// Changes describes the edits and the tasks that would be created
+	Changes []string
+	Errors  []string
+}
+
+// ConditionResult is one condition of a dry run with the task's value
+type ConditionResult struct {
+	Condition string
+	Actual    string
+	Matched   bool
+}
+
+// DryRun shows what a rule would do to a task if its trigger fired now,
+// without saving anything. The rule does not have to be stored.
+func (rs *RuleService) DryRun(rule *entities.Rule, todoID uint) (*RuleReport, error) {
+	if err := rs.Validate(rule); err != nil {
+		return nil, err
+	}
+	conditions, _ := ParseConditions(rule.Conditions)
+	actions, _ := ParseActions(rule.Actions)
+
+	var todos []entities.ToDo
+	if err := rs.db.GetDB().Limit(1).Find(&todos, todoID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get todo: %w", err)
+	}
+	if len(todos) == 0 {
+		return nil, fmt.Errorf("todo with ID %d %w", todoID, ErrNotFound)
+	}
+	todo := &todos[0]
+	before := *todo
+
+	names := newRuleNames(rs)
+	now := time.Now()
+	report := &RuleReport{Task: fmt.Sprintf("#%d %s", todo.ID, todo.Name), Matched: true}
+
+	for _, condition := range conditions {
+		matched, actual := condition.match(&before, todo, names, now)
+		report.Conditions = append(report.Conditions, ConditionResult{
+			Condition: condition.String(),
+			Actual:    actual,
+			Matched:   matched,
+		})
+		report.Matched = report.Matched && matched
+	}
+	if !report.Matched {
+		return report, nil
+	}
+
+	for _, action := range actions {
+		result, err := applyAction(action, todo, names, now)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", action, err))
+			continue
+		}
+		report.Changes = append(report.Changes, result.changes...)
+	}
+
+	return report, nil
+}
+
+// ============================================================================
+// Conditions and actions
+// ============================================================================
+
+// matchAll reports whether todo meets every condition
+func matchAll(conditions []Condition, before, todo *entities.ToDo, names *ruleNames, now time.Time) bool {
+	for _, condition := range conditions {
+		if matched, _ := condition.match(before, todo, names, now); !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// match tests the condition and returns the value it looked at
+func (c Condition) match(before, todo *entities.ToDo, names *ruleNames, now time.Time) (bool, string) {
+	switch c.Field {
+	case "priority":
+		want, _ := enums.ParsePriority(c.Value)
+		return compareOrdered(int(todo.Priority), int(want), c.Op), todo.Priority.String()
+
+	case "status":
+		want, _ := enums.ParseStatus(c.Value)
+		return compareOrdered(int(todo.Status), int(want), c.Op), todo.Status.String()
+
+	case "previous_status":
+		if before == nil {
+			return false, "none"
+		}
+		want, _ := enums.ParseStatus(c.Value)
+		return compareOrdered(int(before.Status), int(want), c.Op), before.Status.String()
+
+	case "done":
+		want, _ := formats.ParseBool(c.Value)
+		return (todo.Done == want) == (c.Op == "="), strconv.FormatBool(todo.Done)
+
+	case "due":
+		if todo.DueDate == nil {
+			return c.None && c.Op == "=", "none"
+		}
+		actual := formats.FormatDate(*todo.DueDate)
+		if c.None {
+			return c.Op == "!=", actual
+		}
+		want, _ := ParseDue(c.Value, now)
+		switch c.Op {
+		case "=":
+			return sameDay(*todo.DueDate, want), actual
+		case "!=":
+			return !sameDay(*todo.DueDate, want), actual
+		default:
+			return compareOrdered(todo.DueDate.Compare(want), 0, c.Op), actual
+		}
+	}
+
+	var actual string
+	switch c.Field {
+	case "name":
+		actual = todo.Name
+	case "description":
+		actual = deref(todo.Description)
+	case "details":
+		actual = deref(todo.Details)
+	case "color":
+		actual = todo.Color
+	case "project":
+		actual = names.project(todo.ProjectID)
+	case "list":
+		actual = names.list(todo.ToDoListID)
+	}
+
+	want := c.Value
+	if c.None {
+		want = ""
+	}
+	switch c.Op {
+	case "=":
+		return strings.EqualFold(actual, want), actual
+	case "!=":
+		return !strings.EqualFold(actual, want), actual
+	default:
+		return strings.Contains(strings.ToLower(actual), strings.ToLower(want)), actual
+	}
+}
+
+func compareOrdered(a, b int, op string) bool {
+	switch op {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	default:
+		return a >= b
+	}
+}
+
+func sameDay(a, b time.Time) bool {
+	a, b = a.Local(), b.Local()
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+type actionResult struct {
+	changes  []string
+	followUp *entities.ToDo
+}
+
+// applyAction applies an action to todo, or builds the task it creates
+func applyAction(action Action, todo *entities.ToDo, names *ruleNames, now time.Time) (actionResult, error) {
+	var result actionResult
+
+	switch action.Kind {
+	case ActionSet, ActionMove:
+		for _, arg := range action.Args {
+			change, err := setField(todo, arg, todo, names, now)
+			if err != nil {
+				return result, err
+			}
+			if change != "" {
+				result.changes = append(result.changes, change)
+			}
+		}
+
+	case ActionNote:
+		note := fmt.Sprintf("[%s] %s", now.Format("2006-01-02 15:04"), expandRuleText(action.Text, todo, names))
+		details := note
+		if existing := deref(todo.Details); existing != "" {
+			details = existing + "\n" + note
+		}
+		todo.Details = &details
+		result.changes = append(result.changes, "note: "+note)
+
+	case ActionCreate:
+		followUp := &entities.ToDo{
+			Name:       expandRuleText(action.Text, todo, names),
+			ProjectID:  todo.ProjectID,
+			ToDoListID: todo.ToDoListID,
+			Priority:   todo.Priority,
+			Status:     enums.New,
+		}
+		for _, arg := range action.Args {
+			if _, err := setField(followUp, arg, todo, names, now); err != nil {
+				return result, err
+			}
+		}
+
+		description := names.project(followUp.ProjectID)
+		if list := names.list(followUp.ToDoListID); list != "" {
+			description += "/" + list
+		}
+		if followUp.DueDate != nil {
+			description += ", due " + formats.FormatDate(*followUp.DueDate)
+		}
+		result.followUp = followUp
+		result.changes = append(result.changes, fmt.Sprintf("create: %q (%s)", followUp.Name, description))
+	}
+
+	return result, nil
+}
+
+// setField sets one field of target from an action argument. Text values
+// may refer to the task the rule fired for (source). It returns what
+// changed, or "" when the field already had the value.
+func setField(target *entities.ToDo, arg ActionArg, source *entities.ToDo, names *ruleNames, now time.Time) (string, error) {
+	none := strings.EqualFold(arg.Value, "none")
+	text := expandRuleText(arg.Value, source, names)
+
+	var from, to string
+	switch arg.Field {
+	case "name":
+		from, to = target.Name, text
+		target.Name = text
+
+	case "description", "details":
+		field := &target.Description
+		if arg.Field == "details" {
+			field = &target.Details
+		}
+		from = deref(*field)
+		if none {
+			*field = nil
+		} else {
+			*field = &text
+		}
+		to = deref(*field)
+
+	case "color":
+		from = target.Color
+		if none {
+			text = ""
+		}
+		target.Color, to = text, text
+
+	case "priority":
+		priority, err := enums.ParsePriority(arg.Value)
+		if err != nil {
+			return "", err
+		}
+		from, to = target.Priority.String(), priority.String()
+		target.Priority = priority
+
+	case "status":
+		status, err := enums.ParseStatus(arg.Value)
+		if err != nil {
+			return "", err
+		}
+		from, to = target.Status.String(), status.String()
+		target.Status = status
+
+	case "done":
+		done, err := formats.ParseBool(arg.Value)
+		if err != nil {
+			return "", err
+		}
+		from, to = strconv.FormatBool(target.Done), strconv.FormatBool(done)
+		target.Done = done
+
+	case "due":
+		from, to = "none", "none"
+		if target.DueDate != nil {
+			from = formats.FormatDate(*target.DueDate)
+		}
+		if none {
+			target.DueDate = nil
+		} else {
+			due, err := ParseDue(arg.Value, now)
+			if err != nil {
+				return "", err
+			}
+			target.DueDate = &due
+			to = formats.FormatDate(due)
+		}
+
+	case "project":
+		id, err := names.projectID(text)
+		if err != nil {
+			return "", err
+		}
+		from, to = names.project(target.ProjectID), names.project(id)
+		target.ProjectID = id
+		target.Project = entities.Project{}
+
+	case "list":
+		var id uint
+		if !none {
+			var err error
+			if id, err = names.listID(text); err != nil {
+				return "", err
+			}
+		}
+		from, to = names.list(target.ToDoListID), names.list(id)
+		target.ToDoListID = id
+		target.ToDoList = entities.ToDoList{}
+	}
+
+	if from == to {
+		return "", nil
+	}
+	return fmt.Sprintf("%s: %s → %s", arg.Field, orNone(from), orNone(to)), nil
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// expandRuleText fills in {name}, {project}, {list}, {priority}, {status},
+// {due} and {id} from the task a rule fired for
+func expandRuleText(s string, todo *entities.ToDo, names *ruleNames) string {
+	if !strings.Contains(s, "{") {
+		return s
+	}
+
+	due := ""
+	if todo.DueDate != nil {
+		due = formats.FormatDate(*todo.DueDate)
+	}
+	return strings.NewReplacer(
+		"{name}", todo.Name,
+		"{project}", names.project(todo.ProjectID),
+		"{list}", names.list(todo.ToDoListID),
+		"{priority}", todo.Priority.String(),
+		"{status}", todo.Status.String(),
+		"{due}", due,
+		"{id}", strconv.FormatUint(uint64(todo.ID), 10),
+	).Replace(s)
+}
+
+// ruleNames looks up project and list names and IDs for one evaluation
+type ruleNames struct {
+	rs       *RuleService
+	projects map[uint]string
+	lists    map[uint]string
+}
+
+func newRuleNames(rs *RuleService) *ruleNames {
+	return &ruleNames{rs: rs, projects: map[uint]string{}, lists: map[uint]string{}}
+}
+
+func (n *ruleNames) project(id uint) string {
+	return n.name(n.projects, &entities.Project{}, id)
+}
+
+func (n *ruleNames) list(id uint) string {
+	return n.name(n.lists, &entities.ToDoList{}, id)
+}
+
+func (n *ruleNames) name(cache map[uint]string, model any, id uint) string {
+	if id == 0 {
+		return ""
+	}
+	if name, ok := cache[id]; ok {
+		return name
+	}
+
+	var names []string
+	n.rs.db.GetDB().Model(model).Where("id = ?", id).Limit(1).Pluck("name", &names)
+	if len(names) == 1 {
+		cache[id] = names[0]
+	}
+	return cache[id]
+}
+
+func (n *ruleNames) projectID(name string) (uint, error) {
+	return n.id(n.projects, &entities.Project{}, "project", name)
+}
+
+func (n *ruleNames) listID(name string) (uint, error) {
+	return n.id(n.lists, &entities.ToDoList{}, "list", name)
+}
+
+func (n *ruleNames) id(cache map[uint]string, model any, kind, name string) (uint, error) {
+	var rows []struct {
+		ID   uint
+		Name string
+	}
+	n.rs.db.GetDB().Model(model).
+		Select("id", "name").
+		Where("LOWER(name) = LOWER(?)", name).
+		Order("id").
+		Limit(1).
+		Find(&rows)
+	if len(rows) == 0 {
+		return 0, fmt.Errorf("%s %q %w", kind, name, ErrNotFound)
+	}
+
+	cache[rows[0].ID] = rows[0].Name
+	return rows[0].ID, nil
+}
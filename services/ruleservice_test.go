@@ -0,0 +1,112 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"tuidoo/entities"
+	"tuidoo/enums"
+)
+
+type testRule struct {
+	name, trigger, conditions, actions string
+	disabled                           bool
+}
+
+func createRules(t *testing.T, sc *ServiceCollection, rules ...testRule) {
+	t.Helper()
+	for _, r := range rules {
+		rule := &entities.Rule{Name: r.name, Enabled: !r.disabled, Trigger: r.trigger, Conditions: r.conditions, Actions: r.actions}
+		if err := sc.RuleService.Create(rule); err != nil {
+			t.Fatalf("create rule %q: %v", r.name, err)
+		}
+	}
+}
+
+func TestRulesRunInCreationOrder(t *testing.T) {
+	hold := testRule{name: "hold", trigger: TriggerStatusChanged, actions: `set status="On Hold"`}
+	pend := testRule{name: "pend", trigger: TriggerStatusChanged, actions: "set status=Pending"}
+
+	tests := []struct {
+		name  string
+		rules []testRule
+		want  enums.Status
+	}{
+		// Each rule fires once per change, so the one created last has the
+		// last word
+		{"hold then pend", []testRule{hold, pend}, enums.Pending},
+		{"pend then hold", []testRule{pend, hold}, enums.OnHold},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := newTestServices(t)
+			createRules(t, sc, tt.rules...)
+			todo := createTodo(t, sc, "Work", "Triage")
+
+			if err := sc.ToDoService.UpdateStatus(todo.ID, enums.InProgress); err != nil {
+				t.Fatalf("UpdateStatus: %v", err)
+			}
+			got, err := sc.ToDoService.GetByID(todo.ID, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != tt.want {
+				t.Errorf("status = %v, want %v", got.Status, tt.want)
+			}
+		})
+	}
+}
+
+func TestRulesTriggerEachOther(t *testing.T) {
+	sc := newTestServices(t)
+	if err := sc.ToDoListService.Create(&entities.ToDoList{Name: "Urgent"}); err != nil {
+		t.Fatal(err)
+	}
+	createRules(t, sc,
+		testRule{name: "escalate", trigger: TriggerCreated, conditions: "name ~ outage", actions: `set status="In Progress"; move list=Urgent`},
+		testRule{name: "prioritise", trigger: TriggerStatusChanged, conditions: `status = "In Progress"`, actions: "set priority=Urgent"},
+		testRule{name: "follow up", trigger: TriggerCompleted, conditions: "list = Urgent", actions: `create "Write up {project}" list=none`},
+		testRule{name: "off", trigger: TriggerCreated, actions: "set priority=High", disabled: true},
+	)
+
+	todo := createTodo(t, sc, "Work", "DB outage")
+	got, err := sc.ToDoService.GetByID(todo.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A new task has no status to change from, so only escalate fires
+	if got.Status != enums.InProgress || got.ToDoList.Name != "Urgent" || got.Priority != enums.Low {
+		t.Errorf("created task = status %v list %q priority %v", got.Status, got.ToDoList.Name, got.Priority)
+	}
+
+	if err := sc.ToDoService.MarkAsComplete(todo.ID); err != nil {
+		t.Fatalf("MarkAsComplete: %v", err)
+	}
+	followUps, err := sc.ToDoService.Find(ToDoFilter{Search: "Write up"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(followUps) != 1 || followUps[0].Name != "Write up Work" || followUps[0].ToDoListID != 0 {
+		t.Errorf("follow-ups = %+v", followUps)
+	}
+}
+
+func TestRulesStopCreatingTasksThreeLevelsDown(t *testing.T) {
+	sc := newTestServices(t)
+	createRules(t, sc, testRule{name: "echo", trigger: TriggerCreated, actions: `create "again: {name}"`})
+
+	createTodo(t, sc, "Work", "seed")
+
+	n, err := sc.ToDoService.Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(1 + maxRuleDepth); n != want {
+		t.Errorf("%d tasks, want %d", n, want)
+	}
+
+	deepest, err := sc.ToDoService.Find(ToDoFilter{Search: strings.Repeat("again: ", maxRuleDepth)})
+	if err != nil || len(deepest) != 1 {
+		t.Errorf("deepest follow-up = %v, %v", deepest, err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"time"
 
+	"tuidoo/config"
 	"tuidoo/entities"
 	"tuidoo/enums"
 	"tuidoo/generated"
@@ -15,7 +16,8 @@ import (
 
 // Seed initializes all default data with transaction support
 func Seed(dbService *DbService) error {
-	log.Println("🌱 Starting database seeding...")
+	progress := dbService.progress()
+	progress.Println("🌱 Starting database seeding...")
 
 	db := dbService.GetDB()
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -23,19 +25,19 @@ func Seed(dbService *DbService) error {
 
 	// Use transaction for atomic seeding
 	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		if err := seedSettings(tx, ctx); err != nil {
+		if err := seedSettings(tx, ctx, progress); err != nil {
 			return err
 		}
 
-		if err := seedProjects(tx, ctx); err != nil {
+		if err := seedProjects(tx, ctx, progress); err != nil {
 			return err
 		}
 
-		if err := seedToDoLists(tx, ctx); err != nil {
+		if err := seedToDoLists(tx, ctx, progress); err != nil {
 			return err
 		}
 
-		if err := seedToDos(tx, ctx); err != nil {
+		if err := seedToDos(tx, ctx, progress); err != nil {
 			return err
 		}
 
@@ -47,17 +49,18 @@ func Seed(dbService *DbService) error {
 		return err
 	}
 
-	if err := verifySeeding(db, ctx); err != nil {
+	if err := verifySeeding(db, ctx, progress); err != nil {
 		log.Printf("⚠️ Warning: Verification failed: %v", err)
 	}
 
-	log.Println("🎉 Seed complete!")
+	progress.Println("🎉 Seed complete!")
 	return nil
 }
 
 // CleanDatabase removes all existing data with error handling
 func CleanDatabase(dbService *DbService) error {
-	log.Println("🧹 Cleaning existing data...")
+	progress := dbService.progress()
+	progress.Println("🧹 Cleaning existing data...")
 
 	db := dbService.GetDB()
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -84,35 +87,35 @@ func CleanDatabase(dbService *DbService) error {
 		return fmt.Errorf("failed to delete settings: %w", err)
 	}
 
-	log.Println("✓ Database cleaned")
+	progress.Println("✓ Database cleaned")
 	return nil
 }
 
-func seedSettings(tx *gorm.DB, ctx context.Context) error {
+func seedSettings(tx *gorm.DB, ctx context.Context, progress *log.Logger) error {
 	var count int64
 	tx.WithContext(ctx).Model(&entities.Settings{}).Count(&count)
 	if count > 0 {
-		log.Println("⚙️  Settings already exist, skipping")
+		progress.Println("⚙️  Settings already exist, skipping")
 		return nil
 	}
 
 	defaultSettings := entities.Settings{
-		ActiveThemeID: "dark",
+		ActiveThemeID: config.DefaultTheme,
 	}
 
 	if err := tx.WithContext(ctx).Create(&defaultSettings).Error; err != nil {
 		return fmt.Errorf("failed to create settings: %w", err)
 	}
 
-	log.Printf("✅ Created default settings (Theme: %s)", defaultSettings.ActiveThemeID)
+	progress.Printf("✅ Created default settings (Theme: %s)", defaultSettings.ActiveThemeID)
 	return nil
 }
 
-func seedProjects(tx *gorm.DB, ctx context.Context) error {
+func seedProjects(tx *gorm.DB, ctx context.Context, progress *log.Logger) error {
 	var count int64
 	tx.WithContext(ctx).Model(&entities.Project{}).Count(&count)
 	if count > 0 {
-		log.Println("📁 Projects already exist, skipping")
+		progress.Println("📁 Projects already exist, skipping")
 		return nil
 	}
 
@@ -126,17 +129,17 @@ func seedProjects(tx *gorm.DB, ctx context.Context) error {
 		if err := tx.WithContext(ctx).Create(p).Error; err != nil {
 			return fmt.Errorf("failed to create project %s: %w", p.Name, err)
 		}
-		log.Printf("✅ Created project: %s (ID: %d)", p.Name, p.ID)
+		progress.Printf("✅ Created project: %s (ID: %d)", p.Name, p.ID)
 	}
 
 	return nil
 }
 
-func seedToDoLists(tx *gorm.DB, ctx context.Context) error {
+func seedToDoLists(tx *gorm.DB, ctx context.Context, progress *log.Logger) error {
 	var count int64
 	tx.WithContext(ctx).Model(&entities.ToDoList{}).Count(&count)
 	if count > 0 {
-		log.Println("📋 ToDo lists already exist, skipping")
+		progress.Println("📋 ToDo lists already exist, skipping")
 		return nil
 	}
 
@@ -151,17 +154,17 @@ func seedToDoLists(tx *gorm.DB, ctx context.Context) error {
 		if err := tx.WithContext(ctx).Create(tl).Error; err != nil {
 			return fmt.Errorf("failed to create todolist %s: %w", tl.Name, err)
 		}
-		log.Printf("✅ Created todolist: %s (ID: %d)", tl.Name, tl.ID)
+		progress.Printf("✅ Created todolist: %s (ID: %d)", tl.Name, tl.ID)
 	}
 
 	return nil
 }
 
-func seedToDos(tx *gorm.DB, ctx context.Context) error {
+func seedToDos(tx *gorm.DB, ctx context.Context, progress *log.Logger) error {
 	var count int64
 	tx.WithContext(ctx).Model(&entities.ToDo{}).Count(&count)
 	if count > 0 {
-		log.Println("✓ ToDos already exist, skipping")
+		progress.Println("✓ ToDos already exist, skipping")
 		return nil
 	}
 
@@ -246,28 +249,28 @@ func seedToDos(tx *gorm.DB, ctx context.Context) error {
 				break
 			}
 		}
-		log.Printf("✅ Created todo: %s (ID: %d, Project: %s)", todo.Name, todo.ID, projectName)
+		progress.Printf("✅ Created todo: %s (ID: %d, Project: %s)", todo.Name, todo.ID, projectName)
 	}
 
 	return nil
 }
 
-func verifySeeding(db *gorm.DB, ctx context.Context) error {
-	log.Println("\n=== VERIFICATION ===")
+func verifySeeding(db *gorm.DB, ctx context.Context, progress *log.Logger) error {
+	progress.Println("\n=== VERIFICATION ===")
 
 	var settingsCount int64
 	db.WithContext(ctx).Model(&entities.Settings{}).Count(&settingsCount)
-	log.Printf("⚙️  Settings: %d", settingsCount)
+	progress.Printf("⚙️  Settings: %d", settingsCount)
 
 	var projectCount int64
 	db.WithContext(ctx).Model(&entities.Project{}).Count(&projectCount)
-	log.Printf("📁 Projects: %d", projectCount)
+	progress.Printf("📁 Projects: %d", projectCount)
 
 	allLists, err := gorm.G[entities.ToDoList](db).Find(ctx)
 	if err != nil {
 		return err
 	}
-	log.Printf("📋 Total lists: %d", len(allLists))
+	progress.Printf("📋 Total lists: %d", len(allLists))
 
 	var workProject entities.Project
 	if err := db.WithContext(ctx).Where("name = ?", "Work").First(&workProject).Error; err == nil {
@@ -277,7 +280,7 @@ func verifySeeding(db *gorm.DB, ctx context.Context) error {
 			Preload("ToDoList", nil).
 			Find(ctx)
 		if err == nil {
-			log.Printf("💼 Work project: %d todos", len(workTodos))
+			progress.Printf("💼 Work project: %d todos", len(workTodos))
 		}
 	}
 
@@ -286,17 +289,17 @@ func verifySeeding(db *gorm.DB, ctx context.Context) error {
 		Where(generated.ToDo.Status.WithName(enums.Pending.String())).
 		Find(ctx)
 	if err == nil {
-		log.Printf("🔥 High priority pending: %d todos", len(urgent))
+		progress.Printf("🔥 High priority pending: %d todos", len(urgent))
 	}
 
 	done, err := gorm.G[entities.ToDo](db).
 		Where(generated.ToDo.Done.Eq(true)).
 		Find(ctx)
 	if err == nil {
-		log.Printf("✅ Completed: %d todos", len(done))
+		progress.Printf("✅ Completed: %d todos", len(done))
 	}
 
-	log.Println("===================")
+	progress.Println("===================")
 	return nil
 }
 
@@ -3,19 +3,63 @@ package services
 import (
 	"fmt"
 	"log"
+	"tuidoo/config"
 )
 
 type ServiceCollection struct {
+	Selection config.Selection
+	// Events carries typed change events from the services to subscribers
+	// such as the TUI, the RPC server and webhooks
+	Events *EventBus
+
 	DbService       *DbService
+	BackupService   *BackupService
 	SettingsService *SettingsService
 	ThemeService    *ThemeService
 	ToDoService     *ToDoService
 	ProjectService  *ProjectService
 	ToDoListService *ToDoListService
+	TransferService *TransferService
+	TodoTxtService  *TodoTxtService
+	WebhookService  *WebhookService
+	WatchService    *WatchService
+	HookService     *HookService
+	RuleService     *RuleService
+
+	// oneShot is set for a single command, see NewCommandServiceCollection
+	oneShot bool
+}
+
+// NewServiceCollection initializes all services against the selected profile
+func NewServiceCollection(sel config.Selection) (*ServiceCollection, error) {
+	sc := &ServiceCollection{
+		Selection: sel,
+		Events:    NewEventBus(),
+		DbService: NewDbService(sel.DbPath),
+	}
+
+	if err := sc.Init(); err != nil {
+		return nil, err
+	}
+
+	return sc, nil
 }
 
-func NewServiceCollection() (*ServiceCollection, error) {
-	sc := &ServiceCollection{}
+// NewCommandServiceCollection initializes the services for a one-shot CLI
+// command. Nothing runs that the command did not ask for: the todo.txt
+// file is not synced on start and no webhooks are sent on their own.
+// Changes the command makes still run on-* hooks, rewrite the todo.txt
+// file and send their webhooks before Close returns. Progress such as
+// migrations and seeding is not logged, only warnings and errors, so
+// scripts reading the output see nothing else.
+func NewCommandServiceCollection(sel config.Selection) (*ServiceCollection, error) {
+	sc := &ServiceCollection{
+		Selection: sel,
+		Events:    NewEventBus(),
+		DbService: NewDbService(sel.DbPath),
+		oneShot:   true,
+	}
+	sc.DbService.quiet = true
 
 	if err := sc.Init(); err != nil {
 		return nil, err
@@ -25,40 +69,142 @@ func NewServiceCollection() (*ServiceCollection, error) {
 }
 
 func (sc *ServiceCollection) Init() error {
-	log.Println("Initializing services...")
+	sc.DbService.progress().Println("Initializing services...")
 
 	// 1. Database
-	sc.DbService = Db
-	if err := sc.DbService.Connect(); err != nil {
+	if sc.DbService == nil {
+		return fmt.Errorf("database service not configured")
+	}
+	if err := sc.DbService.Open(); err != nil {
 		return fmt.Errorf("database connection failed: %w", err)
 	}
+	sc.BackupService = NewBackupService(sc.DbService, sc.Selection.Backup)
 
-	// 2. Settings
-	settingsService, err := NewSettingsService(sc.DbService)
+	// 2. Schema
+	if err := sc.migrate(); err != nil {
+		return fmt.Errorf("database migration failed: %w", err)
+	}
+
+	// 3. Settings
+	settingsService, err := NewSettingsService(sc.DbService, sc.Events, sc.Selection.UI.ThemeID())
 	if err != nil {
 		return fmt.Errorf("settings service initialization failed: %w", err)
 	}
 	sc.SettingsService = settingsService
 
-	// 3. Theme
+	// 4. Theme
 	sc.ThemeService = NewThemeService(sc.DbService, sc.SettingsService)
 
-	// 4. Domain services
-	sc.ToDoService = NewToDoService(sc.DbService)
-	sc.ProjectService = NewProjectService(sc.DbService)
-	sc.ToDoListService = NewToDoListService(sc.DbService)
+	// 5. Domain services
+	sc.HookService = NewHookService(sc.DbService, sc.Events, sc.Selection.Profile, sc.Selection.Hooks)
+	sc.RuleService = NewRuleService(sc.DbService, sc.Events)
+	sc.ToDoService = NewToDoService(sc.DbService, sc.Events, sc.HookService, sc.RuleService)
+	sc.ProjectService = NewProjectService(sc.DbService, sc.Events)
+	sc.ToDoListService = NewToDoListService(sc.DbService, sc.Events)
+	sc.TransferService = NewTransferService(sc.DbService, sc.Events)
+
+	// 6. Seed, unless features.seed is off
+	if sc.Selection.Features.SeedEnabled() {
+		if err := Seed(sc.DbService); err != nil {
+			log.Printf("⚠️  Seeding failed (non-fatal): %v", err)
+		}
+	}
+
+	// 7. todo.txt sync
+	sc.TodoTxtService = NewTodoTxtService(sc.ToDoService, sc.TransferService, sc.Events, sc.Selection.TodoTxt)
+	if sc.TodoTxtService.Enabled() {
+		// One-shot commands only rewrite the file if they change something
+		if !sc.oneShot {
+			if err := sc.TodoTxtService.Sync(); err != nil {
+				log.Printf("⚠️  todo.txt sync failed (non-fatal): %v", err)
+			} else {
+				sc.DbService.progress().Printf("🔄 Synced %s", sc.TodoTxtService.File())
+			}
+		}
+		sc.TodoTxtService.Start()
+	}
+
+	// 8. Webhooks
+	sc.WebhookService = NewWebhookService(sc.DbService, sc.Events, sc.Selection.Profile, sc.Selection.Webhooks)
+	if sc.WebhookService.Enabled() {
+		if sc.oneShot {
+			sc.WebhookService.Record()
+		} else {
+			sc.WebhookService.Start()
+		}
+	}
+
+	// 9. on-* hooks
+	sc.HookService.Start()
+
+	// 10. External changes, watched once someone listens (see WatchService)
+	sc.WatchService = NewWatchService(sc.DbService, sc.Events)
+
+	sc.DbService.progress().Println("✅ Services initialized successfully")
+	return nil
+}
+
+// migrate applies pending migrations, backing up existing data first. It
+// refuses to run against a database newer than this binary.
+func (sc *ServiceCollection) migrate() error {
+	migrator := NewMigrationService(sc.DbService)
+	if err := migrator.CheckCompatible(); err != nil {
+		return err
+	}
+
+	pending, err := migrator.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
 
-	// 5. Seed
-	if err := Seed(sc.DbService); err != nil {
-		log.Printf("⚠️  Seeding failed (non-fatal): %v", err)
+	if sc.hasData(migrator) {
+		if _, err := sc.BackupService.Create("pre-migrate"); err != nil {
+			return fmt.Errorf("pre-migration backup failed: %w", err)
+		}
 	}
 
-	log.Println("✅ Services initialized successfully")
+	applied, err := migrator.Up(0)
+	if err != nil {
+		return err
+	}
+
+	sc.DbService.progress().Printf("✓ Applied %d migrations to %s", len(applied), sc.DbService.Path())
 	return nil
 }
 
+// hasData reports whether the database holds anything worth backing up
+func (sc *ServiceCollection) hasData(migrator *MigrationService) bool {
+	if version, err := migrator.CurrentVersion(); err == nil && version > 0 {
+		return true
+	}
+	return sc.DbService.GetDB().Migrator().HasTable("to_dos")
+}
+
 func (sc *ServiceCollection) Close() error {
-	log.Println("Shutting down services...")
+	sc.DbService.progress().Println("Shutting down services...")
+
+	if sc.WatchService != nil {
+		sc.WatchService.Close()
+	}
+
+	if sc.RuleService != nil {
+		sc.RuleService.Close()
+	}
+
+	if sc.HookService != nil {
+		sc.HookService.Close()
+	}
+
+	if sc.TodoTxtService != nil {
+		sc.TodoTxtService.Close()
+	}
+
+	if sc.WebhookService != nil {
+		sc.WebhookService.Close()
+	}
 
 	if sc.DbService != nil {
 		if err := sc.DbService.Close(); err != nil {
@@ -66,13 +212,17 @@ func (sc *ServiceCollection) Close() error {
 		}
 	}
 
-	log.Println("✅ Services shut down successfully")
+	sc.DbService.progress().Println("✅ Services shut down successfully")
 	return nil
 }
 
-// Reset cleans and reseeds the database
+// Reset backs up, cleans and reseeds the database
 func (sc *ServiceCollection) Reset() error {
-	log.Println("Resetting database...")
+	sc.DbService.progress().Println("Resetting database...")
+
+	if _, err := sc.BackupService.Create("pre-reset"); err != nil {
+		return fmt.Errorf("pre-reset backup failed: %w", err)
+	}
 
 	if err := ResetAndSeed(sc.DbService); err != nil {
 		return fmt.Errorf("reset failed: %w", err)
@@ -82,8 +232,9 @@ func (sc *ServiceCollection) Reset() error {
 	if err := sc.SettingsService.Reload(); err != nil {
 		return fmt.Errorf("failed to reload settings after reset: %w", err)
 	}
+	sc.Events.Publish(DataReset{})
 
-	log.Println("✅ Database reset complete")
+	sc.DbService.progress().Println("✅ Database reset complete")
 	return nil
 }
 
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"tuidoo/config"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestServices initializes the services against a fresh in-memory
+// database, without sample data. configure may change the selection first.
+func newTestServices(t *testing.T, configure ...func(*config.Selection)) *ServiceCollection {
+	t.Helper()
+	return initTestServices(t, false, configure...)
+}
+
+// newCommandTestServices is newTestServices as a one-shot command gets them
+func newCommandTestServices(t *testing.T, configure ...func(*config.Selection)) *ServiceCollection {
+	t.Helper()
+	return initTestServices(t, true, configure...)
+}
+
+func initTestServices(t *testing.T, oneShot bool, configure ...func(*config.Selection)) *ServiceCollection {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	// Every connection to :memory: is a database of its own
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	dbService := &DbService{path: filepath.Join(t.TempDir(), "test.db"), db: db}
+	dbService.once.Do(func() {})
+
+	off := false
+	sel := config.Selection{Profile: "test", DbPath: dbService.path}
+	sel.Features.Seed = &off
+	for _, c := range configure {
+		c(&sel)
+	}
+
+	sc := &ServiceCollection{Selection: sel, Events: NewEventBus(), DbService: dbService, oneShot: oneShot}
+	if err := sc.Init(); err != nil {
+		t.Fatalf("init services: %v", err)
+	}
+	t.Cleanup(func() { sc.Close() })
+
+	return sc
+}
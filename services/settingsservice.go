@@ -10,11 +10,14 @@ import (
 
 type SettingsService struct {
 	db       *DbService
+	events   *EventBus
 	settings *e.Settings
+	// defaultTheme is stored when the database has no settings yet
+	defaultTheme string
 }
 
-func NewSettingsService(dbService *DbService) (*SettingsService, error) {
-	ss := &SettingsService{db: dbService}
+func NewSettingsService(dbService *DbService, events *EventBus, defaultTheme string) (*SettingsService, error) {
+	ss := &SettingsService{db: dbService, events: events, defaultTheme: defaultTheme}
 	if err := ss.loadSettings(); err != nil {
 		return nil, fmt.Errorf("failed to load settings: %w", err)
 	}
@@ -32,7 +35,7 @@ func (ss *SettingsService) loadSettings() error {
 		// If no settings found, create default settings
 		if err == gorm.ErrRecordNotFound {
 			defaultSettings := e.Settings{
-				ActiveThemeID: "dark",
+				ActiveThemeID: ss.defaultTheme,
 			}
 
 			if createErr := ss.db.GetDB().WithContext(ctx).Create(&defaultSettings).Error; createErr != nil {
@@ -85,5 +88,6 @@ func (ss *SettingsService) SetActiveTheme(themeId string) error {
 	}
 
 	ss.settings = settings
+	ss.events.Publish(ThemeChanged{ThemeID: themeId})
 	return nil
 }
@@ -3,14 +3,17 @@ package services
 import (
 	"fmt"
 	"tuidoo/entities"
+
+	"gorm.io/gorm"
 )
 
 type ToDoListService struct {
-	db *DbService
+	db     *DbService
+	events *EventBus
 }
 
-func NewToDoListService(dbService *DbService) *ToDoListService {
-	return &ToDoListService{db: dbService}
+func NewToDoListService(dbService *DbService, events *EventBus) *ToDoListService {
+	return &ToDoListService{db: dbService, events: events}
 }
 
 func (tls *ToDoListService) Create(list *entities.ToDoList) error {
@@ -25,6 +28,7 @@ func (tls *ToDoListService) Create(list *entities.ToDoList) error {
 		return fmt.Errorf("failed to create list: %w", err)
 	}
 
+	tls.events.Publish(ListCreated{ID: list.ID})
 	return nil
 }
 
@@ -46,6 +50,26 @@ func (tls *ToDoListService) GetByID(id uint, includeToDos bool) (*entities.ToDoL
 	return &list, nil
 }
 
+// GetByName retrieves the first list with the given name, ignoring case
+func (tls *ToDoListService) GetByName(name string) (*entities.ToDoList, error) {
+	ctx, cancel := tls.db.NewContext()
+	defer cancel()
+
+	var lists []entities.ToDoList
+	if err := tls.db.GetDB().WithContext(ctx).
+		Where("LOWER(name) = LOWER(?)", name).
+		Order("id").
+		Limit(1).
+		Find(&lists).Error; err != nil {
+		return nil, fmt.Errorf("failed to get list: %w", err)
+	}
+	if len(lists) == 0 {
+		return nil, fmt.Errorf("list %q %w", name, ErrNotFound)
+	}
+
+	return &lists[0], nil
+}
+
 func (tls *ToDoListService) GetAll(includeToDos bool) ([]entities.ToDoList, error) {
 	ctx, cancel := tls.db.NewContext()
 	defer cancel()
@@ -76,6 +100,51 @@ func (tls *ToDoListService) Update(list *entities.ToDoList) error {
 		return fmt.Errorf("failed to update list: %w", err)
 	}
 
+	tls.events.Publish(ListUpdated{ID: list.ID})
+	return nil
+}
+
+// UpdateChecked saves a list only if it is still at the given version
+// (see Version). An empty version skips the check.
+func (tls *ToDoListService) UpdateChecked(list *entities.ToDoList, version string) error {
+	ctx, cancel := tls.db.NewContext()
+	defer cancel()
+
+	if list.Name == "" {
+		return fmt.Errorf("list name cannot be empty")
+	}
+
+	err := tls.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkVersion(tx, &entities.ToDoList{}, list.ID, version); err != nil {
+			return err
+		}
+		return tx.Omit("ToDos").Save(list).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update list %d: %w", list.ID, err)
+	}
+
+	tls.events.Publish(ListUpdated{ID: list.ID})
+	return nil
+}
+
+// DeleteChecked deletes a list only if it is still at the given version.
+// An empty version skips the check.
+func (tls *ToDoListService) DeleteChecked(id uint, version string) error {
+	ctx, cancel := tls.db.NewContext()
+	defer cancel()
+
+	err := tls.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkVersion(tx, &entities.ToDoList{}, id, version); err != nil {
+			return err
+		}
+		return tx.Delete(&entities.ToDoList{}, id).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete list %d: %w", id, err)
+	}
+
+	tls.events.Publish(ListDeleted{ID: id})
 	return nil
 }
 
@@ -90,9 +159,10 @@ func (tls *ToDoListService) Delete(id uint) error {
 	}
 
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("list with ID %d not found", id)
+		return fmt.Errorf("list with ID %d %w", id, ErrNotFound)
 	}
 
+	tls.events.Publish(ListDeleted{ID: id})
 	return nil
 }
 
@@ -1,7 +1,9 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"time"
 	"tuidoo/entities"
 	"tuidoo/enums"
@@ -11,15 +13,189 @@ import (
 )
 
 type ToDoService struct {
-	db *DbService
+	db     *DbService
+	events *EventBus
+	hooks  *HookService
+	rules  *RuleService
 }
 
-func NewToDoService(dbService *DbService) *ToDoService {
-	return &ToDoService{db: dbService}
+// conflictRetries is how often a single-field change is tried when other
+// writers keep getting in between its read and its write
+const conflictRetries = 3
+
+func NewToDoService(dbService *DbService, events *EventBus, hooks *HookService, rules *RuleService) *ToDoService {
+	return &ToDoService{db: dbService, events: events, hooks: hooks, rules: rules}
+}
+
+// updateHook picks the pre hook of an update: pre-complete when it marks an
+// open todo done, pre-modify otherwise
+func updateHook(wasDone, done bool) string {
+	if done && !wasDone {
+		return HookPreComplete
+	}
+	return HookPreModify
+}
+
+// load reads a todo without its associations for a change to it
+func (ts *ToDoService) load(id uint, unscoped bool) (*entities.ToDo, error) {
+	ctx, cancel := ts.db.NewContext()
+	defer cancel()
+
+	query := ts.db.GetDB().WithContext(ctx)
+	if unscoped {
+		query = query.Unscoped()
+	}
+
+	var todos []entities.ToDo
+	if err := query.Limit(1).Find(&todos, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get todo: %w", err)
+	}
+	if len(todos) == 0 {
+		return nil, fmt.Errorf("todo with ID %d %w", id, ErrNotFound)
+	}
+
+	return &todos[0], nil
+}
+
+// current reads a todo as it is before an update, or nil if it is missing
+func (ts *ToDoService) current(id uint) *entities.ToDo {
+	todo, err := ts.load(id, false)
+	if err != nil {
+		return nil
+	}
+	return todo
+}
+
+// saveChanges writes the columns that differ between before, as loaded
+// with load, and todo. The write only goes through while the row is still
+// at before's updated_at, so a change saved in between is never
+// overwritten; ErrConflict reports it.
+func (ts *ToDoService) saveChanges(before, todo *entities.ToDo) error {
+	return ts.writeChanges(todo, before.UpdatedAt, todoChanges(before, todo))
+}
+
+// writeChanges writes changes to todo's row while it is still at version,
+// its updated_at
+func (ts *ToDoService) writeChanges(todo *entities.ToDo, version time.Time, changes map[string]any) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	changes["updated_at"] = now
+
+	ctx, cancel := ts.db.NewContext()
+	defer cancel()
+
+	result := ts.db.GetDB().WithContext(ctx).Model(&entities.ToDo{}).
+		Where("id = ? AND updated_at = ?", todo.ID, version).
+		UpdateColumns(changes)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("todo %d %w", todo.ID, ErrConflict)
+	}
+
+	todo.UpdatedAt = now
+	return nil
+}
+
+// todoChanges maps the columns that differ between before and todo to
+// their new values
+func todoChanges(before, todo *entities.ToDo) map[string]any {
+	changes := map[string]any{}
+
+	if before.Name != todo.Name {
+		changes["name"] = todo.Name
+	}
+	if !sameString(before.Description, todo.Description) {
+		changes["description"] = todo.Description
+	}
+	if !sameString(before.Details, todo.Details) {
+		changes["details"] = todo.Details
+	}
+	if before.ProjectID != todo.ProjectID {
+		changes["project_id"] = todo.ProjectID
+	}
+	if before.ToDoListID != todo.ToDoListID {
+		changes["to_do_list_id"] = todo.ToDoListID
+	}
+	if before.Priority != todo.Priority {
+		changes["priority"] = todo.Priority
+	}
+	if before.Status != todo.Status {
+		changes["status"] = todo.Status
+	}
+	if before.Color != todo.Color {
+		changes["color"] = todo.Color
+	}
+	if before.Done != todo.Done {
+		changes["done"] = todo.Done
+	}
+	if !sameTime(before.DueDate, todo.DueDate) {
+		changes["due_date"] = todo.DueDate
+	}
+
+	return changes
+}
+
+// saveRetrying is saveChanges for changes of a single field, which rules
+// and hooks have already seen. While another writer gets in between, the
+// same columns are written again over the version just read; rules and
+// hooks do not run again, and the other writer's columns are kept.
+func (ts *ToDoService) saveRetrying(before, todo *entities.ToDo) error {
+	changes := todoChanges(before, todo)
+	version := before.UpdatedAt
+
+	var err error
+	for range conflictRetries {
+		if err = ts.writeChanges(todo, version, changes); !errors.Is(err, ErrConflict) {
+			return err
+		}
+		current, loadErr := ts.load(todo.ID, false)
+		if loadErr != nil {
+			return loadErr
+		}
+		version = current.UpdatedAt
+	}
+	return err
+}
+
+// publishUpdate publishes TodoCompleted or TodoReopened when an update
+// changed whether the todo is done, and TodoUpdated otherwise
+func (ts *ToDoService) publishUpdate(id uint, wasDone, done bool) {
+	switch {
+	case done && !wasDone:
+		ts.events.Publish(TodoCompleted{ID: id})
+	case !done && wasDone:
+		ts.events.Publish(TodoReopened{ID: id})
+	default:
+		ts.events.Publish(TodoUpdated{ID: id})
+	}
+}
+
+// isDone reads whether a todo is currently done, so updates can tell
+// whether they completed or reopened it
+func (ts *ToDoService) isDone(tx *gorm.DB, id uint) bool {
+	var done []bool
+	tx.Model(&entities.ToDo{}).Where("id = ?", id).Limit(1).Pluck("done", &done)
+	return len(done) == 1 && done[0]
 }
 
 // Create creates a new todo
 func (ts *ToDoService) Create(todo *entities.ToDo) error {
+	return ts.create(todo, 0)
+}
+
+// create creates a todo; depth counts the rule-created todos leading up to
+// it (see RuleService)
+func (ts *ToDoService) create(todo *entities.ToDo, depth int) error {
+	outcome := ts.rules.evaluate(ruleChange{created: true, depth: depth}, todo)
+	if err := ts.hooks.Before(HookPreAdd, todo); err != nil {
+		return err
+	}
+
 	ctx, cancel := ts.db.NewContext()
 	defer cancel()
 
@@ -31,6 +207,8 @@ func (ts *ToDoService) Create(todo *entities.ToDo) error {
 		return fmt.Errorf("failed to create todo: %w", err)
 	}
 
+	ts.events.Publish(TodoCreated{ID: todo.ID})
+	ts.afterRules(outcome, todo.ID, depth)
 	return nil
 }
 
@@ -48,7 +226,7 @@ func (ts *ToDoService) GetByID(id uint, preload bool) (*entities.ToDo, error) {
 	var todo entities.ToDo
 	if err := query.First(&todo, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("todo with ID %d not found", id)
+			return nil, fmt.Errorf("todo with ID %d %w", id, ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get todo: %w", err)
 	}
@@ -205,93 +383,304 @@ func (ts *ToDoService) GetOverdue(preload bool) ([]entities.ToDo, error) {
 	return todos, nil
 }
 
-// Update updates an existing todo
-func (ts *ToDoService) Update(todo *entities.ToDo) error {
+// ToDoFilter narrows a todo query. Zero values match everything; project and
+// list names match case-insensitively. Limit and Offset page through the
+// results.
+type ToDoFilter struct {
+	Project   string
+	List      string
+	Status    *enums.Status
+	Priority  *enums.Priority
+	Done      *bool
+	Search    string
+	DueBefore *time.Time
+	DueAfter  *time.Time
+	Limit     int
+	Offset    int
+}
+
+// Find retrieves todos matching a filter, ordered by ID, with project and
+// list preloaded
+func (ts *ToDoService) Find(filter ToDoFilter) ([]entities.ToDo, error) {
 	ctx, cancel := ts.db.NewContext()
 	defer cancel()
 
+	query := ts.filterQuery(ts.db.GetDB().WithContext(ctx), filter).
+		Preload("Project").
+		Preload("ToDoList").
+		Order("id")
+
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+
+	var todos []entities.ToDo
+	if err := query.Find(&todos).Error; err != nil {
+		return nil, fmt.Errorf("failed to find todos: %w", err)
+	}
+
+	return todos, nil
+}
+
+// CountMatching returns how many todos match a filter, ignoring Limit and
+// Offset
+func (ts *ToDoService) CountMatching(filter ToDoFilter) (int64, error) {
+	ctx, cancel := ts.db.NewContext()
+	defer cancel()
+
+	var count int64
+	query := ts.filterQuery(ts.db.GetDB().WithContext(ctx).Model(&entities.ToDo{}), filter)
+	if err := query.Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count todos: %w", err)
+	}
+
+	return count, nil
+}
+
+func (ts *ToDoService) filterQuery(query *gorm.DB, filter ToDoFilter) *gorm.DB {
+	if filter.Project != "" {
+		query = query.Where("project_id IN (?)", ts.db.GetDB().
+			Model(&entities.Project{}).
+			Select("id").
+			Where("LOWER(name) = LOWER(?)", filter.Project))
+	}
+	if filter.List != "" {
+		query = query.Where("to_do_list_id IN (?)", ts.db.GetDB().
+			Model(&entities.ToDoList{}).
+			Select("id").
+			Where("LOWER(name) = LOWER(?)", filter.List))
+	}
+	if filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+	if filter.Priority != nil {
+		query = query.Where("priority = ?", *filter.Priority)
+	}
+	if filter.Done != nil {
+		query = query.Where("done = ?", *filter.Done)
+	}
+	if filter.Search != "" {
+		query = query.Where("name LIKE ?", "%"+filter.Search+"%")
+	}
+	if filter.DueBefore != nil {
+		query = query.Where("due_date < ?", *filter.DueBefore)
+	}
+	if filter.DueAfter != nil {
+		query = query.Where("due_date >= ?", *filter.DueAfter)
+	}
+
+	return query
+}
+
+// Update saves the fields of a todo that changed. It fails with
+// ErrConflict when the todo was saved by someone else after it was read.
+func (ts *ToDoService) Update(todo *entities.ToDo) error {
+	version := todo.UpdatedAt
+	before, err := ts.load(todo.ID, false)
+	if err != nil {
+		return err
+	}
+	wasDone := before.Done
+	outcome := ts.updateRules(before, todo)
+	if err := ts.hooks.Before(updateHook(wasDone, todo.Done), todo); err != nil {
+		return err
+	}
+
 	if err := ts.validate(todo); err != nil {
 		return fmt.Errorf("validation failed: %w", err)
 	}
 
-	if err := ts.db.GetDB().WithContext(ctx).Save(todo).Error; err != nil {
+	// Guarded by the version the caller read, so a change saved since then
+	// is not overwritten
+	before.UpdatedAt = version
+	if err := ts.saveChanges(before, todo); err != nil {
 		return fmt.Errorf("failed to update todo: %w", err)
 	}
 
+	ts.publishUpdate(todo.ID, wasDone, todo.Done)
+	ts.afterRules(outcome, todo.ID, 0)
 	return nil
 }
 
-// UpdateStatus updates just the status of a todo
-func (ts *ToDoService) UpdateStatus(id uint, status enums.Status) error {
+// UpdateChecked saves a todo only if it is still at the given version
+// (see Version). An empty version skips the check.
+func (ts *ToDoService) UpdateChecked(todo *entities.ToDo, version string) error {
+	// Rules and hooks may take a while, so they run before the transaction
+	// and its version check
+	before := ts.current(todo.ID)
+	outcome := ts.updateRules(before, todo)
+	if err := ts.hooks.Before(updateHook(before != nil && before.Done, todo.Done), todo); err != nil {
+		return err
+	}
+
 	ctx, cancel := ts.db.NewContext()
 	defer cancel()
 
-	result := ts.db.GetDB().WithContext(ctx).
-		Model(&entities.ToDo{}).
-		Where("id = ?", id).
-		Update("status", status)
+	if err := ts.validate(todo); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
 
-	if result.Error != nil {
-		return fmt.Errorf("failed to update status: %w", result.Error)
+	var wasDone bool
+	err := ts.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkVersion(tx, &entities.ToDo{}, todo.ID, version); err != nil {
+			return err
+		}
+		wasDone = ts.isDone(tx, todo.ID)
+		return tx.Omit("Project", "ToDoList").Save(todo).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update todo %d: %w", todo.ID, err)
 	}
 
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("todo with ID %d not found", id)
+	ts.publishUpdate(todo.ID, wasDone, todo.Done)
+	ts.afterRules(outcome, todo.ID, 0)
+	return nil
+}
+
+// UpdateStatus updates just the status of a todo
+func (ts *ToDoService) UpdateStatus(id uint, status enums.Status) error {
+	todo, err := ts.load(id, false)
+	if err != nil {
+		return err
 	}
 
+	before := *todo
+	todo.Status = status
+	outcome := ts.updateRules(&before, todo)
+	if err := ts.hooks.Before(updateHook(before.Done, todo.Done), todo); err != nil {
+		return err
+	}
+
+	if err := ts.saveRetrying(&before, todo); err != nil {
+		return fmt.Errorf("failed to update status: %w", err)
+	}
+
+	ts.publishUpdate(id, before.Done, todo.Done)
+	ts.afterRules(outcome, id, 0)
 	return nil
 }
 
 // MarkAsComplete marks a todo as completed
 func (ts *ToDoService) MarkAsComplete(id uint) error {
-	ctx, cancel := ts.db.NewContext()
-	defer cancel()
+	return ts.setDone(id, true, enums.Done, "")
+}
 
-	result := ts.db.GetDB().WithContext(ctx).
-		Model(&entities.ToDo{}).
-		Where("id = ?", id).
-		Updates(map[string]interface{}{
-			"done":   true,
-			"status": enums.Done,
-		})
+// MarkAsIncomplete marks a todo as not completed
+func (ts *ToDoService) MarkAsIncomplete(id uint) error {
+	return ts.setDone(id, false, enums.Pending, "")
+}
 
-	if result.Error != nil {
-		return fmt.Errorf("failed to mark todo as complete: %w", result.Error)
+// MarkAsCompleteChecked marks a todo as completed only if it is still at
+// the given version (see Version). An empty version skips the check.
+func (ts *ToDoService) MarkAsCompleteChecked(id uint, version string) error {
+	return ts.setDone(id, true, enums.Done, version)
+}
+
+// MarkAsIncompleteChecked marks a todo as not completed only if it is
+// still at the given version (see Version). An empty version skips the
+// check.
+func (ts *ToDoService) MarkAsIncompleteChecked(id uint, version string) error {
+	return ts.setDone(id, false, enums.Pending, version)
+}
+
+func (ts *ToDoService) setDone(id uint, done bool, status enums.Status, version string) error {
+	todo, err := ts.load(id, false)
+	if err != nil {
+		return err
+	}
+	if version != "" && Version(todo.UpdatedAt) != version {
+		return fmt.Errorf("todo %d %w", id, ErrConflict)
 	}
 
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("todo with ID %d not found", id)
+	before := *todo
+	todo.Done = done
+	todo.Status = status
+	outcome := ts.updateRules(&before, todo)
+	if err := ts.hooks.Before(updateHook(before.Done, todo.Done), todo); err != nil {
+		return err
+	}
+
+	// A checked change must not be retried over a version the caller has
+	// not seen
+	save := ts.saveRetrying
+	if version != "" {
+		save = ts.saveChanges
+	}
+	if err := save(&before, todo); err != nil {
+		if done {
+			return fmt.Errorf("failed to mark todo as complete: %w", err)
+		}
+		return fmt.Errorf("failed to mark todo as incomplete: %w", err)
 	}
 
+	ts.publishUpdate(id, before.Done, todo.Done)
+	ts.afterRules(outcome, id, 0)
 	return nil
 }
 
-// MarkAsIncomplete marks a todo as not completed
-func (ts *ToDoService) MarkAsIncomplete(id uint) error {
-	ctx, cancel := ts.db.NewContext()
-	defer cancel()
+// updateRules runs the rules for an update of a todo that exists
+func (ts *ToDoService) updateRules(before, todo *entities.ToDo) *ruleOutcome {
+	if before == nil {
+		return nil
+	}
+	return ts.rules.evaluate(ruleChange{before: before}, todo)
+}
 
-	result := ts.db.GetDB().WithContext(ctx).
-		Model(&entities.ToDo{}).
-		Where("id = ?", id).
-		Updates(map[string]interface{}{
-			"done":   false,
-			"status": enums.Pending,
-		})
+// applyDueRule runs a due_approaching rule for a todo (see
+// RuleService.checkDue)
+func (ts *ToDoService) applyDueRule(rule *entities.Rule, id uint) error {
+	todo, err := ts.load(id, false)
+	if err != nil {
+		return err
+	}
 
-	if result.Error != nil {
-		return fmt.Errorf("failed to mark todo as incomplete: %w", result.Error)
+	before := *todo
+	outcome := ts.rules.evaluate(ruleChange{before: &before, due: rule}, todo)
+	if outcome.changed {
+		if err := ts.hooks.Before(updateHook(before.Done, todo.Done), todo); err != nil {
+			return err
+		}
+		if err := ts.saveRetrying(&before, todo); err != nil {
+			return fmt.Errorf("failed to apply rule %q: %w", rule.Name, err)
+		}
+	}
+	if len(outcome.fired) == 0 {
+		return nil
 	}
 
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("todo with ID %d not found", id)
+	if outcome.changed {
+		ts.publishUpdate(id, before.Done, todo.Done)
 	}
 
+	ts.afterRules(outcome, id, 0)
 	return nil
 }
 
+// afterRules records the rules that fired for a saved change and creates
+// the todos they asked for
+func (ts *ToDoService) afterRules(outcome *ruleOutcome, id uint, depth int) {
+	if outcome == nil || len(outcome.fired) == 0 {
+		return
+	}
+
+	ts.rules.record(outcome, id)
+	for _, followUp := range outcome.followUps {
+		todo := followUp.todo
+		if err := ts.create(&todo, depth+1); err != nil {
+			log.Printf("⚠️  Rule %q could not create %q: %v", followUp.rule, todo.Name, err)
+		}
+	}
+}
+
 // Delete soft deletes a todo
 func (ts *ToDoService) Delete(id uint) error {
+	if err := ts.beforeDelete(id, false); err != nil {
+		return err
+	}
+
 	ctx, cancel := ts.db.NewContext()
 	defer cancel()
 
@@ -302,14 +691,43 @@ func (ts *ToDoService) Delete(id uint) error {
 	}
 
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("todo with ID %d not found", id)
+		return fmt.Errorf("todo with ID %d %w", id, ErrNotFound)
 	}
 
+	ts.events.Publish(TodoDeleted{ID: id})
+	return nil
+}
+
+// DeleteChecked soft deletes a todo only if it is still at the given
+// version. An empty version skips the check.
+func (ts *ToDoService) DeleteChecked(id uint, version string) error {
+	if err := ts.beforeDelete(id, false); err != nil {
+		return err
+	}
+
+	ctx, cancel := ts.db.NewContext()
+	defer cancel()
+
+	err := ts.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkVersion(tx, &entities.ToDo{}, id, version); err != nil {
+			return err
+		}
+		return tx.Delete(&entities.ToDo{}, id).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete todo %d: %w", id, err)
+	}
+
+	ts.events.Publish(TodoDeleted{ID: id})
 	return nil
 }
 
 // HardDelete permanently deletes a todo
 func (ts *ToDoService) HardDelete(id uint) error {
+	if err := ts.beforeDelete(id, true); err != nil {
+		return err
+	}
+
 	ctx, cancel := ts.db.NewContext()
 	defer cancel()
 
@@ -320,12 +738,22 @@ func (ts *ToDoService) HardDelete(id uint) error {
 	}
 
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("todo with ID %d not found", id)
+		return fmt.Errorf("todo with ID %d %w", id, ErrNotFound)
 	}
 
+	ts.events.Publish(TodoDeleted{ID: id})
 	return nil
 }
 
+// beforeDelete runs the pre-delete hooks, which see the todo as it is
+func (ts *ToDoService) beforeDelete(id uint, unscoped bool) error {
+	todo, err := ts.load(id, unscoped)
+	if err != nil {
+		return err
+	}
+	return ts.hooks.Before(HookPreDelete, todo)
+}
+
 // Search searches todos by name (case-insensitive)
 func (ts *ToDoService) Search(query string, preload bool) ([]entities.ToDo, error) {
 	ctx, cancel := ts.db.NewContext()
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"tuidoo/enums"
+)
+
+func TestToDoServiceSingleFieldChanges(t *testing.T) {
+	sc := newTestServices(t)
+	todo := createTodo(t, sc, "Work", "Write report")
+	ts := sc.ToDoService
+
+	steps := []struct {
+		name   string
+		change func() error
+		done   bool
+		status enums.Status
+	}{
+		{"complete", func() error { return ts.MarkAsComplete(todo.ID) }, true, enums.Done},
+		{"reopen", func() error { return ts.MarkAsIncomplete(todo.ID) }, false, enums.Pending},
+		{"status", func() error { return ts.UpdateStatus(todo.ID, enums.OnHold) }, false, enums.OnHold},
+		{"same status again", func() error { return ts.UpdateStatus(todo.ID, enums.OnHold) }, false, enums.OnHold},
+	}
+
+	for _, step := range steps {
+		if err := step.change(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		got, err := ts.GetByID(todo.ID, false)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got.Done != step.done || got.Status != step.status {
+			t.Errorf("%s: done %v status %v, want %v %v", step.name, got.Done, got.Status, step.done, step.status)
+		}
+	}
+}
+
+func TestToDoServiceSaveChangesKeepsOtherWrites(t *testing.T) {
+	sc := newTestServices(t)
+	created := createTodo(t, sc, "Work", "Write report")
+	ts := sc.ToDoService
+
+	// A stale copy, read before someone else renamed the todo
+	stale, err := ts.load(created.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := *stale
+
+	renamed, err := ts.load(created.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	renamed.Name = "Write the Q3 report"
+	if err := ts.Update(renamed); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+
+	stale.Done = true
+	if err := ts.saveChanges(&before, stale); !errors.Is(err, ErrConflict) {
+		t.Fatalf("stale write = %v, want ErrConflict", err)
+	}
+
+	// Going through the service re-reads and writes only the done column
+	if err := ts.MarkAsComplete(created.ID); err != nil {
+		t.Fatalf("MarkAsComplete: %v", err)
+	}
+	got, err := ts.GetByID(created.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Write the Q3 report" || !got.Done {
+		t.Errorf("todo = %q done %v, want the rename kept and done set", got.Name, got.Done)
+	}
+}
+
+func TestToDoServiceSaveChangesWritesOnlyChangedColumns(t *testing.T) {
+	sc := newTestServices(t)
+	created := createTodo(t, sc, "Work", "Write report")
+	ts := sc.ToDoService
+
+	todo, err := ts.load(created.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := *todo
+	todo.Priority = enums.Urgent
+
+	if got := todoChanges(&before, todo); len(got) != 1 || got["priority"] != enums.Urgent {
+		t.Errorf("changes = %v, want only the priority", got)
+	}
+	if err := ts.saveChanges(&before, todo); err != nil {
+		t.Fatalf("saveChanges: %v", err)
+	}
+	if todo.UpdatedAt.Equal(before.UpdatedAt) {
+		t.Errorf("updated_at was not moved on")
+	}
+
+	// Nothing changed, nothing written, no conflict
+	same := *todo
+	if err := ts.saveChanges(todo, &same); err != nil {
+		t.Errorf("saving no changes = %v", err)
+	}
+}
+
+func TestToDoServiceUpdateRefusesStaleCopies(t *testing.T) {
+	sc := newTestServices(t)
+	created := createTodo(t, sc, "Work", "Write report")
+	ts := sc.ToDoService
+
+	stale, err := ts.load(created.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.UpdateStatus(created.ID, enums.OnHold); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+
+	stale.Name = "Write the Q3 report"
+	if err := ts.Update(stale); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Update of a stale copy = %v, want ErrConflict", err)
+	}
+
+	fresh, err := ts.load(created.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh.Name = "Write the Q3 report"
+	if err := ts.Update(fresh); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err := ts.GetByID(created.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Write the Q3 report" || got.Status != enums.OnHold {
+		t.Errorf("todo = %q %v, want the rename and the status", got.Name, got.Status)
+	}
+}
+
+func TestToDoServiceSaveRetryingWritesOverNewerVersions(t *testing.T) {
+	sc := newTestServices(t)
+	created := createTodo(t, sc, "Work", "Write report")
+	ts := sc.ToDoService
+
+	todo, err := ts.load(created.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := *todo
+	todo.Done = true
+
+	// Someone else saves in between the read and the write
+	if err := ts.UpdateStatus(created.ID, enums.OnHold); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+
+	if err := ts.saveRetrying(&before, todo); err != nil {
+		t.Fatalf("saveRetrying: %v", err)
+	}
+	got, err := ts.GetByID(created.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Done || got.Status != enums.OnHold {
+		t.Errorf("todo done %v status %v, want done and the other writer's status", got.Done, got.Status)
+	}
+}
+
+func TestToDoServiceCheckedCompleteRefusesOldVersions(t *testing.T) {
+	sc := newTestServices(t)
+	created := createTodo(t, sc, "Work", "Write report")
+	ts := sc.ToDoService
+
+	old := Version(created.UpdatedAt)
+	if err := ts.UpdateStatus(created.ID, enums.OnHold); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+
+	if err := ts.MarkAsCompleteChecked(created.ID, old); !errors.Is(err, ErrConflict) {
+		t.Fatalf("complete at an old version = %v, want ErrConflict", err)
+	}
+
+	current, err := ts.GetByID(created.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Done {
+		t.Fatalf("refused complete marked the todo done")
+	}
+	if err := ts.MarkAsCompleteChecked(created.ID, Version(current.UpdatedAt)); err != nil {
+		t.Fatalf("complete at the current version: %v", err)
+	}
+	if err := ts.MarkAsIncompleteChecked(created.ID, ""); err != nil {
+		t.Fatalf("reopen without a version: %v", err)
+	}
+}
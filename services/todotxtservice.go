@@ -0,0 +1,310 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"tuidoo/config"
+	"tuidoo/entities"
+	"tuidoo/formats"
+)
+
+// todoTxtDebounce is how long a sync waits for more changes, so a burst of
+// them is written once
+const todoTxtDebounce = 200 * time.Millisecond
+
+// TodoTxtService keeps a todo.txt file in step with the database. Changes
+// made in tuidoo rewrite the file; edits made to the file by other tools
+// are imported before the next write, so neither side is clobbered.
+type TodoTxtService struct {
+	todos    *ToDoService
+	transfer *TransferService
+	events   *EventBus
+	config   config.TodoTxtConfig
+
+	// mu lets one sync run at a time
+	mu sync.Mutex
+
+	unsubscribe func()
+	wake        chan struct{}
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+// todoTxtState is what the last sync wrote
+type todoTxtState struct {
+	File string   `json:"file"`
+	Hash string   `json:"hash"`
+	UIDs []string `json:"uids"`
+}
+
+func NewTodoTxtService(todoService *ToDoService, transferService *TransferService, events *EventBus, todoTxtConfig config.TodoTxtConfig) *TodoTxtService {
+	return &TodoTxtService{
+		todos:    todoService,
+		transfer: transferService,
+		events:   events,
+		config:   todoTxtConfig,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Start syncs in the background after changes until Close is called.
+// Publishers never wait for the file, and a change made while a sync runs
+// gets a sync of its own once that one is done.
+func (s *TodoTxtService) Start() {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	s.unsubscribe = s.events.Subscribe(s.request)
+
+	go s.run()
+}
+
+// Close stops the background sync, first writing changes still waiting
+// for one
+func (s *TodoTxtService) Close() {
+	if s.stop == nil {
+		return
+	}
+
+	s.unsubscribe()
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+}
+
+// request asks the worker for a sync. Lines carry the project and list
+// names, so renames rewrite the file too; only the theme and notices are
+// not part of it. Other processes sync their own changes.
+func (s *TodoTxtService) request(e Event) {
+	switch e.(type) {
+	case ThemeChanged, ExternalChange, HookFailed, RuleFired:
+		return
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+		// A sync is already pending and will see this change
+	}
+}
+
+func (s *TodoTxtService) run() {
+	defer close(s.done)
+
+	for {
+		select {
+		case <-s.stop:
+			select {
+			case <-s.wake:
+				s.syncLogged()
+			default:
+			}
+			return
+		case <-s.wake:
+		}
+
+		timer := time.NewTimer(todoTxtDebounce)
+		select {
+		case <-s.stop:
+			timer.Stop()
+			s.syncLogged()
+			return
+		case <-timer.C:
+		}
+
+		// Changes up to here are part of this sync; later ones wake the
+		// loop again
+		select {
+		case <-s.wake:
+		default:
+		}
+		s.syncLogged()
+	}
+}
+
+func (s *TodoTxtService) syncLogged() {
+	if err := s.Sync(); err != nil {
+		log.Printf("⚠️  todo.txt sync failed: %v", err)
+	}
+}
+
+// Enabled reports whether sync on every change is configured for the profile
+func (s *TodoTxtService) Enabled() bool {
+	return s.config.Sync && s.config.File != ""
+}
+
+// File returns the synced todo.txt path
+func (s *TodoTxtService) File() string {
+	return s.config.File
+}
+
+// Sync imports edits made to the file since the last sync, then rewrites
+// the file from the database. Lines removed from the file delete their todo.
+// It waits for a sync already running.
+func (s *TodoTxtService) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.config.File == "" {
+		return errors.New("no todo.txt file configured (set todotxt.file in the config)")
+	}
+
+	state := s.loadState()
+
+	if err := s.pull(state); err != nil {
+		return err
+	}
+
+	return s.push()
+}
+
+func (s *TodoTxtService) pull(state *todoTxtState) error {
+	data, err := os.ReadFile(s.config.File)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", s.config.File, err)
+	}
+
+	if state != nil && hashBytes(data) == state.Hash {
+		return nil
+	}
+
+	records, err := formats.ReadTodoTxt(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	// Rewriting the file would drop lines we could not read, so stop here
+	for _, record := range records {
+		if record.Err != nil {
+			return fmt.Errorf("%s:%d: %w", s.config.File, record.Line, record.Err)
+		}
+	}
+
+	report, err := s.transfer.ImportRecords(records, RecordImportOptions{DefaultProject: s.config.Project})
+	if err != nil {
+		return err
+	}
+	if len(report.Errors) > 0 {
+		e := report.Errors[0]
+		return fmt.Errorf("%s:%d: %s", s.config.File, e.Line, e.Error)
+	}
+
+	if state == nil {
+		return nil
+	}
+
+	present := map[string]bool{}
+	for _, record := range records {
+		present[record.UID] = true
+	}
+
+	for _, uid := range state.UIDs {
+		if present[uid] {
+			continue
+		}
+
+		var todo entities.ToDo
+		found, err := findByUID(s.todos.db.GetDB(), &todo, uid)
+		if err != nil {
+			return err
+		}
+		if found {
+			if err := s.todos.Delete(todo.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *TodoTxtService) push() error {
+	todos, err := s.todos.Find(ToDoFilter{})
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := formats.WriteTodoTxt(&buf, todos); err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(s.config.File, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", s.config.File, err)
+	}
+
+	state := todoTxtState{File: s.config.File, Hash: hashBytes(buf.Bytes()), UIDs: make([]string, 0, len(todos))}
+	for _, todo := range todos {
+		state.UIDs = append(state.UIDs, todo.UID)
+	}
+
+	return s.saveState(&state)
+}
+
+// loadState returns nil before the first sync with the configured file
+func (s *TodoTxtService) loadState() *todoTxtState {
+	data, err := os.ReadFile(s.config.State)
+	if err != nil {
+		return nil
+	}
+
+	var state todoTxtState
+	if err := json.Unmarshal(data, &state); err != nil || state.File != s.config.File {
+		return nil
+	}
+	return &state
+}
+
+func (s *TodoTxtService) saveState(state *todoTxtState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.config.State, data)
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeFileAtomic replaces a file via a temporary file and rename so readers
+// never see a partial write
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if info, err := os.Stat(path); err == nil {
+		tmp.Chmod(info.Mode().Perm())
+	} else {
+		tmp.Chmod(0o644)
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
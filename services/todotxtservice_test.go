@@ -0,0 +1,196 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"tuidoo/config"
+	"tuidoo/entities"
+)
+
+func newTodoTxtServices(t *testing.T) (*ServiceCollection, string) {
+	t.Helper()
+
+	file, configure := todoTxtConfig(t)
+	return newTestServices(t, configure), file
+}
+
+// todoTxtConfig syncs a todo.txt file in a temporary directory
+func todoTxtConfig(t *testing.T) (string, func(*config.Selection)) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "todo.txt")
+	return file, func(sel *config.Selection) {
+		sel.TodoTxt = config.TodoTxtConfig{
+			File:    file,
+			Sync:    true,
+			Project: "Inbox",
+			State:   filepath.Join(dir, "todotxt.json"),
+		}
+	}
+}
+
+func createTodo(t *testing.T, sc *ServiceCollection, project, name string) *entities.ToDo {
+	t.Helper()
+
+	p, err := sc.ProjectService.GetByName(project)
+	if err != nil {
+		p = &entities.Project{Name: project}
+		if err := sc.ProjectService.Create(p); err != nil {
+			t.Fatalf("create project: %v", err)
+		}
+	}
+
+	todo := &entities.ToDo{ProjectID: p.ID, Name: name}
+	if err := sc.ToDoService.Create(todo); err != nil {
+		t.Fatalf("create todo: %v", err)
+	}
+	return todo
+}
+
+// waitForFile waits for the synced file to contain every want
+func waitForFile(t *testing.T, file string, want ...string) string {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, _ := os.ReadFile(file)
+		missing := ""
+		for _, w := range want {
+			if !strings.Contains(string(data), w) {
+				missing = w
+				break
+			}
+		}
+		if missing == "" {
+			return string(data)
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s never got %q:\n%s", file, missing, data)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestTodoTxtSyncsChanges(t *testing.T) {
+	sc, file := newTodoTxtServices(t)
+
+	createTodo(t, sc, "Work", "Write report")
+	createTodo(t, sc, "Work", "Call Anna")
+	waitForFile(t, file, "Write report +Work", "Call Anna +Work")
+}
+
+func TestTodoTxtKeepsChangesMadeDuringASync(t *testing.T) {
+	sc, file := newTodoTxtServices(t)
+
+	// Hold the sync lock as a running sync would; the change must still be
+	// written once it is released
+	sc.TodoTxtService.mu.Lock()
+	createTodo(t, sc, "Work", "Made mid-sync")
+	time.Sleep(2 * todoTxtDebounce)
+	sc.TodoTxtService.mu.Unlock()
+
+	createTodo(t, sc, "Work", "Made after")
+	waitForFile(t, file, "Made mid-sync", "Made after")
+}
+
+func TestTodoTxtPublishersDoNotWait(t *testing.T) {
+	sc, _ := newTodoTxtServices(t)
+
+	sc.TodoTxtService.mu.Lock()
+	defer sc.TodoTxtService.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		createTodo(t, sc, "Work", "Not blocked")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("creating a todo waited for the todo.txt sync")
+	}
+}
+
+func TestTodoTxtPullsFileEdits(t *testing.T) {
+	sc, file := newTodoTxtServices(t)
+
+	keep := createTodo(t, sc, "Work", "Keep me")
+	drop := createTodo(t, sc, "Work", "Drop me")
+	data := waitForFile(t, file, "Keep me", "Drop me")
+
+	// Another tool removes a line and adds one
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
+		if !strings.Contains(line, "uid:"+drop.UID) {
+			lines = append(lines, line)
+		}
+	}
+	lines = append(lines, "(A) Added elsewhere +Home due:2026-05-01")
+	if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sc.TodoTxtService.Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	if _, err := sc.ToDoService.GetByID(keep.ID, false); err != nil {
+		t.Errorf("kept todo: %v", err)
+	}
+	if _, err := sc.ToDoService.GetByID(drop.ID, false); err == nil {
+		t.Errorf("todo removed from the file was not deleted")
+	}
+
+	added, err := sc.ToDoService.Find(ToDoFilter{Project: "Home"})
+	if err != nil || len(added) != 1 || added[0].Name != "Added elsewhere" {
+		t.Fatalf("added todo = %+v, %v", added, err)
+	}
+
+	// The file now carries the new todo's uid, so the next sync keeps it
+	waitForFile(t, file, "uid:"+added[0].UID)
+	if err := sc.TodoTxtService.Sync(); err != nil {
+		t.Fatalf("second Sync: %v", err)
+	}
+	if n, _ := sc.ToDoService.Count(); n != 2 {
+		t.Errorf("%d todos after a second sync, want 2", n)
+	}
+}
+
+func TestTodoTxtCloseWritesPendingChanges(t *testing.T) {
+	sc, file := newTodoTxtServices(t)
+
+	createTodo(t, sc, "Work", "Last change")
+	sc.TodoTxtService.Close()
+
+	data, _ := os.ReadFile(file)
+	if !strings.Contains(string(data), "Last change") {
+		t.Errorf("Close did not write the pending change:\n%s", data)
+	}
+}
+
+func TestTodoTxtCommandSyncsOnlyItsChanges(t *testing.T) {
+	file, configure := todoTxtConfig(t)
+	if err := os.WriteFile(file, []byte("Written elsewhere\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sc := newCommandTestServices(t, configure)
+	time.Sleep(2 * todoTxtDebounce)
+	if n, _ := sc.ToDoService.Count(); n != 0 {
+		t.Errorf("%d todos before the command changed anything, want 0", n)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "Written elsewhere\n" {
+		t.Errorf("file was rewritten on start:\n%s", data)
+	}
+
+	createTodo(t, sc, "Work", "From the command")
+	sc.Close()
+
+	data, _ := os.ReadFile(file)
+	if !strings.Contains(string(data), "From the command") || !strings.Contains(string(data), "Written elsewhere") {
+		t.Errorf("Close did not sync the command's change:\n%s", data)
+	}
+}
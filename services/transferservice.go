@@ -0,0 +1,650 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	"tuidoo/entities"
+	"tuidoo/enums"
+
+	"gorm.io/gorm"
+)
+
+// SnapshotFormat identifies tuidoo JSON exports
+const SnapshotFormat = "tuidoo"
+
+// ImportMode controls how an import treats existing data
+type ImportMode string
+
+const (
+	// ImportMerge matches records by UID, updating changed ones and
+	// creating missing ones
+	ImportMerge ImportMode = "merge"
+	// ImportReplace deletes all existing data before importing
+	ImportReplace ImportMode = "replace"
+)
+
+// errDryRun rolls back the import transaction after a dry run
+var errDryRun = errors.New("dry run")
+
+// Snapshot is a full-fidelity export of a database. Records reference each
+// other by UID so snapshots can move between machines.
+type Snapshot struct {
+	Format        string             `json:"format"`
+	SchemaVersion int                `json:"schema_version"`
+	ExportedAt    time.Time          `json:"exported_at"`
+	Settings      *SnapshotSettings  `json:"settings,omitempty"`
+	Projects      []SnapshotProject  `json:"projects"`
+	Lists         []SnapshotToDoList `json:"lists"`
+	ToDos         []SnapshotToDo     `json:"todos"`
+}
+
+type SnapshotSettings struct {
+	ActiveThemeID string `json:"active_theme_id"`
+}
+
+type SnapshotProject struct {
+	UID       string     `json:"uid"`
+	Name      string     `json:"name"`
+	Color     string     `json:"color"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+type SnapshotToDoList struct {
+	UID       string     `json:"uid"`
+	Name      string     `json:"name"`
+	Color     string     `json:"color"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+type SnapshotToDo struct {
+	UID         string         `json:"uid"`
+	ProjectUID  string         `json:"project_uid"`
+	ListUID     string         `json:"list_uid,omitempty"`
+	Name        string         `json:"name"`
+	Description *string        `json:"description,omitempty"`
+	Details     *string        `json:"details,omitempty"`
+	Priority    enums.Priority `json:"priority"`
+	Status      enums.Status   `json:"status"`
+	Color       string         `json:"color"`
+	Done        bool           `json:"done"`
+	DueDate     *time.Time     `json:"due_date,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   *time.Time     `json:"deleted_at,omitempty"`
+}
+
+// ImportChange describes one record an import created, updated or deleted
+type ImportChange struct {
+	Kind   string   `json:"kind"`
+	Action string   `json:"action"`
+	UID    string   `json:"uid"`
+	Name   string   `json:"name"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+// ImportError is a record that was skipped because it could not be imported
+type ImportError struct {
+	Line  int    `json:"line"`
+	Name  string `json:"name,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportReport summarizes an import
+type ImportReport struct {
+	Mode      ImportMode     `json:"mode,omitempty"`
+	DryRun    bool           `json:"dry_run"`
+	Changes   []ImportChange `json:"changes"`
+	Unchanged int            `json:"unchanged"`
+	Errors    []ImportError  `json:"errors,omitempty"`
+}
+
+// Count returns how many changes have the given action
+func (r *ImportReport) Count(action string) int {
+	return r.CountKind("", action)
+}
+
+// CountKind returns how many changes of a kind of record, such as todo or
+// project, have the given action; an empty kind counts every kind
+func (r *ImportReport) CountKind(kind, action string) int {
+	n := 0
+	for _, c := range r.Changes {
+		if c.Action == action && (kind == "" || c.Kind == kind) {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *ImportReport) record(kind, action, uid, name string, fields ...string) {
+	r.Changes = append(r.Changes, ImportChange{Kind: kind, Action: action, UID: uid, Name: name, Fields: fields})
+}
+
+type TransferService struct {
+	db     *DbService
+	events *EventBus
+}
+
+// NewTransferService creates the import/export service. Imports publish
+// DataImported once they are committed.
+func NewTransferService(dbService *DbService, events *EventBus) *TransferService {
+	return &TransferService{db: dbService, events: events}
+}
+
+// Export captures every project, list, todo and the settings, including
+// soft-deleted records
+func (ts *TransferService) Export() (*Snapshot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Session makes the unscoped handle safe to reuse across queries
+	db := ts.db.GetDB().WithContext(ctx).Unscoped().Session(&gorm.Session{})
+
+	version, err := NewMigrationService(ts.db).CurrentVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{
+		Format:        SnapshotFormat,
+		SchemaVersion: version,
+		ExportedAt:    time.Now().UTC(),
+		Projects:      []SnapshotProject{},
+		Lists:         []SnapshotToDoList{},
+		ToDos:         []SnapshotToDo{},
+	}
+
+	var settings entities.Settings
+	if err := db.Order("id").First(&settings).Error; err == nil {
+		snapshot.Settings = &SnapshotSettings{ActiveThemeID: settings.ActiveThemeID}
+	}
+
+	var projects []entities.Project
+	if err := db.Order("id").Find(&projects).Error; err != nil {
+		return nil, fmt.Errorf("failed to export projects: %w", err)
+	}
+	projectUIDs := map[uint]string{}
+	for _, p := range projects {
+		projectUIDs[p.ID] = p.UID
+		snapshot.Projects = append(snapshot.Projects, SnapshotProject{
+			UID:       p.UID,
+			Name:      p.Name,
+			Color:     p.Color,
+			CreatedAt: p.CreatedAt,
+			UpdatedAt: p.UpdatedAt,
+			DeletedAt: deletedAt(p.DeletedAt),
+		})
+	}
+
+	var lists []entities.ToDoList
+	if err := db.Order("id").Find(&lists).Error; err != nil {
+		return nil, fmt.Errorf("failed to export lists: %w", err)
+	}
+	listUIDs := map[uint]string{}
+	for _, l := range lists {
+		listUIDs[l.ID] = l.UID
+		snapshot.Lists = append(snapshot.Lists, SnapshotToDoList{
+			UID:       l.UID,
+			Name:      l.Name,
+			Color:     l.Color,
+			CreatedAt: l.CreatedAt,
+			UpdatedAt: l.UpdatedAt,
+			DeletedAt: deletedAt(l.DeletedAt),
+		})
+	}
+
+	var todos []entities.ToDo
+	if err := db.Order("id").Find(&todos).Error; err != nil {
+		return nil, fmt.Errorf("failed to export todos: %w", err)
+	}
+	for _, t := range todos {
+		snapshot.ToDos = append(snapshot.ToDos, SnapshotToDo{
+			UID:         t.UID,
+			ProjectUID:  projectUIDs[t.ProjectID],
+			ListUID:     listUIDs[t.ToDoListID],
+			Name:        t.Name,
+			Description: t.Description,
+			Details:     t.Details,
+			Priority:    t.Priority,
+			Status:      t.Status,
+			Color:       t.Color,
+			Done:        t.Done,
+			DueDate:     t.DueDate,
+			CreatedAt:   t.CreatedAt,
+			UpdatedAt:   t.UpdatedAt,
+			DeletedAt:   deletedAt(t.DeletedAt),
+		})
+	}
+
+	return snapshot, nil
+}
+
+// Import applies a snapshot in a single transaction. With dryRun set the
+// transaction is rolled back and the report describes what would change.
+func (ts *TransferService) Import(snapshot *Snapshot, mode ImportMode, dryRun bool) (*ImportReport, error) {
+	if err := validateSnapshot(snapshot); err != nil {
+		return nil, err
+	}
+	if mode != ImportMerge && mode != ImportReplace {
+		return nil, fmt.Errorf("unknown import mode %q (expected merge or replace)", mode)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	report := &ImportReport{Mode: mode, DryRun: dryRun, Changes: []ImportChange{}}
+
+	err := ts.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tx = tx.Unscoped().Session(&gorm.Session{})
+
+		if mode == ImportReplace {
+			if err := replaceAll(tx, report); err != nil {
+				return err
+			}
+		}
+
+		if err := importSettings(tx, snapshot.Settings, report); err != nil {
+			return err
+		}
+
+		projectIDs, err := importProjects(tx, snapshot.Projects, report)
+		if err != nil {
+			return err
+		}
+
+		listIDs, err := importLists(tx, snapshot.Lists, report)
+		if err != nil {
+			return err
+		}
+
+		if err := importToDos(tx, snapshot.ToDos, projectIDs, listIDs, report); err != nil {
+			return err
+		}
+
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, fmt.Errorf("import failed: %w", err)
+	}
+
+	if !dryRun {
+		ts.events.Publish(DataImported{})
+	}
+
+	return report, nil
+}
+
+func validateSnapshot(snapshot *Snapshot) error {
+	if snapshot == nil {
+		return errors.New("empty export")
+	}
+	if snapshot.Format != SnapshotFormat {
+		return fmt.Errorf("not a tuidoo export (format %q)", snapshot.Format)
+	}
+
+	if snapshot.SchemaVersion > LatestSchemaVersion() {
+		return fmt.Errorf("%w (export is at version %d)", ErrSchemaTooNew, snapshot.SchemaVersion)
+	}
+
+	seen := map[string]bool{}
+	check := func(kind, uid string) error {
+		if uid == "" {
+			return fmt.Errorf("%s without uid", kind)
+		}
+		if seen[kind+uid] {
+			return fmt.Errorf("duplicate %s uid %s", kind, uid)
+		}
+		seen[kind+uid] = true
+		return nil
+	}
+
+	for _, p := range snapshot.Projects {
+		if err := check("project", p.UID); err != nil {
+			return err
+		}
+	}
+	for _, l := range snapshot.Lists {
+		if err := check("list", l.UID); err != nil {
+			return err
+		}
+	}
+	for _, t := range snapshot.ToDos {
+		if err := check("todo", t.UID); err != nil {
+			return err
+		}
+		if !seen["project"+t.ProjectUID] {
+			return fmt.Errorf("todo %s references unknown project %q", t.UID, t.ProjectUID)
+		}
+		if t.ListUID != "" && !seen["list"+t.ListUID] {
+			return fmt.Errorf("todo %s references unknown list %q", t.UID, t.ListUID)
+		}
+	}
+
+	return nil
+}
+
+func replaceAll(tx *gorm.DB, report *ImportReport) error {
+	var todos []entities.ToDo
+	var lists []entities.ToDoList
+	var projects []entities.Project
+
+	if err := tx.Find(&todos).Error; err != nil {
+		return err
+	}
+	if err := tx.Find(&lists).Error; err != nil {
+		return err
+	}
+	if err := tx.Find(&projects).Error; err != nil {
+		return err
+	}
+
+	for _, t := range todos {
+		report.record("todo", "delete", t.UID, t.Name)
+	}
+	for _, l := range lists {
+		report.record("list", "delete", l.UID, l.Name)
+	}
+	for _, p := range projects {
+		report.record("project", "delete", p.UID, p.Name)
+	}
+
+	global := tx.Session(&gorm.Session{AllowGlobalUpdate: true})
+	if err := global.Delete(&entities.ToDo{}).Error; err != nil {
+		return fmt.Errorf("failed to delete todos: %w", err)
+	}
+	if err := global.Delete(&entities.ToDoList{}).Error; err != nil {
+		return fmt.Errorf("failed to delete lists: %w", err)
+	}
+	if err := global.Delete(&entities.Project{}).Error; err != nil {
+		return fmt.Errorf("failed to delete projects: %w", err)
+	}
+
+	return nil
+}
+
+func importSettings(tx *gorm.DB, snapshot *SnapshotSettings, report *ImportReport) error {
+	if snapshot == nil {
+		return nil
+	}
+
+	var settings entities.Settings
+	result := tx.Order("id").Limit(1).Find(&settings)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		report.record("settings", "create", "", "settings")
+		return tx.Create(&entities.Settings{ActiveThemeID: snapshot.ActiveThemeID}).Error
+	}
+
+	if settings.ActiveThemeID == snapshot.ActiveThemeID {
+		report.Unchanged++
+		return nil
+	}
+
+	report.record("settings", "update", "", "settings", "active_theme_id")
+	return tx.Model(&settings).UpdateColumn("active_theme_id", snapshot.ActiveThemeID).Error
+}
+
+func importProjects(tx *gorm.DB, projects []SnapshotProject, report *ImportReport) (map[string]uint, error) {
+	ids := map[string]uint{}
+
+	for _, sp := range projects {
+		var existing entities.Project
+		found, err := findByUID(tx, &existing, sp.UID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !found {
+			project := entities.Project{
+				UID:   sp.UID,
+				Name:  sp.Name,
+				Color: sp.Color,
+			}
+			project.CreatedAt = sp.CreatedAt
+			project.UpdatedAt = sp.UpdatedAt
+			project.DeletedAt = gormDeletedAt(sp.DeletedAt)
+
+			if err := tx.Create(&project).Error; err != nil {
+				return nil, fmt.Errorf("failed to create project %s: %w", sp.Name, err)
+			}
+			report.record("project", "create", sp.UID, sp.Name)
+			ids[sp.UID] = project.ID
+			continue
+		}
+
+		ids[sp.UID] = existing.ID
+
+		changes := map[string]any{}
+		if existing.Name != sp.Name {
+			changes["name"] = sp.Name
+		}
+		if existing.Color != sp.Color {
+			changes["color"] = sp.Color
+		}
+		if !sameDeletedAt(existing.DeletedAt, sp.DeletedAt) {
+			changes["deleted_at"] = gormDeletedAt(sp.DeletedAt)
+		}
+
+		if err := applyChanges(tx, &existing, changes, sp.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to update project %s: %w", sp.Name, err)
+		}
+		recordChanges(report, "project", sp.UID, sp.Name, changes)
+	}
+
+	return ids, nil
+}
+
+func importLists(tx *gorm.DB, lists []SnapshotToDoList, report *ImportReport) (map[string]uint, error) {
+	ids := map[string]uint{}
+
+	for _, sl := range lists {
+		var existing entities.ToDoList
+		found, err := findByUID(tx, &existing, sl.UID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !found {
+			list := entities.ToDoList{
+				UID:   sl.UID,
+				Name:  sl.Name,
+				Color: sl.Color,
+			}
+			list.CreatedAt = sl.CreatedAt
+			list.UpdatedAt = sl.UpdatedAt
+			list.DeletedAt = gormDeletedAt(sl.DeletedAt)
+
+			if err := tx.Create(&list).Error; err != nil {
+				return nil, fmt.Errorf("failed to create list %s: %w", sl.Name, err)
+			}
+			report.record("list", "create", sl.UID, sl.Name)
+			ids[sl.UID] = list.ID
+			continue
+		}
+
+		ids[sl.UID] = existing.ID
+
+		changes := map[string]any{}
+		if existing.Name != sl.Name {
+			changes["name"] = sl.Name
+		}
+		if existing.Color != sl.Color {
+			changes["color"] = sl.Color
+		}
+		if !sameDeletedAt(existing.DeletedAt, sl.DeletedAt) {
+			changes["deleted_at"] = gormDeletedAt(sl.DeletedAt)
+		}
+
+		if err := applyChanges(tx, &existing, changes, sl.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to update list %s: %w", sl.Name, err)
+		}
+		recordChanges(report, "list", sl.UID, sl.Name, changes)
+	}
+
+	return ids, nil
+}
+
+func importToDos(tx *gorm.DB, todos []SnapshotToDo, projectIDs, listIDs map[string]uint, report *ImportReport) error {
+	for _, st := range todos {
+		projectID := projectIDs[st.ProjectUID]
+		listID := listIDs[st.ListUID]
+
+		var existing entities.ToDo
+		found, err := findByUID(tx, &existing, st.UID)
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			todo := entities.ToDo{
+				UID:         st.UID,
+				ProjectID:   projectID,
+				ToDoListID:  listID,
+				Name:        st.Name,
+				Description: st.Description,
+				Details:     st.Details,
+				Priority:    st.Priority,
+				Status:      st.Status,
+				Color:       st.Color,
+				Done:        st.Done,
+				DueDate:     st.DueDate,
+			}
+			todo.CreatedAt = st.CreatedAt
+			todo.UpdatedAt = st.UpdatedAt
+			todo.DeletedAt = gormDeletedAt(st.DeletedAt)
+
+			if err := tx.Omit("Project", "ToDoList").Create(&todo).Error; err != nil {
+				return fmt.Errorf("failed to create todo %s: %w", st.Name, err)
+			}
+			report.record("todo", "create", st.UID, st.Name)
+			continue
+		}
+
+		changes := map[string]any{}
+		if existing.ProjectID != projectID {
+			changes["project_id"] = projectID
+		}
+		if existing.ToDoListID != listID {
+			changes["to_do_list_id"] = listID
+		}
+		if existing.Name != st.Name {
+			changes["name"] = st.Name
+		}
+		if !sameString(existing.Description, st.Description) {
+			changes["description"] = st.Description
+		}
+		if !sameString(existing.Details, st.Details) {
+			changes["details"] = st.Details
+		}
+		if existing.Priority != st.Priority {
+			changes["priority"] = st.Priority
+		}
+		if existing.Status != st.Status {
+			changes["status"] = st.Status
+		}
+		if existing.Color != st.Color {
+			changes["color"] = st.Color
+		}
+		if existing.Done != st.Done {
+			changes["done"] = st.Done
+		}
+		if !sameTime(existing.DueDate, st.DueDate) {
+			changes["due_date"] = st.DueDate
+		}
+		if !sameDeletedAt(existing.DeletedAt, st.DeletedAt) {
+			changes["deleted_at"] = gormDeletedAt(st.DeletedAt)
+		}
+
+		if err := applyChanges(tx, &existing, changes, st.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to update todo %s: %w", st.Name, err)
+		}
+		recordChanges(report, "todo", st.UID, st.Name, changes)
+	}
+
+	return nil
+}
+
+// findByUID loads the record with the given UID, including soft-deleted ones
+func findByUID(tx *gorm.DB, model any, uid string) (bool, error) {
+	result := tx.Where("uid = ?", uid).Limit(1).Find(model)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// applyChanges writes changed columns without hooks so the exported
+// updated_at is preserved
+func applyChanges(tx *gorm.DB, model any, changes map[string]any, updatedAt time.Time) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	columns := make(map[string]any, len(changes)+1)
+	for k, v := range changes {
+		columns[k] = v
+	}
+	columns["updated_at"] = updatedAt
+
+	return tx.Model(model).UpdateColumns(columns).Error
+}
+
+func recordChanges(report *ImportReport, kind, uid, name string, changes map[string]any) {
+	if len(changes) == 0 {
+		report.Unchanged++
+		return
+	}
+
+	fields := make([]string, 0, len(changes))
+	for field := range changes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	report.record(kind, "update", uid, name, fields...)
+}
+
+func deletedAt(d gorm.DeletedAt) *time.Time {
+	if !d.Valid {
+		return nil
+	}
+	t := d.Time
+	return &t
+}
+
+func gormDeletedAt(t *time.Time) gorm.DeletedAt {
+	if t == nil {
+		return gorm.DeletedAt{}
+	}
+	return gorm.DeletedAt{Time: *t, Valid: true}
+}
+
+func sameDeletedAt(d gorm.DeletedAt, t *time.Time) bool {
+	return sameTime(deletedAt(d), t)
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+func sameString(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"tuidoo/enums"
+	"tuidoo/formats"
+)
+
+const sampleCSV = `name,project,list,priority,status,due,done
+Write report,Work,Reports,high,in progress,2026-03-01,false
+Call Anna,Work,,low,new,,false
+Buy milk,Home,Errands,,,,true
+Buy milk,Home,Errands,,,,false
+`
+
+func readCSV(t *testing.T, s string) []formats.Record {
+	t.Helper()
+	records, err := formats.ReadCSV(strings.NewReader(s), nil)
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	return records
+}
+
+func exportCSV(t *testing.T, sc *ServiceCollection) string {
+	t.Helper()
+	todos, err := sc.ToDoService.Find(ToDoFilter{})
+	if err != nil {
+		t.Fatalf("find todos: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := formats.WriteCSV(&buf, todos, formats.CSVColumns); err != nil {
+		t.Fatalf("write csv: %v", err)
+	}
+	return buf.String()
+}
+
+func countTodos(t *testing.T, sc *ServiceCollection) int64 {
+	t.Helper()
+	n, err := sc.ToDoService.Count()
+	if err != nil {
+		t.Fatalf("count todos: %v", err)
+	}
+	return n
+}
+
+func TestImportRecordsIsIdempotent(t *testing.T) {
+	tests := []struct {
+		name string
+		// reimport is what gets imported the second time
+		reimport func(t *testing.T, sc *ServiceCollection) []formats.Record
+	}{
+		{
+			name: "exported csv with uids",
+			reimport: func(t *testing.T, sc *ServiceCollection) []formats.Record {
+				return readCSV(t, exportCSV(t, sc))
+			},
+		},
+		{
+			name: "same csv without uids",
+			reimport: func(t *testing.T, sc *ServiceCollection) []formats.Record {
+				return readCSV(t, sampleCSV)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := newTestServices(t)
+
+			report, err := sc.TransferService.ImportRecords(readCSV(t, sampleCSV), RecordImportOptions{})
+			if err != nil {
+				t.Fatalf("first import: %v", err)
+			}
+			todos, projects, lists := report.CountKind("todo", "create"), report.CountKind("project", "create"), report.CountKind("list", "create")
+			if todos != 4 || projects != 2 || lists != 2 || report.Count("create") != 8 {
+				t.Fatalf("first import created %d todos, %d projects and %d lists, want 4, 2 and 2", todos, projects, lists)
+			}
+
+			report, err = sc.TransferService.ImportRecords(tt.reimport(t, sc), RecordImportOptions{})
+			if err != nil {
+				t.Fatalf("re-import: %v", err)
+			}
+			if len(report.Changes) != 0 || report.Unchanged != 4 || len(report.Errors) != 0 {
+				t.Errorf("re-import = %+v, want 4 unchanged todos", report)
+			}
+			if n := countTodos(t, sc); n != 4 {
+				t.Errorf("%d todos after re-import, want 4", n)
+			}
+		})
+	}
+}
+
+func TestImportRecordsMergesByName(t *testing.T) {
+	sc := newTestServices(t)
+
+	if _, err := sc.TransferService.ImportRecords(readCSV(t, sampleCSV), RecordImportOptions{}); err != nil {
+		t.Fatalf("first import: %v", err)
+	}
+
+	// Matching ignores the case of project and list names but not of the
+	// todo name
+	report, err := sc.TransferService.ImportRecords(readCSV(t, `name,project,list,priority
+Write report,work,REPORTS,low
+write report,Work,Reports,low
+`), RecordImportOptions{})
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if got, want := report.Count("update"), 1; got != want {
+		t.Errorf("updated %d todos, want %d", got, want)
+	}
+	if got, want := report.Count("create"), 1; got != want {
+		t.Errorf("created %d todos, want %d", got, want)
+	}
+
+	todos, err := sc.ToDoService.Find(ToDoFilter{Search: "Write report"})
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	for _, todo := range todos {
+		if todo.Name == "Write report" && (todo.Priority != enums.Low || todo.Status != enums.InProgress) {
+			t.Errorf("merged todo = priority %v status %v, want low and the status kept", todo.Priority, todo.Status)
+		}
+	}
+}
+
+func TestImportRecordsDryRun(t *testing.T) {
+	sc := newTestServices(t)
+
+	report, err := sc.TransferService.ImportRecords(readCSV(t, sampleCSV), RecordImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if got := report.Count("create"); got != 8 {
+		t.Errorf("dry run reported %d creates, want 8", got)
+	}
+	if n := countTodos(t, sc); n != 0 {
+		t.Errorf("dry run wrote %d todos", n)
+	}
+}
+
+func TestImportSnapshot(t *testing.T) {
+	sc := newTestServices(t)
+
+	if _, err := sc.TransferService.ImportRecords(readCSV(t, sampleCSV), RecordImportOptions{}); err != nil {
+		t.Fatalf("import csv: %v", err)
+	}
+	snapshot, err := sc.TransferService.Export()
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	tests := []struct {
+		mode   ImportMode
+		dryRun bool
+	}{
+		{ImportMerge, true},
+		{ImportMerge, false},
+		{ImportReplace, true},
+		{ImportReplace, false},
+	}
+
+	for _, tt := range tests {
+		report, err := sc.TransferService.Import(snapshot, tt.mode, tt.dryRun)
+		if err != nil {
+			t.Fatalf("%s import (dry run %v): %v", tt.mode, tt.dryRun, err)
+		}
+		if tt.mode == ImportMerge && report.Count("create")+report.Count("update") != 0 {
+			t.Errorf("merging an unchanged snapshot changed %+v", report.Changes)
+		}
+		if n := countTodos(t, sc); n != 4 {
+			t.Errorf("%s import (dry run %v) left %d todos, want 4", tt.mode, tt.dryRun, n)
+		}
+	}
+
+	if after, err := sc.TransferService.Export(); err != nil {
+		t.Fatalf("export: %v", err)
+	} else if len(after.ToDos) != len(snapshot.ToDos) || after.ToDos[0].UID != snapshot.ToDos[0].UID {
+		t.Errorf("replace import did not restore the snapshot")
+	}
+}
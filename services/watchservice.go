@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// watchInterval is how often the database is checked for commits made
+	// by other processes
+	watchInterval = time.Second
+	// watchBulkLimit is how many rows may change in one go before they are
+	// reported as a single DataImported instead of one event per row
+	watchBulkLimit = 50
+)
+
+// watchedTable maps row changes in a table onto events
+type watchedTable struct {
+	name                      string
+	created, updated, deleted func(id uint) Event
+}
+
+var watchedTables = []watchedTable{
+	{
+		name:    "to_dos",
+		created: func(id uint) Event { return TodoCreated{ID: id} },
+		updated: func(id uint) Event { return TodoUpdated{ID: id} },
+		deleted: func(id uint) Event { return TodoDeleted{ID: id} },
+	},
+	{
+		name:    "projects",
+		created: func(id uint) Event { return ProjectCreated{ID: id} },
+		updated: func(id uint) Event { return ProjectUpdated{ID: id} },
+		deleted: func(id uint) Event { return ProjectDeleted{ID: id} },
+	},
+	{
+		name:    "to_do_lists",
+		created: func(id uint) Event { return ListCreated{ID: id} },
+		updated: func(id uint) Event { return ListUpdated{ID: id} },
+		deleted: func(id uint) Event { return ListDeleted{ID: id} },
+	},
+}
+
+// WatchService notices changes other processes commit to the database and
+// publishes them as ExternalChange events. It polls SQLite's data_version,
+// which only moves when another connection commits, and then diffs a
+// snapshot of row versions to find out what changed. Changes made by this
+// process reach the snapshot through the event bus, so they are not
+// reported twice.
+type WatchService struct {
+	db     *DbService
+	events *EventBus
+
+	mu       sync.Mutex
+	versions map[string]map[uint]time.Time
+
+	stopEvents func()
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+func NewWatchService(dbService *DbService, events *EventBus) *WatchService {
+	return &WatchService{db: dbService, events: events}
+}
+
+// Start watches the database in the background until Close is called.
+// Starting a running watcher does nothing.
+func (ws *WatchService) Start() error {
+	if ws.stop != nil {
+		return nil
+	}
+
+	sqlDB, err := ws.db.GetDB().DB()
+	if err != nil {
+		return err
+	}
+
+	// data_version is per connection, so the watcher keeps its own
+	conn, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to open watch connection: %w", err)
+	}
+
+	version, err := dataVersion(conn)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to read data version: %w", err)
+	}
+
+	versions := map[string]map[uint]time.Time{}
+	for _, table := range watchedTables {
+		if versions[table.name], err = ws.load(table.name); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+	ws.versions = versions
+
+	ws.stopEvents = ws.events.Subscribe(ws.track)
+	ws.stop = make(chan struct{})
+	ws.done = make(chan struct{})
+
+	go ws.run(conn, version)
+	return nil
+}
+
+// Close stops watching
+func (ws *WatchService) Close() {
+	if ws.stop == nil {
+		return
+	}
+
+	ws.stopEvents()
+	close(ws.stop)
+	<-ws.done
+	ws.stop = nil
+}
+
+func (ws *WatchService) run(conn *sql.Conn, version int64) {
+	defer close(ws.done)
+	defer conn.Close()
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ws.stop:
+			return
+		case <-ticker.C:
+		}
+
+		// Errors such as a busy database are retried on the next tick
+		current, err := dataVersion(conn)
+		if err != nil || current == version {
+			continue
+		}
+		if err := ws.diff(); err != nil {
+			continue
+		}
+		version = current
+	}
+}
+
+// diff compares the tables against the snapshot and publishes what other
+// processes changed
+func (ws *WatchService) diff() error {
+	current := map[string]map[uint]time.Time{}
+	for _, table := range watchedTables {
+		rows, err := ws.load(table.name)
+		if err != nil {
+			return err
+		}
+		current[table.name] = rows
+	}
+
+	var changes []Event
+
+	ws.mu.Lock()
+	for _, table := range watchedTables {
+		known, rows := ws.versions[table.name], current[table.name]
+
+		for id, updatedAt := range rows {
+			seen, ok := known[id]
+			switch {
+			case !ok:
+				changes = append(changes, table.created(id))
+			case updatedAt.After(seen):
+				changes = append(changes, table.updated(id))
+			case seen.After(updatedAt):
+				// This process saved the row after it was loaded above
+				rows[id] = seen
+			}
+		}
+		for id := range known {
+			if _, ok := rows[id]; !ok {
+				changes = append(changes, table.deleted(id))
+			}
+		}
+	}
+	ws.versions = current
+	ws.mu.Unlock()
+
+	if len(changes) > watchBulkLimit {
+		changes = []Event{DataImported{}}
+	}
+	for _, change := range changes {
+		ws.events.Publish(ExternalChange{Event: change})
+	}
+
+	return nil
+}
+
+// track records changes made by this process in the snapshot
+func (ws *WatchService) track(e Event) {
+	switch e.(type) {
+	case ExternalChange, ThemeChanged:
+		return
+	case DataImported, DataReset:
+		ws.reload()
+		return
+	}
+
+	table, id := eventRow(e)
+	if table == "" {
+		return
+	}
+
+	var rows []struct{ UpdatedAt time.Time }
+	err := ws.db.GetDB().Table(table).
+		Select("updated_at").
+		Where("id = ? AND deleted_at IS NULL", id).
+		Limit(1).
+		Find(&rows).Error
+	if err != nil {
+		return
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if len(rows) == 0 {
+		delete(ws.versions[table], id)
+		return
+	}
+	ws.versions[table][id] = rows[0].UpdatedAt
+}
+
+// reload takes a fresh snapshot after this process changed many rows
+func (ws *WatchService) reload() {
+	versions := map[string]map[uint]time.Time{}
+	for _, table := range watchedTables {
+		rows, err := ws.load(table.name)
+		if err != nil {
+			log.Printf("⚠️  Failed to reload watch snapshot: %v", err)
+			return
+		}
+		versions[table.name] = rows
+	}
+
+	ws.mu.Lock()
+	ws.versions = versions
+	ws.mu.Unlock()
+}
+
+// load reads the version of every live row in a table
+func (ws *WatchService) load(table string) (map[uint]time.Time, error) {
+	ctx, cancel := ws.db.NewContext()
+	defer cancel()
+
+	var rows []struct {
+		ID        uint
+		UpdatedAt time.Time
+	}
+	err := ws.db.GetDB().WithContext(ctx).Table(table).
+		Select("id, updated_at").
+		Where("deleted_at IS NULL").
+		Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", table, err)
+	}
+
+	versions := make(map[uint]time.Time, len(rows))
+	for _, row := range rows {
+		versions[row.ID] = row.UpdatedAt
+	}
+	return versions, nil
+}
+
+// eventRow returns the table and row an event is about
+func eventRow(e Event) (string, uint) {
+	if id, ok := TodoID(e); ok {
+		return "to_dos", id
+	}
+
+	switch e := e.(type) {
+	case ProjectCreated:
+		return "projects", e.ID
+	case ProjectUpdated:
+		return "projects", e.ID
+	case ProjectDeleted:
+		return "projects", e.ID
+	case ListCreated:
+		return "to_do_lists", e.ID
+	case ListUpdated:
+		return "to_do_lists", e.ID
+	case ListDeleted:
+		return "to_do_lists", e.ID
+	default:
+		return "", 0
+	}
+}
+
+func dataVersion(conn *sql.Conn) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var version int64
+	err := conn.QueryRowContext(ctx, "PRAGMA data_version").Scan(&version)
+	return version, err
+}
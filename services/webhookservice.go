@@ -0,0 +1,505 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+	"tuidoo/config"
+	"tuidoo/entities"
+	"tuidoo/enums"
+
+	"gorm.io/gorm"
+)
+
+// Delivery states of the webhook delivery log
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookFailed    = "failed"
+)
+
+const (
+	webhookTimeout = 10 * time.Second
+	// Retries wait 30s, 1m, 2m, ... up to an hour between attempts
+	webhookBaseDelay = 30 * time.Second
+	webhookMaxDelay  = time.Hour
+	// A delivery being sent is leased so another tuidoo process on the same
+	// database does not send it too; a crashed sender's lease runs out
+	webhookLease = 2 * time.Minute
+	// webhookPoll picks up deliveries queued by other processes
+	webhookPoll = time.Minute
+	// Delivered entries are pruned from the log after this long
+	webhookRetention = 30 * 24 * time.Hour
+	// webhookFlush bounds how long Close keeps sending due deliveries
+	webhookFlush = 5 * time.Second
+)
+
+// WebhookService POSTs task events to the configured webhooks and keeps a
+// delivery log in the database
+type WebhookService struct {
+	db      *DbService
+	events  *EventBus
+	profile string
+	hooks   []config.WebhookConfig
+	client  *http.Client
+
+	unsubscribe func()
+	wake        chan struct{}
+	stop        chan struct{}
+	done        chan struct{}
+	// queued is set once this process queued a delivery
+	queued atomic.Bool
+}
+
+// WebhookPayload is the JSON body sent to a webhook
+type WebhookPayload struct {
+	// ID identifies the delivery; it stays the same across retries and
+	// replays so receivers can drop duplicates
+	ID         string      `json:"id"`
+	Event      string      `json:"event"`
+	Webhook    string      `json:"webhook"`
+	Profile    string      `json:"profile"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	ToDo       WebhookToDo `json:"todo"`
+}
+
+// WebhookToDo is the task as it was when the event happened
+type WebhookToDo struct {
+	ID          uint           `json:"id"`
+	UID         string         `json:"uid,omitempty"`
+	Name        string         `json:"name,omitempty"`
+	Description *string        `json:"description,omitempty"`
+	Project     string         `json:"project,omitempty"`
+	List        string         `json:"list,omitempty"`
+	Priority    enums.Priority `json:"priority"`
+	Status      enums.Status   `json:"status"`
+	Done        bool           `json:"done"`
+	DueDate     *time.Time     `json:"due_date,omitempty"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+func NewWebhookService(dbService *DbService, events *EventBus, profile string, hooks []config.WebhookConfig) *WebhookService {
+	return &WebhookService{
+		db:      dbService,
+		events:  events,
+		profile: profile,
+		hooks:   hooks,
+		client:  &http.Client{Timeout: webhookTimeout},
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Enabled reports whether any webhook is configured
+func (ws *WebhookService) Enabled() bool {
+	return len(ws.hooks) > 0
+}
+
+// Start queues deliveries for todo changes and sends them in the background
+// until Close is called
+func (ws *WebhookService) Start() {
+	ws.stop = make(chan struct{})
+	ws.done = make(chan struct{})
+
+	ws.unsubscribe = ws.events.Subscribe(ws.enqueue)
+	ws.prune()
+
+	go ws.run()
+}
+
+// Record queues deliveries for todo changes without the background sender,
+// for one-shot commands. Close sends what is due only if something was
+// queued, so a command that changes nothing sends nothing.
+func (ws *WebhookService) Record() {
+	ws.unsubscribe = ws.events.Subscribe(ws.enqueue)
+}
+
+// Close stops the background sender and gives deliveries that are due a
+// last, short chance to go out
+func (ws *WebhookService) Close() {
+	if ws.unsubscribe == nil {
+		return
+	}
+
+	ws.unsubscribe()
+	ws.unsubscribe = nil
+	if ws.stop != nil {
+		close(ws.stop)
+		<-ws.done
+		ws.stop = nil
+	} else if !ws.queued.Load() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookFlush)
+	defer cancel()
+	ws.deliverDue(ctx)
+}
+
+// Deliveries lists the delivery log, newest first, optionally only in one state
+func (ws *WebhookService) Deliveries(state string, limit int) ([]entities.WebhookDelivery, error) {
+	query := ws.db.GetDB().Order("id DESC")
+	if state != "" {
+		query = query.Where("state = ?", state)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var deliveries []entities.WebhookDelivery
+	if err := query.Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Delivery returns one entry of the delivery log
+func (ws *WebhookService) Delivery(id uint) (*entities.WebhookDelivery, error) {
+	var deliveries []entities.WebhookDelivery
+	if err := ws.db.GetDB().Where("id = ?", id).Limit(1).Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	if len(deliveries) == 0 {
+		return nil, fmt.Errorf("webhook delivery %d %w", id, ErrNotFound)
+	}
+	return &deliveries[0], nil
+}
+
+// Replay sends a delivery again right away with the same payload, starting
+// a fresh series of attempts if it fails
+func (ws *WebhookService) Replay(id uint) (*entities.WebhookDelivery, error) {
+	delivery, err := ws.Delivery(id)
+	if err != nil {
+		return nil, err
+	}
+
+	hook, ok := ws.hook(delivery.Webhook)
+	if !ok {
+		return nil, fmt.Errorf("webhook %q is no longer configured", delivery.Webhook)
+	}
+
+	now := time.Now()
+	delivery.State = WebhookPending
+	delivery.Attempts = 0
+	delivery.MaxAttempts = hook.MaxAttempts
+	delivery.URL = hook.URL
+	delivery.NextAttemptAt = &now
+	if err := ws.db.GetDB().Save(delivery).Error; err != nil {
+		return nil, fmt.Errorf("failed to reset webhook delivery: %w", err)
+	}
+
+	if ws.claim(delivery.ID, now) {
+		ws.send(context.Background(), delivery)
+	}
+	return delivery, nil
+}
+
+// enqueue records a delivery for every webhook the event matches
+func (ws *WebhookService) enqueue(e Event) {
+	event, id := webhookEvent(e)
+	if event == "" {
+		return
+	}
+
+	todo := ws.loadToDo(id)
+	now := time.Now()
+	queued := false
+
+	for _, hook := range ws.hooks {
+		if !slices.Contains(hook.Events, event) || !webhookMatches(hook, todo) {
+			continue
+		}
+
+		delivery := entities.WebhookDelivery{
+			UID:           entities.NewUID(),
+			Webhook:       hook.Name,
+			URL:           hook.URL,
+			Event:         event,
+			ToDoID:        id,
+			State:         WebhookPending,
+			MaxAttempts:   hook.MaxAttempts,
+			NextAttemptAt: &now,
+		}
+
+		payload, err := json.Marshal(WebhookPayload{
+			ID:         delivery.UID,
+			Event:      event,
+			Webhook:    hook.Name,
+			Profile:    ws.profile,
+			OccurredAt: now,
+			ToDo:       todo,
+		})
+		if err != nil {
+			log.Printf("⚠️  webhook %s: %v", hook.Name, err)
+			continue
+		}
+		delivery.Payload = string(payload)
+
+		if err := ws.db.GetDB().Create(&delivery).Error; err != nil {
+			log.Printf("⚠️  webhook %s: failed to queue delivery: %v", hook.Name, err)
+			continue
+		}
+		queued = true
+	}
+
+	if queued {
+		ws.queued.Store(true)
+		select {
+		case ws.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (ws *WebhookService) run() {
+	defer close(ws.done)
+
+	for {
+		wait := webhookPoll
+		if next, ok := ws.deliverDue(context.Background()); ok {
+			wait = min(max(time.Until(next), 0), webhookPoll)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ws.stop:
+			timer.Stop()
+			return
+		case <-ws.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// deliverDue sends every pending delivery that is due and returns when the
+// next one is due
+func (ws *WebhookService) deliverDue(ctx context.Context) (time.Time, bool) {
+	for ctx.Err() == nil {
+		now := time.Now()
+
+		var due []entities.WebhookDelivery
+		if err := ws.db.GetDB().
+			Where("state = ? AND next_attempt_at <= ?", WebhookPending, now).
+			Order("next_attempt_at").
+			Limit(20).
+			Find(&due).Error; err != nil {
+			log.Printf("⚠️  webhooks: %v", err)
+			break
+		}
+		if len(due) == 0 {
+			break
+		}
+
+		for i := range due {
+			if ctx.Err() != nil {
+				break
+			}
+			if ws.claim(due[i].ID, now) {
+				ws.send(ctx, &due[i])
+			}
+		}
+	}
+
+	var next []time.Time
+	ws.db.GetDB().Model(&entities.WebhookDelivery{}).
+		Where("state = ? AND next_attempt_at IS NOT NULL", WebhookPending).
+		Order("next_attempt_at").
+		Limit(1).
+		Pluck("next_attempt_at", &next)
+	if len(next) == 0 {
+		return time.Time{}, false
+	}
+	return next[0], true
+}
+
+// claim leases a due delivery; it fails if another sender got it first
+func (ws *WebhookService) claim(id uint, now time.Time) bool {
+	result := ws.db.GetDB().Model(&entities.WebhookDelivery{}).
+		Where("id = ? AND state = ? AND next_attempt_at <= ?", id, WebhookPending, now).
+		Update("next_attempt_at", now.Add(webhookLease))
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// send makes one attempt and records its outcome
+func (ws *WebhookService) send(ctx context.Context, delivery *entities.WebhookDelivery) {
+	statusCode, err := ws.post(ctx, delivery)
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.StatusCode = statusCode
+	delivery.LastError = ""
+
+	switch {
+	case err == nil:
+		delivery.State = WebhookDelivered
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= delivery.MaxAttempts:
+		delivery.State = WebhookFailed
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = nil
+		log.Printf("❌ Webhook %s gave up on delivery %d after %d attempts: %v", delivery.Webhook, delivery.ID, delivery.Attempts, err)
+	default:
+		next := now.Add(webhookBackoff(delivery.Attempts))
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = &next
+	}
+
+	if err := ws.db.GetDB().Save(delivery).Error; err != nil {
+		log.Printf("⚠️  webhooks: failed to record delivery %d: %v", delivery.ID, err)
+	}
+}
+
+func (ws *WebhookService) post(ctx context.Context, delivery *entities.WebhookDelivery) (int, error) {
+	hook, ok := ws.hook(delivery.Webhook)
+	if !ok {
+		return 0, fmt.Errorf("webhook %q is no longer configured", delivery.Webhook)
+	}
+
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "tuidoo-webhooks")
+	req.Header.Set("X-Tuidoo-Event", delivery.Event)
+	req.Header.Set("X-Tuidoo-Delivery", delivery.UID)
+	if hook.Secret != "" {
+		req.Header.Set("X-Tuidoo-Signature", SignWebhook(hook.Secret, body))
+	}
+
+	res, err := ws.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("webhook answered %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+func (ws *WebhookService) hook(name string) (config.WebhookConfig, bool) {
+	for _, hook := range ws.hooks {
+		if hook.Name == name {
+			return hook, true
+		}
+	}
+	return config.WebhookConfig{}, false
+}
+
+// loadToDo reads the todo including soft-deleted ones, so delete events
+// still carry the task; a hard-deleted todo only has its ID
+func (ws *WebhookService) loadToDo(id uint) WebhookToDo {
+	var todos []entities.ToDo
+	ws.db.GetDB().Unscoped().
+		Preload("Project", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("ToDoList", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("id = ?", id).
+		Limit(1).
+		Find(&todos)
+
+	if len(todos) == 0 {
+		return WebhookToDo{ID: id}
+	}
+
+	todo := todos[0]
+	return WebhookToDo{
+		ID:          todo.ID,
+		UID:         todo.UID,
+		Name:        todo.Name,
+		Description: todo.Description,
+		Project:     todo.Project.Name,
+		List:        todo.ToDoList.Name,
+		Priority:    todo.Priority,
+		Status:      todo.Status,
+		Done:        todo.Done,
+		DueDate:     todo.DueDate,
+		UpdatedAt:   todo.UpdatedAt,
+	}
+}
+
+// prune drops delivered entries older than the retention period
+func (ws *WebhookService) prune() {
+	ws.db.GetDB().Unscoped().
+		Where("state = ? AND delivered_at < ?", WebhookDelivered, time.Now().Add(-webhookRetention)).
+		Delete(&entities.WebhookDelivery{})
+}
+
+// SignWebhook returns the X-Tuidoo-Signature header value for a body:
+// "sha256=" followed by the hex HMAC-SHA256 of the body under the secret
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookEvent names the webhook event for a todo event and returns the
+// todo it is about; imports and other events do not trigger webhooks
+func webhookEvent(e Event) (string, uint) {
+	switch e := e.(type) {
+	case TodoCreated:
+		return "created", e.ID
+	case TodoUpdated:
+		return "updated", e.ID
+	case TodoReopened:
+		return "updated", e.ID
+	case TodoCompleted:
+		return "completed", e.ID
+	case TodoDeleted:
+		return "deleted", e.ID
+	default:
+		return "", 0
+	}
+}
+
+// webhookMatches applies a webhook's project, list, priority and status
+// filters; config validation has already checked the names
+func webhookMatches(hook config.WebhookConfig, todo WebhookToDo) bool {
+	matchName := func(names []string, value string) bool {
+		return len(names) == 0 || slices.ContainsFunc(names, func(n string) bool {
+			return strings.EqualFold(n, value)
+		})
+	}
+
+	if !matchName(hook.Projects, todo.Project) || !matchName(hook.Lists, todo.List) {
+		return false
+	}
+	if len(hook.Priorities) > 0 && !slices.ContainsFunc(hook.Priorities, func(p string) bool {
+		priority, _ := enums.ParsePriority(p)
+		return priority == todo.Priority
+	}) {
+		return false
+	}
+	if len(hook.Statuses) > 0 && !slices.ContainsFunc(hook.Statuses, func(s string) bool {
+		status, _ := enums.ParseStatus(s)
+		return status == todo.Status
+	}) {
+		return false
+	}
+
+	return true
+}
+
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookBaseDelay
+	for i := 1; i < attempts && delay < webhookMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookMaxDelay)
+}
@@ -4,41 +4,59 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"tuidoo/config"
 	"tuidoo/managers"
 	"tuidoo/services"
 	"tuidoo/tui"
+	"tuidoo/tui/keys"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-// RunTUI starts the TUI application
-func RunTUI() error {
+// RunTUI starts the TUI application on the selected profile
+func RunTUI(cfg *config.Config, sel config.Selection) error {
+	km, err := keys.Load(cfg.Keys)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", config.ConfigFile(), err)
+	}
+	keys.Apply(km)
+
 	// Initialize services
-	sc, err := services.NewServiceCollection()
+	sc, err := services.NewServiceCollection(sel)
 	if err != nil {
 		return fmt.Errorf("failed to initialize services: %w", err)
 	}
-	defer sc.Close()
 
 	// Initialize theme manager
 	themeManager := managers.NewThemeManager()
 
-	// Load user's theme preference
+	// Load user's theme preference; ui.theme in the config file pins it
 	settings, err := sc.SettingsService.GetAllSettings()
-	if err == nil && settings.ActiveThemeID != "" {
+	if themeID := cfg.UI.Theme; themeID != "" {
+		if err := themeManager.SetTheme(themeID); err != nil {
+			log.Printf("Failed to load theme '%s', using default: %v", themeID, err)
+		}
+	} else if err == nil && settings.ActiveThemeID != "" {
 		if err := themeManager.SetTheme(settings.ActiveThemeID); err != nil {
 			log.Printf("Failed to load theme '%s', using default: %v", settings.ActiveThemeID, err)
 		}
 	}
 
-	// Create Bubble Tea program
-	m := tui.NewModel(sc, themeManager)
+	// Create Bubble Tea program; closing the model closes whichever profile is
+	// active when the program exits
+	m := tui.NewModel(sc, themeManager, cfg, sel.Profile)
+	defer m.Close()
+
+	if cfg.Features.RPCEnabled() {
+		m.StartRPC()
+	}
 
 	p := tea.NewProgram(
 		m,
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(),
 	)
+	m.ListenForEvents(p.Send)
 
 	if _, err := p.Run(); err != nil {
 		return fmt.Errorf("error running tuidoo: %w", err)
@@ -48,8 +66,8 @@ func RunTUI() error {
 }
 
 // SeedDatabase seeds the database with sample data
-func SeedDatabase() {
-	sc, err := services.NewServiceCollection()
+func SeedDatabase(sel config.Selection) {
+	sc, err := services.NewServiceCollection(sel)
 	if err != nil {
 		log.Fatalf("Failed to initialize services: %v", err)
 	}
@@ -64,8 +82,8 @@ func SeedDatabase() {
 }
 
 // ResetDatabase cleans and reseeds the database
-func ResetDatabase() {
-	sc, err := services.NewServiceCollection()
+func ResetDatabase(sel config.Selection) {
+	sc, err := services.NewServiceCollection(sel)
 	if err != nil {
 		log.Fatalf("Failed to initialize services: %v", err)
 	}
@@ -80,6 +98,10 @@ func ResetDatabase() {
 		os.Exit(0)
 	}
 
+	if _, err := sc.BackupService.Create("pre-reset"); err != nil {
+		log.Fatalf("❌ Backup before reset failed: %v", err)
+	}
+
 	if err := services.ResetAndSeed(sc.DbService); err != nil {
 		log.Fatalf("❌ Reset failed: %v", err)
 	}
@@ -89,8 +111,8 @@ func ResetDatabase() {
 }
 
 // CleanDatabase removes all data from the database
-func CleanDatabase() {
-	sc, err := services.NewServiceCollection()
+func CleanDatabase(sel config.Selection) {
+	sc, err := services.NewServiceCollection(sel)
 	if err != nil {
 		log.Fatalf("Failed to initialize services: %v", err)
 	}
@@ -105,6 +127,10 @@ func CleanDatabase() {
 		os.Exit(0)
 	}
 
+	if _, err := sc.BackupService.Create("pre-clean"); err != nil {
+		log.Fatalf("❌ Backup before clean failed: %v", err)
+	}
+
 	if err := services.CleanDatabase(sc.DbService); err != nil {
 		log.Fatalf("❌ Clean failed: %v", err)
 	}
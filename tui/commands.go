@@ -0,0 +1,264 @@
+package tui
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+	"tuidoo/config"
+	"tuidoo/entities"
+	"tuidoo/formats"
+	"tuidoo/services"
+	"tuidoo/tui/components/footer"
+	"tuidoo/tui/keys"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	log "github.com/charmbracelet/log"
+)
+
+// Command is an action of the TUI. Every registered command is offered by
+// the command palette, with its key binding as the hint.
+type Command struct {
+	// ID names the command in the recent commands, e.g. view.rules
+	ID    string
+	Title string
+	// Binding picks the command's binding from the effective key map; nil
+	// when it has none
+	Binding func(*keys.KeyMap) *key.Binding
+	Run     func(m *Model) tea.Cmd
+}
+
+// commands is the registry, in the order the palette lists them
+var commands []Command
+
+// register adds commands to the registry; features register theirs from an
+// init function next to their code
+func register(cmds ...Command) {
+	commands = append(commands, cmds...)
+}
+
+// findCommand looks a command up by ID
+func findCommand(id string) *Command {
+	for i := range commands {
+		if commands[i].ID == id {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// hint is the help key of a command's binding, or "" when it has none
+func (c Command) hint() string {
+	if c.Binding == nil {
+		return ""
+	}
+	if b := c.Binding(keys.Keys); len(b.Keys()) > 0 {
+		return b.Help().Key
+	}
+	return ""
+}
+
+func init() {
+	register(
+		Command{
+			ID:    "view.tasks",
+			Title: "Show all tasks",
+			Run: func(m *Model) tea.Cmd {
+				m.showView(ViewMain)
+				return m.todoList.SetFilter(services.ToDoFilter{})
+			},
+		},
+		Command{
+			ID:      "task.new",
+			Title:   "New task",
+			Binding: func(k *keys.KeyMap) *key.Binding { return &k.NewTodo },
+			Run: func(m *Model) tea.Cmd {
+				m.newTodo()
+				return nil
+			},
+		},
+		Command{
+			ID:      "task.edit",
+			Title:   "Edit selected task",
+			Binding: func(k *keys.KeyMap) *key.Binding { return &k.EditTodo },
+			Run: func(m *Model) tea.Cmd {
+				if todo := m.todoList.Selected(); todo != nil {
+					m.editTodo(todo)
+				}
+				return nil
+			},
+		},
+		Command{
+			ID:      "task.toggle",
+			Title:   "Toggle done on selected task",
+			Binding: func(k *keys.KeyMap) *key.Binding { return &k.ToggleDone },
+			Run: func(m *Model) tea.Cmd {
+				return m.todoList.ToggleSelected()
+			},
+		},
+		Command{
+			ID:      "tasks.refresh",
+			Title:   "Refresh tasks",
+			Binding: func(k *keys.KeyMap) *key.Binding { return &k.Refresh },
+			Run: func(m *Model) tea.Cmd {
+				return m.todoList.FetchTodos()
+			},
+		},
+		Command{
+			ID:      "view.themes",
+			Title:   "Switch theme",
+			Binding: func(k *keys.KeyMap) *key.Binding { return &k.ToggleThemes },
+			Run: func(m *Model) tea.Cmd {
+				m.showView(ViewThemes)
+				return nil
+			},
+		},
+		Command{
+			ID:      "view.profiles",
+			Title:   "Switch profile",
+			Binding: func(k *keys.KeyMap) *key.Binding { return &k.SwitchProfile },
+			Run: func(m *Model) tea.Cmd {
+				m.profileList.Refresh()
+				m.showView(ViewProfiles)
+				return nil
+			},
+		},
+		Command{
+			ID:      "view.rules",
+			Title:   "Automation rules",
+			Binding: func(k *keys.KeyMap) *key.Binding { return &k.ViewRules },
+			Run: func(m *Model) tea.Cmd {
+				m.showView(ViewRules)
+				return m.ruleList.Load()
+			},
+		},
+		Command{
+			ID:      "view.focus",
+			Title:   "Toggle menu focus",
+			Binding: func(k *keys.KeyMap) *key.Binding { return &k.Tab },
+			Run: func(m *Model) tea.Cmd {
+				m.focusedOnMenu = !m.focusedOnMenu
+				return nil
+			},
+		},
+		Command{
+			ID:      "app.cmdline",
+			Title:   "Command line",
+			Binding: func(k *keys.KeyMap) *key.Binding { return &k.Command },
+			Run: func(m *Model) tea.Cmd {
+				return m.cmdline.Open(m.commandHistory)
+			},
+		},
+		Command{
+			ID:    "export.json",
+			Title: "Export JSON snapshot",
+			Run: func(m *Model) tea.Cmd {
+				return m.export("json", "")
+			},
+		},
+		Command{
+			ID:    "export.markdown",
+			Title: "Export tasks as Markdown",
+			Run: func(m *Model) tea.Cmd {
+				return m.export("markdown", "")
+			},
+		},
+		Command{
+			ID:      "app.quit",
+			Title:   "Quit",
+			Binding: func(k *keys.KeyMap) *key.Binding { return &k.Quit },
+			Run: func(m *Model) tea.Cmd {
+				return tea.Quit
+			},
+		},
+	)
+}
+
+// showView switches the content pane and focuses it
+func (m *Model) showView(v View) {
+	m.currentView = v
+	m.focusedOnMenu = false
+}
+
+// editTodo opens the task form on todo
+func (m *Model) editTodo(todo *entities.ToDo) {
+	m.selectedTodo = todo
+	m.todoForm.SetTodo(todo)
+	m.showView(ViewTodoEdit)
+}
+
+// newTodo opens the task form on a new task, in the project and list the
+// task list is narrowed to, or else the ones of the defaults config section
+func (m *Model) newTodo() {
+	filter := m.todoList.Filter()
+	defaults := m.ctx.Config.Defaults
+	m.selectedTodo = nil
+	m.todoForm.NewTodo(cmp.Or(filter.Project, defaults.Project), cmp.Or(filter.List, defaults.List))
+	m.showView(ViewTodoEdit)
+}
+
+// export writes the active profile in one of formats.Names to path, by
+// default a file in the working directory named after the profile and the
+// time
+func (m *Model) export(format, path string) tea.Cmd {
+	if path == "" {
+		path = fmt.Sprintf("tuidoo-%s-%s%s", m.ctx.Profile, time.Now().Format("20060102-150405"), formats.Extension(format))
+	}
+	path, _ = filepath.Abs(config.ExpandPath(path))
+	sc := m.ctx.Services
+
+	return func() tea.Msg {
+		err := writeExport(path, func(w io.Writer) error {
+			if format == "json" {
+				snapshot, err := sc.TransferService.Export()
+				if err != nil {
+					return err
+				}
+				enc := json.NewEncoder(w)
+				enc.SetIndent("", "  ")
+				return enc.Encode(snapshot)
+			}
+
+			todos, err := sc.ToDoService.GetAll(true)
+			if err != nil {
+				return err
+			}
+			switch format {
+			case "csv":
+				return formats.WriteCSV(w, todos, formats.CSVColumns)
+			case "markdown":
+				return formats.WriteMarkdown(w, todos)
+			case "todotxt":
+				return formats.WriteTodoTxt(w, todos)
+			case "ical":
+				return formats.WriteICal(w, todos)
+			case "taskwarrior":
+				return formats.WriteTaskwarrior(w, todos)
+			default:
+				return fmt.Errorf("unknown export format %q", format)
+			}
+		})
+		if err != nil {
+			log.Error("Export failed", "file", path, "err", err)
+			return footer.StatusMsg{Text: "❌ Export failed: " + err.Error(), Error: true}
+		}
+		log.Info("Exported", "file", path)
+		return footer.StatusMsg{Text: "📤 Exported to " + path}
+	}
+}
+
+func writeExport(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
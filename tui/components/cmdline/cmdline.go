@@ -0,0 +1,260 @@
+package cmdline
+
+import (
+	"strings"
+	"tuidoo/tui/context"
+	"tuidoo/tui/keys"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// SubmittedMsg carries a line entered with enter. The line stays open until
+// the model either closes it or reports what was wrong with Fail.
+type SubmittedMsg struct {
+	Line string
+}
+
+// Completer returns where the word being completed starts in line and the
+// words that could replace it
+type Completer func(line string) (start int, candidates []string)
+
+// editing are the keys the line handles itself rather than the text input
+var editing = struct {
+	Complete key.Binding
+	Older    key.Binding
+	Newer    key.Binding
+}{
+	Complete: key.NewBinding(key.WithKeys("tab")),
+	Older:    key.NewBinding(key.WithKeys("up", "ctrl+p")),
+	Newer:    key.NewBinding(key.WithKeys("down", "ctrl+n")),
+}
+
+type Model struct {
+	ctx      *context.ProgramContext
+	input    textinput.Model
+	complete Completer
+	open     bool
+	err      error
+
+	// history holds the lines entered before, newest first; browsing is the
+	// index shown, -1 while editing draft
+	history  []string
+	browsing int
+	draft    string
+
+	// candidates of the last tab, cycled by pressing it again while the line
+	// still shows completed
+	candidates []string
+	candidate  int
+	start      int
+	rest       string
+	completed  string
+}
+
+func NewModel(ctx *context.ProgramContext, complete Completer) Model {
+	input := textinput.New()
+	input.Prompt = ":"
+	input.CharLimit = 200
+
+	return Model{
+		ctx:      ctx,
+		input:    input,
+		complete: complete,
+	}
+}
+
+// Open starts an empty line, browsing history with up and down
+func (m *Model) Open(history []string) tea.Cmd {
+	m.open = true
+	m.err = nil
+	m.history = history
+	m.browsing = -1
+	m.draft = ""
+	m.candidates = nil
+	m.input.SetValue("")
+	return m.input.Focus()
+}
+
+// Close hides the line
+func (m *Model) Close() {
+	m.open = false
+	m.err = nil
+	m.input.Blur()
+}
+
+// Fail keeps the line open with the reason it did not run. The text is
+// cleared for the next command; history, which should hold the failed line
+// by now, brings it back with up.
+func (m *Model) Fail(err error, history []string) {
+	m.err = err
+	m.history = history
+	m.browsing = -1
+	m.draft = ""
+	m.candidates = nil
+	m.input.SetValue("")
+}
+
+// IsOpen reports whether the line takes the keyboard
+func (m Model) IsOpen() bool {
+	return m.open
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	if !m.open {
+		return m, nil
+	}
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		return m, cmd
+	}
+
+	// Any key but tab starts a fresh completion, and any key hides the error
+	if !key.Matches(keyMsg, editing.Complete) {
+		m.candidates = nil
+	}
+	m.err = nil
+
+	switch {
+	case key.Matches(keyMsg, keys.Keys.Escape):
+		m.Close()
+		return m, nil
+
+	case keyMsg.Type == tea.KeyEnter:
+		line := strings.TrimSpace(m.input.Value())
+		if line == "" {
+			m.Close()
+			return m, nil
+		}
+		return m, func() tea.Msg { return SubmittedMsg{Line: line} }
+
+	case key.Matches(keyMsg, editing.Complete):
+		m.tab()
+		return m, nil
+
+	case key.Matches(keyMsg, editing.Older):
+		m.browse(1)
+		return m, nil
+
+	case key.Matches(keyMsg, editing.Newer):
+		m.browse(-1)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// tab completes the word before the cursor, or moves on to the next
+// candidate when tab was the last key
+func (m *Model) tab() {
+	if m.candidates != nil && m.input.Value() == m.completed {
+		m.candidate = (m.candidate + 1) % len(m.candidates)
+		m.fill()
+		return
+	}
+
+	value := []rune(m.input.Value())
+	line, rest := string(value[:m.input.Position()]), string(value[m.input.Position():])
+	start, candidates := m.complete(line)
+	switch len(candidates) {
+	case 0:
+		return
+	case 1:
+		m.replace(line[:start]+quote(candidates[0])+" ", rest)
+		return
+	}
+
+	// Complete the common prefix first; cycling starts on the next tab
+	if prefix := commonPrefix(candidates); len(prefix) > len(line)-start {
+		line = line[:start] + prefix
+		m.replace(line, rest)
+	}
+	m.candidates = candidates
+	m.candidate = -1
+	m.start = start
+	m.rest = rest
+	m.completed = m.input.Value()
+}
+
+// fill puts the current candidate in place of the word being completed
+func (m *Model) fill() {
+	m.replace(m.completed[:m.start]+quote(m.candidates[m.candidate]), m.rest)
+	m.completed = m.input.Value()
+}
+
+// replace sets the line to before and after with the cursor between them
+func (m *Model) replace(before, after string) {
+	m.input.SetValue(before + after)
+	m.input.SetCursor(len([]rune(before)))
+}
+
+// browse steps through the history; by 1 goes to older lines
+func (m *Model) browse(by int) {
+	next := m.browsing + by
+	if next < -1 || next >= len(m.history) {
+		return
+	}
+	if m.browsing == -1 {
+		m.draft = m.input.Value()
+	}
+	m.browsing = next
+	if next == -1 {
+		m.input.SetValue(m.draft)
+	} else {
+		m.input.SetValue(m.history[next])
+	}
+	m.input.CursorEnd()
+}
+
+func (m Model) View() string {
+	theme := m.ctx.ThemeManager.GetCurrentTheme()
+
+	errorStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.Error)).
+		Bold(true)
+
+	mutedStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.TextSecondary))
+
+	line := " " + m.input.View()
+	switch {
+	case m.err != nil:
+		line += "  " + errorStyle.Render("✗ "+m.err.Error())
+	case len(m.candidates) > 1:
+		shown := make([]string, len(m.candidates))
+		for i, c := range m.candidates {
+			shown[i] = c
+			if i == m.candidate {
+				shown[i] = "[" + c + "]"
+			}
+		}
+		line += "  " + mutedStyle.Render(strings.Join(shown, " "))
+	}
+
+	return lipgloss.NewStyle().MaxWidth(m.ctx.ScreenWidth).Render(line)
+}
+
+// quote wraps a completed word in quotes when it has spaces
+func quote(s string) string {
+	if strings.ContainsAny(s, " \t") {
+		return `"` + s + `"`
+	}
+	return s
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(strings.ToLower(w), strings.ToLower(prefix)) {
+			prefix = string([]rune(prefix)[:len([]rune(prefix))-1])
+		}
+	}
+	return prefix
+}
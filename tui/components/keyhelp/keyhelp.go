@@ -0,0 +1,130 @@
+package keyhelp
+
+import (
+	"strings"
+	"tuidoo/tui/context"
+	"tuidoo/tui/keys"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Model is the full-screen help: the bindings of the screen it was opened
+// on, grouped by where they work
+type Model struct {
+	ctx   *context.ProgramContext
+	help  keys.ScreenHelp
+	title string
+	open  bool
+}
+
+func NewModel(ctx *context.ProgramContext) Model {
+	return Model{ctx: ctx}
+}
+
+// Open shows the help of a screen under title
+func (m *Model) Open(title string, help keys.ScreenHelp) {
+	m.title = title
+	m.help = help
+	m.open = true
+}
+
+// Close hides the help
+func (m *Model) Close() {
+	m.open = false
+}
+
+// IsOpen reports whether the help covers the screen
+func (m Model) IsOpen() bool {
+	return m.open
+}
+
+// Update closes the help on escape or the help key; every other key is
+// swallowed while it is open
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok && m.open {
+		if key.Matches(msg, keys.Keys.Escape) || key.Matches(msg, keys.Keys.Help) {
+			m.Close()
+		}
+	}
+	return m, nil
+}
+
+func (m Model) View() string {
+	theme := m.ctx.ThemeManager.GetCurrentTheme()
+
+	boxStyle := lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(context.TcellToLipgloss(theme.Colors.Primary)).
+		Padding(1, 2)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.Primary)).
+		Bold(true)
+
+	groupStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.Foreground)).
+		Bold(true).
+		Underline(true)
+
+	keyStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.Primary)).
+		Bold(true)
+
+	descStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.Foreground))
+
+	mutedStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.TextSecondary))
+
+	var columns []string
+	for _, g := range m.help.Groups {
+		var bindings []key.Binding
+		keyWidth := 0
+		for _, b := range g.Bindings {
+			if b.Enabled() {
+				bindings = append(bindings, b)
+				keyWidth = max(keyWidth, lipgloss.Width(b.Help().Key))
+			}
+		}
+		if len(bindings) == 0 {
+			continue
+		}
+
+		var s strings.Builder
+		s.WriteString(groupStyle.Render(g.Title))
+		for _, b := range bindings {
+			s.WriteString("\n" + keyStyle.Render(padRight(b.Help().Key, keyWidth)) + "  " + descStyle.Render(b.Help().Desc))
+		}
+		columns = append(columns, s.String())
+	}
+
+	// Side by side when they fit, else one under the other
+	body := lipgloss.JoinHorizontal(lipgloss.Top, spaced(columns, lipgloss.NewStyle().MarginRight(4))...)
+	if lipgloss.Width(body)+6 > m.ctx.ScreenWidth {
+		body = lipgloss.JoinVertical(lipgloss.Left, spaced(columns, lipgloss.NewStyle().MarginBottom(1))...)
+	}
+
+	closeHint := keys.HintLine(keys.Hint(keys.Keys.Escape, "close"), keys.Hint(keys.Keys.Help, "close"))
+	content := titleStyle.Render("Keys · "+m.title) + "\n\n" + body + "\n\n" + mutedStyle.Render(closeHint)
+
+	return lipgloss.Place(m.ctx.ScreenWidth, m.ctx.ScreenHeight, lipgloss.Center, lipgloss.Center, boxStyle.Render(content))
+}
+
+// spaced renders every column but the last with style, which keeps them
+// apart
+func spaced(columns []string, style lipgloss.Style) []string {
+	out := make([]string, len(columns))
+	for i, c := range columns {
+		if i < len(columns)-1 {
+			c = style.Render(c)
+		}
+		out[i] = c
+	}
+	return out
+}
+
+func padRight(s string, width int) string {
+	return s + strings.Repeat(" ", max(0, width-lipgloss.Width(s)))
+}
@@ -33,6 +33,8 @@ func NewModel(ctx *context.ProgramContext) Model {
 		{Label: "View ToDos", Description: "View all todos", Key: 'l', View: "main"},
 		{Label: "New Task", Description: "Create new task", Key: 'n', View: "new"},
 		{Label: "Projects", Description: "Manage projects", Key: 'p', View: "projects"},
+		{Label: "Profiles", Description: "Switch profile", Key: 'P', View: "profiles"},
+		{Label: "Rules", Description: "Automation rules", Key: 'R', View: "rules"},
 		{Label: "Settings", Description: "App settings", Key: 't', View: "themes"},
 		{Label: "Quit", Description: "Exit application", Key: 'q', View: "quit"},
 	}
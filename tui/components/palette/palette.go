@@ -0,0 +1,300 @@
+package palette
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"tuidoo/tui/context"
+	"tuidoo/tui/keys"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sahilm/fuzzy"
+)
+
+// Kind tells what selecting an item opens
+type Kind string
+
+const (
+	KindCommand Kind = "command"
+	KindProject Kind = "project"
+	KindList    Kind = "list"
+	KindTask    Kind = "task"
+)
+
+// Item is an entry of the palette
+type Item struct {
+	Kind Kind
+	// ID is the command ID, or the record ID of a project, list or task
+	ID    string
+	Title string
+	// Hint is shown on the right: the key binding of a command, or where a
+	// task lives
+	Hint string
+}
+
+// Key identifies an item in the recent commands
+func (i Item) Key() string {
+	return string(i.Kind) + ":" + i.ID
+}
+
+// SelectedMsg is sent when an item is picked; the palette closes itself
+type SelectedMsg struct {
+	Item Item
+}
+
+// maxShown is how many matches the palette lists at once
+const maxShown = 12
+
+// navigation moves through the matches without taking letters from the query
+var navigation = struct {
+	Up   key.Binding
+	Down key.Binding
+}{
+	Up:   key.NewBinding(key.WithKeys("up", "ctrl+k")),
+	Down: key.NewBinding(key.WithKeys("down", "ctrl+j")),
+}
+
+type Model struct {
+	ctx   *context.ProgramContext
+	input textinput.Model
+	items []Item
+	// recent holds the keys of recently picked items, newest first
+	recent  []string
+	matches []match
+	cursor  int
+	open    bool
+}
+
+type match struct {
+	item   Item
+	recent bool
+	score  int
+	// indexes are the byte offsets of the matched characters of the title
+	indexes []int
+}
+
+func NewModel(ctx *context.ProgramContext) Model {
+	input := textinput.New()
+	input.Placeholder = "Type a command, project, list or task"
+	input.Prompt = "› "
+	input.CharLimit = 100
+
+	return Model{
+		ctx:   ctx,
+		input: input,
+	}
+}
+
+// Open shows the palette with a fresh query over items, listing the recent
+// ones first
+func (m *Model) Open(items []Item, recent []string) {
+	m.items = items
+	m.recent = recent
+	m.open = true
+	m.input.SetValue("")
+	m.input.Focus()
+	m.filter()
+}
+
+// Close hides the palette
+func (m *Model) Close() {
+	m.open = false
+	m.input.Blur()
+}
+
+// IsOpen reports whether the palette takes the keyboard
+func (m Model) IsOpen() bool {
+	return m.open
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	if !m.open {
+		return m, nil
+	}
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, keys.Keys.Escape), key.Matches(msg, keys.Keys.Palette):
+			m.Close()
+			return m, nil
+
+		case key.Matches(msg, navigation.Up):
+			if m.cursor > 0 {
+				m.cursor--
+			}
+			return m, nil
+
+		case key.Matches(msg, navigation.Down):
+			if m.cursor < len(m.matches)-1 {
+				m.cursor++
+			}
+			return m, nil
+
+		case msg.Type == tea.KeyEnter:
+			if len(m.matches) == 0 {
+				return m, nil
+			}
+			item := m.matches[m.cursor].item
+			m.Close()
+			return m, func() tea.Msg { return SelectedMsg{Item: item} }
+		}
+	}
+
+	var cmd tea.Cmd
+	query := m.input.Value()
+	m.input, cmd = m.input.Update(msg)
+	if m.input.Value() != query {
+		m.filter()
+	}
+	return m, cmd
+}
+
+// filter ranks the items against the query. Without a query the recent
+// items come first, then everything else in order; with one the best fuzzy
+// matches come first, recent ones winning ties.
+func (m *Model) filter() {
+	m.cursor = 0
+	m.matches = m.matches[:0]
+	query := strings.TrimSpace(m.input.Value())
+
+	if query == "" {
+		for _, k := range m.recent {
+			if i := slices.IndexFunc(m.items, func(it Item) bool { return it.Key() == k }); i >= 0 {
+				m.matches = append(m.matches, match{item: m.items[i], recent: true})
+			}
+		}
+		for _, it := range m.items {
+			if !slices.Contains(m.recent, it.Key()) {
+				m.matches = append(m.matches, match{item: it})
+			}
+		}
+		return
+	}
+
+	titles := make([]string, len(m.items))
+	for i, it := range m.items {
+		titles[i] = it.Title
+	}
+	found := fuzzy.Find(query, titles)
+	for _, f := range found {
+		it := m.items[f.Index]
+		m.matches = append(m.matches, match{
+			item:    it,
+			recent:  slices.Contains(m.recent, it.Key()),
+			score:   f.Score,
+			indexes: f.MatchedIndexes,
+		})
+	}
+	slices.SortStableFunc(m.matches, func(a, b match) int {
+		if a.score != b.score {
+			return b.score - a.score
+		}
+		if a.recent != b.recent {
+			if a.recent {
+				return -1
+			}
+			return 1
+		}
+		return 0
+	})
+}
+
+func (m Model) View() string {
+	theme := m.ctx.ThemeManager.GetCurrentTheme()
+	width := min(72, m.ctx.ScreenWidth-8)
+	// inner is the width of a line inside the padding
+	inner := width - 2
+
+	boxStyle := lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(context.TcellToLipgloss(theme.Colors.Primary)).
+		Background(context.TcellToLipgloss(theme.Colors.Background)).
+		Padding(0, 1).
+		Width(width)
+
+	selectedStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.Background)).
+		Background(context.TcellToLipgloss(theme.Colors.Primary)).
+		Bold(true)
+
+	normalStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.Foreground))
+
+	mutedStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.TextSecondary))
+
+	matchStyle := normalStyle.Bold(true).Underline(true)
+
+	var s strings.Builder
+	s.WriteString(m.input.View())
+	s.WriteString("\n")
+
+	if len(m.matches) == 0 {
+		s.WriteString(mutedStyle.Render("  No matches"))
+	}
+
+	// Keep the cursor in the window of shown matches
+	first := max(0, m.cursor-maxShown+1)
+	for i := first; i < len(m.matches) && i < first+maxShown; i++ {
+		mt := m.matches[i]
+		kind := string(mt.item.Kind)
+		if mt.recent {
+			kind = "recent"
+		}
+
+		hintWidth := lipgloss.Width(mt.item.Hint)
+		titleWidth := inner - 2 - 10 - hintWidth - 1
+		title := truncate(mt.item.Title, titleWidth)
+
+		if i == m.cursor {
+			line := "› " + padRight(kind, 10) + padRight(title, titleWidth) + " " + mt.item.Hint
+			s.WriteString("\n" + selectedStyle.Render(padRight(line, inner)))
+			continue
+		}
+		s.WriteString("\n  " + mutedStyle.Render(padRight(kind, 10)) +
+			highlight(title, mt.indexes, normalStyle, matchStyle) +
+			strings.Repeat(" ", max(0, titleWidth-lipgloss.Width(title))) + " " +
+			mutedStyle.Render(mt.item.Hint))
+	}
+
+	if hidden := len(m.matches) - maxShown; hidden > 0 {
+		s.WriteString("\n" + mutedStyle.Render(fmt.Sprintf("  …and %d more", hidden)))
+	}
+
+	return boxStyle.Render(s.String())
+}
+
+// highlight marks the matched characters of a title
+func highlight(title string, indexes []int, normal, matched lipgloss.Style) string {
+	if len(indexes) == 0 {
+		return normal.Render(title)
+	}
+
+	var b strings.Builder
+	for i, r := range title {
+		if slices.Contains(indexes, i) {
+			b.WriteString(matched.Render(string(r)))
+		} else {
+			b.WriteString(normal.Render(string(r)))
+		}
+	}
+	return b.String()
+}
+
+func truncate(s string, width int) string {
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && lipgloss.Width(string(runes))+1 > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
+
+func padRight(s string, width int) string {
+	return s + strings.Repeat(" ", max(0, width-lipgloss.Width(s)))
+}
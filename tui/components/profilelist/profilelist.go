@@ -0,0 +1,133 @@
+package profilelist
+
+import (
+	"strings"
+	"tuidoo/tui/context"
+	"tuidoo/tui/keys"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type Model struct {
+	ctx          *context.ProgramContext
+	profileNames []string
+	cursor       int
+}
+
+type ProfileChangedMsg struct {
+	Profile string
+}
+
+func NewModel(ctx *context.ProgramContext) Model {
+	m := Model{ctx: ctx}
+	m.Refresh()
+	return m
+}
+
+// Refresh reloads the profile names from the config and data directory
+func (m *Model) Refresh() {
+	m.profileNames = m.ctx.Config.ProfileNames()
+	if m.cursor >= len(m.profileNames) {
+		m.cursor = 0
+	}
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, keys.Keys.Up):
+			if m.cursor > 0 {
+				m.cursor--
+			}
+
+		case key.Matches(msg, keys.Keys.Down):
+			if m.cursor < len(m.profileNames)-1 {
+				m.cursor++
+			}
+
+		case key.Matches(msg, keys.Keys.Enter):
+			if len(m.profileNames) == 0 {
+				return m, nil
+			}
+			selected := m.profileNames[m.cursor]
+			return m, func() tea.Msg {
+				return ProfileChangedMsg{Profile: selected}
+			}
+		}
+	}
+
+	return m, nil
+}
+
+func (m Model) View() string {
+	theme := m.ctx.ThemeManager.GetCurrentTheme()
+
+	selectedStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.Background)).
+		Background(context.TcellToLipgloss(theme.Colors.Primary)).
+		Bold(true).
+		Padding(0, 1)
+
+	normalStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.Foreground)).
+		Padding(0, 1)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.Primary)).
+		Bold(true).
+		Padding(1, 1)
+
+	pathStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.TextSecondary))
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.TextSecondary)).
+		Padding(1, 1)
+
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("Profiles"))
+	s.WriteString("\n\n")
+
+	for i, name := range m.profileNames {
+		cursor := "  "
+		checkmark := "  "
+
+		if i == m.cursor {
+			cursor = "› "
+		}
+
+		if name == m.ctx.Profile {
+			checkmark = "✓ "
+		}
+
+		line := checkmark + name
+
+		if i == m.cursor {
+			s.WriteString(cursor + selectedStyle.Render(line))
+		} else {
+			s.WriteString(cursor + normalStyle.Render(line))
+		}
+		s.WriteString(" " + pathStyle.Render(m.ctx.Config.ProfileDbPath(name)))
+		s.WriteString("\n")
+	}
+
+	s.WriteString("\n")
+	s.WriteString(helpStyle.Render(keys.HintLine(
+		keys.Hint(keys.Keys.Enter, "switch profile"),
+		keys.Hint(keys.Keys.Escape, "back"),
+		keys.Hint(keys.Keys.SwitchProfile, "toggle profiles"),
+	)))
+
+	return s.String()
+}
+
+func (m *Model) ApplyTheme() {
+	// Theme applied on next render
+}
+
+func (m *Model) UpdateProgramContext(ctx *context.ProgramContext) {
+	m.ctx = ctx
+}
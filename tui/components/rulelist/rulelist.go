@@ -0,0 +1,591 @@
+package rulelist
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"tuidoo/config"
+	"tuidoo/entities"
+	"tuidoo/services"
+	"tuidoo/tui/context"
+	"tuidoo/tui/keys"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Form fields, in tab order
+const (
+	fieldName = iota
+	fieldTrigger
+	fieldDueWithin
+	fieldConditions
+	fieldActions
+	fieldTestTask
+	fieldCount
+)
+
+// formKeys also move between fields of the form, besides the field bindings
+var formKeys = struct {
+	Next key.Binding
+	Prev key.Binding
+}{
+	Next: key.NewBinding(key.WithKeys("down")),
+	Prev: key.NewBinding(key.WithKeys("up")),
+}
+
+type Model struct {
+	ctx    *context.ProgramContext
+	rules  []entities.Rule
+	cursor int
+	err    error
+
+	// editing is the rule open in the form, nil while the list is shown
+	editing       *entities.Rule
+	trigger       int
+	inputs        []textinput.Model
+	focus         int
+	report        *services.RuleReport
+	confirmDelete bool
+}
+
+type rulesLoadedMsg struct {
+	rules []entities.Rule
+	err   error
+}
+
+type ruleSavedMsg struct {
+	err error
+}
+
+type ruleTestedMsg struct {
+	report *services.RuleReport
+	err    error
+}
+
+func NewModel(ctx *context.ProgramContext) Model {
+	placeholders := map[int]string{
+		fieldName:       "Rule name",
+		fieldDueWithin:  "24h",
+		fieldConditions: "project = Work and priority = Urgent",
+		fieldActions:    `move list=Urgent; note "Escalated"`,
+		fieldTestTask:   "Task ID",
+	}
+
+	inputs := make([]textinput.Model, fieldCount)
+	for i := range inputs {
+		inputs[i] = textinput.New()
+		inputs[i].Placeholder = placeholders[i]
+		inputs[i].Width = 60
+	}
+	inputs[fieldName].CharLimit = 100
+	inputs[fieldTestTask].CharLimit = 10
+
+	return Model{ctx: ctx, inputs: inputs}
+}
+
+// Load reloads the rules
+func (m Model) Load() tea.Cmd {
+	rules := m.ctx.Services.RuleService
+	return func() tea.Msg {
+		all, err := rules.GetAll()
+		return rulesLoadedMsg{rules: all, err: err}
+	}
+}
+
+// Editing reports whether the form is open, in which case it wants every
+// key
+func (m Model) Editing() bool {
+	return m.editing != nil || m.confirmDelete
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case rulesLoadedMsg:
+		m.rules, m.err = msg.rules, msg.err
+		if m.cursor >= len(m.rules) {
+			m.cursor = max(len(m.rules)-1, 0)
+		}
+		return m, nil
+
+	case ruleSavedMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.editing = nil
+		m.err = nil
+		return m, m.Load()
+
+	case ruleTestedMsg:
+		m.report, m.err = msg.report, msg.err
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.confirmDelete {
+			return m.updateConfirm(msg)
+		}
+		if m.editing != nil {
+			return m.updateForm(msg)
+		}
+		return m.updateList(msg)
+	}
+
+	return m, nil
+}
+
+func (m Model) updateList(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, keys.Keys.Up):
+		if m.cursor > 0 {
+			m.cursor--
+		}
+
+	case key.Matches(msg, keys.Keys.Down):
+		if m.cursor < len(m.rules)-1 {
+			m.cursor++
+		}
+
+	case key.Matches(msg, keys.Keys.NewTodo):
+		m.open(&entities.Rule{Enabled: true, Trigger: services.TriggerCreated}, fieldName)
+
+	case key.Matches(msg, keys.Keys.Enter), key.Matches(msg, keys.Keys.EditTodo):
+		if rule := m.selected(); rule != nil {
+			m.open(rule, fieldName)
+		}
+
+	case msg.String() == "t":
+		if rule := m.selected(); rule != nil {
+			m.open(rule, fieldTestTask)
+		}
+
+	case key.Matches(msg, keys.Keys.ToggleDone):
+		if rule := m.selected(); rule != nil {
+			rule.Enabled = !rule.Enabled
+			return m, m.save(rule)
+		}
+
+	case key.Matches(msg, keys.Keys.DeleteTodo):
+		if m.selected() != nil {
+			m.confirmDelete = true
+		}
+	}
+
+	return m, nil
+}
+
+func (m Model) updateConfirm(msg tea.KeyMsg) (Model, tea.Cmd) {
+	m.confirmDelete = false
+	rule := m.selected()
+	if msg.String() != "y" || rule == nil {
+		return m, nil
+	}
+
+	rules := m.ctx.Services.RuleService
+	id := rule.ID
+	return m, func() tea.Msg {
+		return ruleSavedMsg{err: rules.Delete(id)}
+	}
+}
+
+func (m Model) updateForm(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, keys.Keys.Escape):
+		m.editing = nil
+		m.err = nil
+		return m, nil
+
+	case key.Matches(msg, keys.Keys.Save):
+		rule, err := m.formRule()
+		if err != nil {
+			m.err = err
+			return m, nil
+		}
+		return m, m.save(rule)
+
+	case key.Matches(msg, keys.Keys.NextField, keys.Keys.PrevField, formKeys.Next, formKeys.Prev):
+		step := 1
+		if key.Matches(msg, keys.Keys.PrevField, formKeys.Prev) {
+			step = -1
+		}
+		m.focus = (m.focus + step + fieldCount) % fieldCount
+		if m.focus == fieldDueWithin && m.triggerName() != services.TriggerDueApproaching {
+			m.focus = (m.focus + step + fieldCount) % fieldCount
+		}
+		return m, m.focusInput()
+
+	case msg.Type == tea.KeyEnter:
+		if m.focus != fieldTestTask {
+			return m, nil
+		}
+		rule, err := m.formRule()
+		if err != nil {
+			m.err = err
+			return m, nil
+		}
+		id, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(m.inputs[fieldTestTask].Value()), "#"), 10, 64)
+		if err != nil {
+			m.err = fmt.Errorf("enter the ID of a task to test the rule on")
+			return m, nil
+		}
+		rules := m.ctx.Services.RuleService
+		return m, func() tea.Msg {
+			report, err := rules.DryRun(rule, uint(id))
+			return ruleTestedMsg{report: report, err: err}
+		}
+	}
+
+	if m.focus == fieldTrigger {
+		switch {
+		case key.Matches(msg, keys.Keys.Left):
+			m.trigger = (m.trigger + len(services.RuleTriggers) - 1) % len(services.RuleTriggers)
+		case key.Matches(msg, keys.Keys.Right), msg.String() == " ":
+			m.trigger = (m.trigger + 1) % len(services.RuleTriggers)
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.inputs[m.focus], cmd = m.inputs[m.focus].Update(msg)
+	return m, cmd
+}
+
+// open shows a rule in the form with one field focused
+func (m *Model) open(rule *entities.Rule, focus int) {
+	m.editing = rule
+	m.err = nil
+	m.report = nil
+	m.trigger = max(slices.Index(services.RuleTriggers, rule.Trigger), 0)
+
+	dueWithin := ""
+	if rule.DueWithin > 0 {
+		dueWithin = formatWithin(rule.DueWithin)
+	}
+	m.inputs[fieldName].SetValue(rule.Name)
+	m.inputs[fieldDueWithin].SetValue(dueWithin)
+	m.inputs[fieldConditions].SetValue(rule.Conditions)
+	m.inputs[fieldActions].SetValue(rule.Actions)
+
+	m.focus = focus
+	m.focusInput()
+}
+
+func (m *Model) focusInput() tea.Cmd {
+	var cmd tea.Cmd
+	for i := range m.inputs {
+		if i == m.focus {
+			cmd = m.inputs[i].Focus()
+		} else {
+			m.inputs[i].Blur()
+		}
+	}
+	return cmd
+}
+
+func (m Model) triggerName() string {
+	return services.RuleTriggers[m.trigger]
+}
+
+// formRule copies the form into a copy of the rule being edited
+func (m Model) formRule() (*entities.Rule, error) {
+	rule := *m.editing
+	rule.Name = strings.TrimSpace(m.inputs[fieldName].Value())
+	rule.Trigger = m.triggerName()
+	rule.Conditions = strings.TrimSpace(m.inputs[fieldConditions].Value())
+	rule.Actions = strings.TrimSpace(m.inputs[fieldActions].Value())
+
+	rule.DueWithin = 0
+	if value := strings.TrimSpace(m.inputs[fieldDueWithin].Value()); value != "" && rule.Trigger == services.TriggerDueApproaching {
+		within, err := time.ParseDuration(value)
+		if err != nil || within <= 0 {
+			return nil, fmt.Errorf("due within: expected a duration such as 24h or 90m")
+		}
+		rule.DueWithin = within
+	}
+
+	return &rule, nil
+}
+
+func (m Model) save(rule *entities.Rule) tea.Cmd {
+	rules := m.ctx.Services.RuleService
+	return func() tea.Msg {
+		if rule.ID == 0 {
+			return ruleSavedMsg{err: rules.Create(rule)}
+		}
+		return ruleSavedMsg{err: rules.Update(rule)}
+	}
+}
+
+func (m Model) selected() *entities.Rule {
+	if m.cursor >= len(m.rules) {
+		return nil
+	}
+	rule := m.rules[m.cursor]
+	return &rule
+}
+
+func (m Model) View() string {
+	if m.editing != nil {
+		return m.formView()
+	}
+
+	theme := m.ctx.ThemeManager.GetCurrentTheme()
+
+	selectedStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.Background)).
+		Background(context.TcellToLipgloss(theme.Colors.Primary)).
+		Bold(true).
+		Padding(0, 1)
+
+	normalStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.Foreground)).
+		Padding(0, 1)
+
+	disabledStyle := normalStyle.
+		Foreground(context.TcellToLipgloss(theme.Colors.TextDisabled))
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.Primary)).
+		Bold(true).
+		Padding(1, 1)
+
+	detailStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.TextSecondary)).
+		PaddingLeft(6).
+		MaxWidth(max(m.ctx.MainContentWidth-2, 20))
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.TextSecondary)).
+		Padding(1, 1)
+
+	errorStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.Error)).
+		Padding(0, 1)
+
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("Rules"))
+	s.WriteString("\n\n")
+
+	if len(m.rules) == 0 {
+		s.WriteString(normalStyle.Render("No rules yet. Press " + keys.Keys.NewTodo.Help().Key + " to add one."))
+		s.WriteString("\n")
+	}
+
+	for i, rule := range m.rules {
+		cursor := "  "
+		if i == m.cursor {
+			cursor = "› "
+		}
+
+		state := "● "
+		style := normalStyle
+		if !rule.Enabled {
+			state = "○ "
+			style = disabledStyle
+		}
+		if i == m.cursor {
+			style = selectedStyle
+		}
+
+		s.WriteString(cursor + style.Render(state+rule.Name))
+		s.WriteString("\n")
+		s.WriteString(detailStyle.Render(ruleSummary(rule, m.ctx.Config.UI)))
+		s.WriteString("\n")
+	}
+
+	if m.confirmDelete {
+		if rule := m.selected(); rule != nil {
+			s.WriteString("\n")
+			s.WriteString(errorStyle.Render(fmt.Sprintf("Delete rule %q? y/n", rule.Name)))
+			s.WriteString("\n")
+		}
+	}
+	if m.err != nil {
+		s.WriteString("\n")
+		s.WriteString(errorStyle.Render(m.err.Error()))
+		s.WriteString("\n")
+	}
+
+	s.WriteString(helpStyle.Render(keys.HintLine(
+		keys.Hint(keys.Keys.NewTodo, "new"),
+		keys.Hint(keys.Keys.Enter, "edit"),
+		keys.Hint(keys.Keys.ToggleDone, "enable/disable"),
+		"t: test",
+		keys.Hint(keys.Keys.DeleteTodo, "delete"),
+		keys.Hint(keys.Keys.Escape, "back"),
+	)))
+
+	return s.String()
+}
+
+// ruleSummary renders a rule as one when/if/then line
+func ruleSummary(rule entities.Rule, ui config.UIConfig) string {
+	when := rule.Trigger
+	if rule.Trigger == services.TriggerDueApproaching {
+		when += " (" + formatWithin(rule.DueWithin) + ")"
+	}
+
+	summary := "when " + when
+	if rule.Conditions != "" {
+		summary += " if " + rule.Conditions
+	}
+	summary += " then " + rule.Actions
+
+	if rule.LastFiredAt != nil {
+		summary += " · last ran " + ui.FormatDate(*rule.LastFiredAt)
+	}
+	return summary
+}
+
+// formatWithin renders whole hours as 24h rather than 24h0m0s
+func formatWithin(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return d.String()
+}
+
+func (m Model) formView() string {
+	theme := m.ctx.ThemeManager.GetCurrentTheme()
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.Primary)).
+		Bold(true).
+		Padding(1, 1)
+
+	labelStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.Foreground)).
+		Bold(true)
+
+	focusedLabelStyle := labelStyle.
+		Foreground(context.TcellToLipgloss(theme.Colors.Primary))
+
+	valueStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.TextSecondary))
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.TextSecondary)).
+		Padding(1, 0)
+
+	errorStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.Error))
+
+	okStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.Success))
+
+	var s strings.Builder
+
+	title := "New Rule"
+	if m.editing.ID != 0 {
+		title = fmt.Sprintf("Edit Rule: %s", m.editing.Name)
+	}
+	s.WriteString(titleStyle.Render(title))
+	s.WriteString("\n\n")
+
+	label := func(field int, text string) {
+		if m.focus == field {
+			s.WriteString(focusedLabelStyle.Render(text))
+		} else {
+			s.WriteString(labelStyle.Render(text))
+		}
+		s.WriteString("\n")
+	}
+
+	label(fieldName, "Name:")
+	s.WriteString(m.inputs[fieldName].View())
+	s.WriteString("\n\n")
+
+	label(fieldTrigger, "When:")
+	triggers := make([]string, len(services.RuleTriggers))
+	for i, trigger := range services.RuleTriggers {
+		if i == m.trigger {
+			triggers[i] = labelStyle.Render("[" + trigger + "]")
+		} else {
+			triggers[i] = valueStyle.Render(" " + trigger + " ")
+		}
+	}
+	s.WriteString(strings.Join(triggers, " "))
+	s.WriteString("\n\n")
+
+	if m.triggerName() == services.TriggerDueApproaching {
+		label(fieldDueWithin, "Due within:")
+		s.WriteString(m.inputs[fieldDueWithin].View())
+		s.WriteString("\n\n")
+	}
+
+	label(fieldConditions, "If (empty matches every task):")
+	s.WriteString(m.inputs[fieldConditions].View())
+	s.WriteString("\n")
+	s.WriteString(valueStyle.Render("fields: " + strings.Join(services.ConditionFields, ", ")))
+	s.WriteString("\n")
+	s.WriteString(valueStyle.Render("ops: = != ~ (contains) < <= > >= · values: \"quoted text\", none, today, +3d, -2h"))
+	s.WriteString("\n\n")
+
+	label(fieldActions, "Then:")
+	s.WriteString(m.inputs[fieldActions].View())
+	s.WriteString("\n")
+	s.WriteString(valueStyle.Render(`set FIELD=VALUE · move project=P list=L · create "Follow up: {name}" due=+3d · note "text"`))
+	s.WriteString("\n\n")
+
+	label(fieldTestTask, "Test on task (enter runs a dry run):")
+	s.WriteString(m.inputs[fieldTestTask].View())
+	s.WriteString("\n")
+
+	if m.report != nil {
+		s.WriteString("\n")
+		s.WriteString(labelStyle.Render(m.report.Task))
+		s.WriteString("\n")
+		for _, result := range m.report.Conditions {
+			line := fmt.Sprintf("  %s (is %s)", result.Condition, result.Actual)
+			if result.Matched {
+				s.WriteString(okStyle.Render("✓" + line))
+			} else {
+				s.WriteString(errorStyle.Render("✗" + line))
+			}
+			s.WriteString("\n")
+		}
+		switch {
+		case !m.report.Matched:
+			s.WriteString(valueStyle.Render("The rule would not run for this task."))
+			s.WriteString("\n")
+		case len(m.report.Changes) == 0 && len(m.report.Errors) == 0:
+			s.WriteString(valueStyle.Render("The rule would run but change nothing."))
+			s.WriteString("\n")
+		}
+		for _, change := range m.report.Changes {
+			s.WriteString(okStyle.Render("→ " + change))
+			s.WriteString("\n")
+		}
+		for _, err := range m.report.Errors {
+			s.WriteString(errorStyle.Render("✗ " + err))
+			s.WriteString("\n")
+		}
+	}
+
+	if m.err != nil {
+		s.WriteString("\n")
+		s.WriteString(errorStyle.Render(m.err.Error()))
+		s.WriteString("\n")
+	}
+
+	s.WriteString(helpStyle.Render(keys.HintLine(
+		keys.Hint(keys.Keys.Save, "save"),
+		keys.Hint(keys.Keys.Escape, "cancel"),
+		keys.Hint(keys.Keys.NextField, "next field"),
+		"←/→: change trigger",
+	)))
+
+	return s.String()
+}
+
+func (m *Model) ApplyTheme() {
+	// Theme applied on next render
+}
+
+func (m *Model) UpdateProgramContext(ctx *context.ProgramContext) {
+	m.ctx = ctx
+}
@@ -144,7 +144,11 @@ func (m Model) View() string {
 	}
 
 	s.WriteString("\n")
-	s.WriteString(helpStyle.Render("enter: apply theme | esc: back | t: toggle themes"))
+	s.WriteString(helpStyle.Render(keys.HintLine(
+		keys.Hint(keys.Keys.Enter, "apply theme"),
+		keys.Hint(keys.Keys.Escape, "back"),
+		keys.Hint(keys.Keys.ToggleThemes, "toggle themes"),
+	)))
 
 	return s.String()
 }
@@ -1,10 +1,14 @@
 package todoform
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 	"tuidoo/entities"
 	"tuidoo/enums"
+	"tuidoo/services"
 	"tuidoo/tui/context"
 	"tuidoo/tui/keys"
 
@@ -15,17 +19,53 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
+// Form fields, in tab order
+const (
+	fieldName = iota
+	fieldDescription
+	fieldProject
+	fieldList
+	fieldPriority
+	fieldStatus
+	fieldDue
+	fieldCount
+)
+
+// noField marks an error that is not about one field, such as a failed save
+const noField = -1
+
 type Model struct {
-	ctx        *context.ProgramContext
-	todo       *entities.ToDo
+	ctx  *context.ProgramContext
+	todo *entities.ToDo
+	// creating is set for a new todo, which is created rather than updated
+	creating bool
+	version  string
+	err      error
+	// errField is the field err is shown under
+	errField   int
 	nameInput  textinput.Model
 	descInput  textarea.Model
+	dueInput   textinput.Model
 	focusIndex int
-	inputs     []string
+
+	// The pickers choose among these; list 0 is no list
+	projects []entities.Project
+	lists    []entities.ToDoList
+	project  int
+	list     int
+	priority int
+	status   int
 }
 
 type TodoSavedMsg struct {
 	Todo *entities.ToDo
+	// Created is set when the todo was added rather than edited
+	Created bool
+}
+
+// saveFailedMsg keeps the form open with the reason the save failed
+type saveFailedMsg struct {
+	err error
 }
 
 func NewModel(ctx *context.ProgramContext) Model {
@@ -36,22 +76,28 @@ func NewModel(ctx *context.ProgramContext) Model {
 
 	descInput := textarea.New()
 	descInput.Placeholder = "Description..."
-	descInput.SetHeight(5)
+	descInput.SetHeight(3)
 	descInput.SetWidth(50)
 
+	dueInput := textinput.New()
+	dueInput.Placeholder = "none (e.g. 2026-12-01, today, +3d)"
+	dueInput.CharLimit = 40
+	dueInput.Width = 36
+
 	return Model{
 		ctx:        ctx,
 		nameInput:  nameInput,
 		descInput:  descInput,
-		focusIndex: 0,
-		inputs:     []string{"name", "description", "priority", "status", "done"},
+		dueInput:   dueInput,
+		focusIndex: fieldName,
+		errField:   noField,
 	}
 }
 
+// SetTodo opens the form on an existing todo
 func (m *Model) SetTodo(todo *entities.ToDo) {
-	m.todo = todo
-	m.nameInput.SetValue(todo.Name)
-	m.nameInput.Focus()
+	m.open(todo, false)
+	m.version = services.Version(todo.UpdatedAt)
 
 	desc := ""
 	if todo.Description != nil {
@@ -62,54 +108,195 @@ func (m *Model) SetTodo(todo *entities.ToDo) {
 	m.descInput.SetValue(desc)
 }
 
+// NewTodo opens the form on a new todo in the named project and list; names
+// that do not exist fall back to the first project and no list
+func (m *Model) NewTodo(project, list string) {
+	m.open(&entities.ToDo{}, true)
+	m.version = ""
+	m.descInput.SetValue("")
+
+	if i := slices.IndexFunc(m.projects, func(p entities.Project) bool { return strings.EqualFold(p.Name, project) }); i >= 0 {
+		m.project = i
+	}
+	if i := slices.IndexFunc(m.lists, func(l entities.ToDoList) bool { return strings.EqualFold(l.Name, list) }); i >= 0 {
+		m.list = i + 1
+	}
+}
+
+// open resets the form to todo and loads the projects and lists to pick from
+func (m *Model) open(todo *entities.ToDo, creating bool) {
+	m.todo = todo
+	m.creating = creating
+	m.err = nil
+	m.errField = noField
+	m.nameInput.SetValue(todo.Name)
+	m.priority = int(todo.Priority)
+	m.status = int(todo.Status)
+
+	m.dueInput.SetValue("")
+	if todo.DueDate != nil {
+		m.dueInput.SetValue(m.ctx.Config.UI.FormatDate(*todo.DueDate))
+	}
+
+	var err error
+	if m.projects, err = m.ctx.Services.ProjectService.GetAll(false); err != nil {
+		m.fail(noField, err)
+	}
+	if m.lists, err = m.ctx.Services.ToDoListService.GetAll(false); err != nil {
+		m.fail(noField, err)
+	}
+	m.project = max(slices.IndexFunc(m.projects, func(p entities.Project) bool { return p.ID == todo.ProjectID }), 0)
+	m.list = slices.IndexFunc(m.lists, func(l entities.ToDoList) bool { return l.ID == todo.ToDoListID }) + 1
+
+	m.focus(fieldName)
+}
+
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
-	var cmds []tea.Cmd
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
+	case saveFailedMsg:
+		m.fail(noField, msg.err)
+		return m, nil
+
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+s":
-			return m, m.saveTodo()
-
-		case "tab", "shift+tab":
-			if msg.String() == "tab" {
-				m.focusIndex++
-			} else {
-				m.focusIndex--
+		switch {
+		case key.Matches(msg, keys.Keys.Save):
+			todo, field, err := m.formTodo()
+			if err != nil {
+				m.fail(field, err)
+				return m, m.focus(field)
 			}
+			m.err = nil
+			return m, m.saveTodo(todo)
 
-			if m.focusIndex > len(m.inputs)-1 {
-				m.focusIndex = 0
-			} else if m.focusIndex < 0 {
-				m.focusIndex = len(m.inputs) - 1
-			}
+		case key.Matches(msg, keys.Keys.NextField):
+			return m, m.focus((m.focusIndex + 1) % fieldCount)
 
-			if m.focusIndex == 0 {
-				m.nameInput.Focus()
-				m.descInput.Blur()
-			} else if m.focusIndex == 1 {
-				m.nameInput.Blur()
-				m.descInput.Focus()
-			} else {
-				m.nameInput.Blur()
-				m.descInput.Blur()
-			}
+		case key.Matches(msg, keys.Keys.PrevField):
+			return m, m.focus((m.focusIndex + fieldCount - 1) % fieldCount)
+		}
 
+		if m.picking() {
+			switch {
+			case key.Matches(msg, keys.Keys.Left):
+				m.pick(-1)
+			case key.Matches(msg, keys.Keys.Right):
+				m.pick(1)
+			}
 			return m, nil
 		}
 	}
 
 	// Update focused input
-	if m.focusIndex == 0 {
+	switch m.focusIndex {
+	case fieldName:
 		m.nameInput, cmd = m.nameInput.Update(msg)
-		cmds = append(cmds, cmd)
-	} else if m.focusIndex == 1 {
+	case fieldDescription:
 		m.descInput, cmd = m.descInput.Update(msg)
-		cmds = append(cmds, cmd)
+	case fieldDue:
+		m.dueInput, cmd = m.dueInput.Update(msg)
+	}
+
+	return m, cmd
+}
+
+// focus moves the cursor to a field
+func (m *Model) focus(field int) tea.Cmd {
+	if field == noField {
+		return nil
+	}
+	m.focusIndex = field
+	m.nameInput.Blur()
+	m.descInput.Blur()
+	m.dueInput.Blur()
+
+	switch field {
+	case fieldName:
+		return m.nameInput.Focus()
+	case fieldDescription:
+		return m.descInput.Focus()
+	case fieldDue:
+		return m.dueInput.Focus()
+	}
+	return nil
+}
+
+// picking reports whether a picker has the focus
+func (m Model) picking() bool {
+	return m.focusIndex >= fieldProject && m.focusIndex <= fieldStatus
+}
+
+// pick steps the focused picker through its options, wrapping around
+func (m *Model) pick(by int) {
+	step := func(i, n int) int {
+		if n == 0 {
+			return 0
+		}
+		return (i + by + n) % n
+	}
+
+	switch m.focusIndex {
+	case fieldProject:
+		m.project = step(m.project, len(m.projects))
+	case fieldList:
+		m.list = step(m.list, len(m.lists)+1)
+	case fieldPriority:
+		m.priority = step(m.priority, len(enums.PriorityOptions))
+	case fieldStatus:
+		m.status = step(m.status, len(enums.StatusOptions))
+	}
+}
+
+// fail shows err under a field, or above the buttons for noField
+func (m *Model) fail(field int, err error) {
+	m.err = err
+	m.errField = field
+}
+
+// formTodo is the todo as the form shows it, or the field that is invalid
+func (m Model) formTodo() (*entities.ToDo, int, error) {
+	todo := *m.todo
+
+	todo.Name = strings.TrimSpace(m.nameInput.Value())
+	if todo.Name == "" {
+		return nil, fieldName, errors.New("enter a name")
 	}
 
-	return m, tea.Batch(cmds...)
+	desc := m.descInput.Value()
+	todo.Description = &desc
+
+	if len(m.projects) == 0 {
+		return nil, fieldProject, errors.New("no projects yet; add one with 'tuidoo project add'")
+	}
+	todo.ProjectID, todo.Project = m.projects[m.project].ID, entities.Project{}
+	todo.ToDoListID, todo.ToDoList = 0, entities.ToDoList{}
+	if m.list > 0 {
+		todo.ToDoListID = m.lists[m.list-1].ID
+	}
+
+	todo.Priority = enums.Priority(m.priority)
+	if status := enums.Status(m.status); status != todo.Status || m.creating {
+		todo.Status = status
+		todo.Done = status == enums.Done
+	}
+
+	// An unchanged date keeps its time of day, which the date format may
+	// leave out
+	due := strings.TrimSpace(m.dueInput.Value())
+	if m.todo.DueDate != nil && due == m.ctx.Config.UI.FormatDate(*m.todo.DueDate) {
+		return &todo, noField, nil
+	}
+	todo.DueDate = nil
+	if due != "" {
+		t, err := services.ParseDueInput(due, m.ctx.Config.UI, time.Now())
+		if err != nil {
+			return nil, fieldDue, err
+		}
+		todo.DueDate = &t
+	}
+
+	return &todo, noField, nil
 }
 
 func (m Model) View() string {
@@ -126,14 +313,21 @@ func (m Model) View() string {
 
 	labelStyle := lipgloss.NewStyle().
 		Foreground(context.TcellToLipgloss(theme.Colors.Foreground)).
-		Bold(true)
+		Bold(true).
+		Width(14)
 
 	valueStyle := lipgloss.NewStyle().
 		Foreground(context.TcellToLipgloss(theme.Colors.TextSecondary))
 
+	pickerStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.Primary)).
+		Bold(true)
+
 	helpStyle := lipgloss.NewStyle().
-		Foreground(context.TcellToLipgloss(theme.Colors.TextSecondary)).
-		Padding(1, 0)
+		Foreground(context.TcellToLipgloss(theme.Colors.TextSecondary))
+
+	errorStyle := lipgloss.NewStyle().
+		Foreground(context.TcellToLipgloss(theme.Colors.Error))
 
 	buttonStyle := lipgloss.NewStyle().
 		Foreground(context.TcellToLipgloss(theme.Colors.Background)).
@@ -143,80 +337,115 @@ func (m Model) View() string {
 
 	var s strings.Builder
 
-	s.WriteString(titleStyle.Render(fmt.Sprintf("Edit Todo: %s", m.todo.Name)))
-	s.WriteString("\n\n")
+	if m.creating {
+		s.WriteString(titleStyle.Render("New Task"))
+	} else {
+		s.WriteString(titleStyle.Render(fmt.Sprintf("Edit Todo: %s", m.todo.Name)))
+	}
+	s.WriteString("\n")
+
+	// fieldError is the error line under a field, if it has one
+	fieldError := func(field int) string {
+		if m.err == nil || m.errField != field {
+			return ""
+		}
+		return "\n" + errorStyle.Render("✗ "+m.err.Error())
+	}
+
+	// picker shows the chosen option, with arrows while it has the focus
+	picker := func(field int, label string, value string, style lipgloss.Style) string {
+		if m.focusIndex == field {
+			value = pickerStyle.Render("‹ " + value + " ›")
+		} else {
+			value = style.Render("  " + value)
+		}
+		return labelStyle.Render(label) + value + fieldError(field) + "\n"
+	}
 
-	// Name field
 	s.WriteString(labelStyle.Render("Task Name:"))
 	s.WriteString("\n")
 	s.WriteString(m.nameInput.View())
+	s.WriteString(fieldError(fieldName))
 	s.WriteString("\n\n")
 
-	// Description field
 	s.WriteString(labelStyle.Render("Description:"))
 	s.WriteString("\n")
 	s.WriteString(m.descInput.View())
 	s.WriteString("\n\n")
 
-	// Priority (read-only for now, could add selector)
-	s.WriteString(labelStyle.Render("Priority: "))
-	priorityColor := getPriorityColor(m.todo.Priority.String(), theme)
-	priorityStyle := lipgloss.NewStyle().Foreground(priorityColor).Bold(true)
-	s.WriteString(priorityStyle.Render(m.todo.Priority.String()))
-	s.WriteString("\n\n")
-
-	// Status (read-only for now)
-	s.WriteString(labelStyle.Render("Status: "))
-	s.WriteString(valueStyle.Render(m.todo.Status.String()))
-	s.WriteString("\n\n")
+	project := "none yet"
+	if len(m.projects) > 0 {
+		project = m.projects[m.project].Name
+	}
+	s.WriteString(picker(fieldProject, "Project:", project, valueStyle))
 
-	// Done status
-	doneIcon := "☐"
-	if m.todo.Done {
-		doneIcon = "☑"
+	list := "none"
+	if m.list > 0 {
+		list = m.lists[m.list-1].Name
 	}
-	s.WriteString(labelStyle.Render("Completed: "))
-	s.WriteString(valueStyle.Render(doneIcon))
-	s.WriteString("\n\n")
+	s.WriteString(picker(fieldList, "List:", list, valueStyle))
 
-	// Project and List info
-	s.WriteString(labelStyle.Render("Project: "))
-	s.WriteString(valueStyle.Render(m.todo.Project.Name))
-	s.WriteString("  ")
-	s.WriteString(labelStyle.Render("List: "))
-	s.WriteString(valueStyle.Render(m.todo.ToDoList.Name))
-	s.WriteString("\n\n")
+	priority := enums.Priority(m.priority).String()
+	s.WriteString(picker(fieldPriority, "Priority:", priority, lipgloss.NewStyle().Foreground(getPriorityColor(priority, &theme)).Bold(true)))
+	s.WriteString(picker(fieldStatus, "Status:", enums.Status(m.status).String(), valueStyle))
+
+	s.WriteString(labelStyle.Render("Due:"))
+	s.WriteString(m.dueInput.View())
+	s.WriteString(fieldError(fieldDue))
+	s.WriteString("\n")
+
+	if m.err != nil && m.errField == noField {
+		s.WriteString("\n")
+		s.WriteString(errorStyle.Render(m.err.Error()))
+		s.WriteString("\n")
+	}
 
 	// Buttons
-	s.WriteString(buttonStyle.Render("Save (Ctrl+S)"))
+	s.WriteString(buttonStyle.Render("Save (" + keys.Keys.Save.Help().Key + ")"))
 	s.WriteString("  ")
-	s.WriteString(helpStyle.Render("Esc: Cancel"))
+	s.WriteString(helpStyle.Render(keys.Hint(keys.Keys.Escape, "cancel")))
 	s.WriteString("\n\n")
 
-	s.WriteString(helpStyle.Render("Tab: Next field | Shift+Tab: Previous field"))
+	s.WriteString(helpStyle.Render(keys.HintLine(
+		keys.Hint(keys.Keys.NextField, "next field"),
+		keys.Hint(keys.Keys.PrevField, "previous field"),
+		keys.Hint(keys.Keys.Right, "change option"),
+	)))
 
 	return s.String()
 }
 
-func (m Model) saveTodo() tea.Cmd {
-	return func() tea.Msg {
-		if m.todo == nil {
-			return nil
-		}
-
-		// Update todo fields
-		m.todo.Name = m.nameInput.Value()
-		desc := m.descInput.Value()
-		m.todo.Description = &desc
+// saveTodo creates or updates todo; the saved todo carries the names of its
+// project and list for the task list
+func (m Model) saveTodo(todo *entities.ToDo) tea.Cmd {
+	var project entities.Project
+	var list entities.ToDoList
+	if len(m.projects) > 0 {
+		project = m.projects[m.project]
+	}
+	if m.list > 0 {
+		list = m.lists[m.list-1]
+	}
 
-		// Save to database
-		err := m.ctx.Services.ToDoService.Update(m.todo)
-		if err != nil {
-			// Could return error message
-			return nil
+	return func() tea.Msg {
+		if m.creating {
+			if err := m.ctx.Services.ToDoService.Create(todo); err != nil {
+				return saveFailedMsg{err: err}
+			}
+		} else {
+			// Save to database, unless someone else changed the todo since
+			// it was opened
+			err := m.ctx.Services.ToDoService.UpdateChecked(todo, m.version)
+			if errors.Is(err, services.ErrConflict) {
+				return saveFailedMsg{err: errors.New("this task was changed elsewhere since you opened it; press esc and open it again")}
+			}
+			if err != nil {
+				return saveFailedMsg{err: err}
+			}
 		}
 
-		return TodoSavedMsg{Todo: m.todo}
+		todo.Project, todo.ToDoList = project, list
+		return TodoSavedMsg{Todo: todo, Created: m.creating}
 	}
 }
 
@@ -1,8 +1,12 @@
 package todolist
 
 import (
+	"errors"
+	"slices"
 	"strings"
+	"time"
 	"tuidoo/entities"
+	"tuidoo/services"
 	"tuidoo/tui/context"
 	"tuidoo/tui/keys"
 
@@ -18,6 +22,15 @@ type Model struct {
 	todos  []entities.ToDo
 	width  int
 	height int
+
+	// filter narrows the list to a project or a list, opened from the
+	// command palette
+	filter services.ToDoFilter
+	// selectID is a todo to put the cursor on once it is loaded
+	selectID uint
+
+	// externalUpdate is when another process last changed the list
+	externalUpdate time.Time
 }
 
 type TodosLoadedMsg struct {
@@ -28,10 +41,41 @@ type TodoSelectedMsg struct {
 	Todo *entities.ToDo
 }
 
+// NewTodoMsg asks for the form on a new todo
+type NewTodoMsg struct{}
+
 type TodoToggledMsg struct {
 	TodoId uint
 }
 
+// TodoChangedMsg asks the list to reload the row of a todo that was
+// created, changed or deleted. External is set when another process made
+// the change.
+type TodoChangedMsg struct {
+	ID       uint
+	External bool
+}
+
+// TodoRefreshedMsg carries a reloaded todo; Todo is nil when it no longer
+// exists
+type TodoRefreshedMsg struct {
+	ID   uint
+	Todo *entities.ToDo
+}
+
+// TodosStaleMsg asks the list to reload every row, after changes that touch
+// many of them
+type TodosStaleMsg struct {
+	External bool
+}
+
+// updatedShownFor is how long the "updated" indicator stays after another
+// process changed the list
+const updatedShownFor = 3 * time.Second
+
+// updatedExpiredMsg redraws the list once the indicator should be gone
+type updatedExpiredMsg struct{}
+
 func NewModel(ctx *context.ProgramContext) Model {
 	columns := []table.Column{
 		{Title: "✓", Width: 3},
@@ -67,10 +111,33 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	case TodosLoadedMsg:
 		m.todos = msg.Todos
 		m.updateTableRows()
+		m.moveToSelected()
+
+	case TodoChangedMsg:
+		if msg.External {
+			updated := m.markUpdated()
+			return m, tea.Batch(m.refreshTodo(msg.ID), updated)
+		}
+		return m, m.refreshTodo(msg.ID)
+
+	case TodoRefreshedMsg:
+		m.replaceTodo(msg.ID, msg.Todo)
+		m.moveToSelected()
+		return m, nil
+
+	case TodosStaleMsg:
+		if msg.External {
+			updated := m.markUpdated()
+			return m, tea.Batch(m.FetchTodos(), updated)
+		}
+		return m, m.FetchTodos()
 
 	case tea.KeyMsg:
 		switch {
-		case key.Matches(msg, keys.Keys.Enter):
+		case key.Matches(msg, keys.Keys.Escape) && m.Filtered():
+			return m, m.SetFilter(services.ToDoFilter{})
+
+		case key.Matches(msg, keys.Keys.Enter), key.Matches(msg, keys.Keys.EditTodo):
 			if len(m.todos) > 0 {
 				idx := m.table.Cursor()
 				if idx < len(m.todos) {
@@ -80,20 +147,36 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				}
 			}
 
-		case key.Matches(msg, keys.Keys.Space):
-			if len(m.todos) > 0 {
-				idx := m.table.Cursor()
-				if idx < len(m.todos) {
-					return m, m.toggleTodo(&m.todos[idx])
-				}
+		case key.Matches(msg, keys.Keys.ToggleDone):
+			if cmd := m.ToggleSelected(); cmd != nil {
+				return m, cmd
 			}
+
+		case key.Matches(msg, keys.Keys.NewTodo):
+			return m, func() tea.Msg { return NewTodoMsg{} }
 		}
 	}
 
+	m.table.KeyMap = tableKeyMap()
 	m.table, cmd = m.table.Update(msg)
 	return m, cmd
 }
 
+// tableKeyMap moves through the table with the effective bindings. Half
+// pages keep only their ctrl keys, since d deletes.
+func tableKeyMap() table.KeyMap {
+	km := table.DefaultKeyMap()
+	km.LineUp = keys.Keys.Up
+	km.LineDown = keys.Keys.Down
+	km.PageUp = keys.Keys.PageUp
+	km.PageDown = keys.Keys.PageDown
+	km.GotoTop = keys.Keys.Home
+	km.GotoBottom = keys.Keys.End
+	km.HalfPageUp.SetKeys("ctrl+u")
+	km.HalfPageDown.SetKeys("ctrl+d")
+	return km
+}
+
 func (m Model) View() string {
 	theme := m.ctx.ThemeManager.GetCurrentTheme()
 
@@ -103,7 +186,7 @@ func (m Model) View() string {
 			Padding(2, 0).
 			Align(lipgloss.Center)
 
-		return emptyStyle.Render("No todos yet - Press 'n' to create a new task")
+		return emptyStyle.Render("No todos yet - Press '" + keys.Keys.NewTodo.Help().Key + "' to create a new task")
 	}
 
 	m.applyTableTheme()
@@ -118,15 +201,111 @@ func (m Model) View() string {
 		Padding(1, 1)
 
 	var s strings.Builder
-	s.WriteString(titleStyle.Render("TUIDOO - Todo List"))
+	heading := "TUIDOO - Todo List · " + m.ctx.Profile
+	switch {
+	case m.filter.Project != "":
+		heading += " · project " + m.filter.Project
+	case m.filter.List != "":
+		heading += " · list " + m.filter.List
+	}
+	title := titleStyle.Render(heading)
+	if time.Since(m.externalUpdate) < updatedShownFor {
+		title = lipgloss.JoinHorizontal(lipgloss.Center, title, helpStyle.Padding(0).Render("● updated"))
+	}
+	s.WriteString(title)
 	s.WriteString("\n")
 	s.WriteString(m.table.View())
 	s.WriteString("\n")
-	s.WriteString(helpStyle.Render("enter: edit | space: toggle done | n: new | r: refresh"))
+	s.WriteString(helpStyle.Render(keys.HintLine(
+		keys.Hint(keys.Keys.Enter, "edit"),
+		keys.Hint(keys.Keys.ToggleDone, "toggle done"),
+		keys.Hint(keys.Keys.NewTodo, "new"),
+		keys.Hint(keys.Keys.Refresh, "refresh"),
+		m.filterHint(),
+	)))
 
 	return s.String()
 }
 
+func (m Model) filterHint() string {
+	if !m.Filtered() {
+		return ""
+	}
+	return keys.Hint(keys.Keys.Escape, "show all")
+}
+
+// SetFilter narrows the list to the project or list of filter and reloads
+// it; the zero filter shows every todo again
+func (m *Model) SetFilter(filter services.ToDoFilter) tea.Cmd {
+	m.filter = filter
+	m.table.SetCursor(0)
+	return m.FetchTodos()
+}
+
+// Filter returns what the list is narrowed to
+func (m Model) Filter() services.ToDoFilter {
+	return m.filter
+}
+
+// SelectNew puts the cursor on a todo that was just added once the list is
+// reloaded, clearing a filter that would hide it
+func (m *Model) SelectNew(todo *entities.ToDo) tea.Cmd {
+	m.selectID = todo.ID
+	if !m.matches(todo) {
+		return m.SetFilter(services.ToDoFilter{})
+	}
+	return m.FetchTodos()
+}
+
+// Filtered reports whether the list shows a single project or list
+func (m Model) Filtered() bool {
+	return m.filter != services.ToDoFilter{}
+}
+
+// Select puts the cursor on a todo, now or once it is loaded. When a
+// filtered list does not show the todo, the filter is cleared.
+func (m *Model) Select(id uint) tea.Cmd {
+	m.selectID = id
+	m.moveToSelected()
+	if m.selectID != 0 && m.Filtered() {
+		return m.SetFilter(services.ToDoFilter{})
+	}
+	return nil
+}
+
+// Selected returns the todo under the cursor, or nil when the list is empty
+func (m Model) Selected() *entities.ToDo {
+	idx := m.table.Cursor()
+	if idx < 0 || idx >= len(m.todos) {
+		return nil
+	}
+	return &m.todos[idx]
+}
+
+// ToggleSelected flips whether the todo under the cursor is done
+func (m Model) ToggleSelected() tea.Cmd {
+	if todo := m.Selected(); todo != nil {
+		return m.toggleTodo(todo)
+	}
+	return nil
+}
+
+func (m *Model) moveToSelected() {
+	if m.selectID == 0 {
+		return
+	}
+	if idx := slices.IndexFunc(m.todos, func(t entities.ToDo) bool { return t.ID == m.selectID }); idx >= 0 {
+		m.table.SetCursor(idx)
+		m.selectID = 0
+	}
+}
+
+// matches reports whether the filter shows a todo
+func (m Model) matches(todo *entities.ToDo) bool {
+	return (m.filter.Project == "" || strings.EqualFold(todo.Project.Name, m.filter.Project)) &&
+		(m.filter.List == "" || strings.EqualFold(todo.ToDoList.Name, m.filter.List))
+}
+
 func (m *Model) updateTableRows() {
 	rows := make([]table.Row, 0, len(m.todos))
 
@@ -144,6 +323,29 @@ func (m *Model) updateTableRows() {
 	m.table.SetRows(rows)
 }
 
+// replaceTodo swaps in a reloaded row, appends a new one or drops a deleted
+// one. The slice is copied since the todo form may still point into it.
+func (m *Model) replaceTodo(id uint, todo *entities.ToDo) {
+	idx := slices.IndexFunc(m.todos, func(t entities.ToDo) bool { return t.ID == id })
+	if todo != nil && !m.matches(todo) {
+		todo = nil
+	}
+
+	switch {
+	case todo == nil && idx < 0:
+		return
+	case todo == nil:
+		m.todos = slices.Delete(slices.Clone(m.todos), idx, idx+1)
+	case idx < 0:
+		m.todos = append(slices.Clip(m.todos), *todo)
+	default:
+		m.todos = slices.Clone(m.todos)
+		m.todos[idx] = *todo
+	}
+
+	m.updateTableRows()
+}
+
 func (m *Model) applyTableTheme() {
 	theme := m.ctx.ThemeManager.GetCurrentTheme()
 
@@ -167,8 +369,15 @@ func (m *Model) applyTableTheme() {
 }
 
 func (m Model) FetchTodos() tea.Cmd {
+	filter := m.filter
 	return func() tea.Msg {
-		todos, err := m.ctx.Services.ToDoService.GetAll(true)
+		var todos []entities.ToDo
+		var err error
+		if filter == (services.ToDoFilter{}) {
+			todos, err = m.ctx.Services.ToDoService.GetAll(true)
+		} else {
+			todos, err = m.ctx.Services.ToDoService.Find(filter)
+		}
 		if err != nil {
 			return TodosLoadedMsg{Todos: []entities.ToDo{}}
 		}
@@ -176,6 +385,29 @@ func (m Model) FetchTodos() tea.Cmd {
 	}
 }
 
+// markUpdated shows the "updated" indicator for a while
+func (m *Model) markUpdated() tea.Cmd {
+	m.externalUpdate = time.Now()
+	return tea.Tick(updatedShownFor, func(time.Time) tea.Msg {
+		return updatedExpiredMsg{}
+	})
+}
+
+// refreshTodo reloads a single todo
+func (m Model) refreshTodo(id uint) tea.Cmd {
+	return func() tea.Msg {
+		todo, err := m.ctx.Services.ToDoService.GetByID(id, true)
+		if errors.Is(err, services.ErrNotFound) {
+			return TodoRefreshedMsg{ID: id}
+		}
+		if err != nil {
+			return nil
+		}
+		return TodoRefreshedMsg{ID: id, Todo: todo}
+	}
+}
+
+// toggleTodo flips whether a todo is done; the change event refreshes its row
 func (m Model) toggleTodo(todo *entities.ToDo) tea.Cmd {
 	return func() tea.Msg {
 		var err error
@@ -189,13 +421,7 @@ func (m Model) toggleTodo(todo *entities.ToDo) tea.Cmd {
 			return TodosLoadedMsg{Todos: m.todos}
 		}
 
-		// Reload todos
-		todos, err := m.ctx.Services.ToDoService.GetAll(true)
-		if err != nil {
-			return TodosLoadedMsg{Todos: m.todos}
-		}
-
-		return TodosLoadedMsg{Todos: todos}
+		return nil
 	}
 }
 
@@ -0,0 +1,120 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"tuidoo/config"
+	"tuidoo/tui/components/footer"
+	"tuidoo/tui/keys"
+
+	tea "github.com/charmbracelet/bubbletea"
+	log "github.com/charmbracelet/log"
+)
+
+// configCheckInterval is how often the config file is checked for edits
+const configCheckInterval = time.Second
+
+// configStamp tells versions of the config file apart
+type configStamp struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+func statConfig() configStamp {
+	info, err := os.Stat(config.ConfigFile())
+	if err != nil {
+		return configStamp{}
+	}
+	return configStamp{modTime: info.ModTime(), size: info.Size(), exists: true}
+}
+
+// configCheckMsg asks the model to look at the config file again
+type configCheckMsg struct{}
+
+// configLoadedMsg carries the config file after it was edited. Err is set
+// when the edit left it invalid, in which case the running config stays.
+type configLoadedMsg struct {
+	Config *config.Config
+	Keys   *keys.KeyMap
+	Err    error
+}
+
+func checkConfigLater() tea.Cmd {
+	return tea.Tick(configCheckInterval, func(time.Time) tea.Msg {
+		return configCheckMsg{}
+	})
+}
+
+func loadConfig() tea.Msg {
+	cfg, err := config.Load()
+	if err != nil {
+		return configLoadedMsg{Err: err}
+	}
+	km, err := keys.Load(cfg.Keys)
+	return configLoadedMsg{Config: cfg, Keys: km, Err: err}
+}
+
+// onConfigCheck reloads the config file when it changed since the last check
+func (m *Model) onConfigCheck() tea.Cmd {
+	stamp := statConfig()
+	if stamp == m.configStamp {
+		return checkConfigLater()
+	}
+	m.configStamp = stamp
+	return tea.Batch(loadConfig, checkConfigLater())
+}
+
+// onConfigLoaded applies an edited config file to the running TUI. The
+// database and profiles are only read at startup.
+func (m *Model) onConfigLoaded(msg configLoadedMsg) tea.Cmd {
+	if msg.Err != nil {
+		log.Warn("Config not reloaded", "err", msg.Err)
+		problems := strings.Split(msg.Err.Error(), "\n")
+		var invalid *config.ValidationError
+		if errors.As(msg.Err, &invalid) {
+			problems = nil
+			for _, p := range invalid.Problems {
+				problems = append(problems, p.String())
+			}
+		}
+		text := problems[0]
+		if more := len(problems) - 1; more > 0 {
+			text += fmt.Sprintf(" (+%d more, see tuidoo config validate)", more)
+		}
+		return m.showStatus(footer.StatusMsg{Text: "⚠️  Config not reloaded: " + text, Error: true})
+	}
+
+	previous := m.ctx.Config
+	m.ctx.Config = msg.Config
+	cfg := msg.Config
+
+	keys.Apply(msg.Keys)
+	m.layout()
+
+	if cfg.UI.Theme != "" && cfg.UI.Theme != previous.UI.Theme {
+		if err := m.ctx.ThemeManager.SetTheme(cfg.UI.Theme); err == nil {
+			m.refreshTheme()
+		}
+	}
+
+	if cfg.Features.RPCEnabled() != previous.Features.RPCEnabled() {
+		if cfg.Features.RPCEnabled() {
+			m.StartRPC()
+		} else {
+			m.stopRPC()
+		}
+	}
+
+	log.Info("Reloaded config", "file", config.ConfigFile())
+	return m.showStatus(footer.StatusMsg{Text: "🔄 Reloaded " + config.ConfigFile()})
+}
+
+func (m *Model) showStatus(status footer.StatusMsg) tea.Cmd {
+	var cmd tea.Cmd
+	m.footer, cmd = m.footer.Update(status)
+	return cmd
+}
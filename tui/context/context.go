@@ -3,8 +3,11 @@ package context
 import (
 	"fmt"
 	"time"
+	"tuidoo/api"
+	"tuidoo/config"
 	"tuidoo/managers"
 	"tuidoo/services"
+	"tuidoo/tui/keys"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -14,6 +17,11 @@ import (
 type ProgramContext struct {
 	Services     *services.ServiceCollection
 	ThemeManager *managers.ThemeManager
+	Config       *config.Config
+	Profile      string
+
+	// RPC serves the active profile's JSON-RPC socket, nil when it is not running
+	RPC *api.RPCServer
 
 	ScreenWidth       int
 	ScreenHeight      int
@@ -21,6 +29,14 @@ type ProgramContext struct {
 	MainContentHeight int
 
 	StartTask func(task Task) tea.Cmd
+
+	// Sequencer holds the keys of a sequence binding typed so far
+	Sequencer *keys.Sequencer
+
+	// Send delivers a message to the running program from any goroutine
+	Send func(tea.Msg)
+	// StopEvents ends forwarding the active profile's service events
+	StopEvents func()
 }
 
 type TaskState int
@@ -0,0 +1,98 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"tuidoo/services"
+	"tuidoo/tui/components/footer"
+	"tuidoo/tui/components/todolist"
+
+	tea "github.com/charmbracelet/bubbletea"
+	log "github.com/charmbracelet/log"
+)
+
+// themeChangedMsg reports that the active theme setting changed
+type themeChangedMsg struct {
+	ThemeID string
+}
+
+// ListenForEvents forwards the service events of the active profile to the
+// program through send, also after switching profile
+func (m Model) ListenForEvents(send func(tea.Msg)) {
+	m.ctx.Send = send
+	m.subscribeEvents()
+}
+
+func (m Model) subscribeEvents() {
+	m.unsubscribeEvents()
+	if m.ctx.Send == nil {
+		return
+	}
+
+	send := m.ctx.Send
+	if err := m.ctx.Services.WatchService.Start(); err != nil {
+		log.Warn("Not watching for external changes", "err", err)
+	}
+	m.ctx.StopEvents = m.ctx.Services.Events.Subscribe(func(e services.Event) {
+		msg := eventMsg(e)
+		if msg == nil {
+			return
+		}
+		// Events are often published from inside Update, where Send would
+		// block. The messages may arrive out of order, which is fine since
+		// every one of them reloads the current state.
+		go send(msg)
+	})
+	m.ctx.Services.RuleService.Start(m.ctx.Services.ToDoService)
+}
+
+func (m Model) unsubscribeEvents() {
+	if m.ctx.StopEvents != nil {
+		m.ctx.StopEvents()
+		m.ctx.StopEvents = nil
+	}
+}
+
+// eventMsg turns a service event into the message that refreshes what it
+// affects: a single row for todo events, the whole list for changes that
+// touch many rows. Events the TUI does not show are dropped.
+func eventMsg(e services.Event) tea.Msg {
+	if external, ok := e.(services.ExternalChange); ok {
+		switch msg := eventMsg(external.Event).(type) {
+		case todolist.TodoChangedMsg:
+			msg.External = true
+			return msg
+		case todolist.TodosStaleMsg:
+			msg.External = true
+			return msg
+		default:
+			return msg
+		}
+	}
+
+	if id, ok := services.TodoID(e); ok {
+		return todolist.TodoChangedMsg{ID: id}
+	}
+
+	switch e := e.(type) {
+	case services.ProjectUpdated, services.ProjectDeleted,
+		services.ListUpdated, services.ListDeleted,
+		services.DataImported, services.DataReset:
+		return todolist.TodosStaleMsg{}
+	case services.ThemeChanged:
+		return themeChangedMsg{ThemeID: e.ThemeID}
+	case services.RuleFired:
+		if e.Err != nil {
+			return footer.StatusMsg{Text: fmt.Sprintf("⚠️  Rule %q on task %d: %v", e.Rule, e.ID, e.Err), Error: true}
+		}
+		return footer.StatusMsg{Text: fmt.Sprintf("⚙️  Rule %q ran on task %d", e.Rule, e.ID)}
+	case services.HookFailed:
+		text := fmt.Sprintf("⚠️  Hook failed: %v", e.Err)
+		if errors.Is(e.Err, services.ErrHookRejected) {
+			text = fmt.Sprintf("⛔ Change %v", e.Err)
+		}
+		return footer.StatusMsg{Text: text, Error: true}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,423 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"tuidoo/config"
+	"tuidoo/entities"
+	"tuidoo/enums"
+	"tuidoo/formats"
+	"tuidoo/services"
+	"tuidoo/tui/context"
+
+	tea "github.com/charmbracelet/bubbletea"
+	log "github.com/charmbracelet/log"
+)
+
+// commandHistoryLimit is how many lines the command line remembers
+const commandHistoryLimit = 100
+
+// commandHistoryFile keeps the lines of the command line across sessions
+// and profiles
+func commandHistoryFile() string {
+	return config.HistoryFile("commands")
+}
+
+// exCommand is a command of the : line
+type exCommand struct {
+	name    string
+	aliases []string
+	// args is the usage after the name, e.g. [ID] PROJECT
+	args string
+	// task commands act on the selected task, or on the task whose ID comes
+	// first
+	task bool
+	// values lists what the last argument can be, for tab completion
+	values func(ctx *context.ProgramContext) []string
+	run    func(m *Model, todo *entities.ToDo, args []string) (tea.Cmd, error)
+}
+
+// errUsage makes the command line show the usage of the command
+var errUsage = errors.New("usage")
+
+func (c exCommand) usage() error {
+	return errors.New(strings.TrimSpace("usage: " + c.name + " " + c.args))
+}
+
+// exCommands are the commands of the : line
+var exCommands = []exCommand{
+	{name: "move", aliases: []string{"mv"}, args: "[ID] PROJECT", task: true, values: projectNames,
+		run: func(m *Model, todo *entities.ToDo, args []string) (tea.Cmd, error) {
+			if len(args) != 1 {
+				return nil, errUsage
+			}
+			project, err := m.ctx.Services.ProjectService.GetByName(args[0])
+			if err != nil {
+				return nil, err
+			}
+			todo.ProjectID, todo.Project = project.ID, entities.Project{}
+			return nil, m.ctx.Services.ToDoService.Update(todo)
+		}},
+	{name: "list", args: "[ID] LIST|none", task: true, values: listNames,
+		run: func(m *Model, todo *entities.ToDo, args []string) (tea.Cmd, error) {
+			if len(args) != 1 {
+				return nil, errUsage
+			}
+			todo.ToDoListID, todo.ToDoList = 0, entities.ToDoList{}
+			if !strings.EqualFold(args[0], "none") {
+				list, err := m.ctx.Services.ToDoListService.GetByName(args[0])
+				if err != nil {
+					return nil, err
+				}
+				todo.ToDoListID = list.ID
+			}
+			return nil, m.ctx.Services.ToDoService.Update(todo)
+		}},
+	{name: "prio", aliases: []string{"priority"}, args: "[ID] low|medium|high|urgent", task: true, values: lowered(enums.PriorityOptions),
+		run: func(m *Model, todo *entities.ToDo, args []string) (tea.Cmd, error) {
+			if len(args) != 1 {
+				return nil, errUsage
+			}
+			p, err := enums.ParsePriority(args[0])
+			if err != nil {
+				return nil, err
+			}
+			todo.Priority = p
+			return nil, m.ctx.Services.ToDoService.Update(todo)
+		}},
+	{name: "status", args: "[ID] STATUS", task: true, values: statusValues,
+		run: func(m *Model, todo *entities.ToDo, args []string) (tea.Cmd, error) {
+			if len(args) != 1 {
+				return nil, errUsage
+			}
+			s, err := enums.ParseStatus(args[0])
+			if err != nil {
+				return nil, err
+			}
+			todo.Status, todo.Done = s, s == enums.Done
+			return nil, m.ctx.Services.ToDoService.Update(todo)
+		}},
+	{name: "due", args: "[ID] DATE|+3d|none", task: true, values: dueValues,
+		run: func(m *Model, todo *entities.ToDo, args []string) (tea.Cmd, error) {
+			if len(args) != 1 {
+				return nil, errUsage
+			}
+			todo.DueDate = nil
+			if !strings.EqualFold(args[0], "none") {
+				due, err := services.ParseDue(args[0], time.Now())
+				if err != nil {
+					return nil, err
+				}
+				todo.DueDate = &due
+			}
+			return nil, m.ctx.Services.ToDoService.Update(todo)
+		}},
+	{name: "done", args: "[ID]", task: true,
+		run: func(m *Model, todo *entities.ToDo, args []string) (tea.Cmd, error) {
+			if len(args) != 0 {
+				return nil, errUsage
+			}
+			return nil, m.ctx.Services.ToDoService.MarkAsComplete(todo.ID)
+		}},
+	{name: "undone", args: "[ID]", task: true,
+		run: func(m *Model, todo *entities.ToDo, args []string) (tea.Cmd, error) {
+			if len(args) != 0 {
+				return nil, errUsage
+			}
+			return nil, m.ctx.Services.ToDoService.MarkAsIncomplete(todo.ID)
+		}},
+	{name: "theme", args: "NAME", values: func(ctx *context.ProgramContext) []string { return ctx.ThemeManager.GetThemeNames() },
+		run: func(m *Model, _ *entities.ToDo, args []string) (tea.Cmd, error) {
+			if len(args) != 1 {
+				return nil, errUsage
+			}
+			names := m.ctx.ThemeManager.GetThemeNames()
+			i := slices.IndexFunc(names, func(n string) bool { return strings.EqualFold(n, args[0]) })
+			if i < 0 {
+				return nil, fmt.Errorf("unknown theme %q", args[0])
+			}
+			m.applyTheme(names[i])
+			return nil, nil
+		}},
+	{name: "profile", args: "NAME", values: func(ctx *context.ProgramContext) []string { return ctx.Config.ProfileNames() },
+		run: func(m *Model, _ *entities.ToDo, args []string) (tea.Cmd, error) {
+			if len(args) != 1 {
+				return nil, errUsage
+			}
+			if err := m.switchProfile(args[0]); err != nil {
+				return nil, err
+			}
+			m.showView(ViewMain)
+			return m.todoList.FetchTodos(), nil
+		}},
+	{name: "export", args: "[FORMAT] [FILE]", values: exportFormats,
+		run: func(m *Model, _ *entities.ToDo, args []string) (tea.Cmd, error) {
+			format, path := "json", ""
+			var err error
+			switch len(args) {
+			case 0:
+			case 1:
+				// A format, or else a file named after one
+				if format, err = formats.Detect(args[0], ""); err != nil {
+					path = args[0]
+					format, err = formats.Detect("", path)
+				}
+			case 2:
+				format, err = formats.Detect(args[0], "")
+				path = args[1]
+			default:
+				return nil, errUsage
+			}
+			if err != nil {
+				return nil, err
+			}
+			return m.export(format, path), nil
+		}},
+	{name: "quit", aliases: []string{"q"},
+		run: func(*Model, *entities.ToDo, []string) (tea.Cmd, error) {
+			return tea.Quit, nil
+		}},
+}
+
+// findExCommand looks a command up by name, alias or unambiguous prefix
+func findExCommand(name string) (*exCommand, error) {
+	var found []*exCommand
+	for i := range exCommands {
+		c := &exCommands[i]
+		if c.name == name || slices.Contains(c.aliases, name) {
+			return c, nil
+		}
+		if strings.HasPrefix(c.name, name) {
+			found = append(found, c)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("unknown command %q", name)
+	case 1:
+		return found[0], nil
+	default:
+		names := make([]string, len(found))
+		for i, c := range found {
+			names[i] = c.name
+		}
+		return nil, fmt.Errorf("%q could be %s", name, strings.Join(names, ", "))
+	}
+}
+
+// runCommandLine parses and runs a line of the : line. Task commands take
+// the selected task unless the first argument is a task ID.
+func (m *Model) runCommandLine(line string) (tea.Cmd, error) {
+	m.commandHistory = remember(commandHistoryFile(), m.commandHistory, line, commandHistoryLimit)
+
+	words, err := splitArgs(line)
+	if err != nil {
+		return nil, err
+	}
+	c, err := findExCommand(words[0])
+	if err != nil {
+		return nil, err
+	}
+	args := words[1:]
+
+	var todo *entities.ToDo
+	if c.task {
+		todo, args, err = m.commandTask(args)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	log.Info("Command line", "command", c.name, "args", args)
+	cmd, err := c.run(m, todo, args)
+	if errors.Is(err, errUsage) {
+		return nil, c.usage()
+	}
+	return cmd, err
+}
+
+// commandTask loads the task a task command acts on: the one whose ID is
+// the first argument, else the selected one
+func (m *Model) commandTask(args []string) (*entities.ToDo, []string, error) {
+	var id uint
+	if len(args) > 0 {
+		if n, err := strconv.ParseUint(args[0], 10, 64); err == nil {
+			id, args = uint(n), args[1:]
+		}
+	}
+	if id == 0 {
+		selected := m.todoList.Selected()
+		if selected == nil {
+			return nil, nil, errors.New("no task selected")
+		}
+		id = selected.ID
+	}
+
+	todo, err := m.ctx.Services.ToDoService.GetByID(id, false)
+	if errors.Is(err, services.ErrNotFound) {
+		return nil, nil, fmt.Errorf("task %d not found", id)
+	}
+	return todo, args, err
+}
+
+// completeCommandLine completes command names, task IDs where a task
+// command takes one, and the values of the last argument
+func completeCommandLine(ctx *context.ProgramContext, line string) (int, []string) {
+	start, word := lastWord(line)
+	words, err := splitArgs(line[:start])
+	if err != nil {
+		return start, nil
+	}
+
+	if len(words) == 0 {
+		var names []string
+		for _, c := range exCommands {
+			names = append(names, c.name)
+		}
+		return start, matching(names, word)
+	}
+
+	c, err := findExCommand(words[0])
+	if err != nil {
+		return start, nil
+	}
+	if c.task && len(words) == 1 && word != "" && word[0] >= '0' && word[0] <= '9' {
+		return start, matching(taskIDs(ctx), word)
+	}
+	if c.values == nil {
+		return start, nil
+	}
+	return start, matching(c.values(ctx), word)
+}
+
+// taskIDs lists the IDs of the tasks in the list
+func taskIDs(ctx *context.ProgramContext) []string {
+	todos, err := ctx.Services.ToDoService.GetAll(false)
+	if err != nil {
+		log.Warn("No task IDs to complete", "err", err)
+		return nil
+	}
+	ids := make([]string, len(todos))
+	for i, t := range todos {
+		ids[i] = strconv.FormatUint(uint64(t.ID), 10)
+	}
+	return ids
+}
+
+func projectNames(ctx *context.ProgramContext) []string {
+	projects, err := ctx.Services.ProjectService.GetAll(false)
+	if err != nil {
+		log.Warn("No projects to complete", "err", err)
+	}
+	names := make([]string, len(projects))
+	for i, p := range projects {
+		names[i] = p.Name
+	}
+	return names
+}
+
+func listNames(ctx *context.ProgramContext) []string {
+	lists, err := ctx.Services.ToDoListService.GetAll(false)
+	if err != nil {
+		log.Warn("No lists to complete", "err", err)
+	}
+	names := []string{"none"}
+	for _, l := range lists {
+		names = append(names, l.Name)
+	}
+	return names
+}
+
+// statusValues are the statuses as they are typed, e.g. in-progress
+func statusValues(ctx *context.ProgramContext) []string {
+	values := lowered(enums.StatusOptions)(ctx)
+	for i, v := range values {
+		values[i] = strings.ReplaceAll(v, " ", "-")
+	}
+	return values
+}
+
+func dueValues(*context.ProgramContext) []string {
+	return []string{"today", "tomorrow", "+1d", "+1w", "none"}
+}
+
+func exportFormats(*context.ProgramContext) []string {
+	return append(slices.Clone(formats.Names), "md")
+}
+
+func lowered(options []string) func(*context.ProgramContext) []string {
+	return func(*context.ProgramContext) []string {
+		values := make([]string, len(options))
+		for i, o := range options {
+			values[i] = strings.ToLower(o)
+		}
+		return values
+	}
+}
+
+// matching keeps the candidates that start with prefix, ignoring case
+func matching(candidates []string, prefix string) []string {
+	var found []string
+	for _, c := range candidates {
+		if strings.HasPrefix(strings.ToLower(c), strings.ToLower(prefix)) {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+// splitArgs splits a line into words at spaces; double quotes keep spaces
+// in a word, as in move 12 "Home Lab"
+func splitArgs(line string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		inQuote bool
+	)
+	for _, r := range line {
+		switch {
+		case r == '"':
+			inQuote, inWord = !inQuote, true
+		case r == ' ' && !inQuote:
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inQuote {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// lastWord finds the word being typed at the end of line: where it starts,
+// opening quote included, and what it holds so far
+func lastWord(line string) (int, string) {
+	start, inQuote := 0, false
+	for i, r := range line {
+		switch {
+		case r == '"':
+			if !inQuote && (i == 0 || line[i-1] == ' ') {
+				start = i
+			}
+			inQuote = !inQuote
+		case r == ' ' && !inQuote:
+			start = i + 1
+		}
+	}
+	return start, strings.Trim(line[start:], `"`)
+}
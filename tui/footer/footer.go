@@ -1,18 +1,34 @@
 package footer
 
 import (
-	"strings"
+	"time"
 	"tuidoo/tui/context"
 
+	"github.com/charmbracelet/bubbles/help"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
+// statusShownFor is how long a status message replaces the key help
+const statusShownFor = 8 * time.Second
+
 type Model struct {
 	ctx   *context.ProgramContext
 	width int
+
+	status      StatusMsg
+	statusUntil time.Time
 }
 
+// StatusMsg shows a short message in the footer for a while
+type StatusMsg struct {
+	Text  string
+	Error bool
+}
+
+// statusExpiredMsg redraws the footer once a status message should be gone
+type statusExpiredMsg struct{}
+
 func NewModel(ctx *context.ProgramContext) Model {
 	return Model{
 		ctx:   ctx,
@@ -24,11 +40,18 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
+	case StatusMsg:
+		m.status = msg
+		m.statusUntil = time.Now().Add(statusShownFor)
+		return m, tea.Tick(statusShownFor, func(time.Time) tea.Msg {
+			return statusExpiredMsg{}
+		})
 	}
 	return m, nil
 }
 
-func (m Model) View() string {
+// View shows the short help of km, the bindings of the screen in use
+func (m Model) View(km help.KeyMap) string {
 	theme := m.ctx.ThemeManager.GetCurrentTheme()
 
 	helpStyle := lipgloss.NewStyle().
@@ -40,26 +63,31 @@ func (m Model) View() string {
 		Foreground(context.TcellToLipgloss(theme.Colors.Primary)).
 		Bold(true)
 
-	var helpItems []string
-	helpItems = append(helpItems,
-		keyStyle.Render("↑/k")+" up",
-		keyStyle.Render("↓/j")+" down",
-		keyStyle.Render("enter")+" select",
-		keyStyle.Render("space")+" toggle done",
-		keyStyle.Render("tab")+" switch focus",
-		keyStyle.Render("t")+" themes",
-		keyStyle.Render("r")+" refresh",
-		keyStyle.Render("n")+" new task",
-		keyStyle.Render("q")+" quit",
-	)
-
-	help := strings.Join(helpItems, " • ")
+	keyHelp := help.New()
+	keyHelp.Width = max(m.width-2, 0)
+	keyHelp.Styles.ShortKey = keyStyle
+	keyHelp.Styles.ShortDesc = lipgloss.NewStyle()
+	keyHelp.Styles.ShortSeparator = lipgloss.NewStyle()
+	keyHelp.Styles.Ellipsis = lipgloss.NewStyle()
+	line := keyHelp.ShortHelpView(km.ShortHelp())
+
+	if pending := m.ctx.Sequencer.Pending(); pending != "" {
+		line = keyStyle.Render(pending+" …") + "  " + line
+	}
+
+	if time.Now().Before(m.statusUntil) {
+		statusStyle := lipgloss.NewStyle().Foreground(context.TcellToLipgloss(theme.Colors.TextPrimary))
+		if m.status.Error {
+			statusStyle = statusStyle.Foreground(context.TcellToLipgloss(theme.Colors.Error))
+		}
+		line = statusStyle.Render(m.status.Text)
+	}
 
 	if m.width > 0 {
-		return helpStyle.Width(m.width).Render(help)
+		return helpStyle.Width(m.width).Render(line)
 	}
 
-	return helpStyle.Render(help)
+	return helpStyle.Render(line)
 }
 
 func (m *Model) ApplyTheme() {
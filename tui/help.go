@@ -0,0 +1,59 @@
+package tui
+
+import (
+	"tuidoo/tui/keys"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func init() {
+	register(Command{
+		ID:      "app.help",
+		Title:   "Show key bindings",
+		Binding: func(k *keys.KeyMap) *key.Binding { return &k.Help },
+		Run: func(m *Model) tea.Cmd {
+			m.openHelp()
+			return nil
+		},
+	})
+}
+
+// screen is the part of the TUI that has the keyboard, with its name for
+// the help overlay
+func (m Model) screen() (keys.Screen, string) {
+	if m.focusedOnMenu {
+		return keys.ScreenMenu, "menu"
+	}
+
+	switch m.currentView {
+	case ViewMain:
+		return keys.ScreenList, "task list"
+	case ViewThemes:
+		return keys.ScreenThemes, "themes"
+	case ViewProfiles:
+		return keys.ScreenProfiles, "profiles"
+	case ViewRules:
+		if m.ruleList.Editing() {
+			return keys.ScreenForm, "rule form"
+		}
+		return keys.ScreenRules, "rules"
+	case ViewTodoEdit:
+		return keys.ScreenForm, "task form"
+	}
+	return keys.ScreenGlobal, "projects"
+}
+
+// screenHelp is the help of the screen in use, for the footer and the
+// overlay
+func (m Model) screenHelp() keys.ScreenHelp {
+	s, _ := m.screen()
+	return m.keys.For(s)
+}
+
+// openHelp covers the screen with every binding that works where the
+// keyboard is
+func (m *Model) openHelp() {
+	s, name := m.screen()
+	m.keyHelp.Open(name, m.keys.For(s))
+}
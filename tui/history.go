@@ -0,0 +1,40 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	log "github.com/charmbracelet/log"
+)
+
+// loadHistory reads a history file, newest entry first. A missing file is
+// an empty history.
+func loadHistory(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn("Failed to read history", "file", path, "err", err)
+		}
+		return nil
+	}
+	return slices.DeleteFunc(strings.Split(string(data), "\n"), func(e string) bool { return e == "" })
+}
+
+// remember moves entry to the front of a history, keeping at most limit
+// entries, and writes it back
+func remember(path string, history []string, entry string, limit int) []string {
+	history = slices.DeleteFunc(slices.Clone(history), func(e string) bool { return e == entry })
+	history = slices.Insert(history, 0, entry)
+	history = history[:min(len(history), limit)]
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		log.Warn("Failed to save history", "file", path, "err", err)
+		return history
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(history, "\n")+"\n"), 0o600); err != nil {
+		log.Warn("Failed to save history", "file", path, "err", err)
+	}
+	return history
+}
@@ -0,0 +1,264 @@
+package keys
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"tuidoo/config"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// Context is where a binding is active. Keys must be unique within a
+// context and its parents, which are checked first.
+type Context struct {
+	Name   string
+	Parent *Context
+}
+
+var (
+	// Global bindings work in every view
+	Global = &Context{Name: "global"}
+	// Lists are the todo list, the menu and the theme, profile and rule
+	// lists
+	Lists = &Context{Name: "lists", Parent: Global}
+	// Forms take the keyboard, so the global bindings do not apply there
+	Forms = &Context{Name: "forms"}
+)
+
+// Action is a KeyMap field that the keys section of the config file can
+// rebind
+type Action struct {
+	// Name is the config key, e.g. toggle_done
+	Name    string
+	Context *Context
+	Binding func(*KeyMap) *key.Binding
+}
+
+// Actions lists every rebindable KeyMap field
+var Actions = []Action{
+	{"up", Lists, func(k *KeyMap) *key.Binding { return &k.Up }},
+	{"down", Lists, func(k *KeyMap) *key.Binding { return &k.Down }},
+	{"left", Lists, func(k *KeyMap) *key.Binding { return &k.Left }},
+	{"right", Lists, func(k *KeyMap) *key.Binding { return &k.Right }},
+	{"page_up", Lists, func(k *KeyMap) *key.Binding { return &k.PageUp }},
+	{"page_down", Lists, func(k *KeyMap) *key.Binding { return &k.PageDown }},
+	{"home", Lists, func(k *KeyMap) *key.Binding { return &k.Home }},
+	{"end", Lists, func(k *KeyMap) *key.Binding { return &k.End }},
+	{"enter", Lists, func(k *KeyMap) *key.Binding { return &k.Enter }},
+	{"escape", Global, func(k *KeyMap) *key.Binding { return &k.Escape }},
+	{"tab", Global, func(k *KeyMap) *key.Binding { return &k.Tab }},
+	{"quit", Global, func(k *KeyMap) *key.Binding { return &k.Quit }},
+	{"refresh", Global, func(k *KeyMap) *key.Binding { return &k.Refresh }},
+	{"help", Global, func(k *KeyMap) *key.Binding { return &k.Help }},
+	{"command_palette", Global, func(k *KeyMap) *key.Binding { return &k.Palette }},
+	{"command_line", Global, func(k *KeyMap) *key.Binding { return &k.Command }},
+	{"new_todo", Lists, func(k *KeyMap) *key.Binding { return &k.NewTodo }},
+	{"edit_todo", Lists, func(k *KeyMap) *key.Binding { return &k.EditTodo }},
+	{"delete_todo", Lists, func(k *KeyMap) *key.Binding { return &k.DeleteTodo }},
+	{"toggle_done", Lists, func(k *KeyMap) *key.Binding { return &k.ToggleDone }},
+	{"save", Forms, func(k *KeyMap) *key.Binding { return &k.Save }},
+	{"next_field", Forms, func(k *KeyMap) *key.Binding { return &k.NextField }},
+	{"previous_field", Forms, func(k *KeyMap) *key.Binding { return &k.PrevField }},
+	{"toggle_themes", Global, func(k *KeyMap) *key.Binding { return &k.ToggleThemes }},
+	{"view_projects", Global, func(k *KeyMap) *key.Binding { return &k.ViewProjects }},
+	{"switch_profile", Global, func(k *KeyMap) *key.Binding { return &k.SwitchProfile }},
+	{"view_rules", Global, func(k *KeyMap) *key.Binding { return &k.ViewRules }},
+}
+
+// Load returns the default key map with the keys section of the config
+// file applied. Unknown actions, unknown key names and keys that collide
+// within a context are all reported, and nothing is applied then.
+func Load(overrides map[string]config.KeyList) (*KeyMap, error) {
+	km := Default()
+	var errs []error
+
+	for _, name := range slices.Sorted(maps.Keys(overrides)) {
+		action := findAction(name)
+		if action == nil {
+			errs = append(errs, fmt.Errorf("keys.%s: unknown action (expected one of %s)", name, strings.Join(actionNames(), ", ")))
+			continue
+		}
+
+		var bound []string
+		for _, k := range overrides[name] {
+			normalized, err := normalize(k)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("keys.%s: %w", name, err))
+				continue
+			}
+			bound = append(bound, normalized)
+		}
+
+		b := action.Binding(km)
+		b.SetKeys(bound...)
+		b.SetHelp(HelpKeys(bound), b.Help().Desc)
+	}
+
+	errs = append(errs, conflicts(km)...)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return km, nil
+}
+
+// Apply makes km the effective key map
+func Apply(km *KeyMap) {
+	*Keys = *km
+}
+
+// conflicts reports keys bound twice in a context or its parents, and keys
+// that start a sequence bound in the same place, which could never be typed
+func conflicts(km *KeyMap) []error {
+	var errs []error
+	for i, a := range Actions {
+		for _, b := range Actions[i+1:] {
+			if !a.Context.sees(b.Context) && !b.Context.sees(a.Context) {
+				continue
+			}
+			for _, ka := range a.Binding(km).Keys() {
+				for _, kb := range b.Binding(km).Keys() {
+					switch {
+					case ka == kb:
+						errs = append(errs, fmt.Errorf("keys: %q is bound to both %s and %s", DisplayKey(ka), a.Name, b.Name))
+					case isPrefix(ka, kb):
+						errs = append(errs, fmt.Errorf("keys: %q (%s) starts %q (%s), which could never be typed", DisplayKey(ka), a.Name, DisplayKey(kb), b.Name))
+					case isPrefix(kb, ka):
+						errs = append(errs, fmt.Errorf("keys: %q (%s) starts %q (%s), which could never be typed", DisplayKey(kb), b.Name, DisplayKey(ka), a.Name))
+					}
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// sees reports whether bindings of other are active in c
+func (c *Context) sees(other *Context) bool {
+	for ; c != nil; c = c.Parent {
+		if c == other {
+			return true
+		}
+	}
+	return false
+}
+
+// isPrefix reports whether key a is the start of the longer sequence b
+func isPrefix(a, b string) bool {
+	return strings.HasPrefix(b, step(a)+" ")
+}
+
+// step is a key as it is written in a sequence
+func step(k string) string {
+	if k == " " {
+		return "space"
+	}
+	return k
+}
+
+func findAction(name string) *Action {
+	for i := range Actions {
+		if Actions[i].Name == name {
+			return &Actions[i]
+		}
+	}
+	return nil
+}
+
+func actionNames() []string {
+	names := make([]string, len(Actions))
+	for i, a := range Actions {
+		names[i] = a.Name
+	}
+	return names
+}
+
+// namedKeys are the key names bubbletea reports besides printable runes
+var namedKeys = []string{
+	"enter", "tab", "esc", "backspace", "delete", "insert", "space",
+	"up", "down", "left", "right", "home", "end", "pgup", "pgdown",
+	"f1", "f2", "f3", "f4", "f5", "f6", "f7", "f8", "f9", "f10",
+	"f11", "f12", "f13", "f14", "f15", "f16", "f17", "f18", "f19", "f20",
+}
+
+// normalize checks a key as written in the config file and returns it the
+// way key.Matches sees it. Single keys are bubbletea key strings, where the
+// space bar is " "; the steps of a sequence are separated by spaces, so
+// there the space bar is written "space".
+func normalize(k string) (string, error) {
+	steps := strings.Fields(k)
+	if len(steps) == 0 {
+		if k != "" {
+			return " ", nil
+		}
+		return "", fmt.Errorf("empty key")
+	}
+
+	for _, step := range steps {
+		if !validStep(step) {
+			return "", fmt.Errorf("unknown key %q in %q (use e.g. x, ctrl+x, alt+x, enter, space, f1 or \"g g\")", step, k)
+		}
+	}
+	if len(steps) == 1 && steps[0] == "space" {
+		return " ", nil
+	}
+	return strings.Join(steps, " "), nil
+}
+
+func validStep(step string) bool {
+	if utf8.RuneCountInString(step) == 1 || slices.Contains(namedKeys, step) {
+		return true
+	}
+	for _, modifier := range []string{"alt+", "ctrl+", "shift+"} {
+		if rest, ok := strings.CutPrefix(step, modifier); ok {
+			if modifier == "ctrl+" && utf8.RuneCountInString(rest) == 1 {
+				return true
+			}
+			return validStep(rest)
+		}
+	}
+	return false
+}
+
+// DisplayKey shows a key the way people write it
+func DisplayKey(k string) string {
+	switch k {
+	case " ":
+		return "space"
+	case "up":
+		return "↑"
+	case "down":
+		return "↓"
+	case "left":
+		return "←"
+	case "right":
+		return "→"
+	}
+	return k
+}
+
+// HelpKeys shows the first two keys of a binding, e.g. ↑/k
+func HelpKeys(keys []string) string {
+	shown := make([]string, 0, 2)
+	for _, k := range keys[:min(len(keys), 2)] {
+		shown = append(shown, DisplayKey(k))
+	}
+	return strings.Join(shown, "/")
+}
+
+// Hint is "keys: desc" for the help lines under the lists, or "" when the
+// binding has no keys
+func Hint(b key.Binding, desc string) string {
+	if len(b.Keys()) == 0 {
+		return ""
+	}
+	return b.Help().Key + ": " + desc
+}
+
+// HintLine joins hints with " | ", leaving out empty ones
+func HintLine(hints ...string) string {
+	return strings.Join(slices.DeleteFunc(hints, func(h string) bool { return h == "" }), " | ")
+}
@@ -0,0 +1,147 @@
+package keys
+
+import (
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// Screen is a part of the TUI with bindings of its own
+type Screen int
+
+const (
+	// ScreenGlobal has only the global bindings, e.g. a placeholder view
+	ScreenGlobal Screen = iota
+	ScreenList
+	ScreenMenu
+	ScreenForm
+	ScreenThemes
+	ScreenProfiles
+	ScreenRules
+)
+
+// Group is bindings under a title, as the help overlay lists them
+type Group struct {
+	Title    string
+	Bindings []key.Binding
+}
+
+// ScreenHelp is what works on one screen: its own bindings, then the global
+// ones unless the screen takes the keyboard. It implements help.KeyMap.
+type ScreenHelp struct {
+	Groups []Group
+	// Short are the bindings the footer shows
+	Short []key.Binding
+}
+
+var _ help.KeyMap = ScreenHelp{}
+var _ help.KeyMap = (*KeyMap)(nil)
+
+// ShortHelp returns the footer bindings that have keys
+func (h ScreenHelp) ShortHelp() []key.Binding {
+	return bound(h.Short)
+}
+
+// FullHelp returns the groups as columns, leaving out empty ones
+func (h ScreenHelp) FullHelp() [][]key.Binding {
+	var columns [][]key.Binding
+	for _, g := range h.Groups {
+		if b := bound(g.Bindings); len(b) > 0 {
+			columns = append(columns, b)
+		}
+	}
+	return columns
+}
+
+// ShortHelp is the help of the task list
+func (k *KeyMap) ShortHelp() []key.Binding {
+	return k.For(ScreenList).ShortHelp()
+}
+
+// FullHelp is every screen's own bindings, then the global ones
+func (k *KeyMap) FullHelp() [][]key.Binding {
+	var columns [][]key.Binding
+	for s := ScreenList; s <= ScreenRules; s++ {
+		if b := bound(k.For(s).Groups[0].Bindings); len(b) > 0 {
+			columns = append(columns, b)
+		}
+	}
+	return append(columns, bound(k.global().Bindings))
+}
+
+// For returns the help of a screen, with each binding described the way it
+// acts there
+func (k *KeyMap) For(s Screen) ScreenHelp {
+	var own Group
+	var short []key.Binding
+
+	switch s {
+	case ScreenList:
+		own = Group{"Task list", []key.Binding{
+			k.Up, k.Down, k.PageUp, k.PageDown,
+			relabel(k.Home, "first task"), relabel(k.End, "last task"),
+			relabel(k.Enter, "edit"), k.EditTodo, k.ToggleDone, k.NewTodo,
+			relabel(k.Escape, "clear filter"),
+		}}
+		short = []key.Binding{k.Up, k.Down, relabel(k.Enter, "edit"), k.ToggleDone, k.NewTodo}
+
+	case ScreenMenu:
+		own = Group{"Menu", []key.Binding{k.Up, k.Down, relabel(k.Enter, "open")}}
+		short = own.Bindings
+
+	case ScreenForm:
+		// The form has the keyboard; only its own bindings work
+		own = Group{"Form", []key.Binding{
+			k.NextField, k.PrevField, relabel(k.Left, "previous option"),
+			relabel(k.Right, "next option"), k.Save, relabel(k.Escape, "cancel"),
+		}}
+		return ScreenHelp{Groups: []Group{own}, Short: []key.Binding{k.NextField, k.PrevField, k.Save, relabel(k.Escape, "cancel")}}
+
+	case ScreenThemes:
+		own = Group{"Themes", []key.Binding{k.Up, k.Down, relabel(k.Enter, "apply theme"), relabel(k.Escape, "back")}}
+		short = own.Bindings
+
+	case ScreenProfiles:
+		own = Group{"Profiles", []key.Binding{k.Up, k.Down, relabel(k.Enter, "switch profile"), relabel(k.Escape, "back")}}
+		short = own.Bindings
+
+	case ScreenRules:
+		own = Group{"Rules", []key.Binding{
+			k.Up, k.Down, relabel(k.NewTodo, "new rule"), relabel(k.Enter, "edit rule"),
+			relabel(k.EditTodo, "edit rule"), relabel(k.ToggleDone, "enable/disable"),
+			relabel(k.DeleteTodo, "delete rule"), relabel(k.Escape, "back"),
+		}}
+		short = []key.Binding{relabel(k.NewTodo, "new"), relabel(k.Enter, "edit"), relabel(k.Escape, "back")}
+	}
+
+	global := k.global()
+	short = append(short, k.Tab, k.Palette, k.Help, k.Quit)
+	if own.Title == "" {
+		return ScreenHelp{Groups: []Group{global}, Short: short}
+	}
+	return ScreenHelp{Groups: []Group{own, global}, Short: short}
+}
+
+// global are the bindings that work on every screen but the forms
+func (k *KeyMap) global() Group {
+	return Group{"Everywhere", []key.Binding{
+		k.Tab, k.Palette, k.Command, k.ToggleThemes, k.SwitchProfile,
+		k.ViewRules, relabel(k.Refresh, "refresh tasks"), relabel(k.Help, "help"), k.Quit,
+	}}
+}
+
+// relabel is b described as desc
+func relabel(b key.Binding, desc string) key.Binding {
+	b.SetHelp(b.Help().Key, desc)
+	return b
+}
+
+// bound leaves out bindings without keys
+func bound(bindings []key.Binding) []key.Binding {
+	var out []key.Binding
+	for _, b := range bindings {
+		if b.Enabled() {
+			out = append(out, b)
+		}
+	}
+	return out
+}
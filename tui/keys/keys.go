@@ -15,7 +15,6 @@ type KeyMap struct {
 
 	// Actions
 	Enter  key.Binding
-	Space  key.Binding
 	Escape key.Binding
 	Tab    key.Binding
 
@@ -23,6 +22,8 @@ type KeyMap struct {
 	Quit    key.Binding
 	Refresh key.Binding
 	Help    key.Binding
+	Palette key.Binding
+	Command key.Binding
 
 	// Todo specific
 	NewTodo    key.Binding
@@ -30,94 +31,64 @@ type KeyMap struct {
 	DeleteTodo key.Binding
 	ToggleDone key.Binding
 
+	// Forms
+	Save      key.Binding
+	NextField key.Binding
+	PrevField key.Binding
+
 	// Views
-	ToggleThemes key.Binding
-	ViewProjects key.Binding
+	ToggleThemes  key.Binding
+	ViewProjects  key.Binding
+	SwitchProfile key.Binding
+	ViewRules     key.Binding
+}
+
+// Keys is the effective key map: the defaults with the keys section of the
+// config file applied (see Apply)
+var Keys = Default()
+
+// Default returns the built-in key map
+func Default() *KeyMap {
+	return &KeyMap{
+		Up:       newBinding("up", []string{"up", "k"}),
+		Down:     newBinding("down", []string{"down", "j"}),
+		Left:     newBinding("left", []string{"left", "h"}),
+		Right:    newBinding("right", []string{"right", "l"}),
+		PageUp:   newBinding("page up", []string{"pgup", "b"}),
+		PageDown: newBinding("page down", []string{"pgdown", "f"}),
+		Home:     newBinding("go to start", []string{"g", "home"}),
+		End:      newBinding("go to end", []string{"G", "end"}),
+
+		Enter:  newBinding("select", []string{"enter"}),
+		Escape: newBinding("cancel/back", []string{"esc"}),
+		Tab:    newBinding("switch focus", []string{"tab"}),
+
+		Quit:    newBinding("quit", []string{"q", "ctrl+c"}),
+		Refresh: newBinding("refresh", []string{"r"}),
+		Help:    newBinding("toggle help", []string{"?"}),
+		Palette: newBinding("commands", []string{"ctrl+p"}),
+		Command: newBinding("command line", []string{":"}),
+
+		NewTodo:    newBinding("new task", []string{"n"}),
+		EditTodo:   newBinding("edit task", []string{"e"}),
+		DeleteTodo: newBinding("delete task", []string{"d"}),
+		ToggleDone: newBinding("toggle done", []string{" ", "x"}),
+
+		Save:      newBinding("save", []string{"ctrl+s"}),
+		NextField: newBinding("next field", []string{"tab"}),
+		PrevField: newBinding("previous field", []string{"shift+tab"}),
+
+		ToggleThemes:  newBinding("themes", []string{"t"}),
+		ViewProjects:  newBinding("projects", []string{"p"}),
+		SwitchProfile: newBinding("profiles", []string{"P"}),
+		ViewRules:     newBinding("rules", []string{"R"}),
+	}
 }
 
-var Keys = &KeyMap{
-	Up: key.NewBinding(
-		key.WithKeys("up", "k"),
-		key.WithHelp("↑/k", "up"),
-	),
-	Down: key.NewBinding(
-		key.WithKeys("down", "j"),
-		key.WithHelp("↓/j", "down"),
-	),
-	Left: key.NewBinding(
-		key.WithKeys("left", "h"),
-		key.WithHelp("←/h", "left"),
-	),
-	Right: key.NewBinding(
-		key.WithKeys("right", "l"),
-		key.WithHelp("→/l", "right"),
-	),
-	PageUp: key.NewBinding(
-		key.WithKeys("pgup", "b"),
-		key.WithHelp("pgup", "page up"),
-	),
-	PageDown: key.NewBinding(
-		key.WithKeys("pgdown", "f"),
-		key.WithHelp("pgdown", "page down"),
-	),
-	Home: key.NewBinding(
-		key.WithKeys("home", "g"),
-		key.WithHelp("g/home", "go to start"),
-	),
-	End: key.NewBinding(
-		key.WithKeys("end", "G"),
-		key.WithHelp("G/end", "go to end"),
-	),
-	Enter: key.NewBinding(
-		key.WithKeys("enter"),
-		key.WithHelp("enter", "select"),
-	),
-	Space: key.NewBinding(
-		key.WithKeys(" "),
-		key.WithHelp("space", "toggle done"),
-	),
-	Escape: key.NewBinding(
-		key.WithKeys("esc"),
-		key.WithHelp("esc", "cancel/back"),
-	),
-	Tab: key.NewBinding(
-		key.WithKeys("tab"),
-		key.WithHelp("tab", "switch focus"),
-	),
-	Quit: key.NewBinding(
-		key.WithKeys("q", "ctrl+c"),
-		key.WithHelp("q", "quit"),
-	),
-	Refresh: key.NewBinding(
-		key.WithKeys("r"),
-		key.WithHelp("r", "refresh"),
-	),
-	Help: key.NewBinding(
-		key.WithKeys("?"),
-		key.WithHelp("?", "toggle help"),
-	),
-	NewTodo: key.NewBinding(
-		key.WithKeys("n"),
-		key.WithHelp("n", "new task"),
-	),
-	EditTodo: key.NewBinding(
-		key.WithKeys("e"),
-		key.WithHelp("e", "edit task"),
-	),
-	DeleteTodo: key.NewBinding(
-		key.WithKeys("d"),
-		key.WithHelp("d", "delete task"),
-	),
-	ToggleDone: key.NewBinding(
-		key.WithKeys(" ", "x"),
-		key.WithHelp("space/x", "toggle done"),
-	),
-	ToggleThemes: key.NewBinding(
-		key.WithKeys("t"),
-		key.WithHelp("t", "themes"),
-	),
-	ViewProjects: key.NewBinding(
-		key.WithKeys("p"),
-		key.WithHelp("p", "projects"),
-	),
+// newBinding binds keys to an action, with help showing the keys
+func newBinding(desc string, keys []string) key.Binding {
+	return key.NewBinding(
+		key.WithKeys(keys...),
+		key.WithHelp(HelpKeys(keys), desc),
+	)
 }
@@ -0,0 +1,84 @@
+package keys
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SequenceTimeout is how long a started sequence waits for its next key
+const SequenceTimeout = time.Second
+
+// Sequencer turns keys typed one after the other into the single message a
+// sequence binding such as "g g" matches
+type Sequencer struct {
+	pending []string
+	started time.Time
+}
+
+// Feed takes the next key. It returns the message to handle, which is a
+// completed sequence, the key itself, or nothing while a sequence is still
+// being typed. A key that does not continue the sequence drops it.
+func (s *Sequencer) Feed(msg tea.KeyMsg) (tea.KeyMsg, bool) {
+	if len(s.pending) > 0 && time.Since(s.started) > SequenceTimeout {
+		s.pending = nil
+	}
+
+	typed := strings.Join(append(s.pending, step(msg.String())), " ")
+	switch {
+	case s.bound(typed):
+		s.pending = nil
+		if !strings.Contains(typed, " ") {
+			return msg, true
+		}
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(typed)}, true
+
+	case s.starts(typed):
+		if len(s.pending) == 0 {
+			s.started = time.Now()
+		}
+		s.pending = append(s.pending, step(msg.String()))
+		return tea.KeyMsg{}, false
+
+	case len(s.pending) > 0:
+		// Drop the sequence, but the key may start another one
+		s.pending = nil
+		return s.Feed(msg)
+
+	default:
+		return msg, true
+	}
+}
+
+// Pending is the part of a sequence typed so far, for the footer
+func (s *Sequencer) Pending() string {
+	if time.Since(s.started) > SequenceTimeout {
+		return ""
+	}
+	return strings.Join(s.pending, " ")
+}
+
+// bound reports whether typed is a sequence of the effective key map
+func (s *Sequencer) bound(typed string) bool {
+	for _, a := range Actions {
+		for _, k := range a.Binding(Keys).Keys() {
+			if step(k) == typed && strings.Contains(typed, " ") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// starts reports whether typed begins a sequence of the effective key map
+func (s *Sequencer) starts(typed string) bool {
+	for _, a := range Actions {
+		for _, k := range a.Binding(Keys).Keys() {
+			if strings.HasPrefix(k, typed+" ") {
+				return true
+			}
+		}
+	}
+	return false
+}
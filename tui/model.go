@@ -2,11 +2,18 @@ package tui
 
 import (
 	"time"
+	"tuidoo/api"
+	"tuidoo/config"
 	"tuidoo/entities"
 	"tuidoo/managers"
 	"tuidoo/services"
+	"tuidoo/tui/components/cmdline"
 	"tuidoo/tui/components/footer"
+	"tuidoo/tui/components/keyhelp"
 	"tuidoo/tui/components/menu"
+	"tuidoo/tui/components/palette"
+	"tuidoo/tui/components/profilelist"
+	"tuidoo/tui/components/rulelist"
 	"tuidoo/tui/components/themelist"
 	"tuidoo/tui/components/todoform"
 	"tuidoo/tui/components/todolist"
@@ -25,6 +32,8 @@ const (
 	ViewThemes
 	ViewTodoEdit
 	ViewProjects
+	ViewProfiles
+	ViewRules
 )
 
 type Model struct {
@@ -33,46 +42,71 @@ type Model struct {
 	currentView View
 
 	// Components
-	menu      menu.Model
-	todoList  todolist.Model
-	themeList themelist.Model
-	todoForm  todoform.Model
-	footer    footer.Model
+	menu        menu.Model
+	todoList    todolist.Model
+	themeList   themelist.Model
+	profileList profilelist.Model
+	ruleList    rulelist.Model
+	todoForm    todoform.Model
+	footer      footer.Model
+	palette     palette.Model
+	cmdline     cmdline.Model
+	keyHelp     keyhelp.Model
 
 	// State
 	selectedTodo  *entities.ToDo
 	focusedOnMenu bool
 	taskSpinner   spinner.Model
 	tasks         map[string]context.Task
+
+	// configStamp is the version of the config file last loaded
+	configStamp configStamp
+	// paletteRecent holds the recent picks of the palette, newest first
+	paletteRecent []string
+	// commandHistory holds the lines of the command line, newest first
+	commandHistory []string
 }
 
-func NewModel(sc *services.ServiceCollection, tm *managers.ThemeManager) Model {
+func NewModel(sc *services.ServiceCollection, tm *managers.ThemeManager, cfg *config.Config, profile string) Model {
 	taskSpinner := spinner.Model{Spinner: spinner.Dot}
 
 	ctx := &context.ProgramContext{
 		Services:     sc,
 		ThemeManager: tm,
+		Config:       cfg,
+		Profile:      profile,
 		StartTask: func(task context.Task) tea.Cmd {
 			log.Info("Starting task", "id", task.Id)
 			task.StartTime = time.Now()
 			return taskSpinner.Tick
 		},
+		Sequencer: &keys.Sequencer{},
 	}
 
 	m := Model{
-		ctx:           ctx,
-		keys:          keys.Keys,
-		currentView:   ViewMain,
-		focusedOnMenu: true,
-		taskSpinner:   taskSpinner,
-		tasks:         map[string]context.Task{},
+		ctx:            ctx,
+		keys:           keys.Keys,
+		currentView:    ViewMain,
+		focusedOnMenu:  true,
+		taskSpinner:    taskSpinner,
+		tasks:          map[string]context.Task{},
+		configStamp:    statConfig(),
+		paletteRecent:  loadHistory(paletteHistoryFile(profile)),
+		commandHistory: loadHistory(commandHistoryFile()),
 	}
 
 	m.menu = menu.NewModel(ctx)
 	m.todoList = todolist.NewModel(ctx)
 	m.themeList = themelist.NewModel(ctx)
+	m.profileList = profilelist.NewModel(ctx)
+	m.ruleList = rulelist.NewModel(ctx)
 	m.todoForm = todoform.NewModel(ctx)
 	m.footer = footer.NewModel(ctx)
+	m.palette = palette.NewModel(ctx)
+	m.keyHelp = keyhelp.NewModel(ctx)
+	m.cmdline = cmdline.NewModel(ctx, func(line string) (int, []string) {
+		return completeCommandLine(ctx, line)
+	})
 
 	return m
 }
@@ -82,9 +116,38 @@ func (m Model) Init() tea.Cmd {
 		m.initScreen,
 		tea.EnterAltScreen,
 		m.todoList.FetchTodos(),
+		checkConfigLater(),
 	)
 }
 
+// Close releases the services of the active profile
+func (m Model) Close() error {
+	m.unsubscribeEvents()
+	m.stopRPC()
+	return m.ctx.Services.Close()
+}
+
+// StartRPC serves the active profile's JSON-RPC socket for editor
+// integrations. Another tuidoo already serving the profile is not an error.
+func (m Model) StartRPC() {
+	server := api.NewRPCServer(m.ctx.Services, m.ctx.Services.Selection.RPCSocket)
+	if err := server.Start(); err != nil {
+		log.Warn("JSON-RPC disabled", "err", err)
+		return
+	}
+	m.ctx.RPC = server
+}
+
+func (m Model) stopRPC() {
+	if m.ctx.RPC == nil {
+		return
+	}
+	if err := m.ctx.RPC.Close(); err != nil {
+		log.Warn("Failed to close JSON-RPC socket", "err", err)
+	}
+	m.ctx.RPC = nil
+}
+
 func (m *Model) initScreen() tea.Msg {
 	return initMsg{}
 }
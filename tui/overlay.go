@@ -0,0 +1,37 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// placeOverlay draws fg over bg with its top left corner at column x and
+// row y, keeping what bg shows around it
+func placeOverlay(x, y int, fg, bg string) string {
+	bgLines := strings.Split(bg, "\n")
+	for i, line := range strings.Split(fg, "\n") {
+		row := y + i
+		if row < 0 || row >= len(bgLines) {
+			continue
+		}
+		under := bgLines[row]
+		if pad := x - ansi.StringWidth(under); pad > 0 {
+			under += strings.Repeat(" ", pad)
+		}
+		bgLines[row] = ansi.Truncate(under, x, "") + line + ansi.TruncateLeft(under, x+lipgloss.Width(line), "")
+	}
+	return strings.Join(bgLines, "\n")
+}
+
+// centerOverlay draws fg over bg centred across the screen, near its top
+// so that the box stays put while it grows and shrinks. It goes by the
+// screen size rather than bg's, which can hold lines wider than the screen,
+// and moves the box up and left as far as it takes to fit.
+func (m Model) centerOverlay(fg, bg string) string {
+	width, height := lipgloss.Size(fg)
+	x := (m.ctx.ScreenWidth - width) / 2
+	y := min(m.ctx.ScreenHeight/8, m.ctx.ScreenHeight-height)
+	return placeOverlay(max(0, x), max(0, y), fg, bg)
+}
@@ -0,0 +1,114 @@
+package tui
+
+import (
+	"strconv"
+	"strings"
+	"tuidoo/config"
+	"tuidoo/services"
+	"tuidoo/tui/components/palette"
+
+	tea "github.com/charmbracelet/bubbletea"
+	log "github.com/charmbracelet/log"
+)
+
+// paletteRecentLimit is how many recent picks the palette remembers
+const paletteRecentLimit = 20
+
+// paletteLoadedMsg carries the projects, lists and tasks of the active
+// profile for the palette, which opens once they arrive
+type paletteLoadedMsg struct {
+	Items []palette.Item
+}
+
+// paletteHistoryFile keeps the recent picks of a profile, since projects,
+// lists and tasks are per profile
+func paletteHistoryFile(profile string) string {
+	return config.HistoryFile(profile + "-palette")
+}
+
+// openPalette loads the records of the active profile and opens the palette
+// over them and every registered command
+func (m *Model) openPalette() tea.Cmd {
+	items := make([]palette.Item, 0, len(commands))
+	for _, c := range commands {
+		items = append(items, palette.Item{Kind: palette.KindCommand, ID: c.ID, Title: c.Title, Hint: c.hint()})
+	}
+
+	sc := m.ctx.Services
+	return func() tea.Msg {
+		return paletteLoadedMsg{Items: append(items, recordItems(sc)...)}
+	}
+}
+
+// recordItems lists the projects, lists and tasks; any that fail to load
+// are left out of the palette
+func recordItems(sc *services.ServiceCollection) []palette.Item {
+	var items []palette.Item
+
+	projects, err := sc.ProjectService.GetAll(false)
+	if err != nil {
+		log.Warn("Palette without projects", "err", err)
+	}
+	for _, p := range projects {
+		items = append(items, palette.Item{Kind: palette.KindProject, ID: strconv.FormatUint(uint64(p.ID), 10), Title: p.Name})
+	}
+
+	lists, err := sc.ToDoListService.GetAll(false)
+	if err != nil {
+		log.Warn("Palette without lists", "err", err)
+	}
+	for _, l := range lists {
+		items = append(items, palette.Item{Kind: palette.KindList, ID: strconv.FormatUint(uint64(l.ID), 10), Title: l.Name})
+	}
+
+	todos, err := sc.ToDoService.GetAll(true)
+	if err != nil {
+		log.Warn("Palette without tasks", "err", err)
+	}
+	for _, t := range todos {
+		var where []string
+		for _, name := range []string{t.Project.Name, t.ToDoList.Name} {
+			if name != "" {
+				where = append(where, name)
+			}
+		}
+		if t.Done {
+			where = append(where, "done")
+		}
+		items = append(items, palette.Item{Kind: palette.KindTask, ID: strconv.FormatUint(uint64(t.ID), 10), Title: t.Name, Hint: strings.Join(where, " · ")})
+	}
+
+	return items
+}
+
+// runPaletteItem remembers a pick and carries it out: commands run, projects
+// and lists narrow the task list, and tasks are selected in it
+func (m *Model) runPaletteItem(item palette.Item) tea.Cmd {
+	m.paletteRecent = remember(paletteHistoryFile(m.ctx.Profile), m.paletteRecent, item.Key(), paletteRecentLimit)
+	log.Info("Palette", "pick", item.Key())
+
+	switch item.Kind {
+	case palette.KindCommand:
+		if c := findCommand(item.ID); c != nil {
+			return c.Run(m)
+		}
+
+	case palette.KindProject:
+		m.showView(ViewMain)
+		return m.todoList.SetFilter(services.ToDoFilter{Project: item.Title})
+
+	case palette.KindList:
+		m.showView(ViewMain)
+		return m.todoList.SetFilter(services.ToDoFilter{List: item.Title})
+
+	case palette.KindTask:
+		id, err := strconv.ParseUint(item.ID, 10, 64)
+		if err != nil {
+			return nil
+		}
+		m.showView(ViewMain)
+		return m.todoList.Select(uint(id))
+	}
+
+	return nil
+}
@@ -1,11 +1,18 @@
 package tui
 
 import (
+	"fmt"
 	"time"
+	"tuidoo/services"
+	"tuidoo/tui/components/cmdline"
+	"tuidoo/tui/components/footer"
+	"tuidoo/tui/components/palette"
+	"tuidoo/tui/components/profilelist"
 	"tuidoo/tui/components/themelist"
 	"tuidoo/tui/components/todoform"
 	"tuidoo/tui/components/todolist"
 	"tuidoo/tui/context"
+	"tuidoo/tui/keys"
 
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
@@ -18,10 +25,48 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds []tea.Cmd
 	)
 
+	// Sequences such as "g g" arrive as one key once complete; the forms
+	// take keys as typed
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && !m.typing() {
+		completed, ok := m.ctx.Sequencer.Feed(keyMsg)
+		if !ok {
+			// Redraw the footer when the sequence times out
+			return m, tea.Tick(keys.SequenceTimeout, func(time.Time) tea.Msg { return sequenceExpiredMsg{} })
+		}
+		msg = completed
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		log.Info("Key pressed", "key", msg.String())
 
+		// The help, the palette and the command line take every key but
+		// ctrl+c while they are open
+		if m.keyHelp.IsOpen() && msg.String() != "ctrl+c" {
+			m.keyHelp, cmd = m.keyHelp.Update(msg)
+			return m, cmd
+		}
+		if m.palette.IsOpen() && msg.String() != "ctrl+c" {
+			m.palette, cmd = m.palette.Update(msg)
+			return m, cmd
+		}
+		if m.cmdline.IsOpen() && msg.String() != "ctrl+c" {
+			m.cmdline, cmd = m.cmdline.Update(msg)
+			return m, cmd
+		}
+
+		// The rule form takes every key but ctrl+c
+		if m.currentView == ViewRules && !m.focusedOnMenu && m.ruleList.Editing() && msg.String() != "ctrl+c" {
+			m.ruleList, cmd = m.ruleList.Update(msg)
+			return m, cmd
+		}
+
+		// The task form takes every key but ctrl+c; escape leaves it
+		if m.currentView == ViewTodoEdit && !m.focusedOnMenu && msg.String() != "ctrl+c" && !key.Matches(msg, m.keys.Escape) {
+			m.todoForm, cmd = m.todoForm.Update(msg)
+			return m, cmd
+		}
+
 		// Global quit
 		if key.Matches(msg, m.keys.Quit) {
 			return m, tea.Quit
@@ -33,7 +78,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.currentView = ViewMain
 				m.focusedOnMenu = false
 				return m, nil
-			} else if m.currentView == ViewThemes {
+			} else if m.currentView == ViewThemes || m.currentView == ViewProfiles || m.currentView == ViewRules {
 				m.currentView = ViewMain
 				m.focusedOnMenu = false
 				return m, nil
@@ -51,6 +96,36 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.focusedOnMenu = false
 			return m, nil
 
+		case key.Matches(msg, m.keys.SwitchProfile):
+			if m.currentView == ViewProfiles {
+				m.currentView = ViewMain
+			} else {
+				m.profileList.Refresh()
+				m.currentView = ViewProfiles
+			}
+			m.focusedOnMenu = false
+			return m, nil
+
+		case key.Matches(msg, m.keys.ViewRules):
+			if m.currentView == ViewRules {
+				m.currentView = ViewMain
+				m.focusedOnMenu = false
+				return m, nil
+			}
+			m.currentView = ViewRules
+			m.focusedOnMenu = false
+			return m, m.ruleList.Load()
+
+		case key.Matches(msg, m.keys.Palette):
+			return m, m.openPalette()
+
+		case key.Matches(msg, m.keys.Help):
+			m.openHelp()
+			return m, nil
+
+		case key.Matches(msg, m.keys.Command):
+			return m, m.cmdline.Open(m.commandHistory)
+
 		case key.Matches(msg, m.keys.Tab):
 			m.focusedOnMenu = !m.focusedOnMenu
 			return m, nil
@@ -70,6 +145,48 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.todoList, cmd = m.todoList.Update(msg)
 		cmds = append(cmds, cmd)
 
+	case todolist.TodoChangedMsg, todolist.TodoRefreshedMsg, todolist.TodosStaleMsg:
+		m.todoList, cmd = m.todoList.Update(msg)
+		return m, cmd
+
+	case paletteLoadedMsg:
+		m.palette.Open(msg.Items, m.paletteRecent)
+		return m, nil
+
+	case palette.SelectedMsg:
+		return m, m.runPaletteItem(msg.Item)
+
+	case cmdline.SubmittedMsg:
+		cmd, err := m.runCommandLine(msg.Line)
+		if err != nil {
+			m.cmdline.Fail(err, m.commandHistory)
+			return m, nil
+		}
+		m.cmdline.Close()
+		return m, cmd
+
+	case footer.StatusMsg:
+		m.footer, cmd = m.footer.Update(msg)
+		return m, cmd
+
+	case configCheckMsg:
+		return m, m.onConfigCheck()
+
+	case configLoadedMsg:
+		return m, m.onConfigLoaded(msg)
+
+	case themeChangedMsg:
+		if msg.ThemeID != m.ctx.ThemeManager.GetCurrentTheme().ID {
+			if err := m.ctx.ThemeManager.SetTheme(msg.ThemeID); err == nil {
+				m.refreshTheme()
+			}
+		}
+		return m, nil
+
+	case todolist.NewTodoMsg:
+		m.newTodo()
+		return m, nil
+
 	case todolist.TodoSelectedMsg:
 		m.selectedTodo = msg.Todo
 		m.currentView = ViewTodoEdit
@@ -81,13 +198,28 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.applyTheme(msg.ThemeName)
 		return m, nil
 
-	case todoform.TodoSavedMsg:
+	case profilelist.ProfileChangedMsg:
+		if err := m.switchProfile(msg.Profile); err != nil {
+			log.Error("Profile switch failed", "profile", msg.Profile, "err", err)
+			return m, nil
+		}
 		m.currentView = ViewMain
 		m.focusedOnMenu = false
 		return m, m.todoList.FetchTodos()
 
+	case todoform.TodoSavedMsg:
+		m.currentView = ViewMain
+		m.focusedOnMenu = false
+		if msg.Created {
+			return m, m.todoList.SelectNew(msg.Todo)
+		}
+		return m, nil
+
 	case TaskFinishedMsg:
 		m.handleTaskFinished(msg)
+
+	case sequenceExpiredMsg:
+		return m, nil
 	}
 
 	// Update focused component
@@ -101,12 +233,22 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "main":
 				m.currentView = ViewMain
 				m.focusedOnMenu = false
+			case "new":
+				m.newTodo()
 			case "themes":
 				m.currentView = ViewThemes
 				m.focusedOnMenu = false
 			case "projects":
 				m.currentView = ViewProjects
 				m.focusedOnMenu = false
+			case "profiles":
+				m.profileList.Refresh()
+				m.currentView = ViewProfiles
+				m.focusedOnMenu = false
+			case "rules":
+				m.currentView = ViewRules
+				m.focusedOnMenu = false
+				cmds = append(cmds, m.ruleList.Load())
 			}
 			m.menu.ClearAction()
 		}
@@ -120,6 +262,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.themeList, cmd = m.themeList.Update(msg)
 			cmds = append(cmds, cmd)
 
+		case ViewProfiles:
+			m.profileList, cmd = m.profileList.Update(msg)
+			cmds = append(cmds, cmd)
+
+		case ViewRules:
+			m.ruleList, cmd = m.ruleList.Update(msg)
+			cmds = append(cmds, cmd)
+
 		case ViewTodoEdit:
 			m.todoForm, cmd = m.todoForm.Update(msg)
 			cmds = append(cmds, cmd)
@@ -129,27 +279,105 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	m.footer, cmd = m.footer.Update(msg)
 	cmds = append(cmds, cmd)
 
+	// Keep the cursor of the command line blinking
+	m.cmdline, cmd = m.cmdline.Update(msg)
+	cmds = append(cmds, cmd)
+
 	return m, tea.Batch(cmds...)
 }
 
+// sequenceExpiredMsg redraws the footer once a started key sequence timed out
+type sequenceExpiredMsg struct{}
+
+// typing reports whether a form has the keyboard
+func (m Model) typing() bool {
+	return m.palette.IsOpen() || m.cmdline.IsOpen() || m.currentView == ViewTodoEdit ||
+		m.currentView == ViewRules && !m.focusedOnMenu && m.ruleList.Editing()
+}
+
+const (
+	// headerHeight is the logo with its padding
+	headerHeight = 8
+	// chromeHeight is the footer and the borders of menu and content
+	chromeHeight = 7
+	// chromeWidth is the borders and padding beside the menu and content
+	chromeWidth = 7
+)
+
 func (m *Model) onWindowSizeChanged(msg tea.WindowSizeMsg) {
 	m.ctx.ScreenWidth = msg.Width
 	m.ctx.ScreenHeight = msg.Height
-	m.ctx.MainContentHeight = msg.Height - 15 // Header + footer
-	m.ctx.MainContentWidth = msg.Width - 35   // Menu width
+	m.layout()
+}
+
+// layout sizes the content area from the screen and ui.layout
+func (m *Model) layout() {
+	layout := m.ctx.Config.UI.Layout
+	m.ctx.MainContentHeight = m.ctx.ScreenHeight - chromeHeight
+	if layout.ShowHeader() {
+		m.ctx.MainContentHeight -= headerHeight
+	}
+	m.ctx.MainContentWidth = m.ctx.ScreenWidth - layout.MenuColumns() - chromeWidth
 }
 
 func (m *Model) applyTheme(themeName string) {
 	m.ctx.ThemeManager.SetTheme(themeName)
 	m.ctx.Services.SettingsService.SetActiveTheme(themeName)
+	m.refreshTheme()
+}
 
-	// Refresh all components
+// refreshTheme restyles all components with the current theme
+func (m *Model) refreshTheme() {
 	m.todoList.ApplyTheme()
 	m.themeList.ApplyTheme()
 	m.menu.ApplyTheme()
 	m.footer.ApplyTheme()
 }
 
+// switchProfile reconnects the services to another profile's database and
+// applies that profile's theme, unless ui.theme pins one
+func (m *Model) switchProfile(profile string) error {
+	if profile == m.ctx.Profile {
+		return nil
+	}
+
+	sc, err := services.NewServiceCollection(m.ctx.Config.ProfileSelection(profile))
+	if err != nil {
+		return fmt.Errorf("failed to open profile %q: %w", profile, err)
+	}
+
+	previous := m.ctx.Services
+	restartRPC := m.ctx.RPC != nil
+	m.unsubscribeEvents()
+	m.stopRPC()
+
+	m.ctx.Services = sc
+	m.ctx.Profile = profile
+	m.paletteRecent = loadHistory(paletteHistoryFile(profile))
+
+	if err := previous.Close(); err != nil {
+		log.Warn("Failed to close previous profile", "err", err)
+	}
+
+	if restartRPC {
+		m.StartRPC()
+	}
+	m.subscribeEvents()
+
+	themeID := m.ctx.Config.UI.Theme
+	if themeID == "" {
+		themeID, _ = sc.SettingsService.GetActiveTheme()
+	}
+	if themeID != "" {
+		if err := m.ctx.ThemeManager.SetTheme(themeID); err == nil {
+			m.refreshTheme()
+		}
+	}
+
+	log.Info("Switched profile", "profile", profile)
+	return nil
+}
+
 func (m *Model) handleTaskFinished(msg TaskFinishedMsg) {
 	task, ok := m.tasks[msg.TaskId]
 	if ok {
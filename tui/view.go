@@ -21,13 +21,11 @@ func (m Model) View() string {
 		Align(lipgloss.Center).
 		Padding(1, 0)
 
-	header := headerStyle.Render(m.ctx.ThemeManager.CreateHeader())
-
 	// Menu styling
 	menuStyle := lipgloss.NewStyle().
 		BorderStyle(lipgloss.RoundedBorder()).
 		BorderForeground(context.TcellToLipgloss(theme.Colors.Border)).
-		Width(28).
+		Width(m.ctx.Config.UI.Layout.MenuColumns()).
 		Height(m.ctx.MainContentHeight)
 
 	// Content styling
@@ -49,14 +47,16 @@ func (m Model) View() string {
 	case ViewTodoEdit:
 		content = m.todoForm.View()
 
+	case ViewProfiles:
+		content = m.profileList.View()
+
+	case ViewRules:
+		content = m.ruleList.View()
+
 	case ViewProjects:
 		content = "Projects view - Coming soon!"
 	}
 
-	// Highlight focused component
-	menuBorder := lipgloss.RoundedBorder()
-	contentBorder := lipgloss.RoundedBorder()
-
 	if m.focusedOnMenu {
 		menuStyle = menuStyle.BorderForeground(context.TcellToLipgloss(theme.Colors.Primary))
 	} else {
@@ -71,11 +71,23 @@ func (m Model) View() string {
 	)
 
 	var b strings.Builder
-	b.WriteString(header)
-	b.WriteString("\n")
+	if m.ctx.Config.UI.Layout.ShowHeader() {
+		b.WriteString(headerStyle.Render(m.ctx.ThemeManager.CreateHeader()))
+		b.WriteString("\n")
+	}
 	b.WriteString(mainLayout)
 	b.WriteString("\n")
-	b.WriteString(m.footer.View())
+	if m.cmdline.IsOpen() {
+		b.WriteString(m.cmdline.View())
+	} else {
+		b.WriteString(m.footer.View(m.screenHelp()))
+	}
 
+	if m.keyHelp.IsOpen() {
+		return m.keyHelp.View()
+	}
+	if m.palette.IsOpen() {
+		return m.centerOverlay(m.palette.View(), b.String())
+	}
 	return b.String()
 }